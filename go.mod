@@ -5,5 +5,6 @@ go 1.13
 require (
 	github.com/ldsec/lattigo/v2 v2.3.0
 	github.com/stretchr/testify v1.7.0
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 
 )
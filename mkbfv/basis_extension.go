@@ -45,6 +45,22 @@ func NewFastBasisExtender(ringP, ringQ, ringQMul, ringR *ring.Ring) (conv *FastB
 	return conv
 }
 
+// ShallowCopy creates a shallow copy of this basis extender in which the read-only data-structures are
+// shared with the receiver.
+func (conv *FastBasisExtender) ShallowCopy() *FastBasisExtender {
+	return &FastBasisExtender{
+		ringP:        conv.ringP,
+		ringQ:        conv.ringQ,
+		ringQMul:     conv.ringQMul,
+		ringR:        conv.ringR,
+		convQQMul:    conv.convQQMul.ShallowCopy(),
+		polypoolQ:    conv.ringQ.NewPoly(),
+		polypoolQMul: conv.ringQMul.NewPoly(),
+		polypoolR:    conv.ringR.NewPoly(),
+		mFormQMul:    conv.mFormQMul,
+	}
+}
+
 // assume input and output are in InvNTTForm
 func (conv *FastBasisExtender) ModUpQtoR(polyQ, polyR *ring.Poly) {
 
@@ -0,0 +1,129 @@
+package mkbfv
+
+import (
+	"mk-lattigo/mkrlwe"
+)
+
+// checkCiphertexts returns an error of kind mkrlwe.ErrInvalidInput if one of cts is not a ciphertext
+// of the parameters of the evaluator, or of kind mkrlwe.ErrLevelMismatch if it is not at the maximum level.
+func (eval *Evaluator) checkCiphertexts(cts ...*Ciphertext) error {
+	for _, ct := range cts {
+		if ct == nil {
+			return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: nil ciphertext")
+		}
+
+		if err := mkrlwe.CheckCiphertext(eval.params.Parameters, ct.Ciphertext); err != nil {
+			return err
+		}
+
+		if ct.Level() != eval.params.MaxLevel() {
+			return mkrlwe.NewError(mkrlwe.ErrLevelMismatch, "invalid input: ciphertext is at level %d instead of %d", ct.Level(), eval.params.MaxLevel())
+		}
+	}
+
+	return nil
+}
+
+// extended returns ct tagged with the epochs it is extended to by the evaluator, see mkrlwe.JoinKeySet.ExtendEpochs.
+func (eval *Evaluator) extended(ct *Ciphertext) *mkrlwe.Ciphertext {
+	return eval.jkSet.ExtendEpochs(ct.Ciphertext)
+}
+
+// checkBinary returns the error of AddNew and SubNew.
+func (eval *Evaluator) checkBinary(opname string, op0, op1 *Ciphertext) error {
+	if err := eval.checkCiphertexts(op0, op1); err != nil {
+		return err
+	}
+
+	return mkrlwe.CheckEpochs(opname, eval.extended(op0), eval.extended(op1))
+}
+
+// checkMulRelin returns the error of MulRelinNew.
+func (eval *Evaluator) checkMulRelin(op0, op1 *Ciphertext, rlkSet *RelinearizationKeySet) error {
+	params := eval.params.Parameters
+
+	if err := eval.checkCiphertexts(op0, op1); err != nil {
+		return err
+	}
+
+	ct0, ct1 := eval.extended(op0), eval.extended(op1)
+
+	if err := mkrlwe.CheckEpochs("MulRelin", ct0, ct1); err != nil {
+		return err
+	}
+
+	if _, in := params.CRS[-1]; !in {
+		return mkrlwe.NewError(mkrlwe.ErrMissingCRS, "Cannot MulRelin: CRS for idx -1 is not generated")
+	}
+
+	if rlkSet == nil {
+		return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "Cannot MulRelin: nil relinearization key set")
+	}
+
+	for _, id := range ct0.IDSet().Union(ct1.IDSet()).Slice() {
+		rlk, err := rlkSet.LookupRelinearizationKey(id)
+		if err != nil {
+			return err
+		}
+
+		for _, rlkHalf := range rlk.Value {
+			if rlkHalf == nil {
+				return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "Cannot MulRelin: relinearization key of id %s does not match the parameters", id)
+			}
+
+			for _, swk := range rlkHalf.Value {
+				if mkrlwe.CheckSwitchingKey(params, swk) != nil {
+					return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "Cannot MulRelin: relinearization key of id %s does not match the parameters", id)
+				}
+			}
+		}
+
+		ct := ct0
+		if _, in := ct0.Value[id]; !in {
+			ct = ct1
+		}
+
+		if err := mkrlwe.CheckKeyEpoch("MulRelin", ct, id, rlk.Epoch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkRotate returns the error of RotateNew.
+func (eval *Evaluator) checkRotate(ct0 *Ciphertext, rotidx int, rkSet *mkrlwe.RotationKeySet) error {
+	if err := eval.checkCiphertexts(ct0); err != nil {
+		return err
+	}
+
+	// normalize rotidx
+	for rotidx >= eval.params.N()/2 {
+		rotidx -= eval.params.N() / 2
+	}
+
+	for rotidx < 0 {
+		rotidx += eval.params.N() / 2
+	}
+
+	if rotidx == 0 {
+		return nil
+	}
+
+	ct := eval.extended(ct0)
+
+	if _, in := eval.params.CRS[rotidx]; in {
+		return eval.ksw.CheckRotate(ct, rotidx, rkSet, ct0.Level())
+	}
+
+	for k := 1; rotidx > 0; k *= 2 {
+		if rotidx%2 != 0 {
+			if err := eval.ksw.CheckRotate(ct, k, rkSet, ct0.Level()); err != nil {
+				return err
+			}
+		}
+		rotidx /= 2
+	}
+
+	return nil
+}
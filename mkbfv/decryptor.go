@@ -3,6 +3,8 @@ package mkbfv
 import "mk-lattigo/mkrlwe"
 import "github.com/ldsec/lattigo/v2/ring"
 import "github.com/ldsec/lattigo/v2/bfv"
+import "github.com/ldsec/lattigo/v2/rlwe"
+import "github.com/ldsec/lattigo/v2/utils"
 
 type Decryptor struct {
 	*mkrlwe.Decryptor
@@ -13,10 +15,20 @@ type Decryptor struct {
 
 // NewDecryptor instantiates a Decryptor for the CKKS scheme.
 func NewDecryptor(params Parameters) *Decryptor {
+	prng, err := utils.NewPRNG()
+	if err != nil {
+		panic(err)
+	}
+
+	return NewDecryptorWithPRNG(params, prng)
+}
+
+// NewDecryptorWithPRNG instantiates a Decryptor for the BFV scheme, whose noise sampler reads from the given PRNG.
+func NewDecryptorWithPRNG(params Parameters, prng utils.PRNG) *Decryptor {
 	bfvParams, _ := bfv.NewParameters(params.Parameters.Parameters, params.T())
 
 	ret := new(Decryptor)
-	ret.Decryptor = mkrlwe.NewDecryptor(params.Parameters)
+	ret.Decryptor = mkrlwe.NewDecryptorWithPRNG(params.Parameters, prng)
 	ret.encoder = bfv.NewEncoder(bfvParams)
 	ret.params = params
 	ret.ptxtPool = bfv.NewPlaintext(bfvParams)
@@ -24,14 +36,106 @@ func NewDecryptor(params Parameters) *Decryptor {
 	return ret
 }
 
+// SmudgingBound returns the smudging bound of ct: its noise is given by est, and it must stay below Delta / 2 = floor(Q / T) / 2.
+// If est is nil, the noise of ct is unknown and no smudging noise fits in the bound, see mkrlwe.Decryptor.SmudgingBound.
+func (dec *Decryptor) SmudgingBound(ct *Ciphertext, est *mkrlwe.NoiseEstimate) mkrlwe.SmudgingBound {
+	bound := dec.Decryptor.SmudgingBound(ct.Ciphertext, est)
+	bound.LogBudget = dec.params.logHalfDelta()
+
+	return bound
+}
+
+// FreshSmudgingBound is SmudgingBound for a fresh encryption under the public key of each of the ids of ct.
+// It underestimates the noise of a ciphertext which went through evaluation.
+func (dec *Decryptor) FreshSmudgingBound(ct *Ciphertext) mkrlwe.SmudgingBound {
+	bound := dec.Decryptor.FreshSmudgingBound(ct.Ciphertext)
+	bound.LogBudget = dec.params.logHalfDelta()
+
+	return bound
+}
+
 // PartialDecrypt partially decrypts the ct with single secretkey sk and update result inplace
+// As the noise of ct is unknown, it panics with an error of kind mkrlwe.ErrNoiseBudget unless the smudging is disabled:
+// the smudged partial decryptions are given by PartialDecryptEst, or by PartialDecryptFresh for a fresh ciphertext.
 func (dec *Decryptor) PartialDecrypt(ct *Ciphertext, sk *mkrlwe.SecretKey) {
-	dec.Decryptor.PartialDecrypt(ct.Ciphertext, sk)
+	if err := dec.TryPartialDecrypt(ct, sk); err != nil {
+		panic(err)
+	}
 }
 
-// PartialDecrypt partially decrypts the ct with single secretkey sk and update result inplace
+// TryPartialDecrypt is PartialDecrypt returning an error instead of panicking on an invalid request,
+// in which case ct is left unchanged.
+func (dec *Decryptor) TryPartialDecrypt(ct *Ciphertext, sk *mkrlwe.SecretKey) error {
+	return dec.PartialDecryptEst(ct, sk, nil)
+}
+
+// PartialDecryptEst is TryPartialDecrypt with the smudging noise sized for a ciphertext of noise estimate est, see SmudgingBound.
+func (dec *Decryptor) PartialDecryptEst(ct *Ciphertext, sk *mkrlwe.SecretKey, est *mkrlwe.NoiseEstimate) error {
+	if ct == nil {
+		return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: nil ciphertext")
+	}
+
+	if err := dec.Decryptor.CheckPartialDecrypt(ct.Ciphertext, sk); err != nil {
+		return err
+	}
+
+	return dec.Decryptor.PartialDecryptBound(ct.Ciphertext, sk, dec.SmudgingBound(ct, est))
+}
+
+// PartialDecryptFresh is PartialDecryptEst with the smudging noise sized by FreshSmudgingBound. It must only be used on a fresh encryption,
+// as it under-smudges the partial decryptions of a ciphertext which went through evaluation.
+func (dec *Decryptor) PartialDecryptFresh(ct *Ciphertext, sk *mkrlwe.SecretKey) error {
+	if ct == nil {
+		return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: nil ciphertext")
+	}
+
+	if err := dec.Decryptor.CheckPartialDecrypt(ct.Ciphertext, sk); err != nil {
+		return err
+	}
+
+	return dec.Decryptor.PartialDecryptBound(ct.Ciphertext, sk, dec.FreshSmudgingBound(ct))
+}
+
+// PartialDecryptIP replaces the component of ct under sk.ID by its product with sk inplace.
+// As the noise of ct is unknown, it panics with an error of kind mkrlwe.ErrNoiseBudget unless the smudging is disabled:
+// the smudged partial decryptions are given by PartialDecryptIPEst, or by PartialDecryptIPFresh for a fresh ciphertext.
 func (dec *Decryptor) PartialDecryptIP(ct *Ciphertext, sk *mkrlwe.SecretKey) {
-	dec.Decryptor.PartialDecryptIP(ct.Ciphertext, sk)
+	if err := dec.TryPartialDecryptIP(ct, sk); err != nil {
+		panic(err)
+	}
+}
+
+// TryPartialDecryptIP is PartialDecryptIP returning an error instead of panicking on an invalid request,
+// in which case ct is left unchanged.
+func (dec *Decryptor) TryPartialDecryptIP(ct *Ciphertext, sk *mkrlwe.SecretKey) error {
+	return dec.PartialDecryptIPEst(ct, sk, nil)
+}
+
+// PartialDecryptIPEst is TryPartialDecryptIP with the smudging noise sized for a ciphertext of noise estimate est, see SmudgingBound.
+func (dec *Decryptor) PartialDecryptIPEst(ct *Ciphertext, sk *mkrlwe.SecretKey, est *mkrlwe.NoiseEstimate) error {
+	if ct == nil {
+		return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: nil ciphertext")
+	}
+
+	if err := dec.Decryptor.CheckPartialDecrypt(ct.Ciphertext, sk); err != nil {
+		return err
+	}
+
+	return dec.Decryptor.PartialDecryptIPBound(ct.Ciphertext, sk, dec.SmudgingBound(ct, est))
+}
+
+// PartialDecryptIPFresh is PartialDecryptIPEst with the smudging noise sized by FreshSmudgingBound. It must only be used on a fresh encryption,
+// as it under-smudges the partial decryptions of a ciphertext which went through evaluation.
+func (dec *Decryptor) PartialDecryptIPFresh(ct *Ciphertext, sk *mkrlwe.SecretKey) error {
+	if ct == nil {
+		return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: nil ciphertext")
+	}
+
+	if err := dec.Decryptor.CheckPartialDecrypt(ct.Ciphertext, sk); err != nil {
+		return err
+	}
+
+	return dec.Decryptor.PartialDecryptIPBound(ct.Ciphertext, sk, dec.FreshSmudgingBound(ct))
 }
 
 // Decrypt decrypts the ciphertext with given secretkey set and write the result in ptOut.
@@ -39,49 +143,43 @@ func (dec *Decryptor) PartialDecryptIP(ct *Ciphertext, sk *mkrlwe.SecretKey) {
 // Output domain will match plaintext.Value.IsNTT value.
 func (dec *Decryptor) Decrypt(ciphertext *Ciphertext, skSet *mkrlwe.SecretKeySet) (msg *Message) {
 
-	ctTmp := ciphertext.CopyNew()
-
-	idset := ctTmp.IDSet()
-	for _, sk := range skSet.Value {
-		if idset.Has(sk.ID) {
-			dec.PartialDecrypt(ctTmp, sk)
-		}
-	}
+	dec.Decryptor.Decrypt(ciphertext.Ciphertext, skSet, dec.ptxtPool.Plaintext)
 
 	msg = NewMessage(dec.params)
-	dec.ptxtPool.Value = ctTmp.Value["0"]
 	dec.encoder.DecodeInt(dec.ptxtPool, msg.Value)
 
 	return
 }
 
+// TryDecrypt is Decrypt returning an error instead of panicking on an invalid request,
+// e.g. of kind mkrlwe.ErrMissingKey if skSet lacks the secret key of a component of the ciphertext.
+func (dec *Decryptor) TryDecrypt(ciphertext *Ciphertext, skSet *mkrlwe.SecretKeySet) (msg *Message, err error) {
+	if ciphertext == nil {
+		return nil, mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: nil ciphertext")
+	}
+
+	if err = dec.Decryptor.CheckDecrypt(ciphertext.Ciphertext, skSet); err != nil {
+		return nil, err
+	}
+
+	return dec.Decrypt(ciphertext, skSet), nil
+}
+
 func (dec *Decryptor) DecryptSk(ciphertext *Ciphertext, sk *mkrlwe.SecretKey) (msg *Message) {
-	
-	ctTmp := ciphertext.CopyNew()
 
-	dec.PartialDecrypt(ctTmp, sk)
+	dec.Decryptor.DecryptSk(ciphertext.Ciphertext, sk, dec.ptxtPool.Plaintext)
 
 	msg = NewMessage(dec.params)
-	dec.ptxtPool.Value = ctTmp.Value["0"]
 	dec.encoder.DecodeInt(dec.ptxtPool, msg.Value)
 
 	return
 }
 
-// Decrypt decrypts the ciphertext with given secretkey set and write the result in ptOut.
-// The level of the output plaintext is min(ciphertext.Level(), plaintext.Level())
-// Output domain will match plaintext.Value.IsNTT value.
+// DecryptToPtxt decrypts the ciphertext with given secretkey set and returns the result in a newly created polynomial.
 func (dec *Decryptor) DecryptToPtxt(ciphertext *Ciphertext, skSet *mkrlwe.SecretKeySet) *ring.Poly {
 
-	ctTmp := ciphertext.CopyNew()
-
-	idset := ctTmp.IDSet()
-	for _, sk := range skSet.Value {
-		if idset.Has(sk.ID) {
-			dec.PartialDecrypt(ctTmp, sk)
-		}
-	}
-
-	return ctTmp.Value["0"]
+	ptxt := &rlwe.Plaintext{Value: dec.params.RingQ().NewPoly()}
+	dec.Decryptor.Decrypt(ciphertext.Ciphertext, skSet, ptxt)
 
+	return ptxt.Value
 }
@@ -23,6 +23,23 @@ func (ct *Ciphertext) CopyNew() *Ciphertext {
 	return &Ciphertext{ct.Ciphertext.CopyNew()}
 }
 
+// SeededCiphertext is a secret-key encryption whose uniform component is represented by its seed,
+// see mkrlwe.SeededCiphertext. It is created with Encryptor.EncryptSkMsgNewSeeded.
+type SeededCiphertext struct {
+	*mkrlwe.SeededCiphertext
+}
+
+// Expand returns a new Ciphertext equal to the secret-key encryption represented by ct,
+// with its uniform component expanded from the seed.
+func (ct *SeededCiphertext) Expand(params Parameters) (*Ciphertext, error) {
+	el, err := ct.SeededCiphertext.Expand(params.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Ciphertext{el}, nil
+}
+
 type Message struct {
 	Value []int64
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/ldsec/lattigo/v2/bfv"
 	"github.com/ldsec/lattigo/v2/rlwe"
+	"github.com/ldsec/lattigo/v2/utils"
 )
 
 type Encryptor struct {
@@ -16,10 +17,20 @@ type Encryptor struct {
 
 // NewEncryptor instantiates a new Encryptor for the BFV scheme.
 func NewEncryptor(params Parameters) *Encryptor {
+	prng, err := utils.NewPRNG()
+	if err != nil {
+		panic(err)
+	}
+
+	return NewEncryptorWithPRNG(params, prng)
+}
+
+// NewEncryptorWithPRNG instantiates a new Encryptor for the BFV scheme, whose samplers read from the given PRNG.
+func NewEncryptorWithPRNG(params Parameters, prng utils.PRNG) *Encryptor {
 	bfvParams, _ := bfv.NewParameters(params.Parameters.Parameters, params.T())
 
 	ret := new(Encryptor)
-	ret.Encryptor = mkrlwe.NewEncryptor(params.Parameters)
+	ret.Encryptor = mkrlwe.NewEncryptorWithPRNG(params.Parameters, prng)
 	ret.encoder = bfv.NewEncoder(bfvParams)
 	ret.params = params
 	ret.ptxtPool = bfv.NewPlaintext(bfvParams)
@@ -31,11 +42,11 @@ func NewEncryptor(params Parameters) *Encryptor {
 // The encryption algorithm depends on how the receiver encryptor was initialized (see
 // NewEncryptor and NewFastEncryptor).
 func (enc *Encryptor) EncryptPtxt(plaintext *bfv.Plaintext, pk *mkrlwe.PublicKey, ctOut *Ciphertext) {
-	enc.Encryptor.Encrypt(&rlwe.Plaintext{Value: plaintext.Value}, pk, &mkrlwe.Ciphertext{Value: ctOut.Value})
+	enc.Encryptor.Encrypt(&rlwe.Plaintext{Value: plaintext.Value}, pk, ctOut.Ciphertext)
 }
 
 func (enc *Encryptor) EncryptSkPtxt(plaintext *bfv.Plaintext, sk *mkrlwe.SecretKey, ctOut *Ciphertext) {
-	enc.Encryptor.EncryptSk(&rlwe.Plaintext{Value: plaintext.Value}, sk, &mkrlwe.Ciphertext{Value: ctOut.Value})
+	enc.Encryptor.EncryptSk(&rlwe.Plaintext{Value: plaintext.Value}, sk, ctOut.Ciphertext)
 }
 
 // EncryptMsg encode message and then encrypts the input plaintext and write the result on ctOut. The encryption
@@ -87,3 +98,14 @@ func (enc *Encryptor) EncryptSkMsgNew(msg *Message, sk *mkrlwe.SecretKey) (ctOut
 
 	return
 }
+
+// EncryptSkMsgNewSeeded encode message and then encrypts it with sk in a newly created SeededCiphertext,
+// whose uniform component is represented by its seed.
+func (enc *Encryptor) EncryptSkMsgNewSeeded(msg *Message, sk *mkrlwe.SecretKey) (ctOut *SeededCiphertext) {
+	ctOut = &SeededCiphertext{mkrlwe.NewSeededCiphertext(enc.params.Parameters, enc.params.MaxLevel())}
+
+	enc.encoder.EncodeInt(msg.Value, enc.ptxtPool)
+	enc.Encryptor.EncryptSkSeeded(&rlwe.Plaintext{Value: enc.ptxtPool.Value}, sk, ctOut.SeededCiphertext)
+
+	return
+}
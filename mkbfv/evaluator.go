@@ -12,6 +12,11 @@ type Evaluator struct {
 	params Parameters
 	ksw    *KeySwitcher
 	conv   *FastBasisExtender
+	jkSet  *mkrlwe.JoinKeySet
+
+	polyRPool  [2]map[string]*ring.Poly
+	hoistPool1 [2]*mkrlwe.HoistedCiphertext
+	hoistPool2 [2]*mkrlwe.HoistedCiphertext
 }
 
 // NewEvaluator creates a new Evaluator, that can be used to do homomorphic
@@ -22,10 +27,77 @@ func NewEvaluator(params Parameters) *Evaluator {
 	eval.params = params
 	eval.ksw = NewKeySwitcher(params)
 	eval.conv = NewFastBasisExtender(params.RingP(), params.RingQ(), params.RingQMul(), params.RingR())
+	eval.newPools()
 
 	return eval
 }
 
+// ShallowCopy creates a shallow copy of this Evaluator in which all the read-only data-structures are
+// shared with the receiver and the temporary buffers are reallocated. The receiver and the returned
+// Evaluator can be used concurrently, also with shared key sets, as long as they do not write on the same ciphertexts.
+// They share the JoinKeySet too, which is only read, and can share operands from an earlier epoch, see SetJoinKeySet.
+func (eval *Evaluator) ShallowCopy() *Evaluator {
+	evalOut := new(Evaluator)
+	evalOut.params = eval.params
+	evalOut.ksw = eval.ksw.ShallowCopy()
+	evalOut.conv = eval.conv.ShallowCopy()
+	evalOut.jkSet = eval.jkSet
+	evalOut.newPools()
+
+	return evalOut
+}
+
+// newPools allocates the empty pools for the operands of the multiplications in ring R,
+// which grow with the ids of the operands.
+func (eval *Evaluator) newPools() {
+	for i := 0; i < 2; i++ {
+		eval.polyRPool[i] = make(map[string]*ring.Poly)
+		eval.hoistPool1[i] = mkrlwe.NewHoistedCiphertext()
+		eval.hoistPool2[i] = mkrlwe.NewHoistedCiphertext()
+	}
+}
+
+// poolR returns the i-th pool of ring R for the components of ct
+func (eval *Evaluator) poolR(i int, ct *Ciphertext) (ctR *mkrlwe.Ciphertext) {
+	ctR = new(mkrlwe.Ciphertext)
+	ctR.Value = make(map[string]*ring.Poly)
+
+	for _, id := range ct.IDs() {
+		if _, in := eval.polyRPool[i][id]; !in {
+			eval.polyRPool[i][id] = eval.params.RingR().NewPoly()
+		}
+		ctR.Value[id] = eval.polyRPool[i][id]
+	}
+
+	return
+}
+
+// SetJoinKeySet enables the lazy extension of ciphertexts after group updates:
+// the components of an operand from an earlier epoch are switched in place through the join keys of jkSet
+// the first time an operation touches it. Evaluators from ShallowCopy can share stale operands,
+// which are switched once, see mkrlwe.KeySwitcher.Extend. A nil jkSet disables it.
+func (eval *Evaluator) SetJoinKeySet(jkSet *mkrlwe.JoinKeySet) {
+	eval.jkSet = jkSet
+}
+
+// extend switches ct in place to the last epochs of the join keys of the evaluator and returns it.
+func (eval *Evaluator) extend(ct *Ciphertext) *Ciphertext {
+	if eval.jkSet != nil {
+		eval.ksw.Extend(ct.Ciphertext, eval.jkSet)
+	}
+
+	return ct
+}
+
+// extendPair is extend on the operands of a binary operation, which are extended once if they are the same.
+func (eval *Evaluator) extendPair(op0, op1 *Ciphertext) (*Ciphertext, *Ciphertext) {
+	ext0 := eval.extend(op0)
+	if op1 == op0 {
+		return ext0, ext0
+	}
+	return ext0, eval.extend(op1)
+}
+
 func (eval *Evaluator) newCiphertextBinary(op0, op1 *Ciphertext) (ctOut *Ciphertext) {
 	idset := op0.IDSet().Union(op1.IDSet())
 	return NewCiphertext(eval.params, idset)
@@ -33,11 +105,15 @@ func (eval *Evaluator) newCiphertextBinary(op0, op1 *Ciphertext) (ctOut *Ciphert
 
 // evaluateInPlaceBinary applies the provided function in place on el0 and el1 and returns the result in elOut.
 func (eval *Evaluator) evaluateInPlace(ct0, ct1, ctOut *Ciphertext, evaluate func(*ring.Poly, *ring.Poly, *ring.Poly)) {
+	if err := mkrlwe.CheckEpochs("evaluate", ct0.Ciphertext, ct1.Ciphertext); err != nil {
+		panic(err)
+	}
+
 	idset0 := ct0.IDSet()
 	idset1 := ct1.IDSet()
 
 	evaluate(ct0.Value["0"], ct1.Value["0"], ctOut.Value["0"])
-	for id := range ctOut.IDSet().Value {
+	for _, id := range ctOut.IDSet().Slice() {
 		if !idset0.Has(id) {
 			ctOut.Value[id].Copy(ct1.Value[id])
 		} else if !idset1.Has(id) {
@@ -46,13 +122,26 @@ func (eval *Evaluator) evaluateInPlace(ct0, ct1, ctOut *Ciphertext, evaluate fun
 			evaluate(ct0.Value[id], ct1.Value[id], ctOut.Value[id])
 		}
 	}
+
+	ctOut.MergeEpochs(ct0.Ciphertext, ct1.Ciphertext)
 }
 
 // Add adds op0 to op1 and returns the result in ctOut.
 func (eval *Evaluator) add(op0, op1 *Ciphertext, ctOut *Ciphertext) {
+	op0, op1 = eval.extendPair(op0, op1)
 	eval.evaluateInPlace(op0, op1, ctOut, eval.params.RingQ().Add)
 }
 
+// TryAddNew is AddNew returning an error instead of panicking on an invalid request,
+// e.g. operands from different epochs (mkrlwe.ErrEpochMismatch).
+func (eval *Evaluator) TryAddNew(op0, op1 *Ciphertext) (ctOut *Ciphertext, err error) {
+	if err = eval.checkBinary("AddNew", op0, op1); err != nil {
+		return nil, err
+	}
+
+	return eval.AddNew(op0, op1), nil
+}
+
 // AddNew adds op0 to op1 and returns the result in a newly created element.
 func (eval *Evaluator) AddNew(op0, op1 *Ciphertext) (ctOut *Ciphertext) {
 	ctOut = eval.newCiphertextBinary(op0, op1)
@@ -62,18 +151,29 @@ func (eval *Evaluator) AddNew(op0, op1 *Ciphertext) (ctOut *Ciphertext) {
 
 // Sub subtracts op1 from op0 and returns the result in ctOut.
 func (eval *Evaluator) sub(op0, op1 *Ciphertext, ctOut *Ciphertext) {
+	op0, op1 = eval.extendPair(op0, op1)
 
 	eval.evaluateInPlace(op0, op1, ctOut, eval.params.RingQ().Sub)
 
 	//negate polys which is not contained in op0
 	idset0 := op0.IDSet()
-	for id := range ctOut.IDSet().Value {
+	for _, id := range ctOut.IDSet().Slice() {
 		if !idset0.Has(id) {
 			eval.params.RingQ().Neg(ctOut.Value[id], ctOut.Value[id])
 		}
 	}
 }
 
+// TrySubNew is SubNew returning an error instead of panicking on an invalid request,
+// e.g. operands from different epochs (mkrlwe.ErrEpochMismatch).
+func (eval *Evaluator) TrySubNew(op0, op1 *Ciphertext) (ctOut *Ciphertext, err error) {
+	if err = eval.checkBinary("SubNew", op0, op1); err != nil {
+		return nil, err
+	}
+
+	return eval.SubNew(op0, op1), nil
+}
+
 // SubNew subtracts op1 from op0 and returns the result in a newly created element.
 func (eval *Evaluator) SubNew(op0, op1 *Ciphertext) (ctOut *Ciphertext) {
 	ctOut = eval.newCiphertextBinary(op0, op1)
@@ -82,6 +182,17 @@ func (eval *Evaluator) SubNew(op0, op1 *Ciphertext) (ctOut *Ciphertext) {
 	return
 }
 
+// TryMulRelinNew is MulRelinNew returning an error instead of panicking on an invalid request,
+// e.g. a missing relinearization key (mkrlwe.ErrMissingKey) or operands from different epochs.
+// The request is checked before the operands are extended or decomposed.
+func (eval *Evaluator) TryMulRelinNew(op0, op1 *Ciphertext, rlkSet *RelinearizationKeySet) (ctOut *Ciphertext, err error) {
+	if err = eval.checkMulRelin(op0, op1, rlkSet); err != nil {
+		return nil, err
+	}
+
+	return eval.MulRelinNew(op0, op1, rlkSet), nil
+}
+
 // MulRelinNew multiplies ct0 by ct1 with relinearization and returns the result in a newly created element.
 // The procedure will panic if either op0.Degree or op1.Degree > 1.
 // The procedure will panic if the evaluator was not created with an relinearization key.
@@ -95,6 +206,7 @@ func (eval *Evaluator) MulRelinNew(op0, op1 *Ciphertext, rlkSet *Relinearization
 // The procedure will panic if either op0.Degree or op1.Degree > 1.
 // The procedure will panic if the evaluator was not created with an relinearization key.
 func (eval *Evaluator) PrevMulRelinNew(ct0, ct1 *Ciphertext, rlkSet *mkrlwe.RelinearizationKeySet) (ctOut *Ciphertext) {
+	ct0, ct1 = eval.extendPair(ct0, ct1)
 	ctOut = eval.newCiphertextBinary(ct0, ct1)
 
 	ct0R := new(mkrlwe.Ciphertext)
@@ -102,12 +214,12 @@ func (eval *Evaluator) PrevMulRelinNew(ct0, ct1 *Ciphertext, rlkSet *mkrlwe.Reli
 	ct1R := new(mkrlwe.Ciphertext)
 	ct1R.Value = make(map[string]*ring.Poly)
 
-	for id := range ct0.Value {
+	for _, id := range ct0.IDs() {
 		ct0R.Value[id] = eval.params.RingR().NewPoly()
 		eval.conv.ModUpQtoR(ct0.Value[id], ct0R.Value[id])
 	}
 
-	for id := range ct1.Value {
+	for _, id := range ct1.IDs() {
 		ct1R.Value[id] = eval.params.RingR().NewPoly()
 		eval.conv.Rescale(ct1.Value[id], ct1R.Value[id])
 	}
@@ -122,19 +234,18 @@ func (eval *Evaluator) PrevMulRelinNew(ct0, ct1 *Ciphertext, rlkSet *mkrlwe.Reli
 // The procedure will panic if ctOut.Degree != op0.Degree + op1.Degree.
 // The procedure will panic if the evaluator was not created with an relinearization key.
 func (eval *Evaluator) mulRelin(ct0, ct1 *Ciphertext, rlkSet *RelinearizationKeySet, ctOut *Ciphertext) {
+	ct0, ct1 = eval.extendPair(ct0, ct1)
 
-	ct0R := new(mkrlwe.Ciphertext)
-	ct0R.Value = make(map[string]*ring.Poly)
-	ct1R := new(mkrlwe.Ciphertext)
-	ct1R.Value = make(map[string]*ring.Poly)
+	eval.setMulEpochs(ct0, ct1, rlkSet, ctOut)
+
+	ct0R := eval.poolR(0, ct0)
+	ct1R := eval.poolR(1, ct1)
 
-	for id := range ct0.Value {
-		ct0R.Value[id] = rlkSet.PolyRPool1[id]
+	for _, id := range ct0.IDs() {
 		eval.conv.ModUpQtoR(ct0.Value[id], ct0R.Value[id])
 	}
 
-	for id := range ct1.Value {
-		ct1R.Value[id] = rlkSet.PolyRPool2[id]
+	for _, id := range ct1.IDs() {
 		eval.conv.Rescale(ct1.Value[id], ct1R.Value[id])
 	}
 
@@ -146,41 +257,69 @@ func (eval *Evaluator) mulRelin(ct0, ct1 *Ciphertext, rlkSet *RelinearizationKey
 // The procedure will panic if ctOut.Degree != op0.Degree + op1.Degree.
 // The procedure will panic if the evaluator was not created with an relinearization key.
 func (eval *Evaluator) mulRelinHoisted(ct0, ct1 *Ciphertext, rlkSet *RelinearizationKeySet, ctOut *Ciphertext) {
+	ct0, ct1 = eval.extendPair(ct0, ct1)
 
-	ct0R := new(mkrlwe.Ciphertext)
-	ct0R.Value = make(map[string]*ring.Poly)
-	ct1R := new(mkrlwe.Ciphertext)
-	ct1R.Value = make(map[string]*ring.Poly)
+	eval.setMulEpochs(ct0, ct1, rlkSet, ctOut)
 
-	for id := range ct0.Value {
-		ct0R.Value[id] = rlkSet.PolyRPool1[id]
+	ct0R := eval.poolR(0, ct0)
+	ct1R := eval.poolR(1, ct1)
+
+	for _, id := range ct0.IDs() {
 		eval.conv.ModUpQtoR(ct0.Value[id], ct0R.Value[id])
 	}
 
-	for id := range ct1.Value {
-		ct1R.Value[id] = rlkSet.PolyRPool2[id]
+	for _, id := range ct1.IDs() {
 		eval.conv.Rescale(ct1.Value[id], ct1R.Value[id])
 	}
 
 	idset0 := ct0.IDSet()
 	idset1 := ct1.IDSet()
 
-	for id := range idset0.Value {
-		eval.ksw.DecomposeBFV(ct0.Level(), ct0R.Value[id], rlkSet.HoistPool1[0].Value[id], rlkSet.HoistPool2[0].Value[id])
+	for i, idset := range []*mkrlwe.IDSet{idset0, idset1} {
+		eval.hoistPool1[i].Reserve(eval.params.Parameters, idset)
+		eval.hoistPool2[i].Reserve(eval.params.Parameters, idset)
 	}
 
-	for id := range idset1.Value {
-		eval.ksw.DecomposeBFV(ct1.Level(), ct1R.Value[id], rlkSet.HoistPool1[1].Value[id], rlkSet.HoistPool2[1].Value[id])
+	for _, id := range idset0.Slice() {
+		eval.ksw.DecomposeBFV(ct0.Level(), ct0R.Value[id], eval.hoistPool1[0].Value[id], eval.hoistPool2[0].Value[id])
+	}
+
+	for _, id := range idset1.Slice() {
+		eval.ksw.DecomposeBFV(ct1.Level(), ct1R.Value[id], eval.hoistPool1[1].Value[id], eval.hoistPool2[1].Value[id])
 	}
 
 	eval.ksw.MulAndRelinBFVHoisted(ct0R, ct1R,
-		rlkSet.HoistPool1[0], rlkSet.HoistPool2[0],
-		rlkSet.HoistPool1[1], rlkSet.HoistPool2[1],
+		eval.hoistPool1[0], eval.hoistPool2[0],
+		eval.hoistPool1[1], eval.hoistPool2[1],
 		rlkSet, ctOut.Ciphertext)
 }
 
+// setMulEpochs checks the request of MulRelin on ct0 and ct1, including the epochs of their relinearization keys,
+// and tags ctOut with the epochs of their product.
+func (eval *Evaluator) setMulEpochs(ct0, ct1 *Ciphertext, rlkSet *RelinearizationKeySet, ctOut *Ciphertext) {
+	if err := eval.checkMulRelin(ct0, ct1, rlkSet); err != nil {
+		panic(err)
+	}
+
+	ctOut.MergeEpochs(ct0.Ciphertext, ct1.Ciphertext)
+}
+
+// TryMulPtxtNew is MulPtxtNew returning an error instead of panicking on an invalid request.
+func (eval *Evaluator) TryMulPtxtNew(ct *Ciphertext, pt *bfv.Plaintext) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct); err != nil {
+		return nil, err
+	}
+
+	if pt == nil || pt.Plaintext == nil || mkrlwe.CheckPoly(eval.params.Parameters, pt.Value, ct.Level()) != nil {
+		return nil, mkrlwe.NewError(mkrlwe.ErrInvalidInput, "Cannot MulPtxt: plaintext does not match the ciphertext")
+	}
+
+	return eval.MulPtxtNew(ct, pt), nil
+}
+
 // The procedure will panic if either op0.Degree or op1.Degree > 1.
 func (eval *Evaluator) MulPtxtNew(ct *Ciphertext, pt *bfv.Plaintext) (ctOut *Ciphertext) {
+	ct = eval.extend(ct)
 
 	ptNTT := pt
 	ctOut = NewCiphertext(eval.params, ct.IDSet())
@@ -199,26 +338,38 @@ func (eval *Evaluator) MulPtxtNew(ct *Ciphertext, pt *bfv.Plaintext) (ctOut *Cip
 
 	eval.params.RingR().MulCoeffsMontgomeryLvl(ct.Level(), ctOutR.Value["0"], ptNTT.Value, ctOutR.Value["0"])
 
-	for id := range ct.Value {
+	for _, id := range ct.IDs() {
 		eval.conv.ModUpQtoR(ct.Value[id], ctOutR.Value[id])
 		eval.params.RingQ().NTTLvl(ct.Level(), ctOutR.Value[id], ctOutR.Value[id])
 		eval.params.RingQ().MulCoeffsMontgomeryLvl(ctOutR.Level(), ctOutR.Value[id], ptNTT.Value, ctOutR.Value[id])
 		eval.params.RingQ().InvNTTLvl(ct.Level(), ctOutR.Value[id], ctOutR.Value[id])
 
 	}
+	ctOut.CopyEpochs(ct.Ciphertext)
 
 	// }
 	return
 }
 
 func (eval *Evaluator) mulPlaintextMul(ct0 *Ciphertext, ptRt *bfv.PlaintextMul, ctOut *Ciphertext) {
-	for i := range ct0.Value {
+	for _, i := range ct0.IDs() {
 		ringQ := eval.params.RingQ()
 
 		ringQ.NTT(ct0.Value[i], ctOut.Value[i])
 		ringQ.MulCoeffsMontgomeryConstant(ctOut.Value[i], ptRt.Value, ctOut.Value[i])
 		ringQ.InvNTT(ctOut.Value[i], ctOut.Value[i])
 	}
+	ctOut.CopyEpochs(ct0.Ciphertext)
+}
+
+// TryRotateNew is RotateNew returning an error instead of panicking on an invalid request,
+// e.g. a missing rotation key (mkrlwe.ErrMissingKey).
+func (eval *Evaluator) TryRotateNew(ct0 *Ciphertext, rotidx int, rkSet *mkrlwe.RotationKeySet) (ctOut *Ciphertext, err error) {
+	if err = eval.checkRotate(ct0, rotidx, rkSet); err != nil {
+		return nil, err
+	}
+
+	return eval.RotateNew(ct0, rotidx, rkSet), nil
 }
 
 // RotateNew rotates the columns of ct0 by k positions to the left, and returns the result in a newly created element.
@@ -232,6 +383,7 @@ func (eval *Evaluator) RotateNew(ct0 *Ciphertext, rotidx int, rkSet *mkrlwe.Rota
 // Rotate rotates the columns of ct0 by k positions to the left and returns the result in ctOut.
 // If the provided element is a Ciphertext, a key-switching operation is necessary and a rotation key for the specific rotation needs to be provided.
 func (eval *Evaluator) rotate(ct0 *Ciphertext, rotidx int, rkSet *mkrlwe.RotationKeySet, ctOut *Ciphertext) {
+	ct0 = eval.extend(ct0)
 
 	// normalize rotidx
 	for rotidx >= eval.params.N()/2 {
@@ -263,6 +415,20 @@ func (eval *Evaluator) rotate(ct0 *Ciphertext, rotidx int, rkSet *mkrlwe.Rotatio
 	}
 }
 
+// TryConjugateNew is ConjugateNew returning an error instead of panicking on an invalid request,
+// e.g. a missing conjugation key (mkrlwe.ErrMissingKey).
+func (eval *Evaluator) TryConjugateNew(ct0 *Ciphertext, ckSet *mkrlwe.ConjugationKeySet) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct0); err != nil {
+		return nil, err
+	}
+
+	if err = eval.ksw.CheckConjugate(eval.extended(ct0), ckSet, ct0.Level()); err != nil {
+		return nil, err
+	}
+
+	return eval.ConjugateNew(ct0, ckSet), nil
+}
+
 // ConjugateNew conjugates ct0 (which is equivalent to a row rotation) and returns the result in a newly
 // created element. If the provided element is a Ciphertext, a key-switching operation is necessary and a rotation key
 // for the row rotation needs to be provided.
@@ -275,19 +441,161 @@ func (eval *Evaluator) ConjugateNew(ct0 *Ciphertext, ckSet *mkrlwe.ConjugationKe
 // Conjugate conjugates ct0 (which is equivalent to a row rotation) and returns the result in ctOut.
 // If the provided element is a Ciphertext, a key-switching operation is necessary and a rotation key for the row rotation needs to be provided.
 func (eval *Evaluator) conjugate(ct0 *Ciphertext, ckSet *mkrlwe.ConjugationKeySet, ctOut *Ciphertext) {
+	ct0 = eval.extend(ct0)
 	ctTmp := ct0.CopyNew()
 	eval.ksw.Conjugate(ctTmp.Ciphertext, ckSet, ctOut.Ciphertext)
 }
 
+// TryKSNew is KSNew returning an error instead of panicking on an invalid request.
+func (eval *Evaluator) TryKSNew(ct0 *Ciphertext, swk1 *mkrlwe.SWK, swk2 *mkrlwe.SWK) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct0); err != nil {
+		return nil, err
+	}
+
+	if err = eval.ksw.CheckKS(ct0.Ciphertext, swk1, swk2, ct0.Level()); err != nil {
+		return nil, err
+	}
+
+	return eval.KSNew(ct0, swk1, swk2), nil
+}
+
+// KSNew switches ct0 with the pair (swk1, swk2) and returns the result in a newly created element.
+// ct0 is left unchanged.
 func (eval *Evaluator) KSNew(ct0 *Ciphertext, swk1 *mkrlwe.SWK, swk2 *mkrlwe.SWK) (ctOut *Ciphertext) {
 	ctOut = NewCiphertext(eval.params, ct0.IDSet())
 	eval.KS(ct0, swk1, swk2, ctOut)
 	return
 }
 
-// Conjugate conjugates ct0 (which is equivalent to a row rotation) and returns the result in ctOut.
-// If the provided element is a Ciphertext, a key-switching operation is necessary and a rotation key for the row rotation needs to be provided.
+// KS switches ct0 with the pair (swk1, swk2) and returns the result in ctOut.
+// ctOut can be ct0.
 func (eval *Evaluator) KS(ct0 *Ciphertext, swk1 *mkrlwe.SWK, swk2 *mkrlwe.SWK, ctOut *Ciphertext) {
-	ctTmp := ct0.CopyNew()
-	eval.ksw.KS(ctTmp.Ciphertext, swk1, swk2, ctOut.Ciphertext)
+	eval.ksw.KS(ct0.Ciphertext, swk1, swk2, ctOut.Ciphertext)
+}
+
+// TryKSChainNew is KSChainNew returning an error instead of panicking on an invalid request,
+// e.g. join keys which do not form a chain from the epoch of the component (mkrlwe.ErrEpochMismatch).
+func (eval *Evaluator) TryKSChainNew(ct0 *Ciphertext, id string, chain []*mkrlwe.JoinKey) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct0); err != nil {
+		return nil, err
+	}
+
+	if err = eval.ksw.CheckKSChain(ct0.Ciphertext, id, chain, ct0.Level()); err != nil {
+		return nil, err
+	}
+
+	return eval.KSChainNew(ct0, id, chain), nil
+}
+
+// KSChainNew switches the component of ct0 under id through the chain of join keys in one pass
+// and returns the result in a newly created element. Its cost and noise grow with the length of the chain,
+// see mkrlwe.KeySwitcher.KSChain.
+func (eval *Evaluator) KSChainNew(ct0 *Ciphertext, id string, chain []*mkrlwe.JoinKey) (ctOut *Ciphertext) {
+	ctOut = NewCiphertext(eval.params, ct0.IDSet())
+	eval.KSChain(ct0, id, chain, ctOut)
+	return
+}
+
+// KSChain switches the component of ct0 under id through the chain of join keys in one pass
+// and returns the result in ctOut. Its cost and noise grow with the length of the chain,
+// see mkrlwe.KeySwitcher.KSChain.
+func (eval *Evaluator) KSChain(ct0 *Ciphertext, id string, chain []*mkrlwe.JoinKey, ctOut *Ciphertext) {
+	eval.ksw.KSChain(ct0.Ciphertext, id, chain, ctOut.Ciphertext)
+}
+
+// TryMergeKSNew is MergeKSNew returning an error instead of panicking on an invalid request.
+func (eval *Evaluator) TryMergeKSNew(ct0 *Ciphertext, id0, id1 string, swk0, swkhead0, swk1, swkhead1 *mkrlwe.SWK) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct0); err != nil {
+		return nil, err
+	}
+
+	if err = eval.ksw.CheckMergeKS(eval.extended(ct0), id0, id1, swk0, swkhead0, swk1, swkhead1, ct0.Level()); err != nil {
+		return nil, err
+	}
+
+	return eval.MergeKSNew(ct0, id0, id1, swk0, swkhead0, swk1, swkhead1), nil
+}
+
+// MergeKSNew folds the components of ct0 under the merged groups id0 and id1 into a single component under id0
+// and returns the result in a newly created element.
+func (eval *Evaluator) MergeKSNew(ct0 *Ciphertext, id0, id1 string, swk0, swkhead0, swk1, swkhead1 *mkrlwe.SWK) (ctOut *Ciphertext) {
+	idset := ct0.IDSet()
+	idset.Remove(id1)
+	idset.Add(id0)
+
+	ctOut = NewCiphertext(eval.params, idset)
+	eval.MergeKS(ct0, id0, id1, swk0, swkhead0, swk1, swkhead1, ctOut)
+	return
+}
+
+// MergeKS folds the components of ct0 under the merged groups id0 and id1 into a single component under id0
+// and returns the result in ctOut.
+func (eval *Evaluator) MergeKS(ct0 *Ciphertext, id0, id1 string, swk0, swkhead0, swk1, swkhead1 *mkrlwe.SWK, ctOut *Ciphertext) {
+	ct0 = eval.extend(ct0)
+	eval.ksw.MergeKS(ct0.Ciphertext, id0, id1, swk0, swkhead0, swk1, swkhead1, ctOut.Ciphertext)
+}
+
+// TrySplitNew is SplitNew returning an error instead of panicking on an invalid request.
+func (eval *Evaluator) TrySplitNew(ct0 *Ciphertext, id, id0, id1 string) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct0); err != nil {
+		return nil, err
+	}
+
+	if err = eval.ksw.CheckSplit(eval.extended(ct0), id, id0, id1, ct0.Level()); err != nil {
+		return nil, err
+	}
+
+	return eval.SplitNew(ct0, id, id0, id1), nil
+}
+
+// SplitNew carries the component of ct0 under the group id over to its child groups id0 and id1
+// and returns the result in a newly created element.
+func (eval *Evaluator) SplitNew(ct0 *Ciphertext, id, id0, id1 string) (ctOut *Ciphertext) {
+	idset := ct0.IDSet()
+	idset.Remove(id)
+	idset.Add(id0)
+	idset.Add(id1)
+
+	ctOut = NewCiphertext(eval.params, idset)
+	eval.Split(ct0, id, id0, id1, ctOut)
+	return
+}
+
+// Split carries the component of ct0 under the group id over to its child groups id0 and id1
+// and returns the result in ctOut.
+func (eval *Evaluator) Split(ct0 *Ciphertext, id, id0, id1 string, ctOut *Ciphertext) {
+	ct0 = eval.extend(ct0)
+	eval.ksw.Split(ct0.Ciphertext, id, id0, id1, ctOut.Ciphertext)
+}
+
+// TrySplitKSNew is SplitKSNew returning an error instead of panicking on an invalid request.
+func (eval *Evaluator) TrySplitKSNew(ct0 *Ciphertext, id, id0 string, swk, swkhead *mkrlwe.SWK) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct0); err != nil {
+		return nil, err
+	}
+
+	if err = eval.ksw.CheckSplitKS(eval.extended(ct0), id, id0, swk, swkhead, ct0.Level()); err != nil {
+		return nil, err
+	}
+
+	return eval.SplitKSNew(ct0, id, id0, swk, swkhead), nil
+}
+
+// SplitKSNew carries the component of ct0 under the group id over to its child group id0 alone
+// and returns the result in a newly created element.
+func (eval *Evaluator) SplitKSNew(ct0 *Ciphertext, id, id0 string, swk, swkhead *mkrlwe.SWK) (ctOut *Ciphertext) {
+	idset := ct0.IDSet()
+	idset.Remove(id)
+	idset.Add(id0)
+
+	ctOut = NewCiphertext(eval.params, idset)
+	eval.SplitKS(ct0, id, id0, swk, swkhead, ctOut)
+	return
+}
+
+// SplitKS carries the component of ct0 under the group id over to its child group id0 alone
+// and returns the result in ctOut.
+func (eval *Evaluator) SplitKS(ct0 *Ciphertext, id, id0 string, swk, swkhead *mkrlwe.SWK, ctOut *Ciphertext) {
+	ct0 = eval.extend(ct0)
+	eval.ksw.SplitKS(ct0.Ciphertext, id, id0, swk, swkhead, ctOut.Ciphertext)
 }
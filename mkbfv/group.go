@@ -0,0 +1,359 @@
+package mkbfv
+
+import "mk-lattigo/mkrlwe"
+
+// PartyShares is a type for the public key shares a single party contributes to its BFV group.
+// The relinearization key share replaces the mkrlwe one, which is left nil.
+type PartyShares struct {
+	mkrlwe.PartyShares
+	RelinearizationKey *RelinearizationKey
+}
+
+// Group is a type for an rdMPHE group with BFV relinearization keys.
+type Group struct {
+	*mkrlwe.Group
+	kgen *KeyGenerator
+
+	RelinearizationKey *RelinearizationKey
+}
+
+// NewGroup returns a new empty group with the given id.
+func NewGroup(params Parameters, id string) *Group {
+	g := new(Group)
+	g.Group = mkrlwe.NewGroup(params.Parameters, id)
+	g.kgen = NewKeyGenerator(params)
+	return g
+}
+
+// GenPartyShares generates the public key shares of the owner of sk.
+// Rotation keys are generated for every index in rotidx.
+func (keygen *KeyGenerator) GenPartyShares(sk *mkrlwe.SecretKey, rotidx []int) (shares *PartyShares) {
+	shares = new(PartyShares)
+	shares.PublicKey = keygen.GenPublicKey(sk)
+	shares.RelinearizationKey = keygen.GenRelinearizationKey(sk)
+	shares.ConjugationKey = keygen.GenConjugationKey(sk)
+	shares.RotationKeys = make(map[uint]*mkrlwe.RotationKey)
+
+	for _, idx := range rotidx {
+		shares.RotationKeys[uint(idx)] = keygen.GenRotationKey(idx, sk)
+	}
+
+	return shares
+}
+
+// CheckAddShares returns the error of AddShares.
+func (g *Group) CheckAddShares(shares *PartyShares) error {
+	if err := checkShares(g.ID, shares); err != nil {
+		return err
+	}
+
+	return g.Group.CheckAddShares(rlweShares(shares)[0])
+}
+
+// TryAddShares is AddShares returning an error instead of panicking on an invalid request.
+func (g *Group) TryAddShares(shares *PartyShares) error {
+	if err := g.CheckAddShares(shares); err != nil {
+		return err
+	}
+
+	g.AddShares(shares)
+	return nil
+}
+
+// AddShares aggregates the key shares of a founding member into the group keys.
+func (g *Group) AddShares(shares *PartyShares) {
+	if err := g.CheckAddShares(shares); err != nil {
+		panic(err)
+	}
+
+	g.Group.AddShares(&shares.PartyShares)
+	g.aggregate(shares)
+}
+
+// CheckJoin returns the error of Join.
+func (g *Group) CheckJoin(shares *PartyShares, uaux *mkrlwe.SWK) error {
+	return g.CheckBatchJoin([]*PartyShares{shares}, []*mkrlwe.SWK{uaux})
+}
+
+// TryJoin is Join returning an error instead of panicking on an invalid request.
+func (g *Group) TryJoin(shares *PartyShares, uaux *mkrlwe.SWK) (jk, jkhead *mkrlwe.SWK, err error) {
+	return g.TryBatchJoin([]*PartyShares{shares}, []*mkrlwe.SWK{uaux})
+}
+
+// Join admits a new member to the group, see mkrlwe.Group.Join.
+func (g *Group) Join(shares *PartyShares, uaux *mkrlwe.SWK) (jk, jkhead *mkrlwe.SWK) {
+	return g.BatchJoin([]*PartyShares{shares}, []*mkrlwe.SWK{uaux})
+}
+
+// CheckBatchJoin returns the error of BatchJoin.
+func (g *Group) CheckBatchJoin(sharesList []*PartyShares, uauxList []*mkrlwe.SWK) error {
+	if err := checkShares(g.ID, sharesList...); err != nil {
+		return err
+	}
+
+	return g.Group.CheckBatchJoin(rlweShares(sharesList...), uauxList)
+}
+
+// TryBatchJoin is BatchJoin returning an error instead of panicking on an invalid request.
+func (g *Group) TryBatchJoin(sharesList []*PartyShares, uauxList []*mkrlwe.SWK) (jk, jkhead *mkrlwe.SWK, err error) {
+	if err = g.CheckBatchJoin(sharesList, uauxList); err != nil {
+		return nil, nil, err
+	}
+
+	jk, jkhead = g.BatchJoin(sharesList, uauxList)
+	return jk, jkhead, nil
+}
+
+// BatchJoin admits several new members to the group at once, see mkrlwe.Group.BatchJoin.
+func (g *Group) BatchJoin(sharesList []*PartyShares, uauxList []*mkrlwe.SWK) (jk, jkhead *mkrlwe.SWK) {
+	if err := g.CheckBatchJoin(sharesList, uauxList); err != nil {
+		panic(err)
+	}
+
+	jk, jkhead = g.Group.BatchJoin(rlweShares(sharesList...), uauxList)
+
+	for _, shares := range sharesList {
+		g.aggregate(shares)
+	}
+	g.stampEpoch()
+
+	return jk, jkhead
+}
+
+// CheckLeave returns the error of Leave.
+func (g *Group) CheckLeave(shares *PartyShares, laux, swk, swkhead *mkrlwe.SWK) error {
+	if err := checkShares(g.ID, shares); err != nil {
+		return err
+	}
+
+	return g.Group.CheckLeave(rlweShares(shares)[0], laux, swk, swkhead)
+}
+
+// TryLeave is Leave returning an error instead of panicking on an invalid request.
+func (g *Group) TryLeave(shares *PartyShares, laux, swk, swkhead *mkrlwe.SWK) (jk, jkhead *mkrlwe.SWK, err error) {
+	if err = g.CheckLeave(shares, laux, swk, swkhead); err != nil {
+		return nil, nil, err
+	}
+
+	jk, jkhead = g.Leave(shares, laux, swk, swkhead)
+	return jk, jkhead, nil
+}
+
+// Leave removes a member from the group, see mkrlwe.Group.Leave.
+func (g *Group) Leave(shares *PartyShares, laux, swk, swkhead *mkrlwe.SWK) (jk, jkhead *mkrlwe.SWK) {
+	if err := g.CheckLeave(shares, laux, swk, swkhead); err != nil {
+		panic(err)
+	}
+
+	jk, jkhead = g.Group.Leave(&shares.PartyShares, laux, swk, swkhead)
+
+	if shares.RelinearizationKey != nil {
+		g.RelinearizationKey = g.kgen.genRelinKeyWithout(g.RelinearizationKey, shares.RelinearizationKey)
+	}
+	g.stampEpoch()
+
+	return jk, jkhead
+}
+
+// CheckRevoke returns the error of Revoke.
+func (g *Group) CheckRevoke(shares *PartyShares) error {
+	if err := checkShares(g.ID, shares); err != nil {
+		return err
+	}
+
+	return g.Group.CheckRevoke(rlweShares(shares)[0])
+}
+
+// TryRevoke is Revoke returning an error instead of panicking on an invalid request.
+func (g *Group) TryRevoke(shares *PartyShares) error {
+	if err := g.CheckRevoke(shares); err != nil {
+		return err
+	}
+
+	g.Revoke(shares)
+	return nil
+}
+
+// Revoke removes a member from the group without its cooperation, see mkrlwe.Group.Revoke.
+func (g *Group) Revoke(shares *PartyShares) {
+	if err := g.CheckRevoke(shares); err != nil {
+		panic(err)
+	}
+
+	g.Group.Revoke(&shares.PartyShares)
+
+	if shares.RelinearizationKey != nil {
+		g.RelinearizationKey = g.kgen.genRelinKeyWithout(g.RelinearizationKey, shares.RelinearizationKey)
+	}
+	g.stampEpoch()
+}
+
+// CheckRefresh returns the error of Refresh.
+func (g *Group) CheckRefresh(sharesList []*PartyShares, rauxList []*mkrlwe.SWK) error {
+	if err := checkShares(g.ID, sharesList...); err != nil {
+		return err
+	}
+
+	return g.Group.CheckRefresh(rlweShares(sharesList...), rauxList)
+}
+
+// TryRefresh is Refresh returning an error instead of panicking on an invalid request.
+func (g *Group) TryRefresh(sharesList []*PartyShares, rauxList []*mkrlwe.SWK) (jk, jkhead *mkrlwe.SWK, err error) {
+	if err = g.CheckRefresh(sharesList, rauxList); err != nil {
+		return nil, nil, err
+	}
+
+	jk, jkhead = g.Refresh(sharesList, rauxList)
+	return jk, jkhead, nil
+}
+
+// Refresh re-randomizes the group secret without changing the membership, see mkrlwe.Group.Refresh.
+func (g *Group) Refresh(sharesList []*PartyShares, rauxList []*mkrlwe.SWK) (jk, jkhead *mkrlwe.SWK) {
+	if err := g.CheckRefresh(sharesList, rauxList); err != nil {
+		panic(err)
+	}
+
+	jk, jkhead = g.Group.Refresh(rlweShares(sharesList...), rauxList)
+
+	g.RelinearizationKey = nil
+	for _, shares := range sharesList {
+		g.aggregate(shares)
+	}
+	g.stampEpoch()
+
+	return jk, jkhead
+}
+
+// CheckMerge returns the error of Merge.
+func (g *Group) CheckMerge(other *Group, uaux, uauxOther []*mkrlwe.SWK) error {
+	if other == nil {
+		return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "Cannot Merge: the groups have no switching key sum")
+	}
+
+	return g.Group.CheckMerge(other.Group, uaux, uauxOther)
+}
+
+// TryMerge is Merge returning an error instead of panicking on an invalid request.
+func (g *Group) TryMerge(other *Group, uaux, uauxOther []*mkrlwe.SWK) (jk, jkhead, jkOther, jkheadOther *mkrlwe.SWK, err error) {
+	if err = g.CheckMerge(other, uaux, uauxOther); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	jk, jkhead, jkOther, jkheadOther = g.Merge(other, uaux, uauxOther)
+	return jk, jkhead, jkOther, jkheadOther, nil
+}
+
+// Merge merges the group other into the receiver, see mkrlwe.Group.Merge.
+func (g *Group) Merge(other *Group, uaux, uauxOther []*mkrlwe.SWK) (jk, jkhead, jkOther, jkheadOther *mkrlwe.SWK) {
+	if err := g.CheckMerge(other, uaux, uauxOther); err != nil {
+		panic(err)
+	}
+
+	jk, jkhead, jkOther, jkheadOther = g.Group.Merge(other.Group, uaux, uauxOther)
+
+	if g.RelinearizationKey != nil && other.RelinearizationKey != nil {
+		rlk := *other.RelinearizationKey
+		rlk.ID = g.ID
+		g.RelinearizationKey = g.kgen.GenGroupRelinKey([]*RelinearizationKey{g.RelinearizationKey, &rlk})
+	} else {
+		g.RelinearizationKey = nil
+	}
+	g.stampEpoch()
+
+	return jk, jkhead, jkOther, jkheadOther
+}
+
+// CheckSplit returns the error of Split.
+func (g *Group) CheckSplit(id0, id1 string, shares0, shares1 []*PartyShares) error {
+	if err := checkShares(id0, shares0...); err != nil {
+		return err
+	}
+
+	if err := checkShares(id1, shares1...); err != nil {
+		return err
+	}
+
+	return g.Group.CheckSplit(id0, id1, rlweShares(shares0...), rlweShares(shares1...))
+}
+
+// TrySplit is Split returning an error instead of panicking on an invalid request.
+func (g *Group) TrySplit(id0, id1 string, shares0, shares1 []*PartyShares) (g0, g1 *Group, err error) {
+	if err = g.CheckSplit(id0, id1, shares0, shares1); err != nil {
+		return nil, nil, err
+	}
+
+	g0, g1 = g.Split(id0, id1, shares0, shares1)
+	return g0, g1, nil
+}
+
+// Split partitions the group into two child groups, see mkrlwe.Group.Split.
+func (g *Group) Split(id0, id1 string, shares0, shares1 []*PartyShares) (g0, g1 *Group) {
+	if err := g.CheckSplit(id0, id1, shares0, shares1); err != nil {
+		panic(err)
+	}
+
+	g0, g1 = &Group{kgen: g.kgen}, &Group{kgen: g.kgen}
+	g0.Group, g1.Group = g.Group.Split(id0, id1, rlweShares(shares0...), rlweShares(shares1...))
+
+	for _, shares := range shares0 {
+		g0.aggregate(shares)
+	}
+
+	for _, shares := range shares1 {
+		g1.aggregate(shares)
+	}
+
+	return g0, g1
+}
+
+// UpdateKeySets inserts the current group keys into the given key sets.
+// Nil key sets are skipped.
+func (g *Group) UpdateKeySets(pkSet *mkrlwe.PublicKeySet, rlkSet *RelinearizationKeySet, rtkSet *mkrlwe.RotationKeySet, cjkSet *mkrlwe.ConjugationKeySet) {
+	g.Group.UpdateKeySets(pkSet, nil, rtkSet, cjkSet)
+
+	if rlkSet != nil && g.RelinearizationKey != nil {
+		rlkSet.AddRelinearizationKey(g.RelinearizationKey)
+	}
+}
+
+// stampEpoch tags the BFV relinearization key with the current group epoch
+func (g *Group) stampEpoch() {
+	if g.RelinearizationKey != nil {
+		g.RelinearizationKey.Epoch = g.Epoch
+	}
+}
+
+// aggregate adds the BFV relinearization key share into a newly allocated group key
+func (g *Group) aggregate(shares *PartyShares) {
+	if shares.RelinearizationKey == nil {
+		return
+	}
+
+	if g.RelinearizationKey == nil {
+		g.RelinearizationKey = g.kgen.GenGroupRelinKey([]*RelinearizationKey{shares.RelinearizationKey})
+	} else {
+		g.RelinearizationKey = g.kgen.GenGroupRelinKey([]*RelinearizationKey{g.RelinearizationKey, shares.RelinearizationKey})
+	}
+}
+
+// rlweShares returns the mkrlwe key shares of sharesList, with nil for nil shares
+func rlweShares(sharesList ...*PartyShares) []*mkrlwe.PartyShares {
+	rlweSharesList := make([]*mkrlwe.PartyShares, len(sharesList))
+	for i, shares := range sharesList {
+		if shares != nil {
+			rlweSharesList[i] = &shares.PartyShares
+		}
+	}
+	return rlweSharesList
+}
+
+// checkShares checks that the BFV relinearization key shares of sharesList are under the given id,
+// the other key shares being checked by mkrlwe
+func checkShares(id string, sharesList ...*PartyShares) error {
+	for _, shares := range sharesList {
+		if shares != nil && shares.RelinearizationKey != nil && shares.RelinearizationKey.ID != id {
+			return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: IDs are not same")
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package mkbfv
 
 import "mk-lattigo/mkrlwe"
 import "math/big"
+import "github.com/ldsec/lattigo/v2/utils"
 
 type KeyGenerator struct {
 	*mkrlwe.KeyGenerator
@@ -10,9 +11,19 @@ type KeyGenerator struct {
 }
 
 func NewKeyGenerator(params Parameters) (keygen *KeyGenerator) {
+	prng, err := utils.NewPRNG()
+	if err != nil {
+		panic(err)
+	}
+
+	return NewKeyGeneratorWithPRNG(params, prng)
+}
+
+// NewKeyGeneratorWithPRNG creates a KeyGenerator for the BFV scheme, whose samplers read from the given PRNG.
+func NewKeyGeneratorWithPRNG(params Parameters, prng utils.PRNG) (keygen *KeyGenerator) {
 	keygen = new(KeyGenerator)
 	keygen.params = params
-	keygen.KeyGenerator = mkrlwe.NewKeyGenerator(params.Parameters)
+	keygen.KeyGenerator = mkrlwe.NewKeyGeneratorWithPRNG(params.Parameters, prng)
 	keygen.baseconv = NewFastBasisExtender(
 		params.RingP(), params.RingQ(),
 		params.RingQMul(), params.RingR(),
@@ -35,6 +46,7 @@ func (keygen *KeyGenerator) GenRelinearizationKey(sk *mkrlwe.SecretKey) (rlk *Re
 
 	//rlk = (b, d, v)
 	rlk = NewRelinearizationKey(params, id)
+	rlk.Epoch = sk.Epoch
 	beta := params.Beta(levelQ)
 
 	//set CRS
@@ -91,7 +103,7 @@ func (keygen *KeyGenerator) GenRelinearizationKey(sk *mkrlwe.SecretKey) (rlk *Re
 func (keygen *KeyGenerator) GenGroupRelinKey(rlkList []*RelinearizationKey) (rlkOut *RelinearizationKey) {
 
 	if len(rlkList) == 0 {
-		panic("invalid input: empty relinkey list")
+		panic(mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: empty relinkey list"))
 	}
 
 	params := keygen.params
@@ -101,10 +113,11 @@ func (keygen *KeyGenerator) GenGroupRelinKey(rlkList []*RelinearizationKey) (rlk
 
 	id := rlkList[0].ID
 	rlkOut = NewRelinearizationKey(params, id)
+	rlkOut.Epoch = rlkList[0].Epoch
 
 	for _, rlk := range rlkList {
 		if id != rlk.ID {
-			panic("invalid input: IDs are not same")
+			panic(mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: IDs are not same"))
 		}
 
 		for i := 0; i < beta; i++ {
@@ -126,6 +139,37 @@ func (keygen *KeyGenerator) GenGroupRelinKey(rlkList []*RelinearizationKey) (rlk
 	return rlkOut
 }
 
+// genRelinKeyWithout subtracts the relinearization key share rlk from the group key grlk
+func (keygen *KeyGenerator) genRelinKeyWithout(grlk, rlk *RelinearizationKey) (rlkOut *RelinearizationKey) {
+
+	if grlk.ID != rlk.ID {
+		panic(mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: IDs are not same"))
+	}
+
+	params := keygen.params
+	levelQ := params.QCount() - 1
+	levelP := params.PCount() - 1
+	beta := params.Beta(levelQ)
+
+	rlkOut = NewRelinearizationKey(params, grlk.ID)
+	rlkOut.Epoch = grlk.Epoch
+
+	for i := 0; i < beta; i++ {
+		grlk0, rlk0, rlkOut0 := grlk.Value[0], rlk.Value[0], rlkOut.Value[0]
+
+		params.RingQP().SubLvl(levelQ, levelP, grlk0.Value[0].Value[i], rlk0.Value[0].Value[i], rlkOut0.Value[0].Value[i])
+		params.RingQP().SubLvl(levelQ, levelP, grlk0.Value[1].Value[i], rlk0.Value[1].Value[i], rlkOut0.Value[1].Value[i])
+		params.RingQP().SubLvl(levelQ, levelP, grlk0.Value[2].Value[i], rlk0.Value[2].Value[i], rlkOut0.Value[2].Value[i])
+
+		grlk1, rlk1, rlkOut1 := grlk.Value[1], rlk.Value[1], rlkOut.Value[1]
+
+		params.RingQP().SubLvl(levelQ, levelP, grlk1.Value[0].Value[i], rlk1.Value[0].Value[i], rlkOut1.Value[0].Value[i])
+		params.RingQP().SubLvl(levelQ, levelP, grlk1.Value[1].Value[i], rlk1.Value[1].Value[i], rlkOut1.Value[1].Value[i])
+	}
+
+	return rlkOut
+}
+
 // For an input secretkey s, gen gs + e in MForm
 func (keygen *KeyGenerator) GenBFVSwitchingKey(sk *mkrlwe.SecretKey, swk1, swk2 *mkrlwe.SwitchingKey) {
 	params := keygen.params
@@ -1,23 +1,17 @@
 package mkbfv
 
 import "mk-lattigo/mkrlwe"
-import "github.com/ldsec/lattigo/v2/ring"
 
 type RelinearizationKey struct {
 	Value [2]*mkrlwe.RelinearizationKey
 	ID    string
+	Epoch int
 }
 
 // RelinearizationKeySet is a type for a set of multikey BFV relinearization keys.
 type RelinearizationKeySet struct {
 	Value map[string]*RelinearizationKey
 
-	PolyRPool1 map[string]*ring.Poly
-	PolyRPool2 map[string]*ring.Poly
-
-	HoistPool1 [2]*mkrlwe.HoistedCiphertext
-	HoistPool2 [2]*mkrlwe.HoistedCiphertext
-
 	params Parameters
 }
 
@@ -38,32 +32,12 @@ func NewRelinearizationKeySet(params Parameters) *RelinearizationKeySet {
 	rlkSet.Value = make(map[string]*RelinearizationKey)
 	rlkSet.params = params
 
-	rlkSet.PolyRPool1 = make(map[string]*ring.Poly)
-	rlkSet.PolyRPool2 = make(map[string]*ring.Poly)
-
-	rlkSet.PolyRPool1["0"] = params.RingR().NewPoly()
-	rlkSet.PolyRPool2["0"] = params.RingR().NewPoly()
-
-	rlkSet.HoistPool1[0] = mkrlwe.NewHoistedCiphertext()
-	rlkSet.HoistPool1[1] = mkrlwe.NewHoistedCiphertext()
-
-	rlkSet.HoistPool2[0] = mkrlwe.NewHoistedCiphertext()
-	rlkSet.HoistPool2[1] = mkrlwe.NewHoistedCiphertext()
-
 	return rlkSet
 }
 
 // AddRelinearizationKey insert new publickey into RelinearizationKeySet with its id
 func (rlkSet *RelinearizationKeySet) AddRelinearizationKey(rlk *RelinearizationKey) {
 	rlkSet.Value[rlk.ID] = rlk
-	rlkSet.PolyRPool1[rlk.ID] = rlkSet.params.RingR().NewPoly()
-	rlkSet.PolyRPool2[rlk.ID] = rlkSet.params.RingR().NewPoly()
-
-	rlkSet.HoistPool1[0].Value[rlk.ID] = mkrlwe.NewSwitchingKey(rlkSet.params.Parameters)
-	rlkSet.HoistPool1[1].Value[rlk.ID] = mkrlwe.NewSwitchingKey(rlkSet.params.Parameters)
-
-	rlkSet.HoistPool2[0].Value[rlk.ID] = mkrlwe.NewSwitchingKey(rlkSet.params.Parameters)
-	rlkSet.HoistPool2[1].Value[rlk.ID] = mkrlwe.NewSwitchingKey(rlkSet.params.Parameters)
 }
 
 // DelRelinearizationKey delete publickey of given id from SecretKeySet
@@ -73,11 +47,30 @@ func (rlkSet *RelinearizationKeySet) DelRelinearizationKey(id string) {
 
 // GetRelinearizationKey returns a publickey of given id from RelinearizationKeySet
 func (rlkSet *RelinearizationKeySet) GetRelinearizationKey(id string) *RelinearizationKey {
+	ret, err := rlkSet.LookupRelinearizationKey(id)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// LookupRelinearizationKey returns a relinearization key of given id from RelinearizationKeySet,
+// or an error of kind mkrlwe.ErrMissingKey
+func (rlkSet *RelinearizationKeySet) LookupRelinearizationKey(id string) (*RelinearizationKey, error) {
 	ret, in := rlkSet.Value[id]
 
 	if !in {
-		panic("cannot GetRelinearizationKey: there is no relinearization key with given id")
+		return nil, mkrlwe.NewError(mkrlwe.ErrMissingKey, "cannot GetRelinearizationKey: there is no relinearization key with id %s", id)
 	}
 
-	return ret
+	return ret, nil
+}
+
+// IDSet returns the ids of the relinearization keys in RelinearizationKeySet
+func (rlkSet *RelinearizationKeySet) IDSet() *mkrlwe.IDSet {
+	idset := mkrlwe.NewIDSet()
+	for id := range rlkSet.Value {
+		idset.Add(id)
+	}
+	return idset
 }
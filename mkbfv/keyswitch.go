@@ -54,6 +54,37 @@ func NewKeySwitcher(params Parameters) (ks *KeySwitcher) {
 	return
 }
 
+// ShallowCopy creates a copy of a KeySwitcher, only reallocating the memory pools.
+// KeySwitchers returned by ShallowCopy can be used concurrently.
+func (ks *KeySwitcher) ShallowCopy() *KeySwitcher {
+	params := ks.params
+
+	ksOut := new(KeySwitcher)
+	ksOut.KeySwitcher = ks.KeySwitcher.ShallowCopy()
+	ksOut.kswRP = ks.kswRP.ShallowCopy()
+	ksOut.params = params
+	ksOut.conv = ks.conv.ShallowCopy()
+
+	ksOut.swkRPPool = mkrlwe.NewSwitchingKey(params.paramsRP)
+
+	ksOut.swkPool1 = mkrlwe.NewSwitchingKey(params.Parameters)
+	ksOut.swkPool2 = mkrlwe.NewSwitchingKey(params.Parameters)
+	ksOut.swkPool3 = mkrlwe.NewSwitchingKey(params.Parameters)
+	ksOut.swkPool4 = mkrlwe.NewSwitchingKey(params.Parameters)
+	ksOut.swkPool5 = mkrlwe.NewSwitchingKey(params.Parameters)
+	ksOut.swkPool6 = mkrlwe.NewSwitchingKey(params.Parameters)
+
+	ksOut.polyQPool1 = params.RingQ().NewPoly()
+	ksOut.polyQPool2 = params.RingQ().NewPoly()
+
+	ksOut.polyRPool1 = params.RingR().NewPoly()
+	ksOut.polyRPool2 = params.RingR().NewPoly()
+	ksOut.polyRPool3 = params.RingR().NewPoly()
+	ksOut.polyRPool4 = params.RingR().NewPoly()
+
+	return ksOut
+}
+
 func (ks *KeySwitcher) DecomposeBFV(levelQ int, aR *ring.Poly, ad1, ad2 *mkrlwe.SwitchingKey) {
 	params := ks.params
 	levelP := params.PCount() - 1
@@ -115,11 +146,11 @@ func (ks *KeySwitcher) PrevMulAndRelinBFV(op0, op1 *mkrlwe.Ciphertext, rlkSet *m
 	level := ctOut.Level()
 
 	if op0.Level() < level {
-		panic("Cannot MulAndRelin: op0 and op1 have different levels")
+		panic(mkrlwe.NewError(mkrlwe.ErrLevelMismatch, "Cannot MulAndRelin: op0 and op1 have different levels"))
 	}
 
 	if ctOut.Level() < level {
-		panic("Cannot MulAndRelin: op0 and ctOut have different levels")
+		panic(mkrlwe.NewError(mkrlwe.ErrLevelMismatch, "Cannot MulAndRelin: op0 and ctOut have different levels"))
 	}
 
 	idset0 := op0.IDSet()
@@ -141,7 +172,7 @@ func (ks *KeySwitcher) PrevMulAndRelinBFV(op0, op1 *mkrlwe.Ciphertext, rlkSet *m
 	//ctOut_j <- op0_0 * op1_j + op0_j * op1_0
 	ringR.MForm(ks.polyRPool2, ks.polyRPool2)
 
-	for id := range idset0.Value {
+	for _, id := range idset0.Slice() {
 		if !idset1.Has(id) {
 			ringR.NTT(op0.Value[id], ks.polyRPool3)
 			ringR.MulCoeffsMontgomery(ks.polyRPool2, ks.polyRPool3, ks.polyRPool3)
@@ -149,7 +180,7 @@ func (ks *KeySwitcher) PrevMulAndRelinBFV(op0, op1 *mkrlwe.Ciphertext, rlkSet *m
 		}
 	}
 
-	for id := range idset1.Value {
+	for _, id := range idset1.Slice() {
 		if !idset0.Has(id) {
 			ringR.NTT(op1.Value[id], ks.polyRPool3)
 			ringR.MulCoeffsMontgomery(ks.polyRPool1, ks.polyRPool3, ks.polyRPool3)
@@ -166,8 +197,8 @@ func (ks *KeySwitcher) PrevMulAndRelinBFV(op0, op1 *mkrlwe.Ciphertext, rlkSet *m
 	}
 
 	//ctOut_j <- ctOut_j +  SUM_i(Inter(op0_i * op1_j , d_i))
-	for id0 := range idset0.Value { // id: j
-		for id1 := range idset1.Value { // id1: i
+	for _, id0 := range idset0.Slice() { // id: j
+		for _, id1 := range idset1.Slice() { // id1: i
 			if id0 > id1 {
 				if idset1.Has(id0) {
 					continue
@@ -230,11 +261,11 @@ func (ks *KeySwitcher) MulAndRelinBFV(op0, op1 *mkrlwe.Ciphertext, rlkSet *Relin
 	level := ctOut.Level()
 
 	if op0.Level() < ctOut.Level() {
-		panic("Cannot MulAndRelin: op0 and op1 have different levels")
+		panic(mkrlwe.NewError(mkrlwe.ErrLevelMismatch, "Cannot MulAndRelin: op0 and op1 have different levels"))
 	}
 
 	if ctOut.Level() < level {
-		panic("Cannot MulAndRelin: op0 and ctOut have different levels")
+		panic(mkrlwe.NewError(mkrlwe.ErrLevelMismatch, "Cannot MulAndRelin: op0 and ctOut have different levels"))
 	}
 
 	params := ks.params
@@ -270,7 +301,7 @@ func (ks *KeySwitcher) MulAndRelinBFV(op0, op1 *mkrlwe.Ciphertext, rlkSet *Relin
 	}
 
 	//gen x vector
-	for id := range idset0.Value {
+	for _, id := range idset0.Slice() {
 		ks.DecomposeBFV(level, op0.Value[id], ks.swkPool1, ks.swkPool2)
 		d1 := rlkSet.Value[id].Value[0].Value[1]
 		d2 := rlkSet.Value[id].Value[1].Value[1]
@@ -286,7 +317,7 @@ func (ks *KeySwitcher) MulAndRelinBFV(op0, op1 *mkrlwe.Ciphertext, rlkSet *Relin
 	}
 
 	//gen y vector
-	for id := range idset1.Value {
+	for _, id := range idset1.Slice() {
 		ks.DecomposeBFV(level, op1.Value[id], ks.swkPool1, ks.swkPool2)
 		b1 := rlkSet.Value[id].Value[0].Value[0]
 		b2 := rlkSet.Value[id].Value[1].Value[0]
@@ -312,7 +343,7 @@ func (ks *KeySwitcher) MulAndRelinBFV(op0, op1 *mkrlwe.Ciphertext, rlkSet *Relin
 	//ctOut_j <- op0_0 * op1_j + op0_j * op1_0
 	ringR.MForm(ks.polyRPool2, ks.polyRPool2)
 
-	for id := range idset0.Value {
+	for _, id := range idset0.Slice() {
 		if !idset1.Has(id) {
 			ringR.NTT(op0.Value[id], ks.polyRPool3)
 			ringR.MulCoeffsMontgomery(ks.polyRPool2, ks.polyRPool3, ks.polyRPool3)
@@ -320,7 +351,7 @@ func (ks *KeySwitcher) MulAndRelinBFV(op0, op1 *mkrlwe.Ciphertext, rlkSet *Relin
 		}
 	}
 
-	for id := range idset1.Value {
+	for _, id := range idset1.Slice() {
 		if !idset0.Has(id) {
 			ringR.NTT(op1.Value[id], ks.polyRPool3)
 			ringR.MulCoeffsMontgomery(ks.polyRPool1, ks.polyRPool3, ks.polyRPool3)
@@ -337,7 +368,7 @@ func (ks *KeySwitcher) MulAndRelinBFV(op0, op1 *mkrlwe.Ciphertext, rlkSet *Relin
 	}
 
 	//ctOut_j <- ctOut_j +  Inter(op1_j, x)
-	for id := range idset1.Value {
+	for _, id := range idset1.Slice() {
 		ks.ExternalProductBFV(level, op1.Value[id], x1, x2, ks.polyQPool1)
 		ringQ.AddLvl(level, ctOut.Value[id], ks.polyQPool1, ctOut.Value[id])
 	}
@@ -347,7 +378,7 @@ func (ks *KeySwitcher) MulAndRelinBFV(op0, op1 *mkrlwe.Ciphertext, rlkSet *Relin
 
 	u := params.CRS[-1]
 
-	for id := range idset0.Value {
+	for _, id := range idset0.Slice() {
 		v := rlkSet.Value[id].Value[0].Value[2]
 		ks.ExternalProductBFV(level, op0.Value[id], y1, y2, ks.polyQPool1)
 
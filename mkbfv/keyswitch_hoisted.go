@@ -44,11 +44,11 @@ func (ks *KeySwitcher) MulAndRelinBFVHoisted(op0, op1 *mkrlwe.Ciphertext,
 	level := ctOut.Level()
 
 	if op0.Level() < ctOut.Level() {
-		panic("Cannot MulAndRelin: op0 and op1 have different levels")
+		panic(mkrlwe.NewError(mkrlwe.ErrLevelMismatch, "Cannot MulAndRelin: op0 and op1 have different levels"))
 	}
 
 	if ctOut.Level() < level {
-		panic("Cannot MulAndRelin: op0 and ctOut have different levels")
+		panic(mkrlwe.NewError(mkrlwe.ErrLevelMismatch, "Cannot MulAndRelin: op0 and ctOut have different levels"))
 	}
 
 	params := ks.params
@@ -84,7 +84,7 @@ func (ks *KeySwitcher) MulAndRelinBFVHoisted(op0, op1 *mkrlwe.Ciphertext,
 	}
 
 	//gen x vector
-	for id := range idset0.Value {
+	for _, id := range idset0.Slice() {
 		if op0Hoisted1 == nil {
 			ks.DecomposeBFV(level, op0.Value[id], ks.swkPool1, ks.swkPool2)
 			d1 := rlkSet.Value[id].Value[0].Value[1]
@@ -109,7 +109,7 @@ func (ks *KeySwitcher) MulAndRelinBFVHoisted(op0, op1 *mkrlwe.Ciphertext,
 	}
 
 	//gen y vector
-	for id := range idset1.Value {
+	for _, id := range idset1.Slice() {
 		if op1Hoisted1 == nil {
 			ks.DecomposeBFV(level, op1.Value[id], ks.swkPool1, ks.swkPool2)
 			b1 := rlkSet.Value[id].Value[0].Value[0]
@@ -144,7 +144,7 @@ func (ks *KeySwitcher) MulAndRelinBFVHoisted(op0, op1 *mkrlwe.Ciphertext,
 	//ctOut_j <- op0_0 * op1_j + op0_j * op1_0
 	ringR.MForm(ks.polyRPool2, ks.polyRPool2)
 
-	for id := range idset0.Value {
+	for _, id := range idset0.Slice() {
 		if !idset1.Has(id) {
 			ringR.NTT(op0.Value[id], ks.polyRPool3)
 			ringR.MulCoeffsMontgomery(ks.polyRPool2, ks.polyRPool3, ks.polyRPool3)
@@ -152,7 +152,7 @@ func (ks *KeySwitcher) MulAndRelinBFVHoisted(op0, op1 *mkrlwe.Ciphertext,
 		}
 	}
 
-	for id := range idset1.Value {
+	for _, id := range idset1.Slice() {
 		if !idset0.Has(id) {
 			ringR.NTT(op1.Value[id], ks.polyRPool3)
 			ringR.MulCoeffsMontgomery(ks.polyRPool1, ks.polyRPool3, ks.polyRPool3)
@@ -160,7 +160,7 @@ func (ks *KeySwitcher) MulAndRelinBFVHoisted(op0, op1 *mkrlwe.Ciphertext,
 		}
 	}
 
-	for id := range idset1.Value {
+	for _, id := range idset1.Slice() {
 		if idset0.Has(id) {
 			ringR.NTT(op1.Value[id], ks.polyRPool3)
 			ringR.MulCoeffsMontgomery(ks.polyRPool1, ks.polyRPool3, ks.polyRPool3)
@@ -173,7 +173,7 @@ func (ks *KeySwitcher) MulAndRelinBFVHoisted(op0, op1 *mkrlwe.Ciphertext,
 	}
 
 	//ctOut_j <- ctOut_j +  Inter(op1_j, x)
-	for id := range idset1.Value {
+	for _, id := range idset1.Slice() {
 		if op1Hoisted1 == nil {
 			ks.ExternalProductBFV(level, op1.Value[id], x1, x2, ks.polyQPool1)
 		} else {
@@ -187,7 +187,7 @@ func (ks *KeySwitcher) MulAndRelinBFVHoisted(op0, op1 *mkrlwe.Ciphertext,
 
 	u := params.CRS[-1]
 
-	for id := range idset0.Value {
+	for _, id := range idset0.Slice() {
 		v := rlkSet.Value[id].Value[0].Value[2]
 
 		if op0Hoisted1 == nil {
@@ -210,11 +210,11 @@ func (ks *KeySwitcher) PrevMulAndRelinBFVHoisted(op0, op1 *mkrlwe.Ciphertext, rl
 	level := ctOut.Level()
 
 	if op0.Level() < level {
-		panic("Cannot MulAndRelin: op0 and op1 have different levels")
+		panic(mkrlwe.NewError(mkrlwe.ErrLevelMismatch, "Cannot MulAndRelin: op0 and op1 have different levels"))
 	}
 
 	if ctOut.Level() < level {
-		panic("Cannot MulAndRelin: op0 and ctOut have different levels")
+		panic(mkrlwe.NewError(mkrlwe.ErrLevelMismatch, "Cannot MulAndRelin: op0 and ctOut have different levels"))
 	}
 
 	idset0 := op0.IDSet()
@@ -236,7 +236,7 @@ func (ks *KeySwitcher) PrevMulAndRelinBFVHoisted(op0, op1 *mkrlwe.Ciphertext, rl
 	//ctOut_j <- op0_0 * op1_j + op0_j * op1_0
 	ringR.MForm(ks.polyRPool2, ks.polyRPool2)
 
-	for id := range idset0.Value {
+	for _, id := range idset0.Slice() {
 		if !idset1.Has(id) {
 			ringR.NTT(op0.Value[id], ks.polyRPool3)
 			ringR.MulCoeffsMontgomery(ks.polyRPool2, ks.polyRPool3, ks.polyRPool3)
@@ -244,7 +244,7 @@ func (ks *KeySwitcher) PrevMulAndRelinBFVHoisted(op0, op1 *mkrlwe.Ciphertext, rl
 		}
 	}
 
-	for id := range idset1.Value {
+	for _, id := range idset1.Slice() {
 		if !idset0.Has(id) {
 			ringR.NTT(op1.Value[id], ks.polyRPool3)
 			ringR.MulCoeffsMontgomery(ks.polyRPool1, ks.polyRPool3, ks.polyRPool3)
@@ -262,9 +262,9 @@ func (ks *KeySwitcher) PrevMulAndRelinBFVHoisted(op0, op1 *mkrlwe.Ciphertext, rl
 
 	// c_i,j : pool[2]
 	// x_i: pool[3]
-	for id0 := range idset0.Value { // id: j
+	for _, id0 := range idset0.Slice() { // id: j
 		ks.polyQPool2.Zero()
-		for id1 := range idset1.Value { // id1: i
+		for _, id1 := range idset1.Slice() { // id1: i
 			if id0 > id1 {
 				if idset1.Has(id0) {
 					continue
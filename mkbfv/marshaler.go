@@ -0,0 +1,177 @@
+package mkbfv
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"mk-lattigo/mkrlwe"
+)
+
+// MarshalBinary encodes a Ciphertext on a byte slice.
+func (ct *Ciphertext) MarshalBinary() (data []byte, err error) {
+	return ct.Ciphertext.MarshalBinary()
+}
+
+// UnmarshalBinary decodes a previously marshaled Ciphertext on the target Ciphertext.
+func (ct *Ciphertext) UnmarshalBinary(data []byte) (err error) {
+	ct.Ciphertext = new(mkrlwe.Ciphertext)
+	return ct.Ciphertext.UnmarshalBinary(data)
+}
+
+// MarshalBinary encodes a SeededCiphertext on a byte slice.
+func (ct *SeededCiphertext) MarshalBinary() (data []byte, err error) {
+	return ct.SeededCiphertext.MarshalBinary()
+}
+
+// UnmarshalBinary decodes a previously marshaled SeededCiphertext on the target SeededCiphertext.
+func (ct *SeededCiphertext) UnmarshalBinary(data []byte) (err error) {
+	ct.SeededCiphertext = new(mkrlwe.SeededCiphertext)
+	return ct.SeededCiphertext.UnmarshalBinary(data)
+}
+
+// MarshalBinary encodes a RelinearizationKey on a byte slice.
+// The id and epoch are followed by the two mkrlwe relinearization keys, each prefixed by its length on 4 bytes.
+func (rlk *RelinearizationKey) MarshalBinary() (data []byte, err error) {
+	if len(rlk.ID) > 0xff {
+		return nil, errors.New("id is too long to be encoded: " + rlk.ID)
+	}
+
+	data = make([]byte, 0, 1+len(rlk.ID)+4)
+	data = append(data, uint8(len(rlk.ID)))
+	data = append(data, rlk.ID...)
+	data = appendUint32(data, uint32(rlk.Epoch))
+
+	for _, key := range rlk.Value {
+		var keyData []byte
+		if keyData, err = key.MarshalBinary(); err != nil {
+			return nil, err
+		}
+
+		data = appendUint32(data, uint32(len(keyData)))
+		data = append(data, keyData...)
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes a previously marshaled RelinearizationKey on the target RelinearizationKey.
+func (rlk *RelinearizationKey) UnmarshalBinary(data []byte) (err error) {
+	var pointer int
+	if pointer, err = rlk.decode(data); err != nil {
+		return err
+	}
+
+	if pointer != len(data) {
+		return errors.New("remaining unparsed data")
+	}
+
+	return nil
+}
+
+func (rlk *RelinearizationKey) decode(data []byte) (pointer int, err error) {
+	if len(data) < 1 || len(data) < 1+int(data[0])+4 {
+		return 0, errors.New("too small bytearray")
+	}
+
+	rlk.ID = string(data[1 : 1+int(data[0])])
+	pointer = 1 + int(data[0])
+	rlk.Epoch = int(binary.BigEndian.Uint32(data[pointer : pointer+4]))
+	pointer += 4
+
+	for i := range rlk.Value {
+		var keyLen int
+		if keyLen, pointer, err = decodeLen(pointer, data); err != nil {
+			return pointer, err
+		}
+
+		rlk.Value[i] = new(mkrlwe.RelinearizationKey)
+		if err = rlk.Value[i].UnmarshalBinary(data[pointer : pointer+keyLen]); err != nil {
+			return pointer, err
+		}
+		pointer += keyLen
+	}
+
+	return pointer, nil
+}
+
+// MarshalBinary encodes a RelinearizationKeySet on a byte slice, with the keys in increasing order of id,
+// each prefixed by its length on 4 bytes.
+func (rlkSet *RelinearizationKeySet) MarshalBinary() (data []byte, err error) {
+	idset := rlkSet.IDSet()
+
+	data = appendUint32(nil, uint32(idset.Size()))
+
+	for _, id := range idset.Slice() {
+		var keyData []byte
+		if keyData, err = rlkSet.Value[id].MarshalBinary(); err != nil {
+			return nil, err
+		}
+
+		data = appendUint32(data, uint32(len(keyData)))
+		data = append(data, keyData...)
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes a previously marshaled RelinearizationKeySet on the target RelinearizationKeySet,
+// which must have been created with NewRelinearizationKeySet.
+func (rlkSet *RelinearizationKeySet) UnmarshalBinary(data []byte) (err error) {
+	if rlkSet.Value == nil {
+		return errors.New("RelinearizationKeySet must be created with NewRelinearizationKeySet")
+	}
+
+	if len(data) < 4 {
+		return errors.New("too small bytearray")
+	}
+
+	numKeys := int(binary.BigEndian.Uint32(data[0:4]))
+	pointer := 4
+
+	*rlkSet = *NewRelinearizationKeySet(rlkSet.params)
+
+	for i := 0; i < numKeys; i++ {
+		var keyLen int
+		if keyLen, pointer, err = decodeLen(pointer, data); err != nil {
+			return err
+		}
+
+		rlk := new(RelinearizationKey)
+		if err = rlk.UnmarshalBinary(data[pointer : pointer+keyLen]); err != nil {
+			return err
+		}
+		pointer += keyLen
+
+		rlkSet.AddRelinearizationKey(rlk)
+	}
+
+	if pointer != len(data) {
+		return errors.New("remaining unparsed data")
+	}
+
+	return nil
+}
+
+// appendUint32 appends v on 4 bytes to data
+func appendUint32(data []byte, v uint32) []byte {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, v)
+	return append(data, buf...)
+}
+
+// decodeLen reads a length on 4 bytes on data at pointer,
+// and checks that data holds that many bytes after it
+func decodeLen(pointer int, data []byte) (int, int, error) {
+	if len(data) < pointer+4 {
+		return 0, pointer, errors.New("too small bytearray")
+	}
+
+	l := int(binary.BigEndian.Uint32(data[pointer : pointer+4]))
+	pointer += 4
+
+	if len(data) < pointer+l {
+		return 0, pointer, errors.New("too small bytearray")
+	}
+
+	return l, pointer, nil
+}
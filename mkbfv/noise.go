@@ -0,0 +1,122 @@
+package mkbfv
+
+import (
+	"math"
+	"math/big"
+
+	"mk-lattigo/mkrlwe"
+
+	"github.com/ldsec/lattigo/v2/bfv"
+)
+
+// NoiseEstimator follows the noise of BFV ciphertexts through the operations of the Evaluator,
+// for messages whose coefficients are uniform modulo T. Its methods return new estimates and leave their inputs unchanged.
+type NoiseEstimator struct {
+	params Parameters
+	ne     *mkrlwe.NoiseEstimator
+	neRP   *mkrlwe.NoiseEstimator
+}
+
+// NewNoiseEstimator creates a new NoiseEstimator.
+func NewNoiseEstimator(params Parameters) *NoiseEstimator {
+	return &NoiseEstimator{params: params, ne: mkrlwe.NewNoiseEstimator(params.Parameters), neRP: mkrlwe.NewNoiseEstimator(params.paramsRP)}
+}
+
+// EncryptMsg returns the estimate of the output of Encryptor.EncryptMsg with the public key of id,
+// shared by the given number of members.
+func (ne *NoiseEstimator) EncryptMsg(id string, members int) *mkrlwe.NoiseEstimate {
+	return ne.ne.Encrypt(ne.params.MaxLevel(), id, members)
+}
+
+// EncryptSkMsg returns the estimate of the output of Encryptor.EncryptSkMsg with the secret key of id,
+// shared by the given number of members.
+func (ne *NoiseEstimator) EncryptSkMsg(id string, members int) *mkrlwe.NoiseEstimate {
+	return ne.ne.EncryptSk(ne.params.MaxLevel(), id, members)
+}
+
+// Add returns the estimate of the output of Evaluator.AddNew or Evaluator.SubNew.
+func (ne *NoiseEstimator) Add(est0, est1 *mkrlwe.NoiseEstimate) *mkrlwe.NoiseEstimate {
+	return ne.ne.Add(est0, est1)
+}
+
+// MulRelin returns the estimate of the output of Evaluator.MulRelinNew.
+func (ne *NoiseEstimator) MulRelin(est0, est1 *mkrlwe.NoiseEstimate) *mkrlwe.NoiseEstimate {
+	N := float64(ne.params.N())
+	t := float64(ne.params.T())
+	rt := float64(new(big.Int).Mod(ne.params.RingQ().ModulusBigint, new(big.Int).SetUint64(ne.params.T())).Uint64())
+
+	// the phase of a ciphertext is Delta * m + e + Q * k, where k has coefficients of the order of
+	// the ones of the secret keys, and the tensor product is scaled by T / Q, with Delta = (Q - rt) / T
+	vm := t * t / 3
+	vk0 := (1 + ne.ne.SecretKeyVariance(est0)) / 3
+	vk1 := (1 + ne.ne.SecretKeyVariance(est1)) / 3
+	ve0, ve1 := est0.Variance, est1.Variance
+
+	tensorVariance := t * t * N * (ve0*vk1 + ve1*vk0) // T * (e0 * k1 + e1 * k0)
+	tensorVariance += N * vm * (ve0 + ve1)            // m0 * e1 + m1 * e0
+	tensorVariance += rt * rt * N * vm * (vk0 + vk1)  // rt * (m0 * k1 + m1 * k0)
+	tensorVariance += (1 + ne.ne.SecretKeyVariance(est0)) * (1 + ne.ne.SecretKeyVariance(est1)) / 12
+
+	estRP0, estRP1 := est0.CopyNew(), est1.CopyNew()
+	estRP0.Level, estRP1.Level = ne.params.paramsRP.MaxLevel(), ne.params.paramsRP.MaxLevel()
+
+	estOut := ne.neRP.MulAndRelin(estRP0, estRP1, tensorVariance)
+	estOut.Level = ne.params.MaxLevel()
+
+	return estOut
+}
+
+// Rotate returns the estimate of the output of Evaluator.RotateNew.
+func (ne *NoiseEstimator) Rotate(est *mkrlwe.NoiseEstimate) *mkrlwe.NoiseEstimate {
+	return ne.ne.Rotate(est)
+}
+
+// Conjugate returns the estimate of the output of Evaluator.ConjugateNew.
+func (ne *NoiseEstimator) Conjugate(est *mkrlwe.NoiseEstimate) *mkrlwe.NoiseEstimate {
+	return ne.ne.Conjugate(est)
+}
+
+// KS returns the estimate of the output of Evaluator.KSNew, which switches est to the secret key
+// of a group of the given number of members.
+func (ne *NoiseEstimator) KS(est *mkrlwe.NoiseEstimate, members int) *mkrlwe.NoiseEstimate {
+	return ne.ne.KS(est, members)
+}
+
+// logHalfDelta returns the log2 of Delta / 2 = floor(Q / T) / 2, the bound on the noise beyond which decryption fails.
+func (p Parameters) logHalfDelta() float64 {
+	delta := new(big.Int).Quo(p.RingQ().ModulusBigint, new(big.Int).SetUint64(p.T()))
+	mant := new(big.Float)
+	exp := new(big.Float).SetInt(delta).MantExp(mant)
+	m, _ := mant.Float64()
+
+	return math.Log2(m) + float64(exp) - 1
+}
+
+// NoiseBudget returns the estimated noise budget of a ciphertext in bits, that is the log2 of the ratio between
+// Delta / 2 and a bound of 6 standard deviations on the coefficients of its noise. The ciphertext is expected to
+// decrypt correctly as long as it is positive.
+func (ne *NoiseEstimator) NoiseBudget(est *mkrlwe.NoiseEstimate) float64 {
+	return ne.params.logHalfDelta() - math.Log2(6*est.Std())
+}
+
+// MeasureNoise decrypts ciphertext with skSet and returns the log2 of the standard deviation and of the maximum
+// of the coefficients of its noise, to be compared with mkrlwe.NoiseEstimate.LogStd.
+// It is a debugging tool: it requires the secret keys of every id, and assumes that the ciphertext decrypts correctly.
+func (dec *Decryptor) MeasureNoise(ciphertext *Ciphertext, skSet *mkrlwe.SecretKeySet) (logStd, logMax float64) {
+	msg := dec.Decrypt(ciphertext, skSet)
+
+	bfvParams, _ := bfv.NewParameters(dec.params.Parameters.Parameters, dec.params.T())
+	pt := bfv.NewPlaintext(bfvParams)
+	dec.encoder.EncodeInt(msg.Value, pt)
+
+	return dec.Decryptor.MeasureNoise(ciphertext.Ciphertext, skSet, pt.Plaintext)
+}
+
+// NoiseBudget returns the measured noise budget of ciphertext in bits, that is the log2 of the ratio between Delta / 2
+// and the maximum of the coefficients of its noise. It is a debugging tool: it requires the secret keys of every id,
+// and is only meaningful as long as the ciphertext decrypts correctly, that is as long as it is positive.
+func (dec *Decryptor) NoiseBudget(ciphertext *Ciphertext, skSet *mkrlwe.SecretKeySet) float64 {
+	_, logMax := dec.MeasureNoise(ciphertext, skSet)
+
+	return dec.params.logHalfDelta() - logMax
+}
@@ -1,9 +1,17 @@
 package mkbfv
 
-import "mk-lattigo/mkrlwe"
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 
-import "github.com/ldsec/lattigo/v2/ring"
-import "github.com/ldsec/lattigo/v2/rlwe"
+	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/ldsec/lattigo/v2/rlwe"
+	"gopkg.in/yaml.v3"
+
+	"mk-lattigo/mkrlwe"
+)
 
 // ParametersLiteral is a literal representation of BFV parameters.  It has public
 // fields and is used to express unchecked user-defined parameters literally into
@@ -14,11 +22,13 @@ type ParametersLiteral struct {
 	Q       []uint64
 	QMul    []uint64
 	P       []uint64
-	LogQ    []int   `json:",omitempty"`
-	LogQMul []int   `json:",omitempty"`
-	LogP    []int   `json:",omitempty"`
-	Sigma   float64 // Gaussian sampling standard deviation
-	T       uint64  // Plaintext modulus
+	LogQ    []int          `json:",omitempty" yaml:",omitempty"`
+	LogQMul []int          `json:",omitempty" yaml:",omitempty"`
+	LogP    []int          `json:",omitempty" yaml:",omitempty"`
+	Sigma   float64        // Gaussian sampling standard deviation
+	T       uint64         // Plaintext modulus
+	Gamma   int            `json:",omitempty" yaml:",omitempty"` // Gadget parameter of the switching keys, 2 if zero
+	CRSSeed mkrlwe.CRSSeed `json:",omitempty" yaml:",omitempty"` // Public seed of the CRSs, sampled at random if nil, which parameters files do not allow
 }
 
 // Parameters represents a parameter set for the BFV cryptosystem. Its fields are private and
@@ -31,12 +41,31 @@ type Parameters struct {
 	ringQMul *ring.Ring
 }
 
-// NewParameters instantiate a set of MKCKKS parameters from the generic CKKS parameters and the CKKS-specific ones.
-// It returns the empty parameters Parameters{} and a non-nil error if the specified parameters are invalid.
+// NewParametersFromLiteral instantiate a set of MKBFV parameters from a ParametersLiteral,
+// with the CRSs expanded from its CRS seed. It panics if the specified parameters are invalid.
 func NewParametersFromLiteral(pl ParametersLiteral) (params Parameters) {
+	params, err := newParametersFromLiteral(pl)
+	if err != nil {
+		panic(err)
+	}
+
+	return params
+}
+
+// newParametersFromFile instantiates the parameters of a decoded parameters file, which must carry its CRS seed:
+// without it, every party loading the file would sample a different CRS.
+func newParametersFromFile(pl ParametersLiteral) (Parameters, error) {
+	if pl.CRSSeed == nil {
+		return Parameters{}, errors.New("invalid input: parameters file without CRS seed")
+	}
+
+	return newParametersFromLiteral(pl)
+}
+
+func newParametersFromLiteral(pl ParametersLiteral) (params Parameters, err error) {
 
 	if len(pl.Q) != len(pl.QMul) {
-		panic("cannot NewParametersFromLiteral: length of Q & QMul is not equal")
+		return Parameters{}, errors.New("cannot NewParametersFromLiteral: length of Q & QMul is not equal")
 	}
 
 	N := (1 << pl.LogN)
@@ -46,14 +75,14 @@ func NewParametersFromLiteral(pl ParametersLiteral) (params Parameters) {
 
 	ringT, err := ring.NewRing(N, []uint64{pl.T})
 	if err != nil {
-		panic("cannot NewParametersFromLiteral: ring T cannot be generated")
+		return Parameters{}, errors.New("cannot NewParametersFromLiteral: ring T cannot be generated")
 	}
 
 	params.ringT = ringT
 
 	ringQMul, err := ring.NewRing(N, pl.QMul)
 	if err != nil {
-		panic("cannot NewParametersFromLiteral: ring QMul cannot be generated")
+		return Parameters{}, errors.New("cannot NewParametersFromLiteral: ring QMul cannot be generated")
 	}
 
 	params.ringQMul = ringQMul
@@ -62,22 +91,96 @@ func NewParametersFromLiteral(pl ParametersLiteral) (params Parameters) {
 		rlwe.ParametersLiteral{LogN: pl.LogN, Q: pl.Q, P: pl.P, Sigma: pl.Sigma},
 	)
 	if err != nil {
-		panic("cannot NewParametersFromLiteral: ring QP cannot be generated")
+		return Parameters{}, errors.New("cannot NewParametersFromLiteral: ring QP cannot be generated")
 	}
 
 	rlweParamsRP, err := rlwe.NewParametersFromLiteral(
 		rlwe.ParametersLiteral{LogN: pl.LogN, Q: R, P: pl.P, Sigma: pl.Sigma},
 	)
 	if err != nil {
-		panic(err)
-		panic("cannot NewParametersFromLiteral: ring RP cannot be generated")
+		return Parameters{}, errors.New("cannot NewParametersFromLiteral: ring RP cannot be generated")
+	}
+
+	gamma := pl.Gamma
+	if gamma == 0 {
+		gamma = 2
+	}
 
+	if gamma < 1 || gamma > rlweParamsQP.PCount() {
+		return Parameters{}, errors.New("invalid input: gamma must be between 1 and the number of P moduli")
 	}
 
-	params.Parameters = mkrlwe.NewParameters(rlweParamsQP, 2)
-	params.paramsRP = mkrlwe.NewParameters(rlweParamsRP, 2)
+	seed := pl.CRSSeed
+	if seed == nil {
+		seed = make([]byte, mkrlwe.CRSSeedSize)
+		if _, err := rand.Read(seed); err != nil {
+			return Parameters{}, err
+		}
+	} else if len(seed) != mkrlwe.CRSSeedSize {
+		return Parameters{}, errors.New("invalid input: CRS seed must be 32 bytes")
+	}
 
-	return params
+	params.Parameters = mkrlwe.NewParametersFromSeed(rlweParamsQP, gamma, seed)
+	params.paramsRP = mkrlwe.NewParametersFromSeed(rlweParamsRP, gamma, seed)
+
+	return params, nil
+}
+
+// ParametersLiteral returns the ParametersLiteral of the target Parameters, with explicit moduli and CRS seed.
+func (p Parameters) ParametersLiteral() ParametersLiteral {
+	return ParametersLiteral{
+		LogN:    p.LogN(),
+		Q:       p.Q(),
+		QMul:    append([]uint64{}, p.ringQMul.Modulus...),
+		P:       p.P(),
+		Sigma:   p.Sigma(),
+		T:       p.T(),
+		Gamma:   p.Gamma(),
+		CRSSeed: p.CRSSeed(),
+	}
+}
+
+// MarshalJSON returns a JSON representation of this parameter set. See `Marshal` from the `encoding/json` package.
+func (p Parameters) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.ParametersLiteral())
+}
+
+// UnmarshalJSON reads a JSON representation of a parameter set into the receiver Parameter. See `Unmarshal` from the `encoding/json` package.
+func (p *Parameters) UnmarshalJSON(data []byte) (err error) {
+	var pl ParametersLiteral
+	if err = json.Unmarshal(data, &pl); err != nil {
+		return err
+	}
+
+	*p, err = newParametersFromFile(pl)
+	return err
+}
+
+// MarshalYAML returns a YAML representation of this parameter set. See `Marshal` from the `gopkg.in/yaml.v3` package.
+func (p Parameters) MarshalYAML() (interface{}, error) {
+	return p.ParametersLiteral(), nil
+}
+
+// UnmarshalYAML reads a YAML representation of a parameter set into the receiver Parameter. See `Unmarshal` from the `gopkg.in/yaml.v3` package.
+func (p *Parameters) UnmarshalYAML(value *yaml.Node) (err error) {
+	var pl ParametersLiteral
+	if err = value.Decode(&pl); err != nil {
+		return err
+	}
+
+	*p, err = newParametersFromFile(pl)
+	return err
+}
+
+// Fingerprint returns the SHA-256 hash of the JSON representation of the parameter set,
+// so that parties can check that they loaded the same parameters and CRS seed.
+func (p Parameters) Fingerprint() [sha256.Size]byte {
+	data, err := p.MarshalJSON()
+	if err != nil {
+		panic(err)
+	}
+
+	return sha256.Sum256(data)
 }
 
 func (p Parameters) RingQMul() *ring.Ring {
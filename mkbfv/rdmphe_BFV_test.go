@@ -1,6 +1,8 @@
 package mkbfv
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"math"
@@ -15,6 +17,7 @@ import (
 	"github.com/ldsec/lattigo/v2/rlwe"
 	"github.com/ldsec/lattigo/v2/utils"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 // import "github.com/ldsec/lattigo/v2/bfv"
@@ -507,6 +510,235 @@ func Test_MKHE_BFV(t *testing.T) {
 	}
 }
 
+func Test_GroupJoin_BFV(t *testing.T) {
+	params := NewParametersFromLiteral(PN14QP439)
+	kgen := NewKeyGenerator(params)
+
+	id := "group0"
+	numParties := 2
+
+	group := NewGroup(params, id)
+	skList := make([]*mkrlwe.SecretKey, 0)
+
+	for p := 0; p < numParties; p++ {
+		sk := kgen.GenSecretKey(id)
+		skList = append(skList, sk)
+		group.AddShares(kgen.GenPartyShares(sk, []int{1}))
+	}
+
+	for _, sk := range skList {
+		group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+	}
+
+	encryptor := NewEncryptor(params)
+	decryptor := NewDecryptor(params)
+	eval := NewEvaluator(params)
+
+	msg := NewMessage(params)
+	for i := range msg.Value {
+		msg.Value[i] = int64(i % 16)
+	}
+	ct := encryptor.EncryptMsgNew(msg, group.PublicKey)
+
+	// a new party joins the group
+	sk := kgen.GenSecretKey(id)
+	skList = append(skList, sk)
+
+	uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+	jk, jkhead := group.Join(kgen.GenPartyShares(sk, []int{1}), uaux)
+	group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+
+	rlkSet := NewRelinearizationKeySet(params)
+	group.UpdateKeySets(nil, rlkSet, nil, nil)
+
+	skSet := mkrlwe.NewSecretKeySet()
+	skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+	t.Run(GetTestName(params, "GroupJoin: "+strconv.Itoa(numParties)+"+1/ "), func(t *testing.T) {
+		ctRes := eval.KSNew(ct, jk, jkhead)
+		ctRes = eval.MulRelinNew(ctRes, ctRes, rlkSet)
+		msgRes := decryptor.Decrypt(ctRes, skSet)
+
+		for i := range msgRes.Value {
+			require.Equal(t, msg.Value[i]*msg.Value[i], msgRes.Value[i])
+		}
+	})
+
+	t.Run(GetTestName(params, "GroupJoin/Errors/ "), func(t *testing.T) {
+		epoch := group.Epoch
+		skNew := kgen.GenSecretKey(id)
+		shares := kgen.GenPartyShares(skNew, []int{1})
+
+		// the auxiliary key of the previous join is computed against a previous switching key sum
+		_, _, err := group.TryJoin(shares, uaux)
+		require.True(t, errors.Is(err, mkrlwe.ErrEpochMismatch))
+
+		uauxNew, _ := kgen.UAuxKeyGen(group.SWKHeadSum, skNew)
+		sharesOther := kgen.GenPartyShares(skNew, []int{1})
+		sharesOther.RelinearizationKey = kgen.GenRelinearizationKey(kgen.GenSecretKey("group1"))
+		_, _, err = group.TryJoin(sharesOther, uauxNew)
+		require.True(t, errors.Is(err, mkrlwe.ErrInvalidInput))
+		_, _, err = group.TryJoin(nil, uauxNew)
+		require.True(t, errors.Is(err, mkrlwe.ErrInvalidInput))
+		require.Equal(t, epoch, group.Epoch)
+
+		_, _, err = group.TryJoin(shares, uauxNew)
+		require.NoError(t, err)
+		require.Equal(t, epoch+1, group.Epoch)
+		require.Equal(t, group.Epoch, group.RelinearizationKey.Epoch)
+	})
+}
+
+func Test_GroupLazyJoin_BFV(t *testing.T) {
+	params := NewParametersFromLiteral(PN14QP439)
+	kgen := NewKeyGenerator(params)
+
+	id := "group0"
+	numParties := 2
+
+	group := NewGroup(params, id)
+	skList := make([]*mkrlwe.SecretKey, 0)
+
+	for p := 0; p < numParties; p++ {
+		sk := kgen.GenSecretKey(id)
+		skList = append(skList, sk)
+		group.AddShares(kgen.GenPartyShares(sk, []int{1}))
+	}
+
+	for _, sk := range skList {
+		group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+	}
+
+	encryptor := NewEncryptor(params)
+	decryptor := NewDecryptor(params)
+	eval := NewEvaluator(params)
+
+	msg := NewMessage(params)
+	for i := range msg.Value {
+		msg.Value[i] = int64(i % 16)
+	}
+	ctStored := encryptor.EncryptMsgNew(msg, group.PublicKey)
+
+	// two parties join the group, and the stored ciphertext is left as is
+	jkSet := mkrlwe.NewJoinKeySet()
+	for j := 0; j < 2; j++ {
+		sk := kgen.GenSecretKey(id)
+		skList = append(skList, sk)
+
+		uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+		jkSet.AddJoinKey(group.Join(kgen.GenPartyShares(sk, []int{1}), uaux))
+		group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+	}
+
+	rlkSet := NewRelinearizationKeySet(params)
+	group.UpdateKeySets(nil, rlkSet, nil, nil)
+	eval.SetJoinKeySet(jkSet)
+
+	ctFresh := encryptor.EncryptMsgNew(msg, group.PublicKey)
+
+	skSet := mkrlwe.NewSecretKeySet()
+	skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+	t.Run(GetTestName(params, "GroupLazyJoin: "+strconv.Itoa(numParties)+"+2/ "), func(t *testing.T) {
+		require.Equal(t, 0, ctStored.GetEpoch(id))
+
+		ctRes := eval.MulRelinNew(ctStored, ctFresh, rlkSet)
+		require.Equal(t, group.Epoch, ctStored.GetEpoch(id))
+		require.Equal(t, group.Epoch, ctRes.GetEpoch(id))
+
+		ctRes = eval.AddNew(ctRes, ctStored)
+		msgRes := decryptor.Decrypt(ctRes, skSet)
+
+		for i := range msgRes.Value {
+			require.Equal(t, msg.Value[i]*msg.Value[i]+msg.Value[i], msgRes.Value[i])
+		}
+	})
+}
+
+func Test_GroupLeave_BFV(t *testing.T) {
+	params := NewParametersFromLiteral(PN14QP439)
+	kgen := NewKeyGenerator(params)
+
+	id := "group0"
+	numParties := 3
+
+	group := NewGroup(params, id)
+	skList := make([]*mkrlwe.SecretKey, numParties)
+	sharesList := make([]*PartyShares, numParties)
+
+	for p := 0; p < numParties; p++ {
+		skList[p] = kgen.GenSecretKey(id)
+		sharesList[p] = kgen.GenPartyShares(skList[p], []int{1})
+		group.AddShares(sharesList[p])
+	}
+
+	for _, sk := range skList {
+		group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+	}
+
+	encryptor := NewEncryptor(params)
+	decryptor := NewDecryptor(params)
+	eval := NewEvaluator(params)
+
+	msg := NewMessage(params)
+	for i := range msg.Value {
+		msg.Value[i] = int64(i % 16)
+	}
+	ct := encryptor.EncryptMsgNew(msg, group.PublicKey)
+
+	// the last member leaves the group
+	sk := skList[numParties-1]
+	laux := kgen.LAuxKeyGen(group.SWKHeadSum, sk)
+	swk, swkhead := kgen.GenSWK(sk, group.PublicKeyWithout(sharesList[numParties-1].PublicKey))
+	jk, jkhead := group.Leave(sharesList[numParties-1], laux, swk, swkhead)
+
+	rlkSet := NewRelinearizationKeySet(params)
+	group.UpdateKeySets(nil, rlkSet, nil, nil)
+
+	skSet := mkrlwe.NewSecretKeySet()
+	skSet.AddSecretKey(kgen.GenGroupSecretKey(skList[:numParties-1]))
+
+	t.Run(GetTestName(params, "GroupLeave: "+strconv.Itoa(numParties)+"-1/ "), func(t *testing.T) {
+		ctRes := eval.KSNew(ct, jk, jkhead)
+		ctRes = eval.MulRelinNew(ctRes, ctRes, rlkSet)
+		msgRes := decryptor.Decrypt(ctRes, skSet)
+
+		for i := range msgRes.Value {
+			require.Equal(t, msg.Value[i]*msg.Value[i], msgRes.Value[i])
+		}
+	})
+
+	t.Run(GetTestName(params, "GroupRevoke: "+strconv.Itoa(numParties-1)+"-1/ "), func(t *testing.T) {
+		// the remaining members revoke the first member without its secret key
+		for _, sk := range skList[:numParties-1] {
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		epoch := group.Epoch
+		group.Revoke(sharesList[0])
+		require.Equal(t, epoch+1, group.Epoch)
+		require.Equal(t, group.Epoch, group.RelinearizationKey.Epoch)
+
+		rlkSet := NewRelinearizationKeySet(params)
+		group.UpdateKeySets(nil, rlkSet, nil, nil)
+
+		skSet := mkrlwe.NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList[1 : numParties-1]))
+
+		ctNew := encryptor.EncryptMsgNew(msg, group.PublicKey)
+		ctRes := eval.MulRelinNew(ctNew, ctNew, rlkSet)
+		msgRes := decryptor.Decrypt(ctRes, skSet)
+
+		for i := range msgRes.Value {
+			require.Equal(t, msg.Value[i]*msg.Value[i], msgRes.Value[i])
+		}
+
+		// the ciphertexts of the previous epoch cannot be used with the new keys
+		_, err := eval.TryMulRelinNew(ct, ctNew, rlkSet)
+		require.True(t, errors.Is(err, mkrlwe.ErrEpochMismatch))
+	})
+}
+
 func InputSelection(testContext *testParams, userList []string, numParties int, t *testing.T) {
 
 	numParties = numParties + 1
@@ -901,6 +1133,645 @@ func genTestParams(defaultParam Parameters,
 
 }
 
+// genUserTestParams returns a test context with the individual keys of each of ids:
+// its secret key, public key, relinearization key and rotation key by 1.
+func genUserTestParams(params Parameters, ids []string) *testParams {
+	testContext := &testParams{
+		params:    params,
+		ringQ:     params.RingQ(),
+		kgen:      NewKeyGenerator(params),
+		skSet:     mkrlwe.NewSecretKeySet(),
+		pkSet:     mkrlwe.NewPublicKeyKeySet(),
+		rlkSet:    NewRelinearizationKeySet(params),
+		rtkSet:    mkrlwe.NewRotationKeySet(),
+		encryptor: NewEncryptor(params),
+		decryptor: NewDecryptor(params),
+		evaluator: NewEvaluator(params),
+		idset:     mkrlwe.NewIDSet(),
+	}
+
+	for _, id := range ids {
+		sk, pk := testContext.kgen.GenKeyPair(id)
+		testContext.skSet.AddSecretKey(sk)
+		testContext.pkSet.AddPublicKey(pk)
+		testContext.rlkSet.AddRelinearizationKey(testContext.kgen.GenRelinearizationKey(sk))
+		testContext.rtkSet.AddRotationKey(testContext.kgen.GenRotationKey(1, sk))
+		testContext.idset.Add(id)
+	}
+
+	return testContext
+}
+
+// newSumTestVectors returns the sum of a random message of each of ids, and the sum of their encryptions under the public key of their id.
+func newSumTestVectors(testContext *testParams, ids []string, a, b int64) (msg *Message, ciphertext *Ciphertext) {
+	msg = NewMessage(testContext.params)
+	for _, id := range ids {
+		msgId, ctId := newTestVectors(testContext, id, a, b)
+		for i := range msg.Value {
+			msg.Value[i] += msgId.Value[i]
+		}
+
+		if ciphertext == nil {
+			ciphertext = ctId
+		} else {
+			ciphertext = testContext.evaluator.AddNew(ciphertext, ctId)
+		}
+	}
+
+	return msg, ciphertext
+}
+
+func Test_Marshal_BFV(t *testing.T) {
+	params := NewParametersFromLiteral(PN14QP439)
+	kgen := NewKeyGenerator(params)
+
+	idset := mkrlwe.NewIDSet()
+	skSet := mkrlwe.NewSecretKeySet()
+	ctList := make([]*Ciphertext, 0)
+
+	encryptor := NewEncryptor(params)
+	decryptor := NewDecryptor(params)
+	eval := NewEvaluator(params)
+
+	msg := NewMessage(params)
+	for i := range msg.Value {
+		msg.Value[i] = int64(i % 16)
+	}
+
+	for _, id := range []string{"user1", "user0"} {
+		sk, pk := kgen.GenKeyPair(id)
+		skSet.AddSecretKey(sk)
+		idset.Add(id)
+		ctList = append(ctList, encryptor.EncryptMsgNew(msg, pk))
+	}
+
+	ct := eval.AddNew(ctList[0], ctList[1])
+
+	t.Run(GetTestName(params, "Marshal/Ciphertext/ "), func(t *testing.T) {
+		data, err := ct.MarshalBinary()
+		require.NoError(t, err)
+
+		dataRepeat, err := ct.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, data, dataRepeat)
+
+		ctRes := new(Ciphertext)
+		require.NoError(t, ctRes.UnmarshalBinary(data))
+		require.True(t, ctRes.IDSet().Intersection(idset).Size() == idset.Size())
+		require.Equal(t, ct.Level(), ctRes.Level())
+
+		for id := range ct.Value {
+			require.True(t, ct.Value[id].Equals(ctRes.Value[id]))
+		}
+
+		msgRes := decryptor.Decrypt(ctRes, skSet)
+		for i := range msgRes.Value {
+			require.Equal(t, 2*msg.Value[i], msgRes.Value[i])
+		}
+
+		require.Error(t, ctRes.UnmarshalBinary(data[:len(data)-1]))
+	})
+
+	t.Run(GetTestName(params, "Marshal/RelinearizationKeySet/ "), func(t *testing.T) {
+		rlkSet := NewRelinearizationKeySet(params)
+		for id, sk := range skSet.Value {
+			rlkSet.AddRelinearizationKey(kgen.GenRelinearizationKey(sk))
+			require.NotNil(t, rlkSet.GetRelinearizationKey(id))
+		}
+
+		data, err := rlkSet.MarshalBinary()
+		require.NoError(t, err)
+
+		rlkSetRes := NewRelinearizationKeySet(params)
+		require.NoError(t, rlkSetRes.UnmarshalBinary(data))
+
+		dataRes, err := rlkSetRes.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, data, dataRes)
+
+		ctRes := eval.MulRelinNew(ct, ct, rlkSetRes)
+		msgRes := decryptor.Decrypt(ctRes, skSet)
+		for i := range msgRes.Value {
+			require.Equal(t, (4*msg.Value[i]*msg.Value[i])%int64(params.T()), msgRes.Value[i])
+		}
+
+		require.Error(t, rlkSetRes.UnmarshalBinary(data[:len(data)-1]))
+	})
+
+	t.Run(GetTestName(params, "Marshal/CompressedCiphertext/ "), func(t *testing.T) {
+		sk := skSet.GetSecretKey("user0")
+		ctSk := encryptor.EncryptSkMsgNewSeeded(msg, sk)
+		ctSkOther := encryptor.EncryptSkMsgNewSeeded(msg, sk)
+		require.NotEqual(t, ctSk.Seed, ctSkOther.Seed)
+
+		data, err := ctSk.MarshalBinary()
+		require.NoError(t, err)
+
+		ctExpanded, err := ctSk.Expand(params)
+		require.NoError(t, err)
+		dataExpanded, err := ctExpanded.MarshalBinary()
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(data), len(dataExpanded)/2+64)
+
+		ctSeeded := new(SeededCiphertext)
+		require.NoError(t, ctSeeded.UnmarshalBinary(data))
+		ctRes, err := ctSeeded.Expand(params)
+		require.NoError(t, err)
+		require.True(t, ctExpanded.Value["user0"].Equals(ctRes.Value["user0"]))
+
+		msgRes := decryptor.Decrypt(ctRes, skSet)
+		for i := range msgRes.Value {
+			require.Equal(t, msg.Value[i], msgRes.Value[i])
+		}
+
+		require.Error(t, ctSeeded.UnmarshalBinary(data[:len(data)-1]))
+	})
+}
+
+func Test_Params_BFV(t *testing.T) {
+	params := NewParametersFromLiteral(PN14QP439)
+
+	t.Run(GetTestName(params, "Params/JSON/ "), func(t *testing.T) {
+		data, err := json.Marshal(params)
+		require.NoError(t, err)
+
+		var paramsRes Parameters
+		require.NoError(t, json.Unmarshal(data, &paramsRes))
+		require.Equal(t, params.ParametersLiteral(), paramsRes.ParametersLiteral())
+		require.Equal(t, params.Fingerprint(), paramsRes.Fingerprint())
+		require.True(t, params.CRS[0].Value[0].Q.Equals(paramsRes.CRS[0].Value[0].Q))
+		require.Equal(t, params.RingR().Modulus, paramsRes.RingR().Modulus)
+	})
+
+	t.Run(GetTestName(params, "Params/YAML/ "), func(t *testing.T) {
+		data, err := yaml.Marshal(params)
+		require.NoError(t, err)
+
+		var paramsRes Parameters
+		require.NoError(t, yaml.Unmarshal(data, &paramsRes))
+		require.Equal(t, params.ParametersLiteral(), paramsRes.ParametersLiteral())
+		require.Equal(t, params.Fingerprint(), paramsRes.Fingerprint())
+	})
+
+	t.Run(GetTestName(params, "Params/Fingerprint/ "), func(t *testing.T) {
+		pl := params.ParametersLiteral()
+		pl.CRSSeed[0] ^= 1
+		require.NotEqual(t, params.Fingerprint(), NewParametersFromLiteral(pl).Fingerprint())
+
+		pl.CRSSeed = pl.CRSSeed[:16]
+		require.Panics(t, func() { NewParametersFromLiteral(pl) })
+	})
+
+	t.Run(GetTestName(params, "Params/MissingSeed/ "), func(t *testing.T) {
+		// the seed is only sampled at random for the literals given to NewParametersFromLiteral
+		pl := params.ParametersLiteral()
+		pl.CRSSeed = nil
+		require.NotPanics(t, func() { NewParametersFromLiteral(pl) })
+
+		data, err := json.Marshal(pl)
+		require.NoError(t, err)
+		var paramsRes Parameters
+		require.Error(t, json.Unmarshal(data, &paramsRes))
+
+		data, err = yaml.Marshal(pl)
+		require.NoError(t, err)
+		require.Error(t, yaml.Unmarshal(data, &paramsRes))
+	})
+}
+
+func Test_Errors_BFV(t *testing.T) {
+	params := NewParametersFromLiteral(PN14QP439)
+	kgen := NewKeyGenerator(params)
+
+	encryptor := NewEncryptor(params)
+	decryptor := NewDecryptor(params)
+	eval := NewEvaluator(params)
+
+	sk, pk := kgen.GenKeyPair("user0")
+	skSet := mkrlwe.NewSecretKeySet()
+	skSet.AddSecretKey(sk)
+
+	msg := NewMessage(params)
+	ct := encryptor.EncryptMsgNew(msg, pk)
+
+	t.Run(GetTestName(params, "Errors/MissingKey/ "), func(t *testing.T) {
+		_, err := eval.TryMulRelinNew(ct, ct, NewRelinearizationKeySet(params))
+		require.True(t, errors.Is(err, mkrlwe.ErrMissingKey))
+
+		_, err = eval.TryRotateNew(ct, 1, mkrlwe.NewRotationKeySet())
+		require.True(t, errors.Is(err, mkrlwe.ErrMissingKey))
+
+		_, err = eval.TryConjugateNew(ct, mkrlwe.NewConjugationKeySet())
+		require.True(t, errors.Is(err, mkrlwe.ErrMissingKey))
+
+		_, err = decryptor.TryDecrypt(ct, mkrlwe.NewSecretKeySet())
+		require.True(t, errors.Is(err, mkrlwe.ErrMissingKey))
+	})
+
+	t.Run(GetTestName(params, "Errors/Valid/ "), func(t *testing.T) {
+		rlkSet := NewRelinearizationKeySet(params)
+		rlkSet.AddRelinearizationKey(kgen.GenRelinearizationKey(sk))
+
+		ctOut, err := eval.TryMulRelinNew(ct, ct, rlkSet)
+		require.NoError(t, err)
+
+		msgOut, err := decryptor.TryDecrypt(ctOut, skSet)
+		require.NoError(t, err)
+		require.Equal(t, msg.Value, msgOut.Value)
+	})
+
+	t.Run(GetTestName(params, "Errors/EpochMismatch/ "), func(t *testing.T) {
+		ctOld := ct.CopyNew()
+		ctOld.SetEpoch("user0", 1)
+
+		_, err := eval.TryAddNew(ct, ctOld)
+		require.True(t, errors.Is(err, mkrlwe.ErrEpochMismatch))
+
+		_, err = eval.TrySubNew(ct, ctOld)
+		require.True(t, errors.Is(err, mkrlwe.ErrEpochMismatch))
+
+		rlkSet := NewRelinearizationKeySet(params)
+		rlkSet.AddRelinearizationKey(kgen.GenRelinearizationKey(sk))
+
+		_, err = eval.TryMulRelinNew(ctOld, ctOld, rlkSet)
+		require.True(t, errors.Is(err, mkrlwe.ErrEpochMismatch))
+	})
+
+	t.Run(GetTestName(params, "Errors/InvalidInput/ "), func(t *testing.T) {
+		ctData, err := ct.MarshalBinary()
+		require.NoError(t, err)
+
+		ctLow := ct.CopyNew()
+		for _, c := range ctLow.Value {
+			c.Coeffs = c.Coeffs[:1]
+		}
+
+		_, err = eval.TryAddNew(ct, ctLow)
+		require.True(t, errors.Is(err, mkrlwe.ErrLevelMismatch))
+
+		ctBad := ct.CopyNew()
+		ctBad.Value["user0"] = params.RingQ().NewPolyLvl(0)
+
+		_, err = eval.TryMulRelinNew(ct, ctBad, NewRelinearizationKeySet(params))
+		require.True(t, errors.Is(err, mkrlwe.ErrInvalidInput))
+
+		_, err = eval.TryMulPtxtNew(ct, nil)
+		require.True(t, errors.Is(err, mkrlwe.ErrInvalidInput))
+
+		_, err = decryptor.TryDecrypt(ctBad, skSet)
+		require.True(t, errors.Is(err, mkrlwe.ErrInvalidInput))
+
+		sk1 := kgen.GenSecretKey("user1")
+		swk, _ := kgen.GenSWK(sk1, pk)
+
+		_, err = eval.TryKSNew(ct, swk, nil)
+		require.True(t, errors.Is(err, mkrlwe.ErrInvalidInput))
+
+		_, err = eval.TryKSChainNew(ct, "user0", nil)
+		require.True(t, errors.Is(err, mkrlwe.ErrInvalidInput))
+
+		_, err = eval.TrySplitNew(ct, "user0", "user0", "user1")
+		require.True(t, errors.Is(err, mkrlwe.ErrInvalidInput))
+
+		require.True(t, errors.Is(decryptor.TryPartialDecrypt(ct.CopyNew(), sk1), mkrlwe.ErrInvalidInput))
+
+		// a rejected request leaves its operands unchanged
+		data, err := ct.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, ctData, data)
+
+		require.True(t, errors.Is(decryptor.TryPartialDecrypt(ct.CopyNew(), sk), mkrlwe.ErrNoiseBudget))
+		require.NoError(t, decryptor.PartialDecryptFresh(ct.CopyNew(), sk))
+	})
+}
+
+func Test_PRNG_BFV(t *testing.T) {
+	pl := PN14QP439
+	pl.CRSSeed = make([]byte, mkrlwe.CRSSeedSize)
+	params := NewParametersFromLiteral(pl)
+
+	msg := NewMessage(params)
+	for i := range msg.Value {
+		msg.Value[i] = int64(i % 16)
+	}
+
+	// session replays keygen, encryption and evaluation from a keyed PRNG
+	session := func(seed []byte) (data []byte, msgOut *Message) {
+		prng, err := utils.NewKeyedPRNG(seed)
+		require.NoError(t, err)
+
+		kgen := NewKeyGeneratorWithPRNG(params, prng)
+		encryptor := NewEncryptorWithPRNG(params, prng)
+		decryptor := NewDecryptorWithPRNG(params, prng)
+		eval := NewEvaluator(params)
+
+		skSet := mkrlwe.NewSecretKeySet()
+		rlkSet := NewRelinearizationKeySet(params)
+		ctList := make([]*Ciphertext, 0)
+		for _, id := range []string{"user0", "user1"} {
+			sk, pk := kgen.GenKeyPair(id)
+			skSet.AddSecretKey(sk)
+			rlkSet.AddRelinearizationKey(kgen.GenRelinearizationKey(sk))
+			ctList = append(ctList, encryptor.EncryptMsgNew(msg, pk))
+		}
+
+		ct := eval.MulRelinNew(ctList[0], ctList[1], rlkSet)
+		data, err = ct.MarshalBinary()
+		require.NoError(t, err)
+
+		return data, decryptor.Decrypt(ct, skSet)
+	}
+
+	t.Run(GetTestName(params, "PRNG/Replay/ "), func(t *testing.T) {
+		seed := []byte("mkbfv known-answer test seed")
+		data0, msg0 := session(seed)
+		data1, msg1 := session(seed)
+
+		require.Equal(t, data0, data1)
+		require.Equal(t, msg0.Value, msg1.Value)
+		for i := range msg.Value {
+			require.Equal(t, (msg.Value[i]*msg.Value[i])%int64(params.T()), msg0.Value[i])
+		}
+
+		data2, _ := session([]byte("another seed"))
+		require.NotEqual(t, data0, data2)
+	})
+}
+
+func Test_ShallowCopy_BFV(t *testing.T) {
+	params := NewParametersFromLiteral(PN14QP439)
+	kgen := NewKeyGenerator(params)
+
+	encryptor := NewEncryptor(params)
+	eval := NewEvaluator(params)
+
+	rlkSet := NewRelinearizationKeySet(params)
+	ctList := make([]*Ciphertext, 0)
+	for _, id := range []string{"user0", "user1"} {
+		sk, pk := kgen.GenKeyPair(id)
+		rlkSet.AddRelinearizationKey(kgen.GenRelinearizationKey(sk))
+		ctList = append(ctList, encryptor.EncryptMsgNew(NewMessage(params), pk))
+	}
+
+	t.Run(GetTestName(params, "ShallowCopy/MulRelin/ "), func(t *testing.T) {
+		expected, err := eval.MulRelinNew(ctList[0], ctList[1], rlkSet).MarshalBinary()
+		require.NoError(t, err)
+
+		// evaluators from ShallowCopy share the key set but no pool
+		numWorkers := 4
+		results := make(chan []byte, numWorkers)
+		for i := 0; i < numWorkers; i++ {
+			go func(eval *Evaluator) {
+				data, _ := eval.MulRelinNew(ctList[0], ctList[1], rlkSet).MarshalBinary()
+				results <- data
+			}(eval.ShallowCopy())
+		}
+
+		for i := 0; i < numWorkers; i++ {
+			require.Equal(t, expected, <-results)
+		}
+	})
+
+	t.Run(GetTestName(params, "ShallowCopy/LazyJoin/ "), func(t *testing.T) {
+		id := "group0"
+		group := NewGroup(params, id)
+		skList := make([]*mkrlwe.SecretKey, 0)
+
+		for p := 0; p < 2; p++ {
+			sk := kgen.GenSecretKey(id)
+			skList = append(skList, sk)
+			group.AddShares(kgen.GenPartyShares(sk, []int{1}))
+		}
+
+		for _, sk := range skList {
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		msg := NewMessage(params)
+		for i := range msg.Value {
+			msg.Value[i] = int64(i % 16)
+		}
+		ctStored := encryptor.EncryptMsgNew(msg, group.PublicKey)
+
+		// a party joins the group, and the stored ciphertext is left as is
+		sk := kgen.GenSecretKey(id)
+		skList = append(skList, sk)
+		uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+
+		jkSet := mkrlwe.NewJoinKeySet()
+		jkSet.AddJoinKey(group.Join(kgen.GenPartyShares(sk, []int{1}), uaux))
+		group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+
+		grlkSet := NewRelinearizationKeySet(params)
+		group.UpdateKeySets(nil, grlkSet, nil, nil)
+
+		evalJoin := NewEvaluator(params)
+		evalJoin.SetJoinKeySet(jkSet)
+
+		ctFresh := encryptor.EncryptMsgNew(msg, group.PublicKey)
+		ctCopy := ctStored.CopyNew()
+
+		expected, err := evalJoin.AddNew(evalJoin.MulRelinNew(ctCopy, ctFresh, grlkSet), ctCopy).MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, group.Epoch, ctCopy.GetEpoch(id))
+		ctData, err := ctCopy.MarshalBinary()
+		require.NoError(t, err)
+
+		// evaluators from ShallowCopy share the stale operand, which is switched once in place
+		numWorkers := 4
+		results := make(chan []byte, numWorkers)
+		for i := 0; i < numWorkers; i++ {
+			go func(eval *Evaluator) {
+				data, _ := eval.AddNew(eval.MulRelinNew(ctStored, ctFresh, grlkSet), ctStored).MarshalBinary()
+				results <- data
+			}(evalJoin.ShallowCopy())
+		}
+
+		for i := 0; i < numWorkers; i++ {
+			require.Equal(t, expected, <-results)
+		}
+
+		data, err := ctStored.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, ctData, data)
+		require.Equal(t, group.Epoch, ctStored.GetEpoch(id))
+
+		ctRes := evalJoin.AddNew(evalJoin.MulRelinNew(ctStored, ctFresh, grlkSet), ctStored)
+		require.Equal(t, group.Epoch, ctRes.GetEpoch(id))
+
+		skSet := mkrlwe.NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+		msgRes := NewDecryptor(params).Decrypt(ctRes, skSet)
+
+		for i := range msgRes.Value {
+			require.Equal(t, msg.Value[i]*msg.Value[i]+msg.Value[i], msgRes.Value[i])
+		}
+	})
+}
+
+func Test_Smudging_BFV(t *testing.T) {
+	ids := []string{"user0", "user1"}
+	testContext := genUserTestParams(NewParametersFromLiteral(PN14QP439), ids)
+	params := testContext.params
+	ringQ := testContext.ringQ
+	skSet := testContext.skSet
+
+	msg, ct := newSumTestVectors(testContext, ids, 0, 8)
+
+	t.Run(GetTestName(params, "Smudging/Default/ "), func(t *testing.T) {
+		decryptor := NewDecryptor(params)
+		require.Equal(t, mkrlwe.DefaultSmudging(), decryptor.Smudging())
+
+		// the noise of a ciphertext given without its estimate is unknown, so no smudging noise fits in its budget
+		share := ct.CopyNew()
+		require.True(t, errors.Is(decryptor.TryPartialDecryptIP(share, skSet.GetSecretKey(ids[0])), mkrlwe.ErrNoiseBudget))
+		require.True(t, share.Value[ids[0]].Equals(ct.Value[ids[0]]))
+		require.Panics(t, func() { decryptor.PartialDecryptIP(share, skSet.GetSecretKey(ids[0])) })
+
+		ne := NewNoiseEstimator(params)
+		est := ne.Add(ne.EncryptMsg(ids[0], 1), ne.EncryptMsg(ids[1], 1))
+		require.Equal(t, decryptor.SmudgingBound(ct, est), decryptor.FreshSmudgingBound(ct))
+
+		// each party partially decrypts its own component, and the smudged shares are summed up with c0
+		ptxt := ct.Value["0"].CopyNew()
+		for _, id := range ids {
+			share := ct.CopyNew()
+			require.NoError(t, decryptor.PartialDecryptIPEst(share, skSet.GetSecretKey(id), est))
+			ringQ.Add(ptxt, share.Value[id], ptxt)
+		}
+
+		ringQ.Reduce(ptxt, decryptor.ptxtPool.Value)
+		msgOut := NewMessage(params)
+		decryptor.encoder.DecodeInt(decryptor.ptxtPool, msgOut.Value)
+		require.Equal(t, msg.Value, msgOut.Value)
+	})
+	t.Run(GetTestName(params, "Smudging/NoiseBudget/ "), func(t *testing.T) {
+		// the noise budget Delta / 2 is below Q, so that Security = 2 * logQ leaves no room for the smudging noise
+		decryptor := NewDecryptor(params)
+		decryptor.SetSmudging(mkrlwe.Smudging{Security: 2 * params.LogQ()})
+
+		_, err := decryptor.SmudgingSigma(decryptor.FreshSmudgingBound(ct))
+		require.True(t, errors.Is(err, mkrlwe.ErrNoiseBudget))
+
+		share := ct.CopyNew()
+		require.True(t, errors.Is(decryptor.PartialDecryptIPFresh(share, skSet.GetSecretKey(ids[0])), mkrlwe.ErrNoiseBudget))
+		require.True(t, share.Value[ids[0]].Equals(ct.Value[ids[0]]))
+	})
+}
+
+func Test_KeyError_BFV(t *testing.T) {
+	// the relinearization and rotation keys of both users carry their RLWE error
+	ids := []string{"user0", "user1"}
+	testContext := genUserTestParams(NewParametersFromLiteral(PN14QP439), ids)
+	params := testContext.params
+	decryptor := testContext.decryptor
+	eval := testContext.evaluator
+	skSet, rlkSet, rtkSet := testContext.skSet, testContext.rlkSet, testContext.rtkSet
+	slots := params.N() / 2
+
+	msg, ct := newSumTestVectors(testContext, ids, 0, 4)
+
+	t.Run(GetTestName(params, "KeyError/MulRelin/ "), func(t *testing.T) {
+		msgRes := decryptor.Decrypt(eval.MulRelinNew(ct, ct, rlkSet), skSet)
+
+		for i := range msg.Value {
+			require.Equal(t, msg.Value[i]*msg.Value[i], msgRes.Value[i])
+		}
+	})
+
+	t.Run(GetTestName(params, "KeyError/Rotate/ "), func(t *testing.T) {
+		msgRes := decryptor.Decrypt(eval.RotateNew(ct, 1, rtkSet), skSet)
+
+		for i := 0; i < slots; i++ {
+			require.Equal(t, msg.Value[(i+1)%slots], msgRes.Value[i])
+			require.Equal(t, msg.Value[(i+1)%slots+slots], msgRes.Value[i+slots])
+		}
+	})
+}
+
+func Test_Noise_BFV(t *testing.T) {
+	ids := []string{"user0", "user1"}
+	testContext := genUserTestParams(NewParametersFromLiteral(PN14QP439), ids)
+	params := testContext.params
+	kgen := testContext.kgen
+	encryptor := testContext.encryptor
+	decryptor := testContext.decryptor
+	eval := testContext.evaluator
+	skSet, rlkSet, rtkSet := testContext.skSet, testContext.rlkSet, testContext.rtkSet
+	ne := NewNoiseEstimator(params)
+
+	cts := make([]*Ciphertext, len(ids))
+	ests := make([]*mkrlwe.NoiseEstimate, len(ids))
+	for i, id := range ids {
+		_, cts[i] = newTestVectors(testContext, id, 0, int64(params.T()))
+		ests[i] = ne.EncryptMsg(id, 1)
+	}
+
+	checkNoise := func(ct *Ciphertext, est *mkrlwe.NoiseEstimate) {
+		logStd, _ := decryptor.MeasureNoise(ct, skSet)
+		t.Logf("estimated %.2f, measured %.2f", est.LogStd(), logStd)
+		require.InDelta(t, est.LogStd(), logStd, 2)
+		require.Greater(t, decryptor.NoiseBudget(ct, skSet), 0.0)
+	}
+
+	t.Run(GetTestName(params, "Noise/Encrypt/ "), func(t *testing.T) {
+		checkNoise(cts[0], ests[0])
+	})
+
+	ctAdd := eval.AddNew(cts[0], cts[1])
+	estAdd := ne.Add(ests[0], ests[1])
+
+	t.Run(GetTestName(params, "Noise/Add/ "), func(t *testing.T) {
+		checkNoise(ctAdd, estAdd)
+	})
+
+	t.Run(GetTestName(params, "Noise/Rotate/ "), func(t *testing.T) {
+		checkNoise(eval.RotateNew(ctAdd, 1, rtkSet), ne.Rotate(estAdd))
+	})
+
+	t.Run(GetTestName(params, "Noise/MulRelin/ "), func(t *testing.T) {
+		estMul := ne.MulRelin(ests[0], ests[1])
+		checkNoise(eval.MulRelinNew(cts[0], cts[1], rlkSet), estMul)
+		require.Less(t, ne.NoiseBudget(estMul), ne.NoiseBudget(estAdd))
+	})
+
+	t.Run(GetTestName(params, "Noise/GroupJoin/ "), func(t *testing.T) {
+		id := "group0"
+		numParties := 2
+
+		group := NewGroup(params, id)
+		skList := make([]*mkrlwe.SecretKey, 0)
+		for p := 0; p < numParties; p++ {
+			sk := kgen.GenSecretKey(id)
+			skList = append(skList, sk)
+			group.AddShares(kgen.GenPartyShares(sk, []int{1}))
+		}
+
+		for _, sk := range skList {
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		ct := encryptor.EncryptMsgNew(NewMessage(params), group.PublicKey)
+		est := ne.EncryptMsg(id, numParties)
+
+		// a new party joins the group
+		sk := kgen.GenSecretKey(id)
+		skList = append(skList, sk)
+
+		uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+		jk, jkhead := group.Join(kgen.GenPartyShares(sk, []int{1}), uaux)
+
+		skSet := mkrlwe.NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+		ctRes := eval.KSNew(ct, jk, jkhead)
+		estRes := ne.KS(est, numParties+1)
+		logStd, _ := decryptor.MeasureNoise(ctRes, skSet)
+		t.Logf("estimated %.2f, measured %.2f", estRes.LogStd(), logStd)
+		require.InDelta(t, estRes.LogStd(), logStd, 2)
+	})
+}
+
 func testKS(testContext *testParams, userList []string, gsk *mkrlwe.SecretKey, gpk *mkrlwe.PublicKey, sk []*mkrlwe.SecretKey, pk []*mkrlwe.PublicKey, swk []*mkrlwe.SWK, swkhead []*mkrlwe.SWK, t *testing.T) (msg *Message, ctxt *Ciphertext, ctsk *Ciphertext) {
 
 	params := testContext.params
@@ -957,8 +1828,10 @@ func testKS(testContext *testParams, userList []string, gsk *mkrlwe.SecretKey, g
 		ctdecList[p] = ctOut.CopyNew()
 	}
 
+	ne := NewNoiseEstimator(params)
+	est := ne.KS(ne.EncryptSkMsg(sk[0].ID, 1), numUsers)
 	for p := range sk {
-		testContext.decryptor.PartialDecryptIP(ctdecList[p], sk[p])
+		require.NoError(t, testContext.decryptor.PartialDecryptIPEst(ctdecList[p], sk[p], est))
 	}
 	for p := range sk {
 		if p != 0 {
@@ -1469,17 +2342,13 @@ func testJoinPartyMP(testContext *testParams,
 	// 	testContext2.rtkSet.AddRotationKey(grtk)
 	// }
 
-	for idx, _ := range rtksup[0] {
-		rtk := make([]*mkrlwe.RotationKey, numParties+JoiningParties)
-		for p := 0; p < numParties+JoiningParties; p++ {
-			rtk[p] = rtksup[p][idx]
-		}
-		for i := 0; i < beta; i++ {
-			params.RingQP().AddLvl(levelQ, levelP, testContext.rtkSet.Value["group0"][idx].Value.Value[i], rtksup[numParties+JoiningParties-1][idx].Value.Value[i], testContext.rtkSet.Value["group0"][idx].Value.Value[i])
-			_ = idx
-		}
-		testContext2.rtkSet.AddRotationKey(testContext.rtkSet.Value["group0"][idx])
+	// every rotation key of the group and its conjugation key absorb the shares of the joining party
+	joinShares := &mkrlwe.PartyShares{
+		PublicKey:      pkup[numParties+JoiningParties-1],
+		ConjugationKey: cjkup[numParties+JoiningParties-1],
+		RotationKeys:   rtksup[numParties+JoiningParties-1],
 	}
+	testContext2.kgen.JoinKeySets(joinShares, testContext2.rtkSet, testContext2.cjkSet)
 
 	for i := 0; i < beta; i++ {
 		params.RingQP().AddLvl(levelQ, levelP, rlkup[numParties+JoiningParties-1].Value[0].Value[0].Value[i], grlk.Value[0].Value[0].Value[i], grlk.Value[0].Value[0].Value[i])
@@ -1488,11 +2357,8 @@ func testJoinPartyMP(testContext *testParams,
 
 		params.RingQP().AddLvl(levelQ, levelP, rlkup[numParties+JoiningParties-1].Value[1].Value[0].Value[i], grlk.Value[1].Value[0].Value[i], grlk.Value[1].Value[0].Value[i])
 		params.RingQP().AddLvl(levelQ, levelP, rlkup[numParties+JoiningParties-1].Value[1].Value[1].Value[i], grlk.Value[1].Value[1].Value[i], grlk.Value[1].Value[1].Value[i])
-
-		params.RingQP().AddLvl(levelQ, levelP, gcjk.Value.Value[i], cjkup[numParties+JoiningParties-1].Value.Value[i], gcjk.Value.Value[i])
 	}
 	testContext2.rlkSet.AddRelinearizationKey(grlk)
-	testContext2.cjkSet.AddConjugationKey(gcjk)
 
 	////////////////////////// SWK //////////////////////////////////////
 	for p := numParties; p < numParties+JoiningParties; p++ { //for each group...
@@ -1569,7 +2435,7 @@ func testJoinPartyMP(testContext *testParams,
 	jkup = jk
 	jkheadup = jkhead
 	grlkup = grlk
-	gcjkup = gcjk
+	gcjkup = testContext2.cjkSet.GetConjugationKey("group0")
 	grtkup = grtk
 	gskup = gsktemp
 	gpkup = gpk
@@ -0,0 +1,111 @@
+package mkckks
+
+import (
+	"math"
+	"mk-lattigo/mkrlwe"
+)
+
+// checkCiphertexts returns an error of kind mkrlwe.ErrInvalidInput if one of cts is not a ciphertext
+// of the parameters of the evaluator with a positive scale.
+func (eval *Evaluator) checkCiphertexts(cts ...*Ciphertext) error {
+	for _, ct := range cts {
+		if ct == nil {
+			return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: nil ciphertext")
+		}
+
+		if err := mkrlwe.CheckCiphertext(eval.params.Parameters, ct.Ciphertext); err != nil {
+			return err
+		}
+
+		if !(ct.Scale > 0) || math.IsInf(ct.Scale, 1) {
+			return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: ciphertext scale is not positive")
+		}
+	}
+
+	return nil
+}
+
+// checkKeySwitch returns an error of kind mkrlwe.ErrInvalidInput if the parameters of the evaluator have no modulus P.
+func (eval *Evaluator) checkKeySwitch(opname string) error {
+	if eval.ksw == nil {
+		return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "Cannot %s: modulus P is empty", opname)
+	}
+	return nil
+}
+
+// extended returns ct tagged with the epochs it is extended to by the evaluator, see mkrlwe.JoinKeySet.ExtendEpochs.
+func (eval *Evaluator) extended(ct *Ciphertext) *mkrlwe.Ciphertext {
+	return eval.jkSet.ExtendEpochs(ct.Ciphertext)
+}
+
+// checkBinary returns the error of AddNew and SubNew.
+func (eval *Evaluator) checkBinary(opname string, op0, op1 *Ciphertext) error {
+	if err := eval.checkCiphertexts(op0, op1); err != nil {
+		return err
+	}
+
+	return mkrlwe.CheckEpochs(opname, eval.extended(op0), eval.extended(op1))
+}
+
+// checkMulRelin returns the error of MulRelinNew, or of MulRelinHoistedNew if the hoisted forms are not nil.
+func (eval *Evaluator) checkMulRelin(op0, op1 *Ciphertext, op0Hoisted, op1Hoisted *mkrlwe.HoistedCiphertext, rlkSet *mkrlwe.RelinearizationKeySet) error {
+	if err := eval.checkCiphertexts(op0, op1); err != nil {
+		return err
+	}
+
+	if err := eval.checkKeySwitch("MulRelin"); err != nil {
+		return err
+	}
+
+	level := op0.Level()
+	if op1.Level() < level {
+		level = op1.Level()
+	}
+
+	return eval.ksw.CheckMulAndRelinHoisted(eval.extended(op0), eval.extended(op1), op0Hoisted, op1Hoisted, rlkSet, level)
+}
+
+// checkRotate returns the error of RotateNew, or of RotateHoistedNew if hoisted is set.
+func (eval *Evaluator) checkRotate(ct0 *Ciphertext, rotidx int, ct0Hoisted *mkrlwe.HoistedCiphertext, rkSet *mkrlwe.RotationKeySet, hoisted bool) error {
+	if err := eval.checkCiphertexts(ct0); err != nil {
+		return err
+	}
+
+	if err := eval.checkKeySwitch("Rotate"); err != nil {
+		return err
+	}
+
+	// normalize rotidx
+	for rotidx >= eval.params.N()/2 {
+		rotidx -= eval.params.N() / 2
+	}
+
+	for rotidx < 0 {
+		rotidx += eval.params.N() / 2
+	}
+
+	if rotidx == 0 {
+		return nil
+	}
+
+	ct := eval.extended(ct0)
+
+	if hoisted {
+		return eval.ksw.CheckRotateHoisted(ct, rotidx, ct0Hoisted, rkSet, ct0.Level())
+	}
+
+	if _, in := eval.params.CRS[rotidx]; in {
+		return eval.ksw.CheckRotate(ct, rotidx, rkSet, ct0.Level())
+	}
+
+	for k := 1; rotidx > 0; k *= 2 {
+		if rotidx%2 != 0 {
+			if err := eval.ksw.CheckRotate(ct, k, rkSet, ct0.Level()); err != nil {
+				return err
+			}
+		}
+		rotidx /= 2
+	}
+
+	return nil
+}
@@ -1,10 +1,14 @@
 package mkckks
 
 import (
+	"math"
+
 	"mk-lattigo/mkrlwe"
 
 	"github.com/ldsec/lattigo/v2/ckks"
 	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/ldsec/lattigo/v2/rlwe"
+	"github.com/ldsec/lattigo/v2/utils"
 )
 
 type Decryptor struct {
@@ -16,10 +20,20 @@ type Decryptor struct {
 
 // NewDecryptor instantiates a Decryptor for the CKKS scheme.
 func NewDecryptor(params Parameters) *Decryptor {
+	prng, err := utils.NewPRNG()
+	if err != nil {
+		panic(err)
+	}
+
+	return NewDecryptorWithPRNG(params, prng)
+}
+
+// NewDecryptorWithPRNG instantiates a Decryptor for the CKKS scheme, whose noise sampler reads from the given PRNG.
+func NewDecryptorWithPRNG(params Parameters, prng utils.PRNG) *Decryptor {
 	ckksParams, _ := ckks.NewParameters(params.Parameters.Parameters, params.LogSlots(), params.Scale())
 
 	ret := new(Decryptor)
-	ret.Decryptor = mkrlwe.NewDecryptor(params.Parameters)
+	ret.Decryptor = mkrlwe.NewDecryptorWithPRNG(params.Parameters, prng)
 	ret.encoder = ckks.NewEncoder(ckksParams)
 	ret.params = params
 	ret.ptxtPool = ckks.NewPlaintext(ckksParams, params.MaxLevel(), params.Scale())
@@ -27,14 +41,117 @@ func NewDecryptor(params Parameters) *Decryptor {
 	return ret
 }
 
+// SmudgingBound returns the smudging bound of ct: its noise is given by est, and the error it induces on the decoded slots
+// must stay below 1/2, that is its coefficients below ct.Scale / 2 / sqrt(N).
+// If est is nil, the noise of ct is unknown and no smudging noise fits in the bound, see mkrlwe.Decryptor.SmudgingBound.
+func (dec *Decryptor) SmudgingBound(ct *Ciphertext, est *NoiseEstimate) mkrlwe.SmudgingBound {
+	var rlweEst *mkrlwe.NoiseEstimate
+	if est != nil {
+		rlweEst = est.NoiseEstimate
+	}
+
+	bound := dec.Decryptor.SmudgingBound(ct.Ciphertext, rlweEst)
+	bound.LogBudget = dec.logBudget(ct)
+
+	return bound
+}
+
+// FreshSmudgingBound is SmudgingBound for a fresh encryption under the public key of each of the ids of ct.
+// It underestimates the noise of a ciphertext which went through evaluation.
+func (dec *Decryptor) FreshSmudgingBound(ct *Ciphertext) mkrlwe.SmudgingBound {
+	bound := dec.Decryptor.FreshSmudgingBound(ct.Ciphertext)
+	bound.LogBudget = dec.logBudget(ct)
+
+	return bound
+}
+
+// logBudget returns the log2 of the bound the coefficients of the noise of the decryption of ct must stay below.
+func (dec *Decryptor) logBudget(ct *Ciphertext) float64 {
+	return math.Log2(ct.Scale/2) - float64(dec.params.LogN())/2
+}
+
 // PartialDecrypt partially decrypts the ct with single secretkey sk and update result inplace
+// As the noise of ct is unknown, it panics with an error of kind mkrlwe.ErrNoiseBudget unless the smudging is disabled:
+// the smudged partial decryptions are given by PartialDecryptEst, or by PartialDecryptFresh for a fresh ciphertext.
 func (dec *Decryptor) PartialDecrypt(ct *Ciphertext, sk *mkrlwe.SecretKey) {
-	dec.Decryptor.PartialDecrypt(ct.Ciphertext, sk)
+	if err := dec.TryPartialDecrypt(ct, sk); err != nil {
+		panic(err)
+	}
 }
 
-// PartialDecrypt partially decrypts the ct with single secretkey sk and update result inplace
+// TryPartialDecrypt is PartialDecrypt returning an error instead of panicking on an invalid request,
+// in which case ct is left unchanged.
+func (dec *Decryptor) TryPartialDecrypt(ct *Ciphertext, sk *mkrlwe.SecretKey) error {
+	return dec.PartialDecryptEst(ct, sk, nil)
+}
+
+// PartialDecryptEst is TryPartialDecrypt with the smudging noise sized for a ciphertext of noise estimate est, see SmudgingBound.
+func (dec *Decryptor) PartialDecryptEst(ct *Ciphertext, sk *mkrlwe.SecretKey, est *NoiseEstimate) error {
+	if ct == nil {
+		return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: nil ciphertext")
+	}
+
+	if err := dec.Decryptor.CheckPartialDecrypt(ct.Ciphertext, sk); err != nil {
+		return err
+	}
+
+	return dec.Decryptor.PartialDecryptBound(ct.Ciphertext, sk, dec.SmudgingBound(ct, est))
+}
+
+// PartialDecryptFresh is PartialDecryptEst with the smudging noise sized by FreshSmudgingBound. It must only be used on a fresh encryption,
+// as it under-smudges the partial decryptions of a ciphertext which went through evaluation.
+func (dec *Decryptor) PartialDecryptFresh(ct *Ciphertext, sk *mkrlwe.SecretKey) error {
+	if ct == nil {
+		return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: nil ciphertext")
+	}
+
+	if err := dec.Decryptor.CheckPartialDecrypt(ct.Ciphertext, sk); err != nil {
+		return err
+	}
+
+	return dec.Decryptor.PartialDecryptBound(ct.Ciphertext, sk, dec.FreshSmudgingBound(ct))
+}
+
+// PartialDecryptIP replaces the component of ct under sk.ID by its product with sk inplace.
+// As the noise of ct is unknown, it panics with an error of kind mkrlwe.ErrNoiseBudget unless the smudging is disabled:
+// the smudged partial decryptions are given by PartialDecryptIPEst, or by PartialDecryptIPFresh for a fresh ciphertext.
 func (dec *Decryptor) PartialDecryptIP(ct *Ciphertext, sk *mkrlwe.SecretKey) {
-	dec.Decryptor.PartialDecryptIP(ct.Ciphertext, sk)
+	if err := dec.TryPartialDecryptIP(ct, sk); err != nil {
+		panic(err)
+	}
+}
+
+// TryPartialDecryptIP is PartialDecryptIP returning an error instead of panicking on an invalid request,
+// in which case ct is left unchanged.
+func (dec *Decryptor) TryPartialDecryptIP(ct *Ciphertext, sk *mkrlwe.SecretKey) error {
+	return dec.PartialDecryptIPEst(ct, sk, nil)
+}
+
+// PartialDecryptIPEst is TryPartialDecryptIP with the smudging noise sized for a ciphertext of noise estimate est, see SmudgingBound.
+func (dec *Decryptor) PartialDecryptIPEst(ct *Ciphertext, sk *mkrlwe.SecretKey, est *NoiseEstimate) error {
+	if ct == nil {
+		return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: nil ciphertext")
+	}
+
+	if err := dec.Decryptor.CheckPartialDecrypt(ct.Ciphertext, sk); err != nil {
+		return err
+	}
+
+	return dec.Decryptor.PartialDecryptIPBound(ct.Ciphertext, sk, dec.SmudgingBound(ct, est))
+}
+
+// PartialDecryptIPFresh is PartialDecryptIPEst with the smudging noise sized by FreshSmudgingBound. It must only be used on a fresh encryption,
+// as it under-smudges the partial decryptions of a ciphertext which went through evaluation.
+func (dec *Decryptor) PartialDecryptIPFresh(ct *Ciphertext, sk *mkrlwe.SecretKey) error {
+	if ct == nil {
+		return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: nil ciphertext")
+	}
+
+	if err := dec.Decryptor.CheckPartialDecrypt(ct.Ciphertext, sk); err != nil {
+		return err
+	}
+
+	return dec.Decryptor.PartialDecryptIPBound(ct.Ciphertext, sk, dec.FreshSmudgingBound(ct))
 }
 
 // Decrypt decrypts the ciphertext with given secretkey set and write the result in ptOut.
@@ -51,6 +168,20 @@ func (dec *Decryptor) Decrypt(ciphertext *Ciphertext, skSet *mkrlwe.SecretKeySet
 	return
 }
 
+// TryDecrypt is Decrypt returning an error instead of panicking on an invalid request,
+// e.g. of kind mkrlwe.ErrMissingKey if skSet lacks the secret key of a component of the ciphertext.
+func (dec *Decryptor) TryDecrypt(ciphertext *Ciphertext, skSet *mkrlwe.SecretKeySet) (msg *Message, err error) {
+	if ciphertext == nil {
+		return nil, mkrlwe.NewError(mkrlwe.ErrInvalidInput, "invalid input: nil ciphertext")
+	}
+
+	if err = dec.Decryptor.CheckDecrypt(ciphertext.Ciphertext, skSet); err != nil {
+		return nil, err
+	}
+
+	return dec.Decrypt(ciphertext, skSet), nil
+}
+
 func (dec *Decryptor) DecryptSk(ciphertext *Ciphertext, sk *mkrlwe.SecretKey) (msg *Message) {
 	ctTmp := ciphertext.CopyNew()
 
@@ -62,18 +193,10 @@ func (dec *Decryptor) DecryptSk(ciphertext *Ciphertext, sk *mkrlwe.SecretKey) (m
 	return
 }
 
-// Decrypt decrypts the ciphertext with given secretkey set and write the result in ptOut.
-// The level of the output plaintext is min(ciphertext.Level(), plaintext.Level())
-// Output domain will match plaintext.Value.IsNTT value.
+// DecryptToPtxt decrypts the ciphertext with given secretkey set and returns the result in a newly created polynomial.
 func (dec *Decryptor) DecryptToPtxt(ciphertext *Ciphertext, skSet *mkrlwe.SecretKeySet) *ring.Poly {
-	ctTmp := ciphertext.CopyNew()
-
-	idset := ctTmp.IDSet()
-	for _, sk := range skSet.Value {
-		if idset.Has(sk.ID) {
-			dec.PartialDecrypt(ctTmp, sk)
-		}
-	}
+	ptxt := &rlwe.Plaintext{Value: dec.params.RingQ().NewPolyLvl(ciphertext.Level())}
+	dec.Decryptor.Decrypt(ciphertext.Ciphertext, skSet, ptxt)
 
-	return ctTmp.Value["0"]
+	return ptxt.Value
 }
@@ -32,6 +32,24 @@ func (ct *Ciphertext) CopyNew() (ctc *Ciphertext) {
 	return
 }
 
+// SeededCiphertext is a secret-key encryption whose uniform component is represented by its seed,
+// see mkrlwe.SeededCiphertext. It is created with Encryptor.EncryptSkMsgNewSeeded.
+type SeededCiphertext struct {
+	*mkrlwe.SeededCiphertext
+	Scale float64
+}
+
+// Expand returns a new Ciphertext equal to the secret-key encryption represented by ct,
+// with its uniform component expanded from the seed.
+func (ct *SeededCiphertext) Expand(params Parameters) (*Ciphertext, error) {
+	el, err := ct.SeededCiphertext.Expand(params.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Ciphertext{Ciphertext: el, Scale: ct.Scale}, nil
+}
+
 type Message struct {
 	Value []complex128
 }
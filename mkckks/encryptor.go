@@ -5,6 +5,7 @@ import (
 
 	"github.com/ldsec/lattigo/v2/ckks"
 	"github.com/ldsec/lattigo/v2/rlwe"
+	"github.com/ldsec/lattigo/v2/utils"
 )
 
 type Encryptor struct {
@@ -18,10 +19,20 @@ type Encryptor struct {
 // NewEncryptor instatiates a new Encryptor for the CKKS scheme. The key argument can
 // be either a *rlwe.PublicKey or a *rlwe.SecretKey.
 func NewEncryptor(params Parameters) *Encryptor {
+	prng, err := utils.NewPRNG()
+	if err != nil {
+		panic(err)
+	}
+
+	return NewEncryptorWithPRNG(params, prng)
+}
+
+// NewEncryptorWithPRNG instatiates a new Encryptor for the CKKS scheme, whose samplers read from the given PRNG.
+func NewEncryptorWithPRNG(params Parameters, prng utils.PRNG) *Encryptor {
 	ckksParams, _ := ckks.NewParameters(params.Parameters.Parameters, params.LogSlots(), params.Scale())
 
 	ret := new(Encryptor)
-	ret.Encryptor = mkrlwe.NewEncryptor(params.Parameters)
+	ret.Encryptor = mkrlwe.NewEncryptorWithPRNG(params.Parameters, prng)
 	ret.encoder = ckks.NewEncoder(ckksParams)
 	ret.params = params
 	ret.ckksParams = ckksParams
@@ -34,12 +45,12 @@ func NewEncryptor(params Parameters) *Encryptor {
 // and NewFastEncryptor).
 // The level of the output ciphertext is min(plaintext.Level(), ciphertext.Level()).
 func (enc *Encryptor) EncryptPtxt(plaintext *ckks.Plaintext, pk *mkrlwe.PublicKey, ctOut *Ciphertext) {
-	enc.Encryptor.Encrypt(&rlwe.Plaintext{Value: plaintext.Value}, pk, &mkrlwe.Ciphertext{Value: ctOut.Value})
+	enc.Encryptor.Encrypt(&rlwe.Plaintext{Value: plaintext.Value}, pk, ctOut.Ciphertext)
 	ctOut.Scale = plaintext.Scale
 }
 
 func (enc *Encryptor) EncryptSkPtxt(plaintext *ckks.Plaintext, sk *mkrlwe.SecretKey, ctOut *Ciphertext) {
-	enc.Encryptor.EncryptSk(&rlwe.Plaintext{Value: plaintext.Value}, sk, &mkrlwe.Ciphertext{Value: ctOut.Value})
+	enc.Encryptor.EncryptSk(&rlwe.Plaintext{Value: plaintext.Value}, sk, ctOut.Ciphertext)
 	ctOut.Scale = plaintext.Scale
 }
 
@@ -79,6 +90,18 @@ func (enc *Encryptor) EncryptSkMsgNew(msg *Message, sk *mkrlwe.SecretKey) (ctOut
 	return
 }
 
+// EncryptSkMsgNewSeeded encode message and then encrypts it with sk in a newly created SeededCiphertext,
+// whose uniform component is represented by its seed.
+func (enc *Encryptor) EncryptSkMsgNewSeeded(msg *Message, sk *mkrlwe.SecretKey) (ctOut *SeededCiphertext) {
+	ctOut = &SeededCiphertext{SeededCiphertext: mkrlwe.NewSeededCiphertext(enc.params.Parameters, enc.params.MaxLevel())}
+
+	enc.encoder.Encode(enc.ptxtPool, msg.Value, enc.params.LogSlots())
+	enc.Encryptor.EncryptSkSeeded(&rlwe.Plaintext{Value: enc.ptxtPool.Value}, sk, ctOut.SeededCiphertext)
+	ctOut.Scale = enc.ptxtPool.Scale
+
+	return
+}
+
 func (enc *Encryptor) EncodeMsgNew(msg *Message) (ptxtOut *ckks.Plaintext) {
 	ptxtOut = ckks.NewPlaintext(enc.ckksParams, enc.params.MaxLevel(), enc.params.Scale())
 	enc.encoder.Encode(ptxtOut, msg.Value, enc.params.LogSlots())
@@ -16,6 +16,8 @@ type Evaluator struct {
 	ksw       *mkrlwe.KeySwitcher
 	ctxtPool  *mkrlwe.Ciphertext
 	polyQPool *ring.Poly
+	hoistPool [2]*mkrlwe.HoistedCiphertext
+	jkSet     *mkrlwe.JoinKeySet
 }
 
 // NewEvaluator creates a new Evaluator, that can be used to do homomorphic
@@ -35,9 +37,62 @@ func NewEvaluator(params Parameters) *Evaluator {
 	eval.polyQPool = ringQ.NewPoly()
 	eval.polyQPool.IsNTT = true
 
+	eval.hoistPool = [2]*mkrlwe.HoistedCiphertext{mkrlwe.NewHoistedCiphertext(), mkrlwe.NewHoistedCiphertext()}
+
 	return eval
 }
 
+// ShallowCopy creates a shallow copy of this Evaluator in which all the read-only data-structures are
+// shared with the receiver and the temporary buffers are reallocated. The receiver and the returned
+// Evaluator can be used concurrently, also with shared key sets, as long as they do not write on the same ciphertexts.
+// They share the JoinKeySet too, which is only read, and can share operands from an earlier epoch, see SetJoinKeySet.
+func (eval *Evaluator) ShallowCopy() *Evaluator {
+	params := eval.params
+
+	evalOut := new(Evaluator)
+	evalOut.params = params
+	evalOut.jkSet = eval.jkSet
+
+	if eval.ksw != nil {
+		evalOut.ksw = eval.ksw.ShallowCopy()
+	}
+
+	evalOut.ctxtPool = mkrlwe.NewCiphertext(params.Parameters, mkrlwe.NewIDSet(), params.MaxLevel())
+
+	evalOut.polyQPool = params.RingQ().NewPoly()
+	evalOut.polyQPool.IsNTT = true
+
+	evalOut.hoistPool = [2]*mkrlwe.HoistedCiphertext{mkrlwe.NewHoistedCiphertext(), mkrlwe.NewHoistedCiphertext()}
+
+	return evalOut
+}
+
+// SetJoinKeySet enables the lazy extension of ciphertexts after group updates:
+// the components of an operand from an earlier epoch are switched in place through the join keys of jkSet
+// the first time an operation touches it. Evaluators from ShallowCopy can share stale operands,
+// which are switched once, see mkrlwe.KeySwitcher.Extend. A nil jkSet disables it.
+func (eval *Evaluator) SetJoinKeySet(jkSet *mkrlwe.JoinKeySet) {
+	eval.jkSet = jkSet
+}
+
+// extend switches ct in place to the last epochs of the join keys of the evaluator and returns it.
+func (eval *Evaluator) extend(ct *Ciphertext) *Ciphertext {
+	if eval.jkSet != nil {
+		eval.ksw.Extend(ct.Ciphertext, eval.jkSet)
+	}
+
+	return ct
+}
+
+// extendPair is extend on the operands of a binary operation, which are extended once if they are the same.
+func (eval *Evaluator) extendPair(op0, op1 *Ciphertext) (*Ciphertext, *Ciphertext) {
+	ext0 := eval.extend(op0)
+	if op1 == op0 {
+		return ext0, ext0
+	}
+	return ext0, eval.extend(op1)
+}
+
 func (eval *Evaluator) getConstAndScale(level int, constant interface{}) (cReal, cImag, scale float64) {
 
 	// Converts to float64 and determines if a scaling is required (which is the case if either real or imag have a rational part)
@@ -94,9 +149,23 @@ func (eval *Evaluator) getConstAndScale(level int, constant interface{}) (cReal,
 	return
 }
 
+// TryDropLevelNew is DropLevelNew returning an error instead of panicking on an invalid request.
+func (eval *Evaluator) TryDropLevelNew(ct0 *Ciphertext, levels int) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct0); err != nil {
+		return nil, err
+	}
+
+	if levels < 0 || levels > ct0.Level() {
+		return nil, mkrlwe.NewError(mkrlwe.ErrLevelMismatch, "Cannot DropLevel: cannot drop %d levels at level %d", levels, ct0.Level())
+	}
+
+	return eval.DropLevelNew(ct0, levels), nil
+}
+
 // DropLevelNew reduces the level of ct0 by levels and returns the result in a newly created element.
 // No rescaling is applied during this procedure.
 func (eval *Evaluator) DropLevelNew(ct0 *Ciphertext, levels int) (ctOut *Ciphertext) {
+	ct0 = eval.extend(ct0)
 	ctOut = ct0.CopyNew()
 	eval.DropLevel(ctOut, levels)
 	return
@@ -107,15 +176,39 @@ func (eval *Evaluator) DropLevelNew(ct0 *Ciphertext, levels int) (ctOut *Ciphert
 func (eval *Evaluator) DropLevel(ct0 *Ciphertext, levels int) {
 	level := ct0.Level()
 
-	for id := range ct0.Value {
+	for _, id := range ct0.IDs() {
 		ct0.Value[id].Coeffs = ct0.Value[id].Coeffs[:level+1-levels]
 	}
 }
 
+// TryMultByConst is MultByConst returning an error instead of panicking on an invalid request,
+// in which case ctOut is left unchanged.
+func (eval *Evaluator) TryMultByConst(ct0 *Ciphertext, constant interface{}, ctOut *Ciphertext) error {
+	if err := eval.checkCiphertexts(ct0, ctOut); err != nil {
+		return err
+	}
+
+	for _, id := range ct0.IDs() {
+		if _, in := ctOut.Value[id]; !in {
+			return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "Cannot MultByConst: ctOut has no component with id %s", id)
+		}
+	}
+
+	switch constant.(type) {
+	case complex128, float64, uint64, int64, int:
+	default:
+		return mkrlwe.NewError(mkrlwe.ErrInvalidInput, "Cannot MultByConst: invalid constant type %T", constant)
+	}
+
+	eval.MultByConst(ct0, constant, ctOut)
+	return nil
+}
+
 // MultByConst multiplies ct0 by the input constant and returns the result in ctOut.
 // The scale of the output element will depend on the scale of the input element and the constant (if the constant
 // needs to be scaled (its rational part is not zero)). The constant can be a uint64, int64, float64 or complex128.
 func (eval *Evaluator) MultByConst(ct0 *Ciphertext, constant interface{}, ctOut *Ciphertext) {
+	ct0 = eval.extend(ct0)
 
 	var level = utils.MinInt(ct0.Level(), ctOut.Level())
 
@@ -150,7 +243,7 @@ func (eval *Evaluator) MultByConst(ct0 *Ciphertext, constant interface{}, ctOut
 
 		scaledConst = ring.MForm(scaledConst, qi, bredParams)
 
-		for u := range ct0.Value {
+		for _, u := range ct0.IDs() {
 			p0tmp := ct0.Value[u].Coeffs[i]
 			p1tmp := ctOut.Value[u].Coeffs[i]
 
@@ -175,7 +268,7 @@ func (eval *Evaluator) MultByConst(ct0 *Ciphertext, constant interface{}, ctOut
 			scaledConst = ring.MForm(scaledConst, qi, bredParams)
 		}
 
-		for u := range ct0.Value {
+		for _, u := range ct0.IDs() {
 			p0tmp := ct0.Value[u].Coeffs[i]
 			p1tmp := ctOut.Value[u].Coeffs[i]
 			for j := ringQ.N >> 1; j < ringQ.N; j = j + 8 {
@@ -196,12 +289,17 @@ func (eval *Evaluator) MultByConst(ct0 *Ciphertext, constant interface{}, ctOut
 	}
 
 	ctOut.Scale = ct0.Scale * scale
+	ctOut.CopyEpochs(ct0.Ciphertext)
 }
 
 func (eval *Evaluator) evaluateInPlace(c0, c1, ctOut *Ciphertext, evaluate func(int, *ring.Poly, *ring.Poly, *ring.Poly)) {
 
 	var tmp0, tmp1 *mkrlwe.Ciphertext
 
+	if err := mkrlwe.CheckEpochs("evaluate", c0.Ciphertext, c1.Ciphertext); err != nil {
+		panic(err)
+	}
+
 	idset0 := c0.IDSet()
 	idset1 := c1.IDSet()
 	idset := idset0.Union(idset1)
@@ -292,7 +390,7 @@ func (eval *Evaluator) evaluateInPlace(c0, c1, ctOut *Ciphertext, evaluate func(
 	}
 
 	evaluate(level, tmp0.Value["0"], tmp1.Value["0"], ctOut.Value["0"])
-	for id := range ctOut.IDSet().Value {
+	for _, id := range ctOut.IDSet().Slice() {
 		if !idset0.Has(id) {
 			ring.CopyValuesLvl(level, tmp1.Value[id], ctOut.Value[id])
 		} else if !idset1.Has(id) {
@@ -302,6 +400,7 @@ func (eval *Evaluator) evaluateInPlace(c0, c1, ctOut *Ciphertext, evaluate func(
 		}
 	}
 
+	ctOut.MergeEpochs(c0.Ciphertext, c1.Ciphertext)
 }
 
 func (eval *Evaluator) newCiphertextBinary(op0, op1 *Ciphertext) (ctOut *Ciphertext) {
@@ -315,10 +414,21 @@ func (eval *Evaluator) newCiphertextBinary(op0, op1 *Ciphertext) (ctOut *Ciphert
 
 // Add adds op0 to op1 and returns the result in ctOut.
 func (eval *Evaluator) add(op0, op1 *Ciphertext, ctOut *Ciphertext) {
+	op0, op1 = eval.extendPair(op0, op1)
 	eval.evaluateInPlace(op0, op1, ctOut, eval.params.RingQ().AddLvl)
 
 }
 
+// TryAddNew is AddNew returning an error instead of panicking on an invalid request,
+// e.g. operands from different epochs (mkrlwe.ErrEpochMismatch).
+func (eval *Evaluator) TryAddNew(op0, op1 *Ciphertext) (ctOut *Ciphertext, err error) {
+	if err = eval.checkBinary("AddNew", op0, op1); err != nil {
+		return nil, err
+	}
+
+	return eval.AddNew(op0, op1), nil
+}
+
 // AddNew adds op0 to op1 and returns the result in a newly created element.
 func (eval *Evaluator) AddNew(op0, op1 *Ciphertext) (ctOut *Ciphertext) {
 	ctOut = eval.newCiphertextBinary(op0, op1)
@@ -328,6 +438,7 @@ func (eval *Evaluator) AddNew(op0, op1 *Ciphertext) (ctOut *Ciphertext) {
 
 // Sub subtracts op1 from op0 and returns the result in ctOut.
 func (eval *Evaluator) sub(op0, op1 *Ciphertext, ctOut *Ciphertext) {
+	op0, op1 = eval.extendPair(op0, op1)
 
 	eval.evaluateInPlace(op0, op1, ctOut, eval.params.RingQ().SubLvl)
 
@@ -335,7 +446,7 @@ func (eval *Evaluator) sub(op0, op1 *Ciphertext, ctOut *Ciphertext) {
 
 	//negate polys which is not contained in op0
 	idset0 := op0.IDSet()
-	for id := range ctOut.IDSet().Value {
+	for _, id := range ctOut.IDSet().Slice() {
 		if !idset0.Has(id) {
 			eval.params.RingQ().NegLvl(level, ctOut.Value[id], ctOut.Value[id])
 		}
@@ -343,6 +454,16 @@ func (eval *Evaluator) sub(op0, op1 *Ciphertext, ctOut *Ciphertext) {
 
 }
 
+// TrySubNew is SubNew returning an error instead of panicking on an invalid request,
+// e.g. operands from different epochs (mkrlwe.ErrEpochMismatch).
+func (eval *Evaluator) TrySubNew(op0, op1 *Ciphertext) (ctOut *Ciphertext, err error) {
+	if err = eval.checkBinary("SubNew", op0, op1); err != nil {
+		return nil, err
+	}
+
+	return eval.SubNew(op0, op1), nil
+}
+
 // SubNew subtracts op1 from op0 and returns the result in a newly created element.
 func (eval *Evaluator) SubNew(op0, op1 *Ciphertext) (ctOut *Ciphertext) {
 	ctOut = eval.newCiphertextBinary(op0, op1)
@@ -356,24 +477,26 @@ func (eval *Evaluator) SubNew(op0, op1 *Ciphertext) (ctOut *Ciphertext) {
 // in ctOut. Since all the moduli in the moduli chain are generated to be close to the
 // original scale, this procedure is equivalent to dividing the input element by the scale and adding
 // some error.
-// Returns an error if "minScale <= 0", ct.Scale = 0, ct.Level() = 0, ct.IsNTT() != true or if ct.Leve() != ctOut.Level()
+// Returns an error if "minScale <= 0", ct is not a ciphertext of the parameters, ct.Scale <= 0 or ct.Level() = 0.
 func (eval *Evaluator) Rescale(ctIn *Ciphertext, minScale float64, ctOut *Ciphertext) (err error) {
-
 	ringQ := eval.params.RingQ()
 
 	if minScale <= 0 {
 		return errors.New("cannot Rescale: minScale is 0")
 	}
 
-	if ctIn.Scale == 0 {
-		return errors.New("cannot Rescale: ciphertext scale is 0")
+	if err = eval.checkCiphertexts(ctIn); err != nil {
+		return err
 	}
 
 	if ctIn.Level() == 0 {
 		return errors.New("cannot Rescale: input Ciphertext already at level 0")
 	}
 
+	ctIn = eval.extend(ctIn)
+
 	ctOut.Scale = ctIn.Scale
+	ctOut.CopyEpochs(ctIn.Ciphertext)
 
 	var nbRescales int
 	// Divides the scale by each moduli of the modulus chain as long as the scale isn't smaller than minScale/2
@@ -385,7 +508,7 @@ func (eval *Evaluator) Rescale(ctIn *Ciphertext, minScale float64, ctOut *Cipher
 
 	if nbRescales > 0 {
 		level := ctIn.Level()
-		for i := range ctOut.Value {
+		for _, i := range ctOut.IDs() {
 			ringQ.DivRoundByLastModulusManyLvl(level, nbRescales, ctIn.Value[i], eval.polyQPool, ctOut.Value[i])
 			ctOut.Value[i].Coeffs = ctOut.Value[i].Coeffs[:level+1-nbRescales]
 		}
@@ -403,8 +526,11 @@ func (eval *Evaluator) Rescale(ctIn *Ciphertext, minScale float64, ctOut *Cipher
 // in a newly created element. Since all the moduli in the moduli chain are generated to be close to the
 // original scale, this procedure is equivalent to dividing the input element by the scale and adding
 // some error.
-// Returns an error if "threshold <= 0", ct.Scale = 0, ct.Level() = 0, ct.IsNTT() != true
+// Returns an error if "threshold <= 0", ct is not a ciphertext of the parameters, ct.Scale <= 0 or ct.Level() = 0.
 func (eval *Evaluator) RescaleNew(ct0 *Ciphertext, threshold float64) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct0); err != nil {
+		return nil, err
+	}
 
 	ctOut = NewCiphertext(eval.params, ct0.IDSet(), ct0.Level(), ct0.Scale)
 
@@ -412,6 +538,7 @@ func (eval *Evaluator) RescaleNew(ct0 *Ciphertext, threshold float64) (ctOut *Ci
 }
 
 func (eval *Evaluator) PrevMulRelinNew(op0, op1 *Ciphertext, rlkSet *mkrlwe.RelinearizationKeySet) (ctOut *Ciphertext) {
+	op0, op1 = eval.extendPair(op0, op1)
 	ctOut = eval.newCiphertextBinary(op0, op1)
 	ctOut.Scale = 0
 	level := utils.MinInt(utils.MinInt(op0.Level(), op1.Level()), ctOut.Level())
@@ -426,34 +553,54 @@ func (eval *Evaluator) PrevMulRelinNew(op0, op1 *Ciphertext, rlkSet *mkrlwe.Reli
 	return
 }
 
+// TryMulRelinNew is MulRelinNew returning an error instead of panicking on an invalid request,
+// e.g. a missing relinearization key (mkrlwe.ErrMissingKey) or operands from different epochs.
+// The request is checked before the operands are extended or decomposed.
+func (eval *Evaluator) TryMulRelinNew(op0, op1 *Ciphertext, rlkSet *mkrlwe.RelinearizationKeySet) (ctOut *Ciphertext, err error) {
+	if err = eval.checkMulRelin(op0, op1, nil, nil, rlkSet); err != nil {
+		return nil, err
+	}
+
+	return eval.MulRelinNew(op0, op1, rlkSet), nil
+}
+
 // MulRelinNew multiplies ct0 by ct1 with relinearization and returns the result in a newly created element.
 // The procedure will panic if either op0.Degree or op1.Degree > 1.
 // The procedure will panic if the evaluator was not created with an relinearization key.
 func (eval *Evaluator) MulRelinNew(op0, op1 *Ciphertext, rlkSet *mkrlwe.RelinearizationKeySet) (ctOut *Ciphertext) {
+	op0, op1 = eval.extendPair(op0, op1)
+
+	// reject invalid requests before decomposing
+	if err := eval.checkMulRelin(op0, op1, nil, nil, rlkSet); err != nil {
+		panic(err)
+	}
 
 	//case of square
 	if op0 == op1 {
 		idset := op0.IDSet()
-		// Save decomposed ciphertext at rlkSet's pool
-		for id := range idset.Value {
-			eval.ksw.Decompose(op0.Level(), op0.Value[id], rlkSet.HoistPool[0].Value[id])
+		// Save decomposed ciphertext at the evaluator's pool
+		eval.hoistPool[0].Reserve(eval.params.Parameters, idset)
+		for _, id := range idset.Slice() {
+			eval.ksw.Decompose(op0.Level(), op0.Value[id], eval.hoistPool[0].Value[id])
 		}
 
-		return eval.MulRelinHoistedNew(op0, op1, rlkSet.HoistPool[0], rlkSet.HoistPool[0], rlkSet)
+		return eval.MulRelinHoistedNew(op0, op1, eval.hoistPool[0], eval.hoistPool[0], rlkSet)
 
 	} else {
 		idset0 := op0.IDSet()
 		idset1 := op1.IDSet()
 
-		for id := range idset0.Value {
-			eval.ksw.Decompose(op0.Level(), op0.Value[id], rlkSet.HoistPool[0].Value[id])
+		eval.hoistPool[0].Reserve(eval.params.Parameters, idset0)
+		for _, id := range idset0.Slice() {
+			eval.ksw.Decompose(op0.Level(), op0.Value[id], eval.hoistPool[0].Value[id])
 		}
 
-		for id := range idset1.Value {
-			eval.ksw.Decompose(op1.Level(), op1.Value[id], rlkSet.HoistPool[1].Value[id])
+		eval.hoistPool[1].Reserve(eval.params.Parameters, idset1)
+		for _, id := range idset1.Slice() {
+			eval.ksw.Decompose(op1.Level(), op1.Value[id], eval.hoistPool[1].Value[id])
 		}
 
-		return eval.MulRelinHoistedNew(op0, op1, rlkSet.HoistPool[0], rlkSet.HoistPool[1], rlkSet)
+		return eval.MulRelinHoistedNew(op0, op1, eval.hoistPool[0], eval.hoistPool[1], rlkSet)
 	}
 
 }
@@ -463,6 +610,7 @@ func (eval *Evaluator) MulRelinNew(op0, op1 *Ciphertext, rlkSet *mkrlwe.Relinear
 // The procedure will panic if ctOut.Degree != op0.Degree + op1.Degree.
 // The procedure will panic if the evaluator was not created with an relinearization key.
 func (eval *Evaluator) mulRelin(op0, op1 *Ciphertext, rlkSet *mkrlwe.RelinearizationKeySet, ctOut *Ciphertext) {
+	op0, op1 = eval.extendPair(op0, op1)
 
 	level := utils.MinInt(utils.MinInt(op0.Level(), op1.Level()), ctOut.Level())
 
@@ -475,10 +623,24 @@ func (eval *Evaluator) mulRelin(op0, op1 *Ciphertext, rlkSet *mkrlwe.Relineariza
 	eval.Rescale(ctOut, eval.params.Scale(), ctOut)
 }
 
+// TryMulPtxtNew is MulPtxtNew returning an error instead of panicking on an invalid request.
+func (eval *Evaluator) TryMulPtxtNew(ct *Ciphertext, pt *ckks.Plaintext) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct); err != nil {
+		return nil, err
+	}
+
+	if pt == nil || pt.Plaintext == nil || mkrlwe.CheckPoly(eval.params.Parameters, pt.Value, ct.Level()) != nil {
+		return nil, mkrlwe.NewError(mkrlwe.ErrInvalidInput, "Cannot MulPtxt: plaintext does not match the ciphertext")
+	}
+
+	return eval.MulPtxtNew(ct, pt), nil
+}
+
 // MulRelinNew multiplies ct0 by ct1 with relinearization and returns the result in a newly created element.
 // The procedure will panic if either op0.Degree or op1.Degree > 1.
 // The procedure will panic if the evaluator was not created with an relinearization key.
 func (eval *Evaluator) MulPtxtNew(ct *Ciphertext, pt *ckks.Plaintext) (ctOut *Ciphertext) {
+	ct = eval.extend(ct)
 	ctOut = NewCiphertext(eval.params, ct.IDSet(), ct.Level(), ct.Scale*pt.Scale)
 
 	level := ct.Level()
@@ -486,16 +648,27 @@ func (eval *Evaluator) MulPtxtNew(ct *Ciphertext, pt *ckks.Plaintext) (ctOut *Ci
 	eval.params.RingQ().NTTLvl(level, pt.Value, eval.polyQPool)
 	eval.params.RingQ().MFormLvl(level, eval.polyQPool, eval.polyQPool)
 
-	for id := range ct.Value {
+	for _, id := range ct.IDs() {
 		eval.params.RingQ().NTTLvl(level, ct.Value[id], ctOut.Value[id])
 		eval.params.RingQ().MulCoeffsMontgomeryLvl(level, ctOut.Value[id], eval.polyQPool, ctOut.Value[id])
 		eval.params.RingQ().InvNTTLvl(level, ctOut.Value[id], ctOut.Value[id])
 	}
+	ctOut.CopyEpochs(ct.Ciphertext)
 
 	eval.Rescale(ctOut, eval.params.Scale(), ctOut)
 	return
 }
 
+// TryRotateNew is RotateNew returning an error instead of panicking on an invalid request,
+// e.g. a missing rotation key (mkrlwe.ErrMissingKey).
+func (eval *Evaluator) TryRotateNew(ct0 *Ciphertext, rotidx int, rkSet *mkrlwe.RotationKeySet) (ctOut *Ciphertext, err error) {
+	if err = eval.checkRotate(ct0, rotidx, nil, rkSet, false); err != nil {
+		return nil, err
+	}
+
+	return eval.RotateNew(ct0, rotidx, rkSet), nil
+}
+
 // RotateNew rotates the columns of ct0 by k positions to the left, and returns the result in a newly created element.
 // If the provided element is a Ciphertext, a key-switching operation is necessary and a rotation key for the specific rotation needs to be provided.
 func (eval *Evaluator) RotateNew(ct0 *Ciphertext, rotidx int, rkSet *mkrlwe.RotationKeySet) (ctOut *Ciphertext) {
@@ -507,6 +680,7 @@ func (eval *Evaluator) RotateNew(ct0 *Ciphertext, rotidx int, rkSet *mkrlwe.Rota
 // Rotate rotates the columns of ct0 by k positions to the left and returns the result in ctOut.
 // If the provided element is a Ciphertext, a key-switching operation is necessary and a rotation key for the specific rotation needs to be provided.
 func (eval *Evaluator) rotate(ct0 *Ciphertext, rotidx int, rkSet *mkrlwe.RotationKeySet, ctOut *Ciphertext) {
+	ct0 = eval.extend(ct0)
 
 	// normalize rotidx
 	for rotidx >= eval.params.N()/2 {
@@ -540,6 +714,24 @@ func (eval *Evaluator) rotate(ct0 *Ciphertext, rotidx int, rkSet *mkrlwe.Rotatio
 
 }
 
+// TryConjugateNew is ConjugateNew returning an error instead of panicking on an invalid request,
+// e.g. a missing conjugation key (mkrlwe.ErrMissingKey).
+func (eval *Evaluator) TryConjugateNew(ct0 *Ciphertext, ckSet *mkrlwe.ConjugationKeySet) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct0); err != nil {
+		return nil, err
+	}
+
+	if err = eval.checkKeySwitch("Conjugate"); err != nil {
+		return nil, err
+	}
+
+	if err = eval.ksw.CheckConjugate(eval.extended(ct0), ckSet, ct0.Level()); err != nil {
+		return nil, err
+	}
+
+	return eval.ConjugateNew(ct0, ckSet), nil
+}
+
 // ConjugateNew conjugates ct0 (which is equivalent to a row rotation) and returns the result in a newly
 // created element. If the provided element is a Ciphertext, a key-switching operation is necessary and a rotation key
 // for the row rotation needs to be provided.
@@ -549,6 +741,25 @@ func (eval *Evaluator) ConjugateNew(ct0 *Ciphertext, ckSet *mkrlwe.ConjugationKe
 	return
 }
 
+// TryKSNew is KSNew returning an error instead of panicking on an invalid request.
+func (eval *Evaluator) TryKSNew(ct0 *Ciphertext, swk1 *mkrlwe.SWK, swk2 *mkrlwe.SWK) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct0); err != nil {
+		return nil, err
+	}
+
+	if err = eval.checkKeySwitch("KS"); err != nil {
+		return nil, err
+	}
+
+	if err = eval.ksw.CheckKS(ct0.Ciphertext, swk1, swk2, ct0.Level()); err != nil {
+		return nil, err
+	}
+
+	return eval.KSNew(ct0, swk1, swk2), nil
+}
+
+// KSNew switches ct0 with the pair (swk1, swk2) and returns the result in a newly created element.
+// ct0 is left unchanged.
 func (eval *Evaluator) KSNew(ct0 *Ciphertext, swk1 *mkrlwe.SWK, swk2 *mkrlwe.SWK) (ctOut *Ciphertext) {
 	ctOut = NewCiphertext(eval.params, ct0.IDSet(), ct0.Level(), ct0.Scale)
 	eval.KS(ct0, swk1, swk2, ctOut)
@@ -558,19 +769,224 @@ func (eval *Evaluator) KSNew(ct0 *Ciphertext, swk1 *mkrlwe.SWK, swk2 *mkrlwe.SWK
 // Conjugate conjugates ct0 (which is equivalent to a row rotation) and returns the result in ctOut.
 // If the provided element is a Ciphertext, a key-switching operation is necessary and a rotation key for the row rotation needs to be provided.
 func (eval *Evaluator) conjugate(ct0 *Ciphertext, ckSet *mkrlwe.ConjugationKeySet, ctOut *Ciphertext) {
+	ct0 = eval.extend(ct0)
 	eval.ksw.Conjugate(ct0.Ciphertext, ckSet, ctOut.Ciphertext)
 }
 
+// KS switches ct0 with the pair (swk1, swk2) and returns the result in ctOut at the level of ctOut.
+// ctOut can be ct0.
 func (eval *Evaluator) KS(ct0 *Ciphertext, swk1 *mkrlwe.SWK, swk2 *mkrlwe.SWK, ctOut *Ciphertext) {
 	eval.ksw.KS(ct0.Ciphertext, swk1, swk2, ctOut.Ciphertext)
+	ctOut.Scale = ct0.Scale
+}
+
+// TryKSChainNew is KSChainNew returning an error instead of panicking on an invalid request,
+// e.g. join keys which do not form a chain from the epoch of the component (mkrlwe.ErrEpochMismatch).
+func (eval *Evaluator) TryKSChainNew(ct0 *Ciphertext, id string, chain []*mkrlwe.JoinKey) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct0); err != nil {
+		return nil, err
+	}
+
+	if err = eval.checkKeySwitch("KSChain"); err != nil {
+		return nil, err
+	}
+
+	if err = eval.ksw.CheckKSChain(ct0.Ciphertext, id, chain, ct0.Level()); err != nil {
+		return nil, err
+	}
+
+	return eval.KSChainNew(ct0, id, chain), nil
+}
+
+// KSChainNew switches the component of ct0 under id through the chain of join keys in one pass
+// and returns the result in a newly created element. Its cost and noise grow with the length of the chain,
+// see mkrlwe.KeySwitcher.KSChain.
+func (eval *Evaluator) KSChainNew(ct0 *Ciphertext, id string, chain []*mkrlwe.JoinKey) (ctOut *Ciphertext) {
+	ctOut = NewCiphertext(eval.params, ct0.IDSet(), ct0.Level(), ct0.Scale)
+	eval.KSChain(ct0, id, chain, ctOut)
+	return
+}
+
+// KSChain switches the component of ct0 under id through the chain of join keys in one pass
+// and returns the result in ctOut. Its cost and noise grow with the length of the chain,
+// see mkrlwe.KeySwitcher.KSChain.
+func (eval *Evaluator) KSChain(ct0 *Ciphertext, id string, chain []*mkrlwe.JoinKey, ctOut *Ciphertext) {
+	eval.ksw.KSChain(ct0.Ciphertext, id, chain, ctOut.Ciphertext)
+	ctOut.Scale = ct0.Scale
+}
+
+// ComposeChain composes the chain of join keys of the group id in jkSet from the given epoch into a single key,
+// which is cached in jkSet and switches the ciphertexts below the top levels with KSComposed,
+// see mkrlwe.KeySwitcher.ComposeChain. It returns nil if there is nothing to compose.
+func (eval *Evaluator) ComposeChain(jkSet *mkrlwe.JoinKeySet, id string, from int) *mkrlwe.ComposedJoinKey {
+	return eval.ksw.ComposeChain(jkSet, id, from)
+}
+
+// TryKSComposedNew is KSComposedNew returning an error instead of panicking on an invalid request,
+// e.g. a ciphertext above the maximum level of the composed key (mkrlwe.ErrLevelMismatch).
+func (eval *Evaluator) TryKSComposedNew(ct0 *Ciphertext, cjk *mkrlwe.ComposedJoinKey) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct0); err != nil {
+		return nil, err
+	}
+
+	if err = eval.checkKeySwitch("KSComposed"); err != nil {
+		return nil, err
+	}
+
+	if err = eval.ksw.CheckKSComposed(ct0.Ciphertext, cjk, ct0.Level()); err != nil {
+		return nil, err
+	}
+
+	return eval.KSComposedNew(ct0, cjk), nil
+}
+
+// KSComposedNew switches the component of ct0 under cjk.ID with the composed chain of join keys in a single pass
+// and returns the result in a newly created element, see mkrlwe.KeySwitcher.KSComposed.
+func (eval *Evaluator) KSComposedNew(ct0 *Ciphertext, cjk *mkrlwe.ComposedJoinKey) (ctOut *Ciphertext) {
+	ctOut = NewCiphertext(eval.params, ct0.IDSet(), ct0.Level(), ct0.Scale)
+	eval.KSComposed(ct0, cjk, ctOut)
+	return
+}
+
+// KSComposed switches the component of ct0 under cjk.ID with the composed chain of join keys in a single pass
+// and returns the result in ctOut, see mkrlwe.KeySwitcher.KSComposed.
+func (eval *Evaluator) KSComposed(ct0 *Ciphertext, cjk *mkrlwe.ComposedJoinKey, ctOut *Ciphertext) {
+	eval.ksw.KSComposed(ct0.Ciphertext, cjk, ctOut.Ciphertext)
+	ctOut.Scale = ct0.Scale
+}
+
+// TryMergeKSNew is MergeKSNew returning an error instead of panicking on an invalid request.
+func (eval *Evaluator) TryMergeKSNew(ct0 *Ciphertext, id0, id1 string, swk0, swkhead0, swk1, swkhead1 *mkrlwe.SWK) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct0); err != nil {
+		return nil, err
+	}
+
+	if err = eval.checkKeySwitch("MergeKS"); err != nil {
+		return nil, err
+	}
+
+	if err = eval.ksw.CheckMergeKS(eval.extended(ct0), id0, id1, swk0, swkhead0, swk1, swkhead1, ct0.Level()); err != nil {
+		return nil, err
+	}
+
+	return eval.MergeKSNew(ct0, id0, id1, swk0, swkhead0, swk1, swkhead1), nil
+}
+
+// MergeKSNew folds the components of ct0 under the merged groups id0 and id1 into a single component under id0
+// and returns the result in a newly created element.
+func (eval *Evaluator) MergeKSNew(ct0 *Ciphertext, id0, id1 string, swk0, swkhead0, swk1, swkhead1 *mkrlwe.SWK) (ctOut *Ciphertext) {
+	idset := ct0.IDSet()
+	idset.Remove(id1)
+	idset.Add(id0)
+
+	ctOut = NewCiphertext(eval.params, idset, ct0.Level(), ct0.Scale)
+	eval.MergeKS(ct0, id0, id1, swk0, swkhead0, swk1, swkhead1, ctOut)
+	return
+}
+
+// MergeKS folds the components of ct0 under the merged groups id0 and id1 into a single component under id0
+// and returns the result in ctOut.
+func (eval *Evaluator) MergeKS(ct0 *Ciphertext, id0, id1 string, swk0, swkhead0, swk1, swkhead1 *mkrlwe.SWK, ctOut *Ciphertext) {
+	ct0 = eval.extend(ct0)
+	eval.ksw.MergeKS(ct0.Ciphertext, id0, id1, swk0, swkhead0, swk1, swkhead1, ctOut.Ciphertext)
+	ctOut.Scale = ct0.Scale
+}
+
+// TrySplitNew is SplitNew returning an error instead of panicking on an invalid request.
+func (eval *Evaluator) TrySplitNew(ct0 *Ciphertext, id, id0, id1 string) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct0); err != nil {
+		return nil, err
+	}
+
+	if err = eval.checkKeySwitch("Split"); err != nil {
+		return nil, err
+	}
+
+	if err = eval.ksw.CheckSplit(eval.extended(ct0), id, id0, id1, ct0.Level()); err != nil {
+		return nil, err
+	}
+
+	return eval.SplitNew(ct0, id, id0, id1), nil
+}
+
+// SplitNew carries the component of ct0 under the group id over to its child groups id0 and id1
+// and returns the result in a newly created element.
+func (eval *Evaluator) SplitNew(ct0 *Ciphertext, id, id0, id1 string) (ctOut *Ciphertext) {
+	idset := ct0.IDSet()
+	idset.Remove(id)
+	idset.Add(id0)
+	idset.Add(id1)
+
+	ctOut = NewCiphertext(eval.params, idset, ct0.Level(), ct0.Scale)
+	eval.Split(ct0, id, id0, id1, ctOut)
+	return
+}
+
+// Split carries the component of ct0 under the group id over to its child groups id0 and id1
+// and returns the result in ctOut.
+func (eval *Evaluator) Split(ct0 *Ciphertext, id, id0, id1 string, ctOut *Ciphertext) {
+	ct0 = eval.extend(ct0)
+	eval.ksw.Split(ct0.Ciphertext, id, id0, id1, ctOut.Ciphertext)
+	ctOut.Scale = ct0.Scale
+}
+
+// TrySplitKSNew is SplitKSNew returning an error instead of panicking on an invalid request.
+func (eval *Evaluator) TrySplitKSNew(ct0 *Ciphertext, id, id0 string, swk, swkhead *mkrlwe.SWK) (ctOut *Ciphertext, err error) {
+	if err = eval.checkCiphertexts(ct0); err != nil {
+		return nil, err
+	}
+
+	if err = eval.checkKeySwitch("SplitKS"); err != nil {
+		return nil, err
+	}
+
+	if err = eval.ksw.CheckSplitKS(eval.extended(ct0), id, id0, swk, swkhead, ct0.Level()); err != nil {
+		return nil, err
+	}
+
+	return eval.SplitKSNew(ct0, id, id0, swk, swkhead), nil
+}
+
+// SplitKSNew carries the component of ct0 under the group id over to its child group id0 alone
+// and returns the result in a newly created element.
+func (eval *Evaluator) SplitKSNew(ct0 *Ciphertext, id, id0 string, swk, swkhead *mkrlwe.SWK) (ctOut *Ciphertext) {
+	idset := ct0.IDSet()
+	idset.Remove(id)
+	idset.Add(id0)
+
+	ctOut = NewCiphertext(eval.params, idset, ct0.Level(), ct0.Scale)
+	eval.SplitKS(ct0, id, id0, swk, swkhead, ctOut)
+	return
+}
+
+// SplitKS carries the component of ct0 under the group id over to its child group id0 alone
+// and returns the result in ctOut.
+func (eval *Evaluator) SplitKS(ct0 *Ciphertext, id, id0 string, swk, swkhead *mkrlwe.SWK, ctOut *Ciphertext) {
+	ct0 = eval.extend(ct0)
+	eval.ksw.SplitKS(ct0.Ciphertext, id, id0, swk, swkhead, ctOut.Ciphertext)
+	ctOut.Scale = ct0.Scale
+}
+
+// TryHoistedForm is HoistedForm returning an error instead of panicking on an invalid request.
+func (eval *Evaluator) TryHoistedForm(ct *Ciphertext) (ctHoisted *mkrlwe.HoistedCiphertext, err error) {
+	if err = eval.checkCiphertexts(ct); err != nil {
+		return nil, err
+	}
+
+	if err = eval.checkKeySwitch("HoistedForm"); err != nil {
+		return nil, err
+	}
+
+	return eval.HoistedForm(ct), nil
 }
 
 // HoistedForm computes hoisted form of input ciphertext
 func (eval *Evaluator) HoistedForm(ct *Ciphertext) (ctHoisted *mkrlwe.HoistedCiphertext) {
+	ct = eval.extend(ct)
 	idset := ct.IDSet()
 	ctHoisted = mkrlwe.NewHoistedCiphertext()
 
-	for id := range idset.Value {
+	for _, id := range idset.Slice() {
 		ctHoisted.Value[id] = mkrlwe.NewSwitchingKey(eval.params.Parameters)
 		eval.ksw.Decompose(ct.Level(), ct.Value[id], ctHoisted.Value[id])
 	}
@@ -578,6 +994,16 @@ func (eval *Evaluator) HoistedForm(ct *Ciphertext) (ctHoisted *mkrlwe.HoistedCip
 	return
 }
 
+// TryMulRelinHoistedNew is MulRelinHoistedNew returning an error instead of panicking on an invalid request,
+// e.g. hoisted forms lacking the decomposition of a component of the operands (mkrlwe.ErrInvalidInput).
+func (eval *Evaluator) TryMulRelinHoistedNew(op0, op1 *Ciphertext, op0Hoisted, op1Hoisted *mkrlwe.HoistedCiphertext, rlkSet *mkrlwe.RelinearizationKeySet) (ctOut *Ciphertext, err error) {
+	if err = eval.checkMulRelin(op0, op1, op0Hoisted, op1Hoisted, rlkSet); err != nil {
+		return nil, err
+	}
+
+	return eval.MulRelinHoistedNew(op0, op1, op0Hoisted, op1Hoisted, rlkSet), nil
+}
+
 // MulRelinNew multiplies ct0 by ct1 with relinearization and returns the result in a newly created element.
 // The procedure will panic if either op0.Degree or op1.Degree > 1.
 // The procedure will panic if the evaluator was not created with an relinearization key.
@@ -594,6 +1020,7 @@ func (eval *Evaluator) MulRelinHoistedNew(op0, op1 *Ciphertext, op0Hoisted, op1H
 // The procedure will panic if ctOut.Degree != op0.Degree + op1.Degree.
 // The procedure will panic if the evaluator was not created with an relinearization key.
 func (eval *Evaluator) mulRelinHoisted(op0, op1 *Ciphertext, op0Hoisted, op1Hoisted *mkrlwe.HoistedCiphertext, rlkSet *mkrlwe.RelinearizationKeySet, ctOut *Ciphertext) {
+	op0, op1 = eval.extendPair(op0, op1)
 
 	level := utils.MinInt(utils.MinInt(op0.Level(), op1.Level()), ctOut.Level())
 
@@ -606,6 +1033,16 @@ func (eval *Evaluator) mulRelinHoisted(op0, op1 *Ciphertext, op0Hoisted, op1Hois
 	eval.Rescale(ctOut, eval.params.Scale(), ctOut)
 }
 
+// TryRotateHoistedNew is RotateHoistedNew returning an error instead of panicking on an invalid request,
+// e.g. a rotation index without CRS (mkrlwe.ErrMissingCRS).
+func (eval *Evaluator) TryRotateHoistedNew(ct0 *Ciphertext, rotidx int, ct0Hoisted *mkrlwe.HoistedCiphertext, rkSet *mkrlwe.RotationKeySet) (ctOut *Ciphertext, err error) {
+	if err = eval.checkRotate(ct0, rotidx, ct0Hoisted, rkSet, true); err != nil {
+		return nil, err
+	}
+
+	return eval.RotateHoistedNew(ct0, rotidx, ct0Hoisted, rkSet), nil
+}
+
 // RotateNew rotates the columns of ct0 by k positions to the left, and returns the result in a newly created element.
 // If the provided element is a Ciphertext, a key-switching operation is necessary and a rotation key for the specific rotation needs to be provided.
 func (eval *Evaluator) RotateHoistedNew(ct0 *Ciphertext, rotidx int, ct0Hoisted *mkrlwe.HoistedCiphertext, rkSet *mkrlwe.RotationKeySet) (ctOut *Ciphertext) {
@@ -617,6 +1054,7 @@ func (eval *Evaluator) RotateHoistedNew(ct0 *Ciphertext, rotidx int, ct0Hoisted
 // Rotate rotates the columns of ct0 by k positions to the left and returns the result in ctOut.
 // If the provided element is a Ciphertext, a key-switching operation is necessary and a rotation key for the specific rotation needs to be provided.
 func (eval *Evaluator) rotateHoisted(ct0 *Ciphertext, rotidx int, ct0Hoisted *mkrlwe.HoistedCiphertext, rkSet *mkrlwe.RotationKeySet, ctOut *Ciphertext) {
+	ct0 = eval.extend(ct0)
 
 	// normalize rotidx
 	for rotidx >= eval.params.N()/2 {
@@ -632,12 +1070,6 @@ func (eval *Evaluator) rotateHoisted(ct0 *Ciphertext, rotidx int, ct0Hoisted *mk
 		return
 	}
 
-	_, in := eval.params.CRS[rotidx]
-
-	if in {
-		eval.ksw.RotateHoisted(ct0.Ciphertext, rotidx, ct0Hoisted, rkSet, ctOut.Ciphertext)
-		return
-	} else {
-		panic("Hoisted rotation only works for precomputed rotation keys")
-	}
+	// hoisted rotation only works for the rotation indexes of the CRS
+	eval.ksw.RotateHoisted(ct0.Ciphertext, rotidx, ct0Hoisted, rkSet, ctOut.Ciphertext)
 }
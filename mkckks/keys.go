@@ -1,8 +1,22 @@
 package mkckks
 
-import "mk-lattigo/mkrlwe"
+import (
+	"mk-lattigo/mkrlwe"
+
+	"github.com/ldsec/lattigo/v2/utils"
+)
 
 // NewKeyGenerator creates a rlwe.KeyGenerator instance from the CKKS parameters.
 func NewKeyGenerator(params Parameters) *mkrlwe.KeyGenerator {
 	return mkrlwe.NewKeyGenerator(params.Parameters)
 }
+
+// NewKeyGeneratorWithPRNG creates a rlwe.KeyGenerator instance from the CKKS parameters, whose samplers read from the given PRNG.
+func NewKeyGeneratorWithPRNG(params Parameters, prng utils.PRNG) *mkrlwe.KeyGenerator {
+	return mkrlwe.NewKeyGeneratorWithPRNG(params.Parameters, prng)
+}
+
+// NewGroup creates a new empty rdMPHE group from the CKKS parameters.
+func NewGroup(params Parameters, id string) *mkrlwe.Group {
+	return mkrlwe.NewGroup(params.Parameters, id)
+}
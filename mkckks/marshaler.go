@@ -0,0 +1,74 @@
+package mkckks
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"mk-lattigo/mkrlwe"
+)
+
+// GetDataLen returns the length in bytes of the target Ciphertext.
+func (ct *Ciphertext) GetDataLen(WithMetaData bool) (dataLen int) {
+	// MetaData is :
+	// 8 byte : Scale
+	if WithMetaData {
+		dataLen += 8
+	}
+
+	return dataLen + ct.Ciphertext.GetDataLen(WithMetaData)
+}
+
+// MarshalBinary encodes a Ciphertext on a byte slice.
+func (ct *Ciphertext) MarshalBinary() (data []byte, err error) {
+
+	var el []byte
+	if el, err = ct.Ciphertext.MarshalBinary(); err != nil {
+		return nil, err
+	}
+
+	data = make([]byte, 8+len(el))
+	binary.LittleEndian.PutUint64(data[0:8], math.Float64bits(ct.Scale))
+	copy(data[8:], el)
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes a previously marshaled Ciphertext on the target Ciphertext.
+func (ct *Ciphertext) UnmarshalBinary(data []byte) (err error) {
+	if len(data) < 8 {
+		return errors.New("too small bytearray")
+	}
+
+	ct.Scale = math.Float64frombits(binary.LittleEndian.Uint64(data[0:8]))
+	ct.Ciphertext = new(mkrlwe.Ciphertext)
+
+	return ct.Ciphertext.UnmarshalBinary(data[8:])
+}
+
+// MarshalBinary encodes a SeededCiphertext on a byte slice.
+func (ct *SeededCiphertext) MarshalBinary() (data []byte, err error) {
+
+	var el []byte
+	if el, err = ct.SeededCiphertext.MarshalBinary(); err != nil {
+		return nil, err
+	}
+
+	data = make([]byte, 8+len(el))
+	binary.LittleEndian.PutUint64(data[0:8], math.Float64bits(ct.Scale))
+	copy(data[8:], el)
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes a previously marshaled SeededCiphertext on the target SeededCiphertext.
+func (ct *SeededCiphertext) UnmarshalBinary(data []byte) (err error) {
+	if len(data) < 8 {
+		return errors.New("too small bytearray")
+	}
+
+	ct.Scale = math.Float64frombits(binary.LittleEndian.Uint64(data[0:8]))
+	ct.SeededCiphertext = new(mkrlwe.SeededCiphertext)
+
+	return ct.SeededCiphertext.UnmarshalBinary(data[8:])
+}
@@ -0,0 +1,134 @@
+package mkckks
+
+import (
+	"math"
+
+	"mk-lattigo/mkrlwe"
+
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// NoiseEstimate is the estimated noise of a CKKS ciphertext. Besides the noise of its phase, it follows the scale
+// of the ciphertext and the variance of the coefficients of the encoding of its message, on which the noise of a
+// multiplication depends.
+type NoiseEstimate struct {
+	*mkrlwe.NoiseEstimate
+	Scale           float64
+	MessageVariance float64 // variance of the coefficients of the encoding of the message
+}
+
+// CopyNew returns a deep copy of the estimate.
+func (est *NoiseEstimate) CopyNew() *NoiseEstimate {
+	return &NoiseEstimate{NoiseEstimate: est.NoiseEstimate.CopyNew(), Scale: est.Scale, MessageVariance: est.MessageVariance}
+}
+
+// NoiseEstimator follows the noise of CKKS ciphertexts through the operations of the Evaluator.
+// Its methods return new estimates and leave their inputs unchanged.
+type NoiseEstimator struct {
+	params Parameters
+	ne     *mkrlwe.NoiseEstimator
+}
+
+// NewNoiseEstimator creates a new NoiseEstimator.
+func NewNoiseEstimator(params Parameters) *NoiseEstimator {
+	return &NoiseEstimator{params: params, ne: mkrlwe.NewNoiseEstimator(params.Parameters)}
+}
+
+// messageVariance returns the variance of the coefficients of the encoding at the given scale
+// of a message whose slots have an average squared modulus of msgStd^2.
+func (ne *NoiseEstimator) messageVariance(scale, msgStd float64) float64 {
+	return scale * scale * msgStd * msgStd / float64(ne.params.N())
+}
+
+// EncryptMsg returns the estimate of the output of Encryptor.EncryptMsg with the public key of id,
+// shared by the given number of members, for a message whose slots have an average squared modulus of msgStd^2.
+func (ne *NoiseEstimator) EncryptMsg(id string, members int, msgStd float64) *NoiseEstimate {
+	est := ne.ne.Encrypt(ne.params.MaxLevel(), id, members)
+	est.Variance += 1.0 / 12 // rounding of the encoding
+
+	return &NoiseEstimate{NoiseEstimate: est, Scale: ne.params.Scale(), MessageVariance: ne.messageVariance(ne.params.Scale(), msgStd)}
+}
+
+// EncryptSkMsg returns the estimate of the output of Encryptor.EncryptSkMsg with the secret key of id,
+// shared by the given number of members, for a message whose slots have an average squared modulus of msgStd^2.
+func (ne *NoiseEstimator) EncryptSkMsg(id string, members int, msgStd float64) *NoiseEstimate {
+	est := ne.ne.EncryptSk(ne.params.MaxLevel(), id, members)
+	est.Variance += 1.0 / 12
+
+	return &NoiseEstimate{NoiseEstimate: est, Scale: ne.params.Scale(), MessageVariance: ne.messageVariance(ne.params.Scale(), msgStd)}
+}
+
+// Add returns the estimate of the output of Evaluator.AddNew or Evaluator.SubNew on ciphertexts of independent messages.
+func (ne *NoiseEstimator) Add(est0, est1 *NoiseEstimate) *NoiseEstimate {
+	return &NoiseEstimate{NoiseEstimate: ne.ne.Add(est0.NoiseEstimate, est1.NoiseEstimate), Scale: est0.Scale, MessageVariance: est0.MessageVariance + est1.MessageVariance}
+}
+
+// MulRelin returns the estimate of the output of Evaluator.MulRelinNew on ciphertexts of independent messages,
+// which is rescaled to the default scale.
+func (ne *NoiseEstimator) MulRelin(est0, est1 *NoiseEstimate) *NoiseEstimate {
+	N := float64(ne.params.N())
+	vm0, vm1 := est0.MessageVariance, est1.MessageVariance
+	ve0, ve1 := est0.Variance, est1.Variance
+
+	// (m0 + e0) * (m1 + e1) - m0 * m1
+	tensorVariance := N * (vm0*ve1 + vm1*ve0 + ve0*ve1)
+
+	estOut := &NoiseEstimate{
+		NoiseEstimate:   ne.ne.MulAndRelin(est0.NoiseEstimate, est1.NoiseEstimate, tensorVariance),
+		Scale:           est0.Scale * est1.Scale,
+		MessageVariance: N * vm0 * vm1,
+	}
+
+	return ne.Rescale(estOut, ne.params.Scale())
+}
+
+// Rescale returns the estimate of the output of Evaluator.Rescale with the given minimum scale.
+func (ne *NoiseEstimator) Rescale(est *NoiseEstimate, minScale float64) *NoiseEstimate {
+	Q := ne.params.Q()
+	estOut := est.CopyNew()
+
+	for estOut.Level > 0 && estOut.Scale/float64(Q[estOut.Level]) >= minScale/2 {
+		q := float64(Q[estOut.Level])
+		estOut.Scale /= q
+		estOut.MessageVariance /= q * q
+		estOut.Variance /= q * q
+		estOut.Level--
+		estOut.Variance += ne.ne.RoundingVariance(estOut.NoiseEstimate)
+	}
+
+	return estOut
+}
+
+// Rotate returns the estimate of the output of Evaluator.RotateNew.
+func (ne *NoiseEstimator) Rotate(est *NoiseEstimate) *NoiseEstimate {
+	return &NoiseEstimate{NoiseEstimate: ne.ne.Rotate(est.NoiseEstimate), Scale: est.Scale, MessageVariance: est.MessageVariance}
+}
+
+// Conjugate returns the estimate of the output of Evaluator.ConjugateNew.
+func (ne *NoiseEstimator) Conjugate(est *NoiseEstimate) *NoiseEstimate {
+	return &NoiseEstimate{NoiseEstimate: ne.ne.Conjugate(est.NoiseEstimate), Scale: est.Scale, MessageVariance: est.MessageVariance}
+}
+
+// KS returns the estimate of the output of Evaluator.KSNew, which switches est to the secret key
+// of a group of the given number of members.
+func (ne *NoiseEstimator) KS(est *NoiseEstimate, members int) *NoiseEstimate {
+	return &NoiseEstimate{NoiseEstimate: ne.ne.KS(est.NoiseEstimate, members), Scale: est.Scale, MessageVariance: est.MessageVariance}
+}
+
+// LogError returns the log2 of the estimated standard deviation of the error on the slots of the decrypted message.
+func (ne *NoiseEstimator) LogError(est *NoiseEstimate) float64 {
+	return 0.5*math.Log2(float64(ne.params.N())*est.Variance) - math.Log2(est.Scale)
+}
+
+// MeasureNoise decrypts ciphertext with skSet and returns the log2 of the standard deviation and of the maximum
+// of the coefficients of the difference between its phase and the encoding of msg at the scale of the ciphertext,
+// to be compared with NoiseEstimate.LogStd. It is a debugging tool: it requires the secret keys of every id.
+func (dec *Decryptor) MeasureNoise(ciphertext *Ciphertext, skSet *mkrlwe.SecretKeySet, msg *Message) (logStd, logMax float64) {
+	ckksParams, _ := ckks.NewParameters(dec.params.Parameters.Parameters, dec.params.LogSlots(), ciphertext.Scale)
+
+	pt := ckks.NewPlaintext(ckksParams, utils.MinInt(ciphertext.Level(), dec.params.MaxLevel()), ciphertext.Scale)
+	dec.encoder.Encode(pt, msg.Value, dec.params.LogSlots())
+
+	return dec.Decryptor.MeasureNoise(ciphertext.Ciphertext, skSet, pt.Plaintext)
+}
@@ -1,7 +1,35 @@
 package mkckks
 
-import "github.com/ldsec/lattigo/v2/ckks"
-import "mk-lattigo/mkrlwe"
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+
+	"github.com/ldsec/lattigo/v2/ckks"
+	"gopkg.in/yaml.v3"
+
+	"mk-lattigo/mkrlwe"
+)
+
+// ParametersLiteral is a literal representation of MKCKKS parameters, used as the format of parameters files.
+// Gamma defaults to 2 and the CRS seed is sampled at random if nil.
+type ParametersLiteral struct {
+	LogN     int // Log Ring degree (power of 2)
+	Q        []uint64
+	P        []uint64
+	LogQ     []int   `json:",omitempty" yaml:",omitempty"`
+	LogP     []int   `json:",omitempty" yaml:",omitempty"`
+	Sigma    float64 // Gaussian sampling standard deviation
+	LogSlots int
+	Scale    float64
+	Gamma    int            `json:",omitempty" yaml:",omitempty"` // Gadget parameter of the switching keys, 2 if zero
+	CRSSeed  mkrlwe.CRSSeed `json:",omitempty" yaml:",omitempty"` // Public seed of the CRSs, sampled at random if nil, which parameters files do not allow
+}
+
+// NewParametersLiteral returns the ParametersLiteral with the given CKKS parameters, default gamma and random CRS seed.
+func NewParametersLiteral(pl ckks.ParametersLiteral) ParametersLiteral {
+	return ParametersLiteral{LogN: pl.LogN, Q: pl.Q, P: pl.P, LogQ: pl.LogQ, LogP: pl.LogP, Sigma: pl.Sigma, LogSlots: pl.LogSlots, Scale: pl.Scale}
+}
 
 // Parameters represents a parameter set for the CKKS cryptosystem. Its fields are private and
 // immutable. See ParametersLiteral for user-specified parameters.
@@ -24,6 +52,127 @@ func NewParameters(ckksParams ckks.Parameters) Parameters {
 	return *ret
 }
 
+// NewParametersFromSeed instantiate a set of MKCKKS parameters from the generic CKKS parameters,
+// with the CRSs expanded deterministically from the given public seed of mkrlwe.CRSSeedSize bytes.
+func NewParametersFromSeed(ckksParams ckks.Parameters, seed []byte) Parameters {
+
+	ret := new(Parameters)
+	ret.Parameters = mkrlwe.NewParametersFromSeed(ckksParams.Parameters, 2, seed)
+	ret.logSlots = ckksParams.LogSlots()
+	ret.scale = ckksParams.Scale()
+
+	return *ret
+}
+
+// NewParametersFromLiteral instantiate a set of MKCKKS parameters from a ParametersLiteral,
+// with the CRSs expanded from its CRS seed.
+func NewParametersFromLiteral(pl ParametersLiteral) Parameters {
+	params, err := newParametersFromLiteral(pl)
+	if err != nil {
+		panic(err)
+	}
+
+	return params
+}
+
+// newParametersFromFile instantiates the parameters of a decoded parameters file, which must carry its CRS seed:
+// without it, every party loading the file would sample a different CRS.
+func newParametersFromFile(pl ParametersLiteral) (Parameters, error) {
+	if pl.CRSSeed == nil {
+		return Parameters{}, errors.New("invalid input: parameters file without CRS seed")
+	}
+
+	return newParametersFromLiteral(pl)
+}
+
+func newParametersFromLiteral(pl ParametersLiteral) (params Parameters, err error) {
+	ckksParams, err := ckks.NewParametersFromLiteral(ckks.ParametersLiteral{
+		LogN: pl.LogN, Q: pl.Q, P: pl.P, LogQ: pl.LogQ, LogP: pl.LogP, Sigma: pl.Sigma, LogSlots: pl.LogSlots, Scale: pl.Scale,
+	})
+	if err != nil {
+		return Parameters{}, err
+	}
+
+	gamma := pl.Gamma
+	if gamma == 0 {
+		gamma = 2
+	}
+
+	if gamma < 1 || gamma > ckksParams.PCount() {
+		return Parameters{}, errors.New("invalid input: gamma must be between 1 and the number of P moduli")
+	}
+
+	if pl.CRSSeed == nil {
+		params.Parameters = mkrlwe.NewParameters(ckksParams.Parameters, gamma)
+	} else {
+		if len(pl.CRSSeed) != mkrlwe.CRSSeedSize {
+			return Parameters{}, errors.New("invalid input: CRS seed must be 32 bytes")
+		}
+		params.Parameters = mkrlwe.NewParametersFromSeed(ckksParams.Parameters, gamma, pl.CRSSeed)
+	}
+	params.logSlots = ckksParams.LogSlots()
+	params.scale = ckksParams.Scale()
+
+	return params, nil
+}
+
+// ParametersLiteral returns the ParametersLiteral of the target Parameters, with explicit moduli and CRS seed.
+func (p Parameters) ParametersLiteral() ParametersLiteral {
+	return ParametersLiteral{
+		LogN:     p.LogN(),
+		Q:        p.Q(),
+		P:        p.P(),
+		Sigma:    p.Sigma(),
+		LogSlots: p.logSlots,
+		Scale:    p.scale,
+		Gamma:    p.Gamma(),
+		CRSSeed:  p.CRSSeed(),
+	}
+}
+
+// MarshalJSON returns a JSON representation of this parameter set. See `Marshal` from the `encoding/json` package.
+func (p Parameters) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.ParametersLiteral())
+}
+
+// UnmarshalJSON reads a JSON representation of a parameter set into the receiver Parameter. See `Unmarshal` from the `encoding/json` package.
+func (p *Parameters) UnmarshalJSON(data []byte) (err error) {
+	var pl ParametersLiteral
+	if err = json.Unmarshal(data, &pl); err != nil {
+		return err
+	}
+
+	*p, err = newParametersFromFile(pl)
+	return err
+}
+
+// MarshalYAML returns a YAML representation of this parameter set. See `Marshal` from the `gopkg.in/yaml.v3` package.
+func (p Parameters) MarshalYAML() (interface{}, error) {
+	return p.ParametersLiteral(), nil
+}
+
+// UnmarshalYAML reads a YAML representation of a parameter set into the receiver Parameter. See `Unmarshal` from the `gopkg.in/yaml.v3` package.
+func (p *Parameters) UnmarshalYAML(value *yaml.Node) (err error) {
+	var pl ParametersLiteral
+	if err = value.Decode(&pl); err != nil {
+		return err
+	}
+
+	*p, err = newParametersFromFile(pl)
+	return err
+}
+
+// Fingerprint returns the SHA-256 hash of the JSON representation of the parameter set,
+// so that parties can check that they loaded the same parameters and CRS seed.
+func (p Parameters) Fingerprint() [sha256.Size]byte {
+	data, err := p.MarshalJSON()
+	if err != nil {
+		panic(err)
+	}
+
+	return sha256.Sum256(data)
+}
+
 // Scale returns the default plaintext/ciphertext scale
 func (p Parameters) Scale() float64 {
 	return p.scale
@@ -1,6 +1,8 @@
 package mkckks
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"strconv"
@@ -19,6 +21,7 @@ import (
 	"math/cmplx"
 
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 	// "github.com/xuri/excelize/v2"
 )
 
@@ -1066,6 +1069,603 @@ func newTestVectorsSk(testContext *testParams, id string, a, b complex128) (msg
 	return msg, ciphertext
 }
 
+// genUserTestParams returns a test context with the individual keys of each of ids:
+// its secret key, public key, relinearization key and rotation key by 1.
+func genUserTestParams(params Parameters, ids []string) *testParams {
+	testContext := &testParams{
+		params:    params,
+		ringQ:     params.RingQ(),
+		kgen:      NewKeyGenerator(params),
+		skSet:     mkrlwe.NewSecretKeySet(),
+		pkSet:     mkrlwe.NewPublicKeyKeySet(),
+		rlkSet:    mkrlwe.NewRelinearizationKeySet(params.Parameters),
+		rtkSet:    mkrlwe.NewRotationKeySet(),
+		encryptor: NewEncryptor(params),
+		decryptor: NewDecryptor(params),
+		evaluator: NewEvaluator(params),
+		idset:     mkrlwe.NewIDSet(),
+	}
+
+	for _, id := range ids {
+		sk, pk := testContext.kgen.GenKeyPair(id)
+		testContext.skSet.AddSecretKey(sk)
+		testContext.pkSet.AddPublicKey(pk)
+		testContext.rlkSet.AddRelinearizationKey(testContext.kgen.GenRelinearizationKey(sk))
+		testContext.rtkSet.AddRotationKey(testContext.kgen.GenRotationKey(1, sk))
+		testContext.idset.Add(id)
+	}
+
+	return testContext
+}
+
+// newSumTestVectors returns the sum of a random message of each of ids, and the sum of their encryptions under the public key of their id.
+func newSumTestVectors(testContext *testParams, ids []string, a, b complex128) (msg *Message, ciphertext *Ciphertext) {
+	msg = NewMessage(testContext.params)
+	for _, id := range ids {
+		msgId, ctId := newTestVectors(testContext, id, a, b)
+		for i := range msg.Value {
+			msg.Value[i] += msgId.Value[i]
+		}
+
+		if ciphertext == nil {
+			ciphertext = ctId
+		} else {
+			ciphertext = testContext.evaluator.AddNew(ciphertext, ctId)
+		}
+	}
+
+	return msg, ciphertext
+}
+
+func Test_Params_CKKS(t *testing.T) {
+	params := NewParametersFromLiteral(NewParametersLiteral(PN14QP439))
+
+	t.Run(GetTestName(params, "Params/JSON/"), func(t *testing.T) {
+		data, err := json.Marshal(params)
+		require.NoError(t, err)
+
+		var paramsRes Parameters
+		require.NoError(t, json.Unmarshal(data, &paramsRes))
+		require.Equal(t, params.ParametersLiteral(), paramsRes.ParametersLiteral())
+		require.Equal(t, params.Fingerprint(), paramsRes.Fingerprint())
+		require.True(t, params.CRS[0].Value[0].Q.Equals(paramsRes.CRS[0].Value[0].Q))
+	})
+
+	t.Run(GetTestName(params, "Params/YAML/"), func(t *testing.T) {
+		data, err := yaml.Marshal(params)
+		require.NoError(t, err)
+
+		var paramsRes Parameters
+		require.NoError(t, yaml.Unmarshal(data, &paramsRes))
+		require.Equal(t, params.ParametersLiteral(), paramsRes.ParametersLiteral())
+		require.Equal(t, params.Fingerprint(), paramsRes.Fingerprint())
+	})
+
+	t.Run(GetTestName(params, "Params/Fingerprint/"), func(t *testing.T) {
+		pl := params.ParametersLiteral()
+		pl.Scale *= 2
+		require.NotEqual(t, params.Fingerprint(), NewParametersFromLiteral(pl).Fingerprint())
+	})
+
+	t.Run(GetTestName(params, "Params/MissingSeed/"), func(t *testing.T) {
+		// the seed is only sampled at random for the literals given to NewParametersFromLiteral
+		pl := params.ParametersLiteral()
+		pl.CRSSeed = nil
+		require.NotPanics(t, func() { NewParametersFromLiteral(pl) })
+
+		data, err := json.Marshal(pl)
+		require.NoError(t, err)
+		var paramsRes Parameters
+		require.Error(t, json.Unmarshal(data, &paramsRes))
+
+		data, err = yaml.Marshal(pl)
+		require.NoError(t, err)
+		require.Error(t, yaml.Unmarshal(data, &paramsRes))
+	})
+}
+
+func Test_Errors_CKKS(t *testing.T) {
+	params := NewParametersFromLiteral(NewParametersLiteral(PN14QP439))
+	kgen := NewKeyGenerator(params)
+
+	encryptor := NewEncryptor(params)
+	decryptor := NewDecryptor(params)
+	eval := NewEvaluator(params)
+
+	sk, pk := kgen.GenKeyPair("user0")
+	skSet := mkrlwe.NewSecretKeySet()
+	skSet.AddSecretKey(sk)
+
+	ct := encryptor.EncryptMsgNew(NewMessage(params), pk)
+
+	t.Run(GetTestName(params, "Errors/MissingKey/"), func(t *testing.T) {
+		_, err := eval.TryMulRelinNew(ct, ct, mkrlwe.NewRelinearizationKeySet(params.Parameters))
+		require.True(t, errors.Is(err, mkrlwe.ErrMissingKey))
+
+		_, err = eval.TryRotateNew(ct, 1, mkrlwe.NewRotationKeySet())
+		require.True(t, errors.Is(err, mkrlwe.ErrMissingKey))
+
+		_, err = eval.TryConjugateNew(ct, mkrlwe.NewConjugationKeySet())
+		require.True(t, errors.Is(err, mkrlwe.ErrMissingKey))
+
+		_, err = decryptor.TryDecrypt(ct, mkrlwe.NewSecretKeySet())
+		require.True(t, errors.Is(err, mkrlwe.ErrMissingKey))
+
+		_, err = decryptor.TryDecrypt(ct, skSet)
+		require.NoError(t, err)
+	})
+
+	t.Run(GetTestName(params, "Errors/LevelMismatch/"), func(t *testing.T) {
+		rlkSet := mkrlwe.NewRelinearizationKeySet(params.Parameters)
+		rlkSet.AddRelinearizationKey(kgen.GenRelinearizationKey(sk))
+
+		ctLow := ct.CopyNew()
+		eval.DropLevel(ctLow, 1)
+		ctOut := NewCiphertext(params, ct.IDSet(), ct.Level(), ct.Scale)
+
+		err := eval.ksw.CheckMulAndRelin(ctLow.Ciphertext, ct.Ciphertext, rlkSet, ctOut.Level())
+		require.True(t, errors.Is(err, mkrlwe.ErrLevelMismatch))
+		require.Panics(t, func() { eval.ksw.MulAndRelin(ctLow.Ciphertext, ct.Ciphertext, rlkSet, ctOut.Ciphertext) })
+
+		_, err = eval.TryDropLevelNew(ct, ct.Level()+1)
+		require.True(t, errors.Is(err, mkrlwe.ErrLevelMismatch))
+	})
+
+	t.Run(GetTestName(params, "Errors/EpochMismatch/"), func(t *testing.T) {
+		ctOld := ct.CopyNew()
+		ctOld.SetEpoch("user0", 1)
+
+		_, err := eval.TryAddNew(ct, ctOld)
+		require.True(t, errors.Is(err, mkrlwe.ErrEpochMismatch))
+
+		_, err = eval.TrySubNew(ct, ctOld)
+		require.True(t, errors.Is(err, mkrlwe.ErrEpochMismatch))
+
+		_, err = eval.TryAddNew(ct, ct)
+		require.NoError(t, err)
+	})
+
+	t.Run(GetTestName(params, "Errors/InvalidInput/"), func(t *testing.T) {
+		ctData, err := ct.MarshalBinary()
+		require.NoError(t, err)
+
+		// a rejected request leaves its operands unchanged
+		_, err = eval.TryMulRelinNew(ct, ct, mkrlwe.NewRelinearizationKeySet(params.Parameters))
+		require.Error(t, err)
+
+		ctBad := ct.CopyNew()
+		ctBad.Value["user0"] = params.RingQ().NewPolyLvl(0)
+
+		_, err = eval.TryAddNew(ct, ctBad)
+		require.True(t, errors.Is(err, mkrlwe.ErrInvalidInput))
+
+		_, err = eval.TryMulPtxtNew(ct, nil)
+		require.True(t, errors.Is(err, mkrlwe.ErrInvalidInput))
+
+		_, err = decryptor.TryDecrypt(ctBad, skSet)
+		require.True(t, errors.Is(err, mkrlwe.ErrInvalidInput))
+
+		sk1 := kgen.GenSecretKey("user1")
+		swk, swkhead := kgen.GenSWK(sk1, pk)
+
+		_, err = eval.TryKSNew(ct, swk, nil)
+		require.True(t, errors.Is(err, mkrlwe.ErrInvalidInput))
+
+		_, err = eval.TryKSNew(ct, swk, swkhead)
+		require.NoError(t, err)
+
+		_, err = eval.TryKSChainNew(ct, "user0", nil)
+		require.True(t, errors.Is(err, mkrlwe.ErrInvalidInput))
+
+		_, err = eval.TrySplitNew(ct, "user0", "user0", "user1")
+		require.True(t, errors.Is(err, mkrlwe.ErrInvalidInput))
+
+		require.True(t, errors.Is(decryptor.TryPartialDecrypt(ct.CopyNew(), sk1), mkrlwe.ErrInvalidInput))
+
+		data, err := ct.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, ctData, data)
+
+		require.True(t, errors.Is(decryptor.TryPartialDecrypt(ct.CopyNew(), sk), mkrlwe.ErrNoiseBudget))
+		require.NoError(t, decryptor.PartialDecryptFresh(ct.CopyNew(), sk))
+	})
+
+	t.Run(GetTestName(params, "Errors/Hoisted/"), func(t *testing.T) {
+		ctHoisted, err := eval.TryHoistedForm(ct)
+		require.NoError(t, err)
+
+		_, err = eval.TryMulRelinHoistedNew(ct, ct, ctHoisted, ctHoisted, mkrlwe.NewRelinearizationKeySet(params.Parameters))
+		require.True(t, errors.Is(err, mkrlwe.ErrMissingKey))
+
+		_, err = eval.TryRotateHoistedNew(ct, 3, ctHoisted, mkrlwe.NewRotationKeySet())
+		require.True(t, errors.Is(err, mkrlwe.ErrMissingCRS))
+
+		_, err = eval.TryRotateHoistedNew(ct, 1, ctHoisted, mkrlwe.NewRotationKeySet())
+		require.True(t, errors.Is(err, mkrlwe.ErrMissingKey))
+	})
+}
+
+func Test_ShallowCopy_CKKS(t *testing.T) {
+	params := NewParametersFromLiteral(NewParametersLiteral(PN14QP439))
+	kgen := NewKeyGenerator(params)
+
+	encryptor := NewEncryptor(params)
+	eval := NewEvaluator(params)
+
+	rlkSet := mkrlwe.NewRelinearizationKeySet(params.Parameters)
+	ctList := make([]*Ciphertext, 0)
+	for _, id := range []string{"user0", "user1"} {
+		sk, pk := kgen.GenKeyPair(id)
+		rlkSet.AddRelinearizationKey(kgen.GenRelinearizationKey(sk))
+		ctList = append(ctList, encryptor.EncryptMsgNew(NewMessage(params), pk))
+	}
+
+	t.Run(GetTestName(params, "ShallowCopy/MulRelin/"), func(t *testing.T) {
+		expected, err := eval.MulRelinNew(ctList[0], ctList[1], rlkSet).MarshalBinary()
+		require.NoError(t, err)
+
+		// evaluators from ShallowCopy share the key set but no pool
+		numWorkers := 4
+		results := make(chan []byte, numWorkers)
+		for i := 0; i < numWorkers; i++ {
+			go func(eval *Evaluator) {
+				data, _ := eval.MulRelinNew(ctList[0], ctList[1], rlkSet).MarshalBinary()
+				results <- data
+			}(eval.ShallowCopy())
+		}
+
+		for i := 0; i < numWorkers; i++ {
+			require.Equal(t, expected, <-results)
+		}
+	})
+
+	t.Run(GetTestName(params, "ShallowCopy/LazyJoin/"), func(t *testing.T) {
+		id := "group0"
+		group := NewGroup(params, id)
+		skList := make([]*mkrlwe.SecretKey, 0)
+
+		for p := 0; p < 2; p++ {
+			sk := kgen.GenSecretKey(id)
+			skList = append(skList, sk)
+			group.AddShares(kgen.GenPartyShares(sk, []int{1}))
+		}
+
+		for _, sk := range skList {
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		msg := NewMessage(params)
+		for i := range msg.Value {
+			msg.Value[i] = complex(utils.RandFloat64(-1, 1), 0)
+		}
+		ctStored := encryptor.EncryptMsgNew(msg, group.PublicKey)
+
+		// a party joins the group, and the stored ciphertext is left as is
+		sk := kgen.GenSecretKey(id)
+		skList = append(skList, sk)
+		uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+
+		jkSet := mkrlwe.NewJoinKeySet()
+		jkSet.AddJoinKey(group.Join(kgen.GenPartyShares(sk, []int{1}), uaux))
+		group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+
+		grlkSet := mkrlwe.NewRelinearizationKeySet(params.Parameters)
+		group.UpdateKeySets(nil, grlkSet, nil, nil)
+
+		evalJoin := NewEvaluator(params)
+		evalJoin.SetJoinKeySet(jkSet)
+
+		ctFresh := encryptor.EncryptMsgNew(msg, group.PublicKey)
+		ctCopy := ctStored.CopyNew()
+
+		expected, err := evalJoin.AddNew(evalJoin.MulRelinNew(ctCopy, ctFresh, grlkSet), ctCopy).MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, group.Epoch, ctCopy.GetEpoch(id))
+		ctData, err := ctCopy.MarshalBinary()
+		require.NoError(t, err)
+
+		// evaluators from ShallowCopy share the stale operand, which is switched once in place
+		numWorkers := 4
+		results := make(chan []byte, numWorkers)
+		for i := 0; i < numWorkers; i++ {
+			go func(eval *Evaluator) {
+				data, _ := eval.AddNew(eval.MulRelinNew(ctStored, ctFresh, grlkSet), ctStored).MarshalBinary()
+				results <- data
+			}(evalJoin.ShallowCopy())
+		}
+
+		for i := 0; i < numWorkers; i++ {
+			require.Equal(t, expected, <-results)
+		}
+
+		data, err := ctStored.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, ctData, data)
+		require.Equal(t, group.Epoch, ctStored.GetEpoch(id))
+
+		ctRes := evalJoin.AddNew(evalJoin.MulRelinNew(ctStored, ctFresh, grlkSet), ctStored)
+		require.Equal(t, group.Epoch, ctRes.GetEpoch(id))
+
+		skSet := mkrlwe.NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+		msgRes := NewDecryptor(params).Decrypt(ctRes, skSet)
+
+		for i := range msg.Value {
+			delta := msg.Value[i]*msg.Value[i] + msg.Value[i] - msgRes.Value[i]
+			require.Less(t, math.Abs(real(delta)), 1e-3)
+		}
+	})
+}
+
+func Test_KS_CKKS(t *testing.T) {
+	params := NewParametersFromLiteral(NewParametersLiteral(PN14QP439))
+	kgen := NewKeyGenerator(params)
+
+	encryptor := NewEncryptor(params)
+	decryptor := NewDecryptor(params)
+	eval := NewEvaluator(params)
+
+	// a party switches its individual key encryption to the key of its group
+	sk := kgen.GenSecretKey("group0")
+	gsk, gpk := kgen.GenKeyPair("group0")
+	swk, swkhead := kgen.GenSWK(sk, gpk)
+
+	msg := NewMessage(params)
+	for i := range msg.Value {
+		msg.Value[i] = complex(utils.RandFloat64(-1, 1), utils.RandFloat64(-1, 1))
+	}
+	ct := encryptor.EncryptSkMsgNew(msg, sk)
+
+	checkMsg := func(msgOut *Message) {
+		for i := range msg.Value {
+			delta := msg.Value[i] - msgOut.Value[i]
+			require.GreaterOrEqual(t, -math.Log2(params.Scale())+float64(params.LogSlots())+42, math.Log2(math.Abs(real(delta))))
+		}
+	}
+
+	t.Run(GetTestName(params, "KS/New/"), func(t *testing.T) {
+		ctData, err := ct.MarshalBinary()
+		require.NoError(t, err)
+
+		ctOut := eval.KSNew(ct, swk, swkhead)
+
+		data, err := ct.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, ctData, data)
+
+		checkMsg(decryptor.DecryptSk(ct, sk))
+		checkMsg(decryptor.DecryptSk(ctOut, gsk))
+	})
+
+	t.Run(GetTestName(params, "KS/Level/"), func(t *testing.T) {
+		ctOut := NewCiphertext(params, ct.IDSet(), ct.Level()-1, ct.Scale)
+		eval.KS(ct, swk, swkhead, ctOut)
+
+		require.Equal(t, ct.Level()-1, ctOut.Level())
+		checkMsg(decryptor.DecryptSk(ctOut, gsk))
+	})
+
+	t.Run(GetTestName(params, "KS/InPlace/"), func(t *testing.T) {
+		ctOut := eval.KSNew(ct, swk, swkhead)
+		ctIn := ct.CopyNew()
+		eval.KS(ctIn, swk, swkhead, ctIn)
+
+		expected, err := ctOut.MarshalBinary()
+		require.NoError(t, err)
+		data, err := ctIn.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, expected, data)
+	})
+
+	t.Run(GetTestName(params, "KS/Composed/"), func(t *testing.T) {
+		id := "group0"
+		group := NewGroup(params, id)
+		skList := make([]*mkrlwe.SecretKey, 0)
+
+		for p := 0; p < 2; p++ {
+			sk := kgen.GenSecretKey(id)
+			skList = append(skList, sk)
+			group.AddShares(kgen.GenPartyShares(sk, []int{1}))
+		}
+
+		for _, sk := range skList {
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		ctStored := encryptor.EncryptMsgNew(msg, group.PublicKey)
+
+		// two parties join the group after the ciphertext is stored
+		jkSet := mkrlwe.NewJoinKeySet()
+		for p := 0; p < 2; p++ {
+			sk := kgen.GenSecretKey(id)
+			skList = append(skList, sk)
+
+			uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+			jkSet.AddJoinKey(group.Join(kgen.GenPartyShares(sk, []int{1}), uaux))
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		cjk := eval.ComposeChain(jkSet, id, 0)
+		require.NotNil(t, cjk)
+
+		_, err := eval.TryKSComposedNew(ctStored, cjk)
+		require.True(t, errors.Is(err, mkrlwe.ErrLevelMismatch))
+
+		ctLow := eval.DropLevelNew(ctStored, ctStored.Level()-cjk.MaxLevel())
+		ctOut, err := eval.TryKSComposedNew(ctLow, cjk)
+		require.NoError(t, err)
+		require.Equal(t, group.Epoch, ctOut.GetEpoch(id))
+
+		skSet := mkrlwe.NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+		checkMsg(decryptor.Decrypt(ctOut, skSet))
+	})
+}
+
+func Test_Smudging_CKKS(t *testing.T) {
+	ids := []string{"user0", "user1"}
+	testContext := genUserTestParams(NewParametersFromLiteral(NewParametersLiteral(PN14QP439)), ids)
+	params := testContext.params
+	ringQ := testContext.ringQ
+	skSet := testContext.skSet
+
+	msg, ct := newSumTestVectors(testContext, ids, complex(-0.5, -0.5), complex(0.5, 0.5))
+
+	t.Run(GetTestName(params, "Smudging/Default/"), func(t *testing.T) {
+		decryptor := NewDecryptor(params)
+		require.Equal(t, mkrlwe.DefaultSmudging(), decryptor.Smudging())
+
+		// the noise of a ciphertext given without its estimate is unknown, so no smudging noise fits in its budget
+		_, err := decryptor.SmudgingSigma(decryptor.SmudgingBound(ct, nil))
+		require.True(t, errors.Is(err, mkrlwe.ErrNoiseBudget))
+		share := ct.CopyNew()
+		require.True(t, errors.Is(decryptor.TryPartialDecryptIP(share, skSet.GetSecretKey(ids[0])), mkrlwe.ErrNoiseBudget))
+		require.True(t, share.Value[ids[0]].Equals(ct.Value[ids[0]]))
+		require.Panics(t, func() { decryptor.PartialDecrypt(share, skSet.GetSecretKey(ids[0])) })
+
+		ne := NewNoiseEstimator(params)
+		est := ne.Add(ne.EncryptMsg(ids[0], 1, 0.5), ne.EncryptMsg(ids[1], 1, 0.5))
+		bound := decryptor.SmudgingBound(ct, est)
+		require.Equal(t, len(ids), bound.Shares)
+		sigma, err := decryptor.SmudgingSigma(bound)
+		require.NoError(t, err)
+
+		// each party partially decrypts its own component, and the smudged shares are summed up with c0
+		ptxt := ct.Value["0"].CopyNew()
+		for _, id := range ids {
+			share := ct.CopyNew()
+			require.NoError(t, decryptor.PartialDecryptIPEst(share, skSet.GetSecretKey(id), est))
+			ringQ.AddLvl(ct.Level(), ptxt, share.Value[id], ptxt)
+		}
+
+		ringQ.ReduceLvl(ct.Level(), ptxt, decryptor.ptxtPool.Value)
+		decryptor.ptxtPool.Scale = ct.Scale
+		msgOut := decryptor.encoder.Decode(decryptor.ptxtPool, params.LogSlots())
+
+		// the real part of a slot sums N coefficients of the smudging noise of the shares, rotated by the roots of unity
+		std := sigma * math.Sqrt(float64(bound.Shares)) * math.Sqrt(float64(params.N())/2) / ct.Scale
+		var sum float64
+		for i := range msg.Value {
+			delta := real(msg.Value[i] - msgOut[i])
+			require.LessOrEqual(t, math.Abs(delta), 6*std)
+			sum += delta * delta
+		}
+		require.InDelta(t, 1, math.Sqrt(sum/float64(len(msg.Value)))/std, 0.2)
+	})
+
+	t.Run(GetTestName(params, "Smudging/NoiseBudget/"), func(t *testing.T) {
+		// a scale of 2^52 leaves no room for 80 bits of security
+		smudging := mkrlwe.Smudging{Security: 80}
+		decryptor := NewDecryptor(params)
+		decryptor.SetSmudging(smudging)
+		require.Equal(t, smudging, decryptor.Smudging())
+
+		_, err := decryptor.SmudgingSigma(decryptor.FreshSmudgingBound(ct))
+		require.True(t, errors.Is(err, mkrlwe.ErrNoiseBudget))
+
+		share := ct.CopyNew()
+		require.True(t, errors.Is(decryptor.PartialDecryptIPFresh(share, skSet.GetSecretKey(ids[0])), mkrlwe.ErrNoiseBudget))
+		require.True(t, share.Value[ids[0]].Equals(ct.Value[ids[0]]))
+		require.True(t, errors.Is(decryptor.PartialDecryptFresh(share, skSet.GetSecretKey(ids[0])), mkrlwe.ErrNoiseBudget))
+	})
+}
+
+func Test_KeyError_CKKS(t *testing.T) {
+	// the relinearization and rotation keys of both users carry their RLWE error
+	ids := []string{"user0", "user1"}
+	testContext := genUserTestParams(NewParametersFromLiteral(NewParametersLiteral(PN14QP439)), ids)
+	params := testContext.params
+	decryptor := testContext.decryptor
+	eval := testContext.evaluator
+	skSet, rlkSet, rtkSet := testContext.skSet, testContext.rlkSet, testContext.rtkSet
+
+	msg, ct := newSumTestVectors(testContext, ids, complex(-0.5, -0.5), complex(0.5, 0.5))
+
+	checkMsg := func(expected func(i int) complex128, msgOut *Message) {
+		for i := range msgOut.Value {
+			delta := expected(i) - msgOut.Value[i]
+			require.GreaterOrEqual(t, -math.Log2(params.Scale())+float64(params.LogSlots())+42, math.Log2(math.Abs(real(delta))))
+			require.GreaterOrEqual(t, -math.Log2(params.Scale())+float64(params.LogSlots())+42, math.Log2(math.Abs(imag(delta))))
+		}
+	}
+
+	t.Run(GetTestName(params, "KeyError/MulRelin/"), func(t *testing.T) {
+		ctRes := eval.MulRelinNew(ct, ct, rlkSet)
+		ctRes, err := eval.RescaleNew(ctRes, params.Scale())
+		require.NoError(t, err)
+
+		checkMsg(func(i int) complex128 { return msg.Value[i] * msg.Value[i] }, decryptor.Decrypt(ctRes, skSet))
+	})
+
+	t.Run(GetTestName(params, "KeyError/Rotate/"), func(t *testing.T) {
+		ctRes := eval.RotateNew(ct, 1, rtkSet)
+
+		checkMsg(func(i int) complex128 { return msg.Value[(i+1)%len(msg.Value)] }, decryptor.Decrypt(ctRes, skSet))
+	})
+}
+
+func Test_Noise_CKKS(t *testing.T) {
+	ids := []string{"user0", "user1"}
+	testContext := genUserTestParams(NewParametersFromLiteral(NewParametersLiteral(PN14QP439)), ids)
+	params := testContext.params
+	decryptor := testContext.decryptor
+	eval := testContext.evaluator
+	skSet, rlkSet, rtkSet := testContext.skSet, testContext.rlkSet, testContext.rtkSet
+	ne := NewNoiseEstimator(params)
+
+	// slots uniform in [-1, 1] + i[-1, 1], of average squared modulus 2/3
+	msgStd := math.Sqrt(2.0 / 3)
+
+	msgs := make([]*Message, len(ids))
+	cts := make([]*Ciphertext, len(ids))
+	ests := make([]*NoiseEstimate, len(ids))
+	for i, id := range ids {
+		msgs[i], cts[i] = newTestVectors(testContext, id, complex(-1, -1), complex(1, 1))
+		ests[i] = ne.EncryptMsg(id, 1, msgStd)
+	}
+
+	checkNoise := func(ct *Ciphertext, est *NoiseEstimate, msg *Message) {
+		logStd, _ := decryptor.MeasureNoise(ct, skSet, msg)
+		t.Logf("estimated %.2f, measured %.2f", est.LogStd(), logStd)
+		require.InDelta(t, est.LogStd(), logStd, 2)
+		require.Equal(t, est.Level, ct.Level())
+		require.Equal(t, est.Scale, ct.Scale)
+	}
+
+	t.Run(GetTestName(params, "Noise/Encrypt/"), func(t *testing.T) {
+		checkNoise(cts[0], ests[0], msgs[0])
+	})
+
+	msgAdd := NewMessage(params)
+	for j := range msgAdd.Value {
+		msgAdd.Value[j] = msgs[0].Value[j] + msgs[1].Value[j]
+	}
+	ctAdd := eval.AddNew(cts[0], cts[1])
+	estAdd := ne.Add(ests[0], ests[1])
+
+	t.Run(GetTestName(params, "Noise/Add/"), func(t *testing.T) {
+		checkNoise(ctAdd, estAdd, msgAdd)
+	})
+
+	t.Run(GetTestName(params, "Noise/Rotate/"), func(t *testing.T) {
+		msgRot := NewMessage(params)
+		for j := range msgRot.Value {
+			msgRot.Value[j] = msgAdd.Value[(j+1)%len(msgAdd.Value)]
+		}
+		checkNoise(eval.RotateNew(ctAdd, 1, rtkSet), ne.Rotate(estAdd), msgRot)
+	})
+
+	t.Run(GetTestName(params, "Noise/MulRelin/"), func(t *testing.T) {
+		msgMul := NewMessage(params)
+		for j := range msgMul.Value {
+			msgMul.Value[j] = msgs[0].Value[j] * msgAdd.Value[j]
+		}
+		estMul := ne.MulRelin(ests[0], estAdd)
+		checkNoise(eval.MulRelinNew(cts[0], ctAdd, rlkSet), estMul, msgMul)
+		require.Less(t, ne.LogError(estMul), -math.Log2(params.Scale())+float64(params.LogSlots())+42)
+	})
+}
+
 func testEncAndDec(testContext *testParams, userList []string, t *testing.T) {
 
 	params := testContext.params
@@ -1212,8 +1812,10 @@ func testKS(testContext *testParams, userList []string, gsk *mkrlwe.SecretKey, g
 		ctdecList[p] = ctOut.CopyNew()
 	}
 
+	ne := NewNoiseEstimator(params)
+	est := ne.KS(ne.EncryptSkMsg(sk[0].ID, 1, 0.1*math.Sqrt2), numUsers)
 	for p := range sk {
-		testContext.decryptor.PartialDecryptIP(ctdecList[p], sk[p])
+		require.NoError(t, testContext.decryptor.PartialDecryptIPEst(ctdecList[p], sk[p], est))
 	}
 	for p := range sk {
 		if p != 0 {
@@ -1677,26 +2279,20 @@ func testJoinPartyMP(testContext *testParams,
 	// 	rtktemp.RotIdx = uint(idx)
 	// 	testContext2.rtkSet.AddRotationKey(rtktemp)
 	// }
-	for idx, _ := range rtksup[0] {
-		rtk := make([]*mkrlwe.RotationKey, numParties+JoiningParties)
-		for p := 0; p < numParties+JoiningParties; p++ {
-			rtk[p] = rtksup[p][idx]
-		}
-		for i := 0; i < beta; i++ {
-			params.RingQP().AddLvl(levelQ, levelP, testContext.rtkSet.Value["group0"][idx].Value.Value[i], rtksup[numParties+JoiningParties-1][idx].Value.Value[i], testContext.rtkSet.Value["group0"][idx].Value.Value[i])
-			_ = idx
-		}
-		testContext2.rtkSet.AddRotationKey(testContext.rtkSet.Value["group0"][idx])
+	// every rotation key of the group and its conjugation key absorb the shares of the joining party
+	joinShares := &mkrlwe.PartyShares{
+		PublicKey:      pkup[numParties+JoiningParties-1],
+		ConjugationKey: cjkup[numParties+JoiningParties-1],
+		RotationKeys:   rtksup[numParties+JoiningParties-1],
 	}
+	testContext2.kgen.JoinKeySets(joinShares, testContext2.rtkSet, testContext2.cjkSet)
 
 	for i := 0; i < beta; i++ {
 		params.RingQP().AddLvl(levelQ, levelP, grlk.Value[0].Value[i], rlkup[numParties+JoiningParties-1].Value[0].Value[i], grlk.Value[0].Value[i])
 		params.RingQP().AddLvl(levelQ, levelP, grlk.Value[1].Value[i], rlkup[numParties+JoiningParties-1].Value[1].Value[i], grlk.Value[1].Value[i])
 		params.RingQP().AddLvl(levelQ, levelP, grlk.Value[2].Value[i], rlkup[numParties+JoiningParties-1].Value[2].Value[i], grlk.Value[2].Value[i])
-		params.RingQP().AddLvl(levelQ, levelP, gcjk.Value.Value[i], cjkup[numParties+JoiningParties-1].Value.Value[i], gcjk.Value.Value[i])
 	}
 	testContext2.rlkSet.AddRelinearizationKey(grlk)
-	testContext2.cjkSet.AddConjugationKey(gcjk)
 
 	ExtGentemp = time.Since(Update_KG_start)
 	fmt.Print("Extend (key) Generation time = ", ExtGentemp, "\n")
@@ -1782,7 +2378,7 @@ func testJoinPartyMP(testContext *testParams,
 	jkup = jk
 	jkheadup = jkhead
 	grlkup = grlk
-	gcjkup = gcjk
+	gcjkup = testContext2.cjkSet.GetConjugationKey("group0")
 	grtkup = grtk
 	gskup = gsktemp
 	gpkup = gpk
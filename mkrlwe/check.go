@@ -0,0 +1,835 @@
+package mkrlwe
+
+import "github.com/ldsec/lattigo/v2/ring"
+
+// The Check functions return the error the operation they are named after panics with on the given inputs, or nil.
+// They only read their inputs, so that a server can reject an invalid request before any pool or operand is touched.
+// The operations of KeySwitcher call them on their inputs for an output at the level of ctOut.
+// The updates of Group, and the generation of their auxiliary keys, call them before touching any key,
+// so that a rejected update leaves the group as it was.
+
+// CheckPoly returns an error of kind ErrInvalidInput if p is not a polynomial of degree N with at least level+1 moduli.
+func CheckPoly(params Parameters, p *ring.Poly, level int) error {
+	if !checkPoly(params, p, level+1) {
+		return NewError(ErrInvalidInput, "invalid input: polynomial does not match the parameters at level %d", level)
+	}
+	return nil
+}
+
+// CheckCiphertext returns an error of kind ErrInvalidInput if ct is not a ciphertext of params:
+// its component "0" must be at a level of params, and its components must be polynomials of degree N at this level or above.
+func CheckCiphertext(params Parameters, ct *Ciphertext) error {
+	if ct == nil {
+		return NewError(ErrInvalidInput, "invalid input: nil ciphertext")
+	}
+
+	c0, in := ct.Value["0"]
+	if !in || c0 == nil || len(c0.Coeffs) == 0 || len(c0.Coeffs) > params.QCount() {
+		return NewError(ErrInvalidInput, "invalid input: ciphertext has no component 0 at a level of the parameters")
+	}
+
+	for id, c := range ct.Value {
+		if !checkPoly(params, c, len(c0.Coeffs)) {
+			return NewError(ErrInvalidInput, "invalid input: component %s of the ciphertext does not match the parameters", id)
+		}
+	}
+
+	return nil
+}
+
+// CheckSecretKey returns an error of kind ErrInvalidInput if sk is not a secret key of params.
+func CheckSecretKey(params Parameters, sk *SecretKey) error {
+	if sk == nil || !checkPoly(params, sk.Value.Q, params.QCount()) {
+		return NewError(ErrInvalidInput, "invalid input: secret key does not match the parameters")
+	}
+	return nil
+}
+
+// CheckSwitchingKey returns an error of kind ErrInvalidInput if swk is not a switching key of params,
+// i.e. a gadget vector of Beta(MaxLevel) polynomials in QP.
+func CheckSwitchingKey(params Parameters, swk *SwitchingKey) error {
+	if swk == nil || len(swk.Value) < params.Beta(params.MaxLevel()) {
+		return NewError(ErrInvalidInput, "invalid input: switching key does not match the parameters")
+	}
+
+	for _, v := range swk.Value {
+		if !checkPoly(params, v.Q, params.QCount()) || !checkPoly(params, v.P, params.PCount()) {
+			return NewError(ErrInvalidInput, "invalid input: switching key does not match the parameters")
+		}
+	}
+
+	return nil
+}
+
+// checkPoly reports whether p is a polynomial of degree N with at least the given number of moduli.
+func checkPoly(params Parameters, p *ring.Poly, moduli int) bool {
+	if p == nil || len(p.Coeffs) < moduli {
+		return false
+	}
+
+	for _, coeffs := range p.Coeffs {
+		if len(coeffs) != params.N() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkOperands checks that cts are ciphertexts of params at the given level or above.
+func checkOperands(params Parameters, opname string, level int, cts ...*Ciphertext) error {
+	for _, ct := range cts {
+		if err := CheckCiphertext(params, ct); err != nil {
+			return err
+		}
+
+		if ct.Level() < level {
+			return NewError(ErrLevelMismatch, "Cannot %s: the input is at level %d, below the output at level %d", opname, ct.Level(), level)
+		}
+	}
+
+	return nil
+}
+
+// checkCRS checks that params have the CRS of the given index.
+func checkCRS(params Parameters, opname string, idx int) error {
+	if _, in := params.CRS[idx]; !in {
+		return NewError(ErrMissingCRS, "Cannot %s: CRS for idx %d is not generated", opname, idx)
+	}
+	return nil
+}
+
+// checkSWK checks that swk is a switching key of params.
+func checkSWK(params Parameters, opname string, swk *SWK) error {
+	if swk == nil || CheckSwitchingKey(params, swk.Value) != nil {
+		return NewError(ErrInvalidInput, "Cannot %s: switching key does not match the parameters", opname)
+	}
+	return nil
+}
+
+// checkHoisted checks that ctHoisted, if not nil, has a decomposition for every id of idset.
+func checkHoisted(params Parameters, opname string, ctHoisted *HoistedCiphertext, idset *IDSet) error {
+	if ctHoisted == nil {
+		return nil
+	}
+
+	for _, id := range idset.Slice() {
+		if CheckSwitchingKey(params, ctHoisted.Value[id]) != nil {
+			return NewError(ErrInvalidInput, "Cannot %s: hoisted ciphertext has no decomposition for id %s", opname, id)
+		}
+	}
+
+	return nil
+}
+
+// CheckMulAndRelin returns the error of MulAndRelin and PrevMulAndRelin for an output at the given level.
+func (ks *KeySwitcher) CheckMulAndRelin(op0, op1 *Ciphertext, rlkSet *RelinearizationKeySet, level int) error {
+	params := ks.Parameters
+
+	if err := checkOperands(params, "MulAndRelin", level, op0, op1); err != nil {
+		return err
+	}
+
+	if err := CheckEpochs("MulAndRelin", op0, op1); err != nil {
+		return err
+	}
+
+	if err := checkCRS(params, "MulAndRelin", -1); err != nil {
+		return err
+	}
+
+	for _, id := range op0.IDSet().Union(op1.IDSet()).Slice() {
+		rlk, err := rlkSet.LookupRelinearizationKey(id)
+		if err != nil {
+			return err
+		}
+
+		for _, swk := range rlk.Value {
+			if CheckSwitchingKey(params, swk) != nil {
+				return NewError(ErrInvalidInput, "Cannot MulAndRelin: relinearization key of id %s does not match the parameters", id)
+			}
+		}
+
+		ct := op0
+		if _, in := op0.Value[id]; !in {
+			ct = op1
+		}
+
+		if err := CheckKeyEpoch("MulAndRelin", ct, id, rlk.Epoch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckMulAndRelinHoisted returns the error of MulAndRelinHoisted for an output at the given level.
+func (ks *KeySwitcher) CheckMulAndRelinHoisted(op0, op1 *Ciphertext, op0Hoisted, op1Hoisted *HoistedCiphertext, rlkSet *RelinearizationKeySet, level int) error {
+	if err := ks.CheckMulAndRelin(op0, op1, rlkSet, level); err != nil {
+		return err
+	}
+
+	if err := checkHoisted(ks.Parameters, "MulAndRelin", op0Hoisted, op0.IDSet()); err != nil {
+		return err
+	}
+
+	return checkHoisted(ks.Parameters, "MulAndRelin", op1Hoisted, op1.IDSet())
+}
+
+// CheckRotate returns the error of Rotate for an output at the given level.
+func (ks *KeySwitcher) CheckRotate(ctIn *Ciphertext, rotidx int, rkSet *RotationKeySet, level int) error {
+	params := ks.Parameters
+
+	if err := checkOperands(params, "Rotate", level, ctIn); err != nil {
+		return err
+	}
+
+	for rotidx < 0 {
+		rotidx += (params.N() / 2)
+	}
+
+	if err := checkCRS(params, "Rotate", rotidx); err != nil {
+		return err
+	}
+
+	for _, id := range ctIn.IDSet().Slice() {
+		rk, err := rkSet.LookupRotationKey(id, uint(rotidx))
+		if err != nil {
+			return err
+		}
+
+		if CheckSwitchingKey(params, rk.Value) != nil {
+			return NewError(ErrInvalidInput, "Cannot Rotate: rotation key of id %s does not match the parameters", id)
+		}
+
+		if err := CheckKeyEpoch("Rotate", ctIn, id, rk.Epoch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckRotateHoisted returns the error of RotateHoisted for an output at the given level.
+func (ks *KeySwitcher) CheckRotateHoisted(ctIn *Ciphertext, rotidx int, ctInHoisted *HoistedCiphertext, rkSet *RotationKeySet, level int) error {
+	if err := ks.CheckRotate(ctIn, rotidx, rkSet, level); err != nil {
+		return err
+	}
+
+	if ctInHoisted == nil {
+		return NewError(ErrInvalidInput, "Cannot Rotate: nil hoisted ciphertext")
+	}
+
+	return checkHoisted(ks.Parameters, "Rotate", ctInHoisted, ctIn.IDSet())
+}
+
+// CheckConjugate returns the error of Conjugate for an output at the given level.
+func (ks *KeySwitcher) CheckConjugate(ctIn *Ciphertext, ckSet *ConjugationKeySet, level int) error {
+	params := ks.Parameters
+
+	if err := checkOperands(params, "Conjugate", level, ctIn); err != nil {
+		return err
+	}
+
+	if err := checkCRS(params, "Conjugate", -2); err != nil {
+		return err
+	}
+
+	for _, id := range ctIn.IDSet().Slice() {
+		ck, err := ckSet.LookupConjugationKey(id)
+		if err != nil {
+			return err
+		}
+
+		if CheckSwitchingKey(params, ck.Value) != nil {
+			return NewError(ErrInvalidInput, "Cannot Conjugate: conjugation key of id %s does not match the parameters", id)
+		}
+
+		if err := CheckKeyEpoch("Conjugate", ctIn, id, ck.Epoch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckKS returns the error of KS for an output at the given level.
+func (ks *KeySwitcher) CheckKS(ctIn *Ciphertext, swk, swkhead *SWK, level int) error {
+	params := ks.Parameters
+
+	if err := checkOperands(params, "KS", level, ctIn); err != nil {
+		return err
+	}
+
+	if err := checkSWK(params, "KS", swk); err != nil {
+		return err
+	}
+
+	if err := checkSWK(params, "KS", swkhead); err != nil {
+		return err
+	}
+
+	for _, id := range ctIn.IDSet().Slice() {
+		if err := checkSWKEpoch("KS", ctIn, id, swk, swkhead); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckKSChain returns the error of KSChain for an output at the given level.
+func (ks *KeySwitcher) CheckKSChain(ctIn *Ciphertext, id string, chain []*JoinKey, level int) error {
+	params := ks.Parameters
+
+	if err := checkOperands(params, "KSChain", level, ctIn); err != nil {
+		return err
+	}
+
+	if _, in := ctIn.Value[id]; !in || id == "0" {
+		return NewError(ErrInvalidInput, "Cannot KSChain: there is no component with given id")
+	}
+
+	if len(chain) == 0 {
+		return NewError(ErrInvalidInput, "Cannot KSChain: empty chain")
+	}
+
+	epoch := ctIn.GetEpoch(id)
+	for _, jk := range chain {
+		if jk == nil {
+			return NewError(ErrInvalidInput, "Cannot KSChain: nil join key")
+		}
+
+		if err := checkSWK(params, "KSChain", jk.Value); err != nil {
+			return err
+		}
+
+		if err := checkSWK(params, "KSChain", jk.Head); err != nil {
+			return err
+		}
+
+		if jk.Value.ID != id || jk.From != epoch {
+			return NewError(ErrEpochMismatch, "Cannot KSChain: the join keys do not form a chain from the epoch of the component")
+		}
+		epoch = jk.Value.Epoch
+	}
+
+	return nil
+}
+
+// CheckKSComposed returns the error of KSComposed for an output at the given level.
+func (ks *KeySwitcher) CheckKSComposed(ctIn *Ciphertext, cjk *ComposedJoinKey, level int) error {
+	params := ks.Parameters
+
+	if err := checkOperands(params, "KSComposed", level, ctIn); err != nil {
+		return err
+	}
+
+	if cjk == nil || len(cjk.Value) == 0 || len(cjk.Value) != len(cjk.Head) {
+		return NewError(ErrInvalidInput, "Cannot KSComposed: composed key does not match the parameters")
+	}
+
+	for i := range cjk.Value {
+		if CheckPoly(params, cjk.Value[i], params.MaxLevel()) != nil || CheckPoly(params, cjk.Head[i], params.MaxLevel()) != nil {
+			return NewError(ErrInvalidInput, "Cannot KSComposed: composed key does not match the parameters")
+		}
+	}
+
+	if _, in := ctIn.Value[cjk.ID]; !in || cjk.ID == "0" {
+		return NewError(ErrInvalidInput, "Cannot KSComposed: there is no component with the id of the composed key")
+	}
+
+	if level > cjk.MaxLevel() || level >= params.MaxLevel() {
+		return NewError(ErrLevelMismatch, "Cannot KSComposed: the output is at level %d, above the maximum level %d of the composed key", level, cjk.MaxLevel())
+	}
+
+	return CheckKeyEpoch("KSComposed", ctIn, cjk.ID, cjk.From)
+}
+
+// CheckMergeKS returns the error of MergeKS for an output at the given level.
+func (ks *KeySwitcher) CheckMergeKS(ctIn *Ciphertext, id0, id1 string, swk0, swkhead0, swk1, swkhead1 *SWK, level int) error {
+	params := ks.Parameters
+
+	if err := checkOperands(params, "MergeKS", level, ctIn); err != nil {
+		return err
+	}
+
+	if id0 == id1 || id0 == "0" || id1 == "0" {
+		return NewError(ErrInvalidInput, "Cannot MergeKS: IDs are not distinct")
+	}
+
+	for _, swk := range []*SWK{swk0, swkhead0, swk1, swkhead1} {
+		if err := checkSWK(params, "MergeKS", swk); err != nil {
+			return err
+		}
+	}
+
+	if _, in := ctIn.Value[id0]; in {
+		if err := checkSWKEpoch("MergeKS", ctIn, id0, swk0, swkhead0); err != nil {
+			return err
+		}
+	}
+
+	if _, in := ctIn.Value[id1]; in {
+		if err := checkSWKEpoch("MergeKS", ctIn, id1, swk1, swkhead1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckSplit returns the error of Split for an output at the given level.
+func (ks *KeySwitcher) CheckSplit(ctIn *Ciphertext, id, id0, id1 string, level int) error {
+	if err := checkOperands(ks.Parameters, "Split", level, ctIn); err != nil {
+		return err
+	}
+
+	if id == id0 || id == id1 || id0 == id1 || id0 == "0" || id1 == "0" {
+		return NewError(ErrInvalidInput, "Cannot Split: IDs are not distinct")
+	}
+
+	if _, in := ctIn.Value[id]; !in || id == "0" {
+		return NewError(ErrInvalidInput, "Cannot Split: there is no component with given id")
+	}
+
+	return nil
+}
+
+// CheckSplitKS returns the error of SplitKS for an output at the given level.
+func (ks *KeySwitcher) CheckSplitKS(ctIn *Ciphertext, id, id0 string, swk, swkhead *SWK, level int) error {
+	params := ks.Parameters
+
+	if err := checkOperands(params, "SplitKS", level, ctIn); err != nil {
+		return err
+	}
+
+	if id == id0 || id0 == "0" {
+		return NewError(ErrInvalidInput, "Cannot SplitKS: IDs are not distinct")
+	}
+
+	if _, in := ctIn.Value[id]; !in || id == "0" {
+		return NewError(ErrInvalidInput, "Cannot SplitKS: there is no component with given id")
+	}
+
+	if err := checkSWK(params, "SplitKS", swk); err != nil {
+		return err
+	}
+
+	if err := checkSWK(params, "SplitKS", swkhead); err != nil {
+		return err
+	}
+
+	return checkSWKEpoch("SplitKS", ctIn, id, swk, swkhead)
+}
+
+// checkSWKEpoch returns an error of kind ErrEpochMismatch if (swk, swkhead) is not a pair from the same update,
+// or if it does not switch from the epoch of the component of ctIn under the given id.
+func checkSWKEpoch(opname string, ctIn *Ciphertext, id string, swk, swkhead *SWK) error {
+	if swk.Epoch != swkhead.Epoch || swk.From != swkhead.From {
+		return NewError(ErrEpochMismatch, "Cannot %s: the switching keys are not from the same update", opname)
+	}
+
+	return CheckKeyEpoch(opname, ctIn, id, swk.From)
+}
+
+// CheckJoinKeySets returns the error of JoinKeySets.
+func (keygen *KeyGenerator) CheckJoinKeySets(shares *PartyShares, rtkSet *RotationKeySet, cjkSet *ConjugationKeySet) error {
+	params := keygen.params
+
+	if shares == nil || shares.PublicKey == nil {
+		return NewError(ErrInvalidInput, "Cannot JoinKeySets: there is no public key share")
+	}
+
+	id := shares.PublicKey.ID
+
+	var rotidx map[uint]*RotationKey
+	if rtkSet != nil {
+		rotidx = rtkSet.Value[id]
+	}
+
+	if err := checkShares(params, "JoinKeySets", id, shares, rotidx); err != nil {
+		return err
+	}
+
+	if cjkSet != nil {
+		if _, in := cjkSet.Value[id]; in && shares.ConjugationKey == nil {
+			return NewError(ErrMissingKey, "Cannot JoinKeySets: there is a missing conjugation key share")
+		}
+	}
+
+	return nil
+}
+
+// CheckGenSWK returns the error of GenSWK.
+func (keygen *KeyGenerator) CheckGenSWK(sk *SecretKey, pk *PublicKey) error {
+	if err := checkSK(keygen.params, "GenSWK", sk); err != nil {
+		return err
+	}
+
+	return checkPK(keygen.params, "GenSWK", pk)
+}
+
+// CheckUAuxKeyGen returns the error of UAuxKeyGen.
+func (keygen *KeyGenerator) CheckUAuxKeyGen(swkheadsum *SWK, sk *SecretKey) error {
+	if err := checkSWK(keygen.params, "UAuxKeyGen", swkheadsum); err != nil {
+		return err
+	}
+
+	return checkSK(keygen.params, "UAuxKeyGen", sk)
+}
+
+// CheckLAuxKeyGen returns the error of LAuxKeyGen.
+func (keygen *KeyGenerator) CheckLAuxKeyGen(swkheadsum *SWK, sk *SecretKey) error {
+	if err := checkSWK(keygen.params, "LAuxKeyGen", swkheadsum); err != nil {
+		return err
+	}
+
+	return checkSK(keygen.params, "LAuxKeyGen", sk)
+}
+
+// CheckRAuxKeyGen returns the error of RAuxKeyGen.
+func (keygen *KeyGenerator) CheckRAuxKeyGen(swkheadsum *SWK, skOld, skNew *SecretKey) error {
+	params := keygen.params
+
+	if err := checkSWK(params, "RAuxKeyGen", swkheadsum); err != nil {
+		return err
+	}
+
+	if err := checkSK(params, "RAuxKeyGen", skOld); err != nil {
+		return err
+	}
+
+	if err := checkSK(params, "RAuxKeyGen", skNew); err != nil {
+		return err
+	}
+
+	if skOld.ID != skNew.ID {
+		return NewError(ErrInvalidInput, "invalid input: IDs are not same")
+	}
+
+	return nil
+}
+
+// CheckAddShares returns the error of AddShares.
+func (g *Group) CheckAddShares(shares *PartyShares) error {
+	if g.Size == 0 {
+		return checkShares(g.params, "AddShares", g.ID, shares, nil)
+	}
+
+	if err := checkShares(g.params, "AddShares", g.ID, shares, g.RotationKeys); err != nil {
+		return err
+	}
+
+	if len(shares.RotationKeys) != len(g.RotationKeys) {
+		return NewError(ErrInvalidInput, "invalid input: rotation indexes are not same")
+	}
+
+	return nil
+}
+
+// CheckAddSWK returns the error of AddSWK.
+func (g *Group) CheckAddSWK(swk, swkhead *SWK) error {
+	if err := g.checkSWK("AddSWK", swk, g.ID); err != nil {
+		return err
+	}
+
+	if err := g.checkSWK("AddSWK", swkhead, g.ID); err != nil {
+		return err
+	}
+
+	if swk.Epoch != g.Epoch || swkhead.Epoch != g.Epoch {
+		return NewError(ErrInvalidInput, "Cannot AddSWK: the SWK pair is not generated against the current group public key")
+	}
+
+	return nil
+}
+
+// CheckJoin returns the error of Join.
+func (g *Group) CheckJoin(shares *PartyShares, uaux *SWK) error {
+	return g.CheckBatchJoin([]*PartyShares{shares}, []*SWK{uaux})
+}
+
+// CheckBatchJoin returns the error of BatchJoin.
+func (g *Group) CheckBatchJoin(sharesList []*PartyShares, uauxList []*SWK) error {
+	if g.SWKSum == nil {
+		return NewError(ErrInvalidInput, "Cannot Join: the group has no switching key sum")
+	}
+
+	if len(sharesList) == 0 || len(sharesList) != len(uauxList) {
+		return NewError(ErrInvalidInput, "invalid input: the numbers of shares and auxiliary keys are not same")
+	}
+
+	for _, shares := range sharesList {
+		if err := checkShares(g.params, "Join", g.ID, shares, g.RotationKeys); err != nil {
+			return err
+		}
+	}
+
+	for _, uaux := range uauxList {
+		if err := g.checkAux("Join", uaux, g.ID, g.Epoch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckLeave returns the error of Leave.
+func (g *Group) CheckLeave(shares *PartyShares, laux, swk, swkhead *SWK) error {
+	if g.SWKSum == nil {
+		return NewError(ErrInvalidInput, "Cannot Leave: the group has no switching key sum")
+	}
+
+	if g.Size < 2 {
+		return NewError(ErrInvalidInput, "Cannot Leave: the last member cannot leave the group")
+	}
+
+	if err := checkShares(g.params, "Leave", g.ID, shares, g.RotationKeys); err != nil {
+		return err
+	}
+
+	if err := g.checkAux("Leave", laux, g.ID, g.Epoch); err != nil {
+		return err
+	}
+
+	if err := g.checkSWK("Leave", swk, g.ID); err != nil {
+		return err
+	}
+
+	if err := g.checkSWK("Leave", swkhead, g.ID); err != nil {
+		return err
+	}
+
+	if swk.Epoch != g.Epoch+1 || swkhead.Epoch != g.Epoch+1 {
+		return NewError(ErrInvalidInput, "Cannot Leave: the SWK pair is not generated against the group public key without the leaving member")
+	}
+
+	return nil
+}
+
+// CheckRevoke returns the error of Revoke.
+func (g *Group) CheckRevoke(shares *PartyShares) error {
+	if g.Size < 2 {
+		return NewError(ErrInvalidInput, "Cannot Revoke: the last member cannot be revoked")
+	}
+
+	return checkShares(g.params, "Revoke", g.ID, shares, g.RotationKeys)
+}
+
+// CheckRefresh returns the error of Refresh.
+func (g *Group) CheckRefresh(sharesList []*PartyShares, rauxList []*SWK) error {
+	if g.SWKSum == nil {
+		return NewError(ErrInvalidInput, "Cannot Refresh: the group has no switching key sum")
+	}
+
+	if len(sharesList) != g.Size || len(rauxList) != g.Size {
+		return NewError(ErrInvalidInput, "invalid input: every member must provide its shares and auxiliary key")
+	}
+
+	for _, shares := range sharesList {
+		if err := checkShares(g.params, "Refresh", g.ID, shares, g.RotationKeys); err != nil {
+			return err
+		}
+	}
+
+	if err := checkSharesList(g.params, "Refresh", g.ID, sharesList); err != nil {
+		return err
+	}
+
+	for _, raux := range rauxList {
+		if err := g.checkAux("Refresh", raux, g.ID, g.Epoch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckMerge returns the error of Merge.
+func (g *Group) CheckMerge(other *Group, uaux, uauxOther []*SWK) error {
+	if other == nil || g.SWKSum == nil || other.SWKSum == nil || g.PublicKey == nil || other.PublicKey == nil {
+		return NewError(ErrInvalidInput, "Cannot Merge: the groups have no switching key sum")
+	}
+
+	if g.ID == other.ID {
+		return NewError(ErrInvalidInput, "invalid input: IDs are same")
+	}
+
+	if len(uaux) != other.Size || len(uauxOther) != g.Size {
+		return NewError(ErrInvalidInput, "invalid input: every member must provide an auxiliary key")
+	}
+
+	// the members of each group compute their auxiliary keys under their own ID against the sum of the other group
+	for _, aux := range uaux {
+		if err := g.checkAux("Merge", aux, other.ID, g.Epoch); err != nil {
+			return err
+		}
+	}
+
+	for _, aux := range uauxOther {
+		if err := g.checkAux("Merge", aux, g.ID, other.Epoch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckSplit returns the error of Split.
+func (g *Group) CheckSplit(id0, id1 string, shares0, shares1 []*PartyShares) error {
+	if id0 == id1 || id0 == g.ID || id1 == g.ID {
+		return NewError(ErrInvalidInput, "invalid input: IDs are not distinct")
+	}
+
+	if len(shares0) == 0 || len(shares1) == 0 || len(shares0)+len(shares1) != g.Size {
+		return NewError(ErrInvalidInput, "invalid input: the shares do not partition the group")
+	}
+
+	if err := checkSharesList(g.params, "Split", id0, shares0); err != nil {
+		return err
+	}
+
+	return checkSharesList(g.params, "Split", id1, shares1)
+}
+
+// CheckSplitSWK returns the error of SplitSWK.
+func (g *Group) CheckSplitSWK(swkList, swkheadList []*SWK) error {
+	if len(swkList) == 0 || len(swkList) != len(swkheadList) {
+		return NewError(ErrInvalidInput, "invalid input: the numbers of switching keys are not same")
+	}
+
+	if err := checkSWK(g.params, "SplitSWK", swkList[0]); err != nil {
+		return err
+	}
+
+	// the members of the child group id1 generate the pairs under their own ID
+	for _, swk := range append(swkList, swkheadList...) {
+		if err := g.checkSWK("SplitSWK", swk, swkList[0].ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkSWK checks that swk is a switching key of the group parameters under the given id.
+func (g *Group) checkSWK(opname string, swk *SWK, id string) error {
+	if err := checkSWK(g.params, opname, swk); err != nil {
+		return err
+	}
+
+	if swk.ID != id {
+		return NewError(ErrInvalidInput, "invalid input: IDs are not same")
+	}
+
+	return nil
+}
+
+// checkAux checks that aux is an auxiliary key under the given id, computed against a switching key sum of the given epoch.
+func (g *Group) checkAux(opname string, aux *SWK, id string, epoch int) error {
+	if err := g.checkSWK(opname, aux, id); err != nil {
+		return err
+	}
+
+	if aux.Epoch != epoch {
+		return NewError(ErrEpochMismatch, "Cannot %s: the auxiliary key is computed against the switching key sum of epoch %d, not %d", opname, aux.Epoch, epoch)
+	}
+
+	return nil
+}
+
+// checkShares checks that shares are key shares of params under the given id,
+// with a rotation key share for every index of rotidx.
+func checkShares(params Parameters, opname, id string, shares *PartyShares, rotidx map[uint]*RotationKey) error {
+	if shares == nil {
+		return NewError(ErrInvalidInput, "Cannot %s: nil shares", opname)
+	}
+
+	if err := checkPK(params, opname, shares.PublicKey); err != nil {
+		return err
+	}
+
+	if shares.PublicKey.ID != id {
+		return NewError(ErrInvalidInput, "invalid input: IDs are not same")
+	}
+
+	if rlk := shares.RelinearizationKey; rlk != nil {
+		for _, swk := range rlk.Value {
+			if CheckSwitchingKey(params, swk) != nil {
+				return NewError(ErrInvalidInput, "Cannot %s: relinearization key share does not match the parameters", opname)
+			}
+		}
+
+		if rlk.ID != id {
+			return NewError(ErrInvalidInput, "invalid input: IDs are not same")
+		}
+	}
+
+	if cjk := shares.ConjugationKey; cjk != nil {
+		if CheckSwitchingKey(params, cjk.Value) != nil {
+			return NewError(ErrInvalidInput, "Cannot %s: conjugation key share does not match the parameters", opname)
+		}
+
+		if cjk.ID != id {
+			return NewError(ErrInvalidInput, "invalid input: IDs are not same")
+		}
+	}
+
+	for idx := range rotidx {
+		if _, in := shares.RotationKeys[idx]; !in {
+			return NewError(ErrMissingKey, "Cannot %s: there is a missing rotation key share", opname)
+		}
+	}
+
+	for _, rtk := range shares.RotationKeys {
+		if rtk == nil || CheckSwitchingKey(params, rtk.Value) != nil {
+			return NewError(ErrInvalidInput, "Cannot %s: rotation key share does not match the parameters", opname)
+		}
+
+		if rtk.ID != id {
+			return NewError(ErrInvalidInput, "invalid input: IDs are not same")
+		}
+	}
+
+	return nil
+}
+
+// checkSharesList checks that sharesList are the key shares of the founding members of a group with the given id,
+// which must all provide rotation key shares for the same set of indexes.
+func checkSharesList(params Parameters, opname, id string, sharesList []*PartyShares) error {
+	for _, shares := range sharesList {
+		if err := checkShares(params, opname, id, shares, sharesList[0].RotationKeys); err != nil {
+			return err
+		}
+
+		if len(shares.RotationKeys) != len(sharesList[0].RotationKeys) {
+			return NewError(ErrInvalidInput, "invalid input: rotation indexes are not same")
+		}
+	}
+
+	return nil
+}
+
+// checkSK checks that sk is a secret key of params in QP.
+func checkSK(params Parameters, opname string, sk *SecretKey) error {
+	if CheckSecretKey(params, sk) != nil || !checkPoly(params, sk.Value.P, params.PCount()) {
+		return NewError(ErrInvalidInput, "Cannot %s: secret key does not match the parameters", opname)
+	}
+	return nil
+}
+
+// checkPK checks that pk is a public key of params.
+func checkPK(params Parameters, opname string, pk *PublicKey) error {
+	if pk == nil {
+		return NewError(ErrInvalidInput, "Cannot %s: public key does not match the parameters", opname)
+	}
+
+	for _, v := range pk.Value {
+		if !checkPoly(params, v.Q, params.QCount()) || !checkPoly(params, v.P, params.PCount()) {
+			return NewError(ErrInvalidInput, "Cannot %s: public key does not match the parameters", opname)
+		}
+	}
+
+	return nil
+}
@@ -14,12 +14,23 @@ type Decryptor struct {
 	pool               *ring.Poly
 	sk                 *SecretKey
 	NFgaussianSamplerQ *ring.GaussianSampler
+	smudging           Smudging
 }
 
 // NewDecryptor instantiates a new generic RLWE Decryptor.
 func NewDecryptor(params Parameters) *Decryptor {
-	prng, _ := utils.NewPRNG()
-	return &Decryptor{
+	prng, err := utils.NewPRNG()
+	if err != nil {
+		panic(err)
+	}
+
+	return NewDecryptorWithPRNG(params, prng)
+}
+
+// NewDecryptorWithPRNG instantiates a new generic RLWE Decryptor whose noise sampler reads from the given PRNG.
+// Its partial decryptions are smudged with DefaultSmudging.
+func NewDecryptorWithPRNG(params Parameters, prng utils.PRNG) *Decryptor {
+	decryptor := &Decryptor{
 		params:             params,
 		ringQ:              params.RingQ(),
 		pool:               params.RingQ().NewPoly(),
@@ -28,6 +39,9 @@ func NewDecryptor(params Parameters) *Decryptor {
 		// 2^64 = 18446744073709551616
 		// 2^40 = 1099511627776
 	}
+	decryptor.SetSmudging(DefaultSmudging())
+
+	return decryptor
 }
 
 // PartialDecrypt partially decrypts the ct with single secretkey sk and update result inplace
@@ -50,33 +64,121 @@ func (decryptor *Decryptor) PartialDecryptOriginal(ct *Ciphertext, sk *SecretKey
 	// delete(ct.Value, id)
 }
 
-// PartialDecrypt partially decrypts the ct with single secretkey sk and update result inplace
-func (decryptor *Decryptor) PartialDecrypt(ct *Ciphertext, sk *SecretKey) {
-	ringQ := decryptor.ringQ
-	id := sk.ID
-	level := ct.Level()
+// CheckPartialDecrypt returns the error of PartialDecrypt, PartialDecryptIP and DecryptSk:
+// ct must be a ciphertext of the parameters of the decryptor with a component under the id of sk.
+func (decryptor *Decryptor) CheckPartialDecrypt(ct *Ciphertext, sk *SecretKey) error {
+	if err := CheckCiphertext(decryptor.params, ct); err != nil {
+		return err
+	}
 
-	if !ct.Value[id].IsNTT {
-		ringQ.NTTLvl(level, ct.Value[id], ct.Value[id])
+	if err := CheckSecretKey(decryptor.params, sk); err != nil {
+		return err
 	}
 
-	e1 := ringQ.NewPoly()
-	// decryptor.gaussianSamplerQ.Read(e1) // Partial dec 노이즈 제로
-	ringQ.NTTLvl(level, e1, e1)
+	if _, in := ct.Value[sk.ID]; !in || sk.ID == "0" {
+		return NewError(ErrInvalidInput, "Cannot PartialDecrypt: there is no component with id %s", sk.ID)
+	}
 
-	ringQ.MulCoeffsMontgomeryLvl(level, ct.Value[id], sk.Value.Q, ct.Value[id])
-	ringQ.AddLvl(level, e1, ct.Value[id], ct.Value[id])
+	return nil
+}
 
-	if !ct.Value[id].IsNTT {
-		ringQ.InvNTTLvl(level, ct.Value[id], ct.Value[id])
+// TryPartialDecrypt is PartialDecrypt returning an error instead of panicking on an invalid request,
+// in which case ct is left unchanged.
+func (decryptor *Decryptor) TryPartialDecrypt(ct *Ciphertext, sk *SecretKey) error {
+	if err := decryptor.CheckPartialDecrypt(ct, sk); err != nil {
+		return err
 	}
 
-	ringQ.AddLvl(level, ct.Value["0"], ct.Value[id], ct.Value["0"])
-	delete(ct.Value, id)
+	return decryptor.PartialDecryptBound(ct, sk, decryptor.SmudgingBound(ct, nil))
+}
+
+// TryPartialDecryptIP is PartialDecryptIP returning an error instead of panicking on an invalid request,
+// in which case ct is left unchanged.
+func (decryptor *Decryptor) TryPartialDecryptIP(ct *Ciphertext, sk *SecretKey) error {
+	if err := decryptor.CheckPartialDecrypt(ct, sk); err != nil {
+		return err
+	}
+
+	return decryptor.PartialDecryptIPBound(ct, sk, decryptor.SmudgingBound(ct, nil))
 }
 
 // PartialDecrypt partially decrypts the ct with single secretkey sk and update result inplace
+// As the noise of ct is unknown, it panics with an error of kind ErrNoiseBudget unless the smudging is disabled:
+// the smudged partial decryptions are given by PartialDecryptBound, or by PartialDecryptFresh for a fresh ciphertext.
+func (decryptor *Decryptor) PartialDecrypt(ct *Ciphertext, sk *SecretKey) {
+	if err := decryptor.TryPartialDecrypt(ct, sk); err != nil {
+		panic(err)
+	}
+}
+
+// PartialDecryptIP replaces the component of ct under sk.ID by its product with sk inplace,
+// so that the partial decryptions of the members of a group can be summed up.
+// As the noise of ct is unknown, it panics with an error of kind ErrNoiseBudget unless the smudging is disabled:
+// the smudged partial decryptions are given by PartialDecryptIPBound, or by PartialDecryptIPFresh for a fresh ciphertext.
 func (decryptor *Decryptor) PartialDecryptIP(ct *Ciphertext, sk *SecretKey) {
+	if err := decryptor.TryPartialDecryptIP(ct, sk); err != nil {
+		panic(err)
+	}
+}
+
+// PartialDecryptFresh is PartialDecryptBound with the smudging noise sized by FreshSmudgingBound.
+// It must only be used on a fresh encryption, as it under-smudges the partial decryptions of a ciphertext
+// which went through evaluation.
+func (decryptor *Decryptor) PartialDecryptFresh(ct *Ciphertext, sk *SecretKey) error {
+	if err := decryptor.CheckPartialDecrypt(ct, sk); err != nil {
+		return err
+	}
+
+	return decryptor.PartialDecryptBound(ct, sk, decryptor.FreshSmudgingBound(ct))
+}
+
+// PartialDecryptIPFresh is PartialDecryptIPBound with the smudging noise sized by FreshSmudgingBound.
+// It must only be used on a fresh encryption, as it under-smudges the partial decryptions of a ciphertext
+// which went through evaluation.
+func (decryptor *Decryptor) PartialDecryptIPFresh(ct *Ciphertext, sk *SecretKey) error {
+	if err := decryptor.CheckPartialDecrypt(ct, sk); err != nil {
+		return err
+	}
+
+	return decryptor.PartialDecryptIPBound(ct, sk, decryptor.FreshSmudgingBound(ct))
+}
+
+// PartialDecryptBound is PartialDecrypt with the smudging noise sized for a ciphertext of the given bound.
+// It returns an error, and leaves ct unchanged, on an invalid request or if the smudging noise does not fit
+// in the budget of the bound (ErrNoiseBudget).
+func (decryptor *Decryptor) PartialDecryptBound(ct *Ciphertext, sk *SecretKey, bound SmudgingBound) error {
+	if err := decryptor.PartialDecryptIPBound(ct, sk, bound); err != nil {
+		return err
+	}
+
+	decryptor.ringQ.AddLvl(ct.Level(), ct.Value["0"], ct.Value[sk.ID], ct.Value["0"])
+	delete(ct.Value, sk.ID)
+	return nil
+}
+
+// PartialDecryptIPBound is PartialDecryptIP with the smudging noise sized for a ciphertext of the given bound.
+// It returns an error, and leaves ct unchanged, on an invalid request or if the smudging noise does not fit
+// in the budget of the bound (ErrNoiseBudget).
+func (decryptor *Decryptor) PartialDecryptIPBound(ct *Ciphertext, sk *SecretKey, bound SmudgingBound) error {
+	if err := decryptor.CheckPartialDecrypt(ct, sk); err != nil {
+		return err
+	}
+
+	sigmas, err := decryptor.smudgingSigmas(bound)
+	if err != nil {
+		return err
+	}
+
+	decryptor.partialDecrypt(ct, sk, sigmas)
+	return nil
+}
+
+// partialDecrypt multiplies the component of ct under sk.ID by sk inplace, and adds the smudging noise of the given digits.
+func (decryptor *Decryptor) partialDecrypt(ct *Ciphertext, sk *SecretKey, sigmas []float64) {
+	if err := decryptor.CheckPartialDecrypt(ct, sk); err != nil {
+		panic(err)
+	}
+
 	ringQ := decryptor.ringQ
 	id := sk.ID
 	level := ct.Level()
@@ -85,44 +187,81 @@ func (decryptor *Decryptor) PartialDecryptIP(ct *Ciphertext, sk *SecretKey) {
 		ringQ.NTTLvl(level, ct.Value[id], ct.Value[id])
 	}
 
-	e1 := ringQ.NewPoly()
-	decryptor.NFgaussianSamplerQ.Read(e1)
-	// fmt.Print(e1)
-	ringQ.NTTLvl(level, e1, e1)
-
 	ringQ.MulCoeffsMontgomeryLvl(level, ct.Value[id], sk.Value.Q, ct.Value[id])
-	ringQ.AddLvl(level, e1, ct.Value[id], ct.Value[id])
+
+	if len(sigmas) > 0 {
+		e1 := decryptor.pool
+		decryptor.readSmudgingLvl(level, e1, sigmas)
+		ringQ.NTTLvl(level, e1, e1)
+		ringQ.AddLvl(level, e1, ct.Value[id], ct.Value[id])
+	}
 
 	if !ct.Value[id].IsNTT {
 		ringQ.InvNTTLvl(level, ct.Value[id], ct.Value[id])
 	}
-
-	// ringQ.AddLvl(level, ct.Value["0"], ct.Value[id], ct.Value["0"])
-	// delete(ct.Value, id)
 }
 
 // Decrypt decrypts the ciphertext with given secretkey set and write the result in ptOut.
 // The level of the output plaintext is min(ciphertext.Level(), plaintext.Level())
 // Output domain will match plaintext.Value.IsNTT value.
 func (decryptor *Decryptor) Decrypt(ciphertext *Ciphertext, skSet *SecretKeySet, plaintext *rlwe.Plaintext) {
+	if err := decryptor.CheckDecrypt(ciphertext, skSet); err != nil {
+		panic(err)
+	}
+
 	ringQ := decryptor.ringQ
 	level := utils.MinInt(ciphertext.Level(), plaintext.Level())
 	plaintext.Value.Coeffs = plaintext.Value.Coeffs[:level+1]
 
 	ctTmp := ciphertext.CopyNew()
 	idset := ctTmp.IDSet()
-	for _, sk := range skSet.Value {
-		if idset.Has(sk.ID) {
-			decryptor.PartialDecrypt(ctTmp, sk)
-			// fmt.Print(sk.ID, "\n")
+	for _, id := range idset.Slice() {
+		decryptor.partialDecrypt(ctTmp, skSet.Value[id], nil)
+		ringQ.AddLvl(level, ctTmp.Value["0"], ctTmp.Value[id], ctTmp.Value["0"])
+		delete(ctTmp.Value, id)
+	}
+
+	ringQ.ReduceLvl(level, ctTmp.Value["0"], plaintext.Value)
+}
+
+// CheckDecrypt returns the error of Decrypt: ciphertext must be a ciphertext of the parameters of the decryptor,
+// and skSet must have the secret key of each of its components (ErrMissingKey).
+func (decryptor *Decryptor) CheckDecrypt(ciphertext *Ciphertext, skSet *SecretKeySet) error {
+	if err := CheckCiphertext(decryptor.params, ciphertext); err != nil {
+		return err
+	}
+
+	for _, id := range ciphertext.IDSet().Slice() {
+		sk, err := skSet.LookupSecretKey(id)
+		if err != nil {
+			return err
+		}
+
+		if sk.ID != id {
+			return NewError(ErrInvalidInput, "Cannot Decrypt: secret key under id %s has id %s", id, sk.ID)
+		}
+
+		if err := CheckSecretKey(decryptor.params, sk); err != nil {
+			return err
 		}
 	}
 
-	if len(ctTmp.Value) > 1 {
-		panic("Cannot Decrypt: there is a missing secretkey")
+	return nil
+}
+
+// TryDecrypt is Decrypt returning an error instead of panicking on an invalid request,
+// e.g. if skSet lacks the secret key of a component of the ciphertext (ErrMissingKey).
+func (decryptor *Decryptor) TryDecrypt(ciphertext *Ciphertext, skSet *SecretKeySet, plaintext *rlwe.Plaintext) error {
+	if err := decryptor.CheckDecrypt(ciphertext, skSet); err != nil {
+		return err
 	}
 
-	ringQ.ReduceLvl(level, ctTmp.Value["0"], plaintext.Value)
+	if plaintext == nil || CheckPoly(decryptor.params, plaintext.Value, 0) != nil {
+		return NewError(ErrInvalidInput, "Cannot Decrypt: plaintext does not match the parameters")
+	}
+
+	decryptor.Decrypt(ciphertext, skSet, plaintext)
+	return nil
 }
 
 func (decryptor *Decryptor) DecryptSk(ciphertext *Ciphertext, sk *SecretKey, plaintext *rlwe.Plaintext) {
@@ -133,7 +272,8 @@ func (decryptor *Decryptor) DecryptSk(ciphertext *Ciphertext, sk *SecretKey, pla
 	ctTmp := ciphertext.CopyNew()
 	// idset := ctTmp.IDSet()
 	// idset.Has(sk.ID)
-	decryptor.PartialDecrypt(ctTmp, sk)
+	decryptor.partialDecrypt(ctTmp, sk, nil)
+	ringQ.AddLvl(level, ctTmp.Value["0"], ctTmp.Value[sk.ID], ctTmp.Value["0"])
 	// fmt.Print(sk.ID, "\n")
 
 	ringQ.ReduceLvl(level, ctTmp.Value["0"], plaintext.Value)
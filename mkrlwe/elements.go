@@ -1,6 +1,10 @@
 package mkrlwe
 
-import "github.com/ldsec/lattigo/v2/ring"
+import (
+	"sync"
+
+	"github.com/ldsec/lattigo/v2/ring"
+)
 
 type HoistedCiphertext struct {
 	Value map[string]*SwitchingKey
@@ -14,14 +18,68 @@ func NewHoistedCiphertext() *HoistedCiphertext {
 	return ctHoisted
 }
 
+// Reserve allocates a decomposition in ctHoisted for each id of idset which has none yet,
+// so that ctHoisted can be used as a pool for the decompositions of ciphertexts of these ids.
+func (ctHoisted *HoistedCiphertext) Reserve(params Parameters, idset *IDSet) {
+	for _, id := range idset.Slice() {
+		if _, in := ctHoisted.Value[id]; !in {
+			ctHoisted.Value[id] = NewSwitchingKey(params)
+		}
+	}
+}
+
 type Ciphertext struct {
 	Value map[string]*ring.Poly
+	Epoch map[string]int // epoch of the keys each component is encrypted under
+
+	extendMu sync.Mutex // serializes the lazy extensions of the ciphertext, see KeySwitcher.Extend
+}
+
+// SeededCiphertext is a secret-key encryption under a single id whose uniform component is represented by
+// the seed it is expanded from, which roughly halves its size. It is produced by Encryptor.EncryptSkSeeded and sent
+// as is. The receiver recovers the Ciphertext with Expand, on which it can then evaluate.
+type SeededCiphertext struct {
+	ID    string
+	Epoch int
+	Seed  []byte
+	Value *ring.Poly // component "0"
+}
+
+// NewSeededCiphertext returns a new SeededCiphertext with zero values at the given level.
+func NewSeededCiphertext(params Parameters, level int) *SeededCiphertext {
+	return &SeededCiphertext{Value: ring.NewPoly(params.N(), level+1)}
+}
+
+// Level returns the level of the target element.
+func (el *SeededCiphertext) Level() int {
+	return len(el.Value.Coeffs) - 1
+}
+
+// Expand returns a new Ciphertext equal to the secret-key encryption represented by el,
+// with its uniform component expanded from the seed.
+// It returns an error of kind ErrInvalidInput if el does not match params.
+func (el *SeededCiphertext) Expand(params Parameters) (*Ciphertext, error) {
+	if el.Value == nil || el.Value.Degree() != params.N() || el.Level() > params.MaxLevel() {
+		return nil, NewError(ErrInvalidInput, "Cannot Expand: component 0 does not match the parameters")
+	}
+
+	if len(el.Seed) != CiphertextSeedSize || el.ID == "" || el.ID == "0" {
+		return nil, NewError(ErrInvalidInput, "Cannot Expand: invalid seed or id")
+	}
+
+	ct := &Ciphertext{Value: make(map[string]*ring.Poly), Epoch: make(map[string]int)}
+	ct.Value["0"] = el.Value.CopyNew()
+	ct.Value[el.ID] = expandSeed(params, el.Seed, el.Level(), el.Value.IsNTT)
+	ct.SetEpoch(el.ID, el.Epoch)
+
+	return ct, nil
 }
 
 // NewCiphertext returns a new Element with zero values
 func NewCiphertext(params Parameters, idset *IDSet, level int) *Ciphertext {
 	el := new(Ciphertext)
 	el.Value = make(map[string]*ring.Poly)
+	el.Epoch = make(map[string]int)
 
 	el.Value["0"] = ring.NewPoly(params.N(), level+1)
 
@@ -56,6 +114,12 @@ func (el *Ciphertext) IDSet() *IDSet {
 	return idset
 }
 
+// IDs returns the ids of the components of the target element in canonical order,
+// "0" followed by the ids of IDSet in sorted order
+func (el *Ciphertext) IDs() []string {
+	return append([]string{"0"}, el.IDSet().Slice()...)
+}
+
 // Level returns the level of the target elements
 func (el *Ciphertext) Level() int {
 	return len(el.Value["0"].Coeffs) - 1
@@ -77,6 +141,8 @@ func (el *Ciphertext) CopyNew() *Ciphertext {
 		ctxCopy.Value[id] = el.Value[id].CopyNew()
 	}
 
+	ctxCopy.CopyEpochs(el)
+
 	return ctxCopy
 }
 
@@ -85,6 +151,8 @@ func (el *Ciphertext) Copy(ct *Ciphertext) {
 	for id := range ct.Value {
 		el.Value[id].Copy(ct.Value[id])
 	}
+
+	el.CopyEpochs(ct)
 }
 
 // PadCiphertext pads a ciphertext with an input idset
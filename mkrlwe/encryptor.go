@@ -19,6 +19,7 @@ type encryptorBase struct {
 	gaussianSampler *ring.GaussianSampler
 	ternarySampler  *ring.TernarySampler
 	uniformSampler  *ring.UniformSampler
+	prng            utils.PRNG
 }
 
 // Encryptor is a struct used to encrypt plaintext with public key
@@ -26,16 +27,11 @@ type Encryptor struct {
 	encryptorBase
 }
 
-func newEncryptorBase(params Parameters) encryptorBase {
+func newEncryptorBase(params Parameters, prng utils.PRNG) encryptorBase {
 
 	ringQ := params.RingQ()
 	ringP := params.RingP()
 
-	prng, err := utils.NewPRNG()
-	if err != nil {
-		panic(err)
-	}
-
 	var poolP [3]*ring.Poly
 	if params.PCount() != 0 {
 		poolP = [3]*ring.Poly{ringP.NewPoly(), ringP.NewPoly(), ringP.NewPoly()}
@@ -50,6 +46,7 @@ func newEncryptorBase(params Parameters) encryptorBase {
 		gaussianSampler: ring.NewGaussianSampler(prng, ringQ, params.Sigma(), uint64(6*params.Sigma())),
 		ternarySampler:  ring.NewTernarySampler(prng, ringQ, 0.5, false),
 		uniformSampler:  ring.NewUniformSampler(prng, ringQ),
+		prng:            prng,
 	}
 }
 
@@ -117,10 +114,31 @@ func (encryptor *Encryptor) Encrypt(plaintext *rlwe.Plaintext, pk *PublicKey, ct
 	ctOut.Value["0"].Coeffs = ctOut.Value["0"].Coeffs[:levelQ+1]
 	ctOut.Value[id].Coeffs = ctOut.Value[id].Coeffs[:levelQ+1]
 
+	ctOut.SetEpoch(id, pk.Epoch)
 }
 
 // EncryptSk encrypts the input Plaintext with sk and write the result in ctOut.
 func (encryptor *Encryptor) EncryptSk(plaintext *rlwe.Plaintext, sk *SecretKey, ctOut *Ciphertext) {
+	encryptor.encryptSk(plaintext, sk, encryptor.uniformSampler, ctOut)
+}
+
+// EncryptSkSeeded encrypts the input Plaintext with sk and write the result in ctOut,
+// whose uniform component is expanded from a fresh seed stored in ctOut.
+// The level of ctOut is min(plaintext.Level(), ctOut.Level()), and its NTT flag is the one of ctOut.Value.
+func (encryptor *Encryptor) EncryptSkSeeded(plaintext *rlwe.Plaintext, sk *SecretKey, ctOut *SeededCiphertext) {
+	seed := make([]byte, CiphertextSeedSize)
+	encryptor.prng.Clock(seed)
+
+	ct := &Ciphertext{Value: map[string]*ring.Poly{"0": ctOut.Value}, Epoch: make(map[string]int)}
+	encryptor.encryptSk(plaintext, sk, newSeededSampler(encryptor.ringQ, seed), ct)
+
+	ctOut.ID = sk.ID
+	ctOut.Epoch = sk.Epoch
+	ctOut.Seed = seed
+	ctOut.Value = ct.Value["0"]
+}
+
+func (encryptor *Encryptor) encryptSk(plaintext *rlwe.Plaintext, sk *SecretKey, uniformSampler *ring.UniformSampler, ctOut *Ciphertext) {
 	id := sk.ID
 	levelQ := utils.MinInt(plaintext.Level(), ctOut.Level())
 
@@ -130,21 +148,20 @@ func (encryptor *Encryptor) EncryptSk(plaintext *rlwe.Plaintext, sk *SecretKey,
 
 	ciphertextNTT := ctOut.Value["0"].IsNTT
 
-	encryptor.uniformSampler.ReadLvl(levelQ, poolQ0)
-	ringQ.NTTLvl(levelQ, poolQ0, poolQ0)
-	ringQ.MFormLvl(levelQ, poolQ0, poolQ0)
+	if _, in := ctOut.Value[id]; !in {
+		ctOut.Value[id] = ringQ.NewPoly()
+	}
 
-	// ct0 = u*sk
-	ringQ.MulCoeffsMontgomeryLvl(levelQ, poolQ0, sk.Value.Q, ctOut.Value["0"])
 	// ct1 = u
-	ctOut.Value[id] = poolQ0
+	u := ctOut.Value[id]
+	uniformSampler.ReadLvl(levelQ, u)
+	ringQ.NTTLvl(levelQ, u, u)
+	ringQ.MFormLvl(levelQ, u, u)
 
-	if ciphertextNTT {
+	// ct0 = u*sk
+	ringQ.MulCoeffsMontgomeryLvl(levelQ, u, sk.Value.Q, ctOut.Value["0"])
 
-		// ct1 = u*pk1 + e1
-		// encryptor.gaussianSampler.ReadLvl(levelQ, poolQ0)
-		// ringQ.NTTLvl(levelQ, poolQ0, poolQ0)
-		// ringQ.AddLvl(levelQ, ctOut.Value[id], poolQ0, ctOut.Value[id])
+	if ciphertextNTT {
 
 		encryptor.gaussianSampler.ReadLvl(levelQ, poolQ0)
 
@@ -161,18 +178,14 @@ func (encryptor *Encryptor) EncryptSk(plaintext *rlwe.Plaintext, sk *SecretKey,
 			// ct0 = (e - u*sk) + ptxt
 			ringQ.AddLvl(levelQ, ctOut.Value["0"], plaintext.Value, ctOut.Value["0"])
 		}
-		// ringQP.MulCoeffsMontgomeryAndSubLvl(levelQ, levelP, sk.Value, pk.Value[1], pk.Value[0])
 	} else {
 
 		ringQ.InvNTTLvl(levelQ, ctOut.Value["0"], ctOut.Value["0"])
-		ringQ.InvNTTLvl(levelQ, ctOut.Value[id], ctOut.Value[id])
+		ringQ.InvNTTLvl(levelQ, u, u)
 
 		// ct[0] = e0 + u*sk
 		encryptor.gaussianSampler.ReadAndAddLvl(ctOut.Level(), ctOut.Value["0"])
 
-		// ct[1] = pk[1]*u + e1
-		// encryptor.gaussianSampler.ReadAndAddLvl(ctOut.Level(), ctOut.Value[id])
-
 		if !plaintext.Value.IsNTT {
 			// ct0 = ptxt - (e0 + u*sk)
 			ringQ.SubLvl(levelQ, plaintext.Value, ctOut.Value["0"], ctOut.Value["0"])
@@ -188,6 +201,38 @@ func (encryptor *Encryptor) EncryptSk(plaintext *rlwe.Plaintext, sk *SecretKey,
 	ctOut.Value["0"].Coeffs = ctOut.Value["0"].Coeffs[:levelQ+1]
 	ctOut.Value[id].Coeffs = ctOut.Value[id].Coeffs[:levelQ+1]
 
+	ctOut.SetEpoch(id, sk.Epoch)
+}
+
+// CiphertextSeedSize is the size in bytes of the seed of the uniform component of a seeded secret-key encryption.
+const CiphertextSeedSize = 32
+
+// newSeededSampler returns a uniform sampler over ringQ keyed by seed.
+func newSeededSampler(ringQ *ring.Ring, seed []byte) *ring.UniformSampler {
+	prng, err := utils.NewKeyedPRNG(seed)
+	if err != nil {
+		panic(err)
+	}
+
+	return ring.NewUniformSampler(prng, ringQ)
+}
+
+// expandSeed returns the uniform component at the given level of a secret-key encryption
+// whose uniform component was expanded from seed, as computed by EncryptSkSeeded.
+func expandSeed(params Parameters, seed []byte, level int, isNTT bool) (u *ring.Poly) {
+	ringQ := params.RingQ()
+
+	u = ring.NewPoly(params.N(), level+1)
+	newSeededSampler(ringQ, seed).ReadLvl(level, u)
+	ringQ.NTTLvl(level, u, u)
+	ringQ.MFormLvl(level, u, u)
+
+	if !isNTT {
+		ringQ.InvNTTLvl(level, u, u)
+	}
+	u.IsNTT = isNTT
+
+	return u
 }
 
 // 	id := sk.ID
@@ -233,5 +278,17 @@ func (encryptor *Encryptor) EncryptSk(plaintext *rlwe.Plaintext, sk *SecretKey,
 // NewEncryptor instatiates a new generic RLWE Encryptor. The key argument can
 // be either a *rlwe.PublicKey or a *rlwe.SecretKey.
 func NewEncryptor(params Parameters) *Encryptor {
-	return &Encryptor{newEncryptorBase(params)}
+	prng, err := utils.NewPRNG()
+	if err != nil {
+		panic(err)
+	}
+
+	return NewEncryptorWithPRNG(params, prng)
+}
+
+// NewEncryptorWithPRNG instatiates a new generic RLWE Encryptor whose samplers and seeds all read from the given PRNG.
+// An Encryptor built on a utils.NewKeyedPRNG outputs the same ciphertexts from the same seed,
+// which is insecure unless the seed is kept secret.
+func NewEncryptorWithPRNG(params Parameters, prng utils.PRNG) *Encryptor {
+	return &Encryptor{newEncryptorBase(params, prng)}
 }
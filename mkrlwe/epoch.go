@@ -0,0 +1,62 @@
+package mkrlwe
+
+// GetEpoch returns the epoch of the component of the ciphertext under the given id.
+// Components without an epoch tag are at epoch 0.
+func (el *Ciphertext) GetEpoch(id string) int {
+	return el.Epoch[id]
+}
+
+// SetEpoch sets the epoch of the component of the ciphertext under the given id.
+func (el *Ciphertext) SetEpoch(id string, epoch int) {
+	if el.Epoch == nil {
+		el.Epoch = make(map[string]int)
+	}
+	el.Epoch[id] = epoch
+}
+
+// CopyEpochs replaces the epoch tags of the receiver by the ones of ct.
+func (el *Ciphertext) CopyEpochs(ct *Ciphertext) {
+	if el == ct {
+		return
+	}
+
+	el.Epoch = make(map[string]int)
+	for id, epoch := range ct.Epoch {
+		el.Epoch[id] = epoch
+	}
+}
+
+// MergeEpochs replaces the epoch tags of the receiver by the union of the ones of op0 and op1.
+// op0 and op1 are assumed to be checked with CheckEpochs.
+func (el *Ciphertext) MergeEpochs(op0, op1 *Ciphertext) {
+	epochs := make(map[string]int)
+	for id, epoch := range op0.Epoch {
+		epochs[id] = epoch
+	}
+
+	for id, epoch := range op1.Epoch {
+		epochs[id] = epoch
+	}
+
+	el.Epoch = epochs
+}
+
+// CheckEpochs returns an error of kind ErrEpochMismatch if op0 and op1 have components under the same id from different epochs,
+// i.e. encrypted under the keys of a group before and after a membership change.
+func CheckEpochs(opname string, op0, op1 *Ciphertext) error {
+	for _, id := range op0.IDSet().Intersection(op1.IDSet()).Slice() {
+		if op0.GetEpoch(id) != op1.GetEpoch(id) {
+			return NewError(ErrEpochMismatch, "Cannot %s: operands of id %s are from different epochs (%d and %d)", opname, id, op0.GetEpoch(id), op1.GetEpoch(id))
+		}
+	}
+	return nil
+}
+
+// CheckKeyEpoch returns an error of kind ErrEpochMismatch if the component of ct under the given id
+// and the key of this id are from different epochs.
+func CheckKeyEpoch(opname string, ct *Ciphertext, id string, epoch int) error {
+	if ct.GetEpoch(id) != epoch {
+		return NewError(ErrEpochMismatch, "Cannot %s: ciphertext and key of id %s are from different epochs (%d and %d)", opname, id, ct.GetEpoch(id), epoch)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package mkrlwe
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors classifying the failures of invalid requests.
+// Test them with errors.Is on the errors returned by the Check and Try functions, or panicked by the others.
+var (
+	// ErrMissingKey means that a key set has no key of the requested id (and rotation index).
+	ErrMissingKey = errors.New("missing key")
+	// ErrMissingCRS means that the parameters have no CRS of the requested index.
+	ErrMissingCRS = errors.New("missing CRS")
+	// ErrLevelMismatch means that the operands of an operation have incompatible levels.
+	ErrLevelMismatch = errors.New("level mismatch")
+	// ErrEpochMismatch means that the operands or keys of an operation are from different epochs.
+	ErrEpochMismatch = errors.New("epoch mismatch")
+	// ErrNoiseBudget means that the noise an operation adds to a ciphertext does not fit in its noise budget.
+	ErrNoiseBudget = errors.New("noise budget exceeded")
+	// ErrInvalidInput means that the input of an operation is otherwise malformed.
+	ErrInvalidInput = errors.New("invalid input")
+)
+
+// Error is the error type of this package. Its Kind is one of the sentinel errors above.
+type Error struct {
+	Kind error
+	Msg  string
+}
+
+// NewError returns an Error of the given kind with a formatted message.
+func NewError(kind error, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+// Unwrap returns the kind of e, so that errors.Is(e, e.Kind) holds.
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
@@ -0,0 +1,624 @@
+package mkrlwe
+
+// PartyShares is a type for the public key shares a single party contributes to its group.
+// Every key must carry the ID of the group.
+type PartyShares struct {
+	PublicKey          *PublicKey
+	RelinearizationKey *RelinearizationKey
+	ConjugationKey     *ConjugationKey
+	RotationKeys       map[uint]*RotationKey
+}
+
+// Group is a type for an rdMPHE group.
+// It stores the aggregated group keys together with the running sums swksum and swkheadsum
+// of the members' SWK pairs, which are required to admit new members.
+// The group secret key is never stored: it only exists as the sum of the members' secret keys.
+// Epoch counts the changes of the group secret, and every group key is tagged with it.
+type Group struct {
+	params Parameters
+	kgen   *KeyGenerator
+
+	ID    string
+	Size  int
+	Epoch int
+
+	PublicKey          *PublicKey
+	RelinearizationKey *RelinearizationKey
+	ConjugationKey     *ConjugationKey
+	RotationKeys       map[uint]*RotationKey
+
+	SWKSum     *SWK
+	SWKHeadSum *SWK
+}
+
+// NewGroup returns a new empty group with the given id.
+func NewGroup(params Parameters, id string) *Group {
+	g := new(Group)
+	g.params = params
+	g.kgen = NewKeyGenerator(params)
+	g.ID = id
+	g.RotationKeys = make(map[uint]*RotationKey)
+	return g
+}
+
+// GenPartyShares generates the public key shares of the owner of sk.
+// Rotation keys are generated for every index in rotidx.
+func (keygen *KeyGenerator) GenPartyShares(sk *SecretKey, rotidx []int) (shares *PartyShares) {
+	shares = new(PartyShares)
+	shares.PublicKey = keygen.GenPublicKey(sk)
+	shares.RelinearizationKey = keygen.GenRelinearizationKey(sk)
+	shares.ConjugationKey = keygen.GenConjugationKey(sk)
+	shares.RotationKeys = make(map[uint]*RotationKey)
+
+	for _, idx := range rotidx {
+		shares.RotationKeys[uint(idx)] = keygen.GenRotationKey(idx, sk)
+	}
+
+	return shares
+}
+
+// TryJoinKeySets is JoinKeySets returning an error instead of panicking on an invalid request,
+// e.g. if a rotation key share of the group is missing (ErrMissingKey).
+func (keygen *KeyGenerator) TryJoinKeySets(shares *PartyShares, rtkSet *RotationKeySet, cjkSet *ConjugationKeySet) error {
+	if err := keygen.CheckJoinKeySets(shares, rtkSet, cjkSet); err != nil {
+		return err
+	}
+
+	keygen.JoinKeySets(shares, rtkSet, cjkSet)
+	return nil
+}
+
+// JoinKeySets adds the rotation and conjugation key shares of a joining party
+// into the keys of its group in rtkSet and cjkSet, for key sets maintained without a Group.
+// Every rotation key of the group in rtkSet is replaced by a newly allocated key,
+// so the shares must cover all the rotation indexes the group has.
+// Nil key sets are skipped.
+func (keygen *KeyGenerator) JoinKeySets(shares *PartyShares, rtkSet *RotationKeySet, cjkSet *ConjugationKeySet) {
+	if err := keygen.CheckJoinKeySets(shares, rtkSet, cjkSet); err != nil {
+		panic(err)
+	}
+
+	id := shares.PublicKey.ID
+
+	if rtkSet != nil {
+		for idx, grtk := range rtkSet.Value[id] {
+			rtkSet.AddRotationKey(keygen.GenGroupRotKey([]*RotationKey{grtk, shares.RotationKeys[idx]}))
+		}
+	}
+
+	if cjkSet != nil {
+		if gcjk, in := cjkSet.Value[id]; in {
+			cjkSet.AddConjugationKey(keygen.GenGroupConjKey([]*ConjugationKey{gcjk, shares.ConjugationKey}))
+		}
+	}
+}
+
+// TryAddShares is AddShares returning an error instead of panicking on an invalid request.
+func (g *Group) TryAddShares(shares *PartyShares) error {
+	if err := g.CheckAddShares(shares); err != nil {
+		return err
+	}
+
+	g.AddShares(shares)
+	return nil
+}
+
+// AddShares aggregates the key shares of a founding member into the group keys.
+// The relinearization key share can be nil if the group does not use mkrlwe relinearization keys.
+// Every member must provide rotation keys for the same set of indexes.
+func (g *Group) AddShares(shares *PartyShares) {
+	if err := g.CheckAddShares(shares); err != nil {
+		panic(err)
+	}
+
+	g.aggregate(shares)
+	g.Size++
+}
+
+// TryAddSWK is AddSWK returning an error instead of panicking on an invalid request.
+func (g *Group) TryAddSWK(swk, swkhead *SWK) error {
+	if err := g.CheckAddSWK(swk, swkhead); err != nil {
+		return err
+	}
+
+	g.AddSWK(swk, swkhead)
+	return nil
+}
+
+// AddSWK aggregates the SWK pair of a member into swksum and swkheadsum.
+// The pair must be generated with GenSWK against the current group public key.
+func (g *Group) AddSWK(swk, swkhead *SWK) {
+	if err := g.CheckAddSWK(swk, swkhead); err != nil {
+		panic(err)
+	}
+
+	kgen := g.kgen
+
+	if g.SWKSum == nil {
+		g.SWKSum = kgen.GenGroupSWK([]*SWK{swk})
+		g.SWKHeadSum = kgen.GenGroupSWK([]*SWK{swkhead})
+		return
+	}
+
+	g.SWKSum = kgen.GenGroupSWK([]*SWK{g.SWKSum, swk})
+	g.SWKHeadSum = kgen.GenGroupSWK([]*SWK{g.SWKHeadSum, swkhead})
+}
+
+// TryJoin is Join returning an error instead of panicking on an invalid request,
+// e.g. if uaux is computed against a previous switching key sum (ErrEpochMismatch).
+func (g *Group) TryJoin(shares *PartyShares, uaux *SWK) (jk, jkhead *SWK, err error) {
+	return g.TryBatchJoin([]*PartyShares{shares}, []*SWK{uaux})
+}
+
+// Join admits a new member to the group.
+// uaux is the auxiliary key computed by the new member with UAuxKeyGen(g.SWKHeadSum, sk).
+// The group keys are replaced by newly allocated keys under the extended group secret,
+// and the returned pair (jk, jkhead) switches ciphertexts from the previous group secret to the new one with KS.
+// The new member must then generate its SWK pair against the new group public key and add it with AddSWK.
+func (g *Group) Join(shares *PartyShares, uaux *SWK) (jk, jkhead *SWK) {
+	return g.BatchJoin([]*PartyShares{shares}, []*SWK{uaux})
+}
+
+// TryBatchJoin is BatchJoin returning an error instead of panicking on an invalid request.
+func (g *Group) TryBatchJoin(sharesList []*PartyShares, uauxList []*SWK) (jk, jkhead *SWK, err error) {
+	if err = g.CheckBatchJoin(sharesList, uauxList); err != nil {
+		return nil, nil, err
+	}
+
+	jk, jkhead = g.BatchJoin(sharesList, uauxList)
+	return jk, jkhead, nil
+}
+
+// BatchJoin admits several new members to the group at once.
+// Every new member computes its auxiliary key with UAuxKeyGen against the same g.SWKHeadSum,
+// and the auxiliary keys are combined into a single pair (jk, jkhead),
+// so that ciphertexts are switched to the extended group secret with one KS.
+// The new members must then generate their SWK pairs against the new group public key and add them with AddSWK.
+func (g *Group) BatchJoin(sharesList []*PartyShares, uauxList []*SWK) (jk, jkhead *SWK) {
+	if err := g.CheckBatchJoin(sharesList, uauxList); err != nil {
+		panic(err)
+	}
+
+	kgen := g.kgen
+
+	// jk <- swksum + sum(uaux)
+	jk = kgen.GenGroupSWK(append([]*SWK{g.SWKSum}, uauxList...))
+	jkhead = kgen.GenGroupSWK([]*SWK{g.SWKHeadSum})
+
+	for _, shares := range sharesList {
+		g.aggregate(shares)
+		g.Size++
+	}
+
+	// (jk, jkhead) is an encryption of the previous group secret under the new one,
+	// so it is the starting point of the new sums.
+	jk.From, jkhead.From = g.Epoch, g.Epoch
+	g.SWKSum = kgen.GenGroupSWK([]*SWK{jk})
+	g.SWKHeadSum = kgen.GenGroupSWK([]*SWK{jkhead})
+
+	g.nextEpoch(jk, jkhead)
+
+	return jk, jkhead
+}
+
+// TryLeave is Leave returning an error instead of panicking on an invalid request.
+func (g *Group) TryLeave(shares *PartyShares, laux, swk, swkhead *SWK) (jk, jkhead *SWK, err error) {
+	if err = g.CheckLeave(shares, laux, swk, swkhead); err != nil {
+		return nil, nil, err
+	}
+
+	jk, jkhead = g.Leave(shares, laux, swk, swkhead)
+	return jk, jkhead, nil
+}
+
+// Leave removes a member from the group.
+// laux is the auxiliary key computed by the leaving member with LAuxKeyGen(g.SWKHeadSum, sk),
+// and (swk, swkhead) is its SWK pair generated with GenSWK against g.PublicKeyWithout(shares.PublicKey).
+// The group keys are replaced by newly allocated keys under the secret of the remaining members,
+// and the returned pair (jk, jkhead) switches ciphertexts from the previous group secret to the new one with KS.
+// Since laux and (swk, swkhead) depend on its secret key, a member is removed without its cooperation with Revoke instead.
+func (g *Group) Leave(shares *PartyShares, laux, swk, swkhead *SWK) (jk, jkhead *SWK) {
+	if err := g.CheckLeave(shares, laux, swk, swkhead); err != nil {
+		panic(err)
+	}
+
+	kgen := g.kgen
+
+	g.remove(shares)
+	g.Size--
+
+	// swksum <- swksum + laux
+	g.SWKSum = kgen.GenGroupSWK([]*SWK{g.SWKSum, laux})
+
+	// (swk, swkhead) encrypts the share of the leaving member under the new group secret
+	jk = kgen.GenGroupSWK([]*SWK{g.SWKSum, swk})
+	jkhead = kgen.GenGroupSWK([]*SWK{g.SWKHeadSum, swkhead})
+	jk.From, jkhead.From = g.Epoch, g.Epoch
+
+	g.nextEpoch(jk, jkhead)
+
+	return jk, jkhead
+}
+
+// TryRefresh is Refresh returning an error instead of panicking on an invalid request.
+func (g *Group) TryRefresh(sharesList []*PartyShares, rauxList []*SWK) (jk, jkhead *SWK, err error) {
+	if err = g.CheckRefresh(sharesList, rauxList); err != nil {
+		return nil, nil, err
+	}
+
+	jk, jkhead = g.Refresh(sharesList, rauxList)
+	return jk, jkhead, nil
+}
+
+// TryRevoke is Revoke returning an error instead of panicking on an invalid request.
+func (g *Group) TryRevoke(shares *PartyShares) error {
+	if err := g.CheckRevoke(shares); err != nil {
+		return err
+	}
+
+	g.Revoke(shares)
+	return nil
+}
+
+// Revoke removes a member from the group without its cooperation.
+// shares are the public key shares the revoked member contributed, and no key of the revoked member is needed,
+// so that the remaining members drive the revocation.
+// The group keys are replaced by newly allocated keys under the secret of the remaining members, which the revoked member does not know.
+// The switching key sums are dropped, and every remaining member must generate its SWK pair against the new group public key and add it with AddSWK,
+// after which the remaining members can also re-randomize their secret keys with Refresh.
+// No pair is returned: a ciphertext of a previous epoch has a share of the revoked member in its group secret,
+// which cannot be switched away without its key, so it must be decrypted with its partial decryption or discarded.
+func (g *Group) Revoke(shares *PartyShares) {
+	if err := g.CheckRevoke(shares); err != nil {
+		panic(err)
+	}
+
+	g.remove(shares)
+	g.Size--
+
+	g.SWKSum = nil
+	g.SWKHeadSum = nil
+
+	g.nextEpoch()
+}
+
+// Refresh re-randomizes the group secret without changing the membership.
+// sharesList are the key shares of every member generated with a fresh secret key under the group ID,
+// and rauxList the auxiliary keys computed with RAuxKeyGen(g.SWKHeadSum, skOld, skNew).
+// The group keys are replaced by newly allocated keys under the refreshed group secret,
+// and the returned pair (jk, jkhead) switches ciphertexts from the previous group secret to the new one with KS.
+// Every member must then generate its SWK pair with its fresh secret key against the new group public key and add it with AddSWK.
+func (g *Group) Refresh(sharesList []*PartyShares, rauxList []*SWK) (jk, jkhead *SWK) {
+	if err := g.CheckRefresh(sharesList, rauxList); err != nil {
+		panic(err)
+	}
+
+	kgen := g.kgen
+
+	// jk <- swksum + sum(raux)
+	jk = kgen.GenGroupSWK(append([]*SWK{g.SWKSum}, rauxList...))
+	jkhead = kgen.GenGroupSWK([]*SWK{g.SWKHeadSum})
+	jk.From, jkhead.From = g.Epoch, g.Epoch
+
+	g.PublicKey = nil
+	g.RelinearizationKey = nil
+	g.ConjugationKey = nil
+	g.RotationKeys = make(map[uint]*RotationKey)
+	g.SWKSum = nil
+	g.SWKHeadSum = nil
+	g.Size = 0
+
+	for _, shares := range sharesList {
+		g.AddShares(shares)
+	}
+
+	g.nextEpoch(jk, jkhead)
+
+	return jk, jkhead
+}
+
+// TryMerge is Merge returning an error instead of panicking on an invalid request.
+func (g *Group) TryMerge(other *Group, uaux, uauxOther []*SWK) (jk, jkhead, jkOther, jkheadOther *SWK, err error) {
+	if err = g.CheckMerge(other, uaux, uauxOther); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	jk, jkhead, jkOther, jkheadOther = g.Merge(other, uaux, uauxOther)
+	return jk, jkhead, jkOther, jkheadOther, nil
+}
+
+// Merge merges the group other into the receiver, which keeps its ID.
+// uaux are the auxiliary keys computed by every member of other with UAuxKeyGen(g.SWKHeadSum, sk),
+// and uauxOther the ones computed by every member of the receiver with UAuxKeyGen(other.SWKHeadSum, sk).
+// The group keys are replaced by newly allocated keys under the sum of both group secrets.
+// The returned pairs switch the components of the receiver and of other to the merged group with MergeKS.
+// Rotation keys are only kept for the indexes both groups have.
+func (g *Group) Merge(other *Group, uaux, uauxOther []*SWK) (jk, jkhead, jkOther, jkheadOther *SWK) {
+	if err := g.CheckMerge(other, uaux, uauxOther); err != nil {
+		panic(err)
+	}
+
+	params := g.params
+	id := g.ID
+
+	// jk <- swksum + sum(uaux), where uaux remove the other group secret from the head
+	jk = sumSWK(params, id, append([]*SWK{g.SWKSum}, uaux...))
+	jkhead = sumSWK(params, id, []*SWK{g.SWKHeadSum})
+	jkOther = sumSWK(params, id, append([]*SWK{other.SWKSum}, uauxOther...))
+	jkheadOther = sumSWK(params, id, []*SWK{other.SWKHeadSum})
+	jk.From, jkhead.From = g.Epoch, g.Epoch
+	jkOther.From, jkheadOther.From = other.Epoch, other.Epoch
+
+	pk := g.PublicKey.CopyNew()
+	params.RingQP().AddLvl(params.QCount()-1, params.PCount()-1, pk.Value[0], other.PublicKey.Value[0], pk.Value[0])
+	g.PublicKey = pk
+
+	if g.RelinearizationKey != nil && other.RelinearizationKey != nil {
+		rlk := NewRelinearizationKey(params, id)
+		for j := 0; j < 3; j++ {
+			addSwitchingKey(params, g.RelinearizationKey.Value[j], other.RelinearizationKey.Value[j], rlk.Value[j])
+		}
+		g.RelinearizationKey = rlk
+	} else {
+		g.RelinearizationKey = nil
+	}
+
+	if g.ConjugationKey != nil && other.ConjugationKey != nil {
+		cjk := NewConjugationKey(params, id)
+		addSwitchingKey(params, g.ConjugationKey.Value, other.ConjugationKey.Value, cjk.Value)
+		g.ConjugationKey = cjk
+	} else {
+		g.ConjugationKey = nil
+	}
+
+	rtks := make(map[uint]*RotationKey)
+	for idx, grtk := range g.RotationKeys {
+		if ortk, in := other.RotationKeys[idx]; in {
+			rtks[idx] = NewRotationKey(params, idx, id)
+			addSwitchingKey(params, grtk.Value, ortk.Value, rtks[idx].Value)
+		}
+	}
+	g.RotationKeys = rtks
+
+	// the merged sums encrypt the sum of both group secrets
+	g.SWKSum = sumSWK(params, id, []*SWK{jk, jkOther})
+	g.SWKHeadSum = sumSWK(params, id, []*SWK{jkhead, jkheadOther})
+	g.Size += other.Size
+
+	if other.Epoch > g.Epoch {
+		g.Epoch = other.Epoch
+	}
+	g.nextEpoch(jk, jkhead, jkOther, jkheadOther)
+
+	return jk, jkhead, jkOther, jkheadOther
+}
+
+// TrySplit is Split returning an error instead of panicking on an invalid request.
+func (g *Group) TrySplit(id0, id1 string, shares0, shares1 []*PartyShares) (g0, g1 *Group, err error) {
+	if err = g.CheckSplit(id0, id1, shares0, shares1); err != nil {
+		return nil, nil, err
+	}
+
+	g0, g1 = g.Split(id0, id1, shares0, shares1)
+	return g0, g1, nil
+}
+
+// Split partitions the group into two child groups with IDs id0 and id1.
+// shares0 and shares1 are the key shares of the members of each child group, generated with their secret keys under the child IDs.
+// Every member must then generate its SWK pair against the public key of its child group and add it with AddSWK.
+// The ciphertexts of the group are carried over to the child groups with KeySwitcher.Split,
+// or to one of them with KeySwitcher.SplitKS and the pair returned by SplitSWK.
+func (g *Group) Split(id0, id1 string, shares0, shares1 []*PartyShares) (g0, g1 *Group) {
+	if err := g.CheckSplit(id0, id1, shares0, shares1); err != nil {
+		panic(err)
+	}
+
+	g0 = NewGroup(g.params, id0)
+	for _, shares := range shares0 {
+		g0.AddShares(shares)
+	}
+
+	g1 = NewGroup(g.params, id1)
+	for _, shares := range shares1 {
+		g1.AddShares(shares)
+	}
+
+	return g0, g1
+}
+
+// TrySplitSWK is SplitSWK returning an error instead of panicking on an invalid request.
+func (g *Group) TrySplitSWK(swkList, swkheadList []*SWK) (swk, swkhead *SWK, err error) {
+	if err = g.CheckSplitSWK(swkList, swkheadList); err != nil {
+		return nil, nil, err
+	}
+
+	swk, swkhead = g.SplitSWK(swkList, swkheadList)
+	return swk, swkhead, nil
+}
+
+// SplitSWK sums the SWK pairs generated with GenSWK by the members of the child group id1 against the public key of the child group id0,
+// into the pair carrying the ciphertexts of the group over to id0 alone with KeySwitcher.SplitKS.
+func (g *Group) SplitSWK(swkList, swkheadList []*SWK) (swk, swkhead *SWK) {
+	if err := g.CheckSplitSWK(swkList, swkheadList); err != nil {
+		panic(err)
+	}
+
+	swk = g.kgen.GenGroupSWK(swkList)
+	swkhead = g.kgen.GenGroupSWK(swkheadList)
+	swk.From, swkhead.From = g.Epoch, g.Epoch
+
+	return swk, swkhead
+}
+
+// PublicKeyWithout returns the group public key after the owner of pk leaves the group.
+func (g *Group) PublicKeyWithout(pk *PublicKey) (pkOut *PublicKey) {
+	params := g.params
+	levelQ := params.QCount() - 1
+	levelP := params.PCount() - 1
+
+	if pk.ID != g.ID {
+		panic(NewError(ErrInvalidInput, "invalid input: IDs are not same"))
+	}
+
+	pkOut = g.PublicKey.CopyNew()
+	params.RingQP().SubLvl(levelQ, levelP, pkOut.Value[0], pk.Value[0], pkOut.Value[0])
+	pkOut.Epoch = g.Epoch + 1
+
+	return pkOut
+}
+
+// UpdateKeySets inserts the current group keys into the given key sets.
+// Nil key sets are skipped.
+func (g *Group) UpdateKeySets(pkSet *PublicKeySet, rlkSet *RelinearizationKeySet, rtkSet *RotationKeySet, cjkSet *ConjugationKeySet) {
+	if pkSet != nil && g.PublicKey != nil {
+		pkSet.AddPublicKey(g.PublicKey)
+	}
+
+	if rlkSet != nil && g.RelinearizationKey != nil {
+		rlkSet.AddRelinearizationKey(g.RelinearizationKey)
+	}
+
+	if rtkSet != nil {
+		// rotation keys the group no longer has are removed, so that no stale key is left in the set
+		for idx := range rtkSet.Value[g.ID] {
+			if _, in := g.RotationKeys[idx]; !in {
+				rtkSet.DelRotationKey(g.ID, idx)
+			}
+		}
+
+		for _, rtk := range g.RotationKeys {
+			rtkSet.AddRotationKey(rtk)
+		}
+	}
+
+	if cjkSet != nil && g.ConjugationKey != nil {
+		cjkSet.AddConjugationKey(g.ConjugationKey)
+	}
+}
+
+// nextEpoch moves the group to the next epoch, and tags the group keys and the given switching keys with it
+func (g *Group) nextEpoch(swkList ...*SWK) {
+	g.Epoch++
+
+	if g.PublicKey != nil {
+		g.PublicKey.Epoch = g.Epoch
+	}
+
+	if g.RelinearizationKey != nil {
+		g.RelinearizationKey.Epoch = g.Epoch
+	}
+
+	if g.ConjugationKey != nil {
+		g.ConjugationKey.Epoch = g.Epoch
+	}
+
+	for _, rtk := range g.RotationKeys {
+		rtk.Epoch = g.Epoch
+	}
+
+	for _, swk := range append([]*SWK{g.SWKSum, g.SWKHeadSum}, swkList...) {
+		if swk != nil {
+			swk.Epoch = g.Epoch
+		}
+	}
+}
+
+// aggregate adds the key shares into newly allocated group keys
+func (g *Group) aggregate(shares *PartyShares) {
+	kgen := g.kgen
+
+	if g.PublicKey == nil {
+		g.PublicKey = kgen.GenGroupPublicKey([]*PublicKey{shares.PublicKey})
+	} else {
+		g.PublicKey = kgen.GenGroupPublicKey([]*PublicKey{g.PublicKey, shares.PublicKey})
+	}
+
+	if shares.RelinearizationKey != nil {
+		if g.RelinearizationKey == nil {
+			g.RelinearizationKey = kgen.GenGroupRelinKey([]*RelinearizationKey{shares.RelinearizationKey})
+		} else {
+			g.RelinearizationKey = kgen.GenGroupRelinKey([]*RelinearizationKey{g.RelinearizationKey, shares.RelinearizationKey})
+		}
+	}
+
+	if shares.ConjugationKey != nil {
+		if g.ConjugationKey == nil {
+			g.ConjugationKey = kgen.GenGroupConjKey([]*ConjugationKey{shares.ConjugationKey})
+		} else {
+			g.ConjugationKey = kgen.GenGroupConjKey([]*ConjugationKey{g.ConjugationKey, shares.ConjugationKey})
+		}
+	}
+
+	if g.Size == 0 {
+		for idx, rtk := range shares.RotationKeys {
+			g.RotationKeys[idx] = kgen.GenGroupRotKey([]*RotationKey{rtk})
+		}
+		return
+	}
+
+	for idx, grtk := range g.RotationKeys {
+		rtk, in := shares.RotationKeys[idx]
+		if !in {
+			panic(NewError(ErrInvalidInput, "invalid input: rotation indexes are not same"))
+		}
+		g.RotationKeys[idx] = kgen.GenGroupRotKey([]*RotationKey{grtk, rtk})
+	}
+}
+
+// remove subtracts the key shares from newly allocated group keys
+func (g *Group) remove(shares *PartyShares) {
+	params := g.params
+
+	g.PublicKey = g.PublicKeyWithout(shares.PublicKey)
+
+	if shares.RelinearizationKey != nil {
+		rlk := NewRelinearizationKey(params, g.ID)
+		for j := 0; j < 3; j++ {
+			subSwitchingKey(params, g.RelinearizationKey.Value[j], shares.RelinearizationKey.Value[j], rlk.Value[j])
+		}
+		g.RelinearizationKey = rlk
+	}
+
+	if shares.ConjugationKey != nil {
+		cjk := NewConjugationKey(params, g.ID)
+		subSwitchingKey(params, g.ConjugationKey.Value, shares.ConjugationKey.Value, cjk.Value)
+		g.ConjugationKey = cjk
+	}
+
+	for idx, grtk := range g.RotationKeys {
+		rtk := NewRotationKey(params, idx, g.ID)
+		subSwitchingKey(params, grtk.Value, shares.RotationKeys[idx].Value, rtk.Value)
+		g.RotationKeys[idx] = rtk
+	}
+}
+
+// sumSWK returns the sum of the SWKs in swkList under the given id, regardless of their IDs
+func sumSWK(params Parameters, id string, swkList []*SWK) (swkOut *SWK) {
+	swkOut = NewSWK(params, id)
+	for _, swk := range swkList {
+		addSwitchingKey(params, swkOut.Value, swk.Value, swkOut.Value)
+	}
+	return swkOut
+}
+
+// addSwitchingKey computes swkOut = swk0 + swk1
+func addSwitchingKey(params Parameters, swk0, swk1, swkOut *SwitchingKey) {
+	levelQ := params.QCount() - 1
+	levelP := params.PCount() - 1
+	beta := params.Beta(levelQ)
+
+	for i := 0; i < beta; i++ {
+		params.RingQP().AddLvl(levelQ, levelP, swk0.Value[i], swk1.Value[i], swkOut.Value[i])
+	}
+}
+
+// subSwitchingKey computes swkOut = swk0 - swk1
+func subSwitchingKey(params Parameters, swk0, swk1, swkOut *SwitchingKey) {
+	levelQ := params.QCount() - 1
+	levelP := params.PCount() - 1
+	beta := params.Beta(levelQ)
+
+	for i := 0; i < beta; i++ {
+		params.RingQP().SubLvl(levelQ, levelP, swk0.Value[i], swk1.Value[i], swkOut.Value[i])
+	}
+}
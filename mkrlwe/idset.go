@@ -1,5 +1,10 @@
 package mkrlwe
 
+import (
+	"sort"
+	"strings"
+)
+
 type IDSet struct {
 	Value map[string]struct{} //empty structs occupy 0 memory
 }
@@ -11,7 +16,7 @@ func (s *IDSet) Has(v string) bool {
 
 func (s *IDSet) Add(v string) {
 	if v == "0" {
-		panic("Cannot IDSet Add : 0 cannot be used")
+		panic(NewError(ErrInvalidInput, "Cannot IDSet Add : 0 cannot be used"))
 	}
 	s.Value[v] = struct{}{}
 }
@@ -60,3 +65,44 @@ func (s *IDSet) Union(s2 *IDSet) *IDSet {
 	}
 	return res
 }
+
+// Difference returns the set of ids of s which are not in s2
+func (s *IDSet) Difference(s2 *IDSet) *IDSet {
+	res := NewIDSet()
+	for v := range s.Value {
+		if !s2.Has(v) {
+			res.Add(v)
+		}
+	}
+	return res
+}
+
+// Equal returns true if s and s2 have the same ids
+func (s *IDSet) Equal(s2 *IDSet) bool {
+	if s.Size() != s2.Size() {
+		return false
+	}
+
+	for v := range s.Value {
+		if !s2.Has(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Slice returns the ids of s in sorted order.
+// Loops whose result or randomness depends on the order of the ids should range over Slice instead of Value.
+func (s *IDSet) Slice() []string {
+	res := make([]string, 0, len(s.Value))
+	for v := range s.Value {
+		res = append(res, v)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// String returns the sorted ids of s, e.g. {user0, user1}
+func (s *IDSet) String() string {
+	return "{" + strings.Join(s.Slice(), ", ") + "}"
+}
@@ -22,6 +22,9 @@ type KeyGenerator struct {
 	uniformSamplerQ    *ring.UniformSampler
 	uniformSamplerP    *ring.UniformSampler
 	ternarySampler     *ring.TernarySampler
+	prng               utils.PRNG
+
+	insecureErrorFreeSwitchingKeys bool
 
 	ringQ *ring.Ring
 }
@@ -29,16 +32,24 @@ type KeyGenerator struct {
 // NewKeyGenerator creates a new KeyGenerator, from which the secret and public keys, as well as the evaluation,
 // rotation and switching keys can be generated.
 func NewKeyGenerator(params Parameters) *KeyGenerator {
-
-	ringQ := params.RingQ()
-
 	prng, err := utils.NewPRNG()
 	if err != nil {
 		panic(err)
 	}
 
+	return NewKeyGeneratorWithPRNG(params, prng)
+}
+
+// NewKeyGeneratorWithPRNG creates a new KeyGenerator whose samplers all read from the given PRNG.
+// A KeyGenerator built on a utils.NewKeyedPRNG generates the same keys from the same seed,
+// which is insecure unless the seed is kept secret.
+func NewKeyGeneratorWithPRNG(params Parameters, prng utils.PRNG) *KeyGenerator {
+
+	ringQ := params.RingQ()
+
 	keygen := new(KeyGenerator)
 	keygen.params = params
+	keygen.prng = prng
 	keygen.poolQ = params.RingQ().NewPoly()
 	keygen.poolQP = params.RingQP().NewPoly()
 	keygen.gaussianSamplerQ = ring.NewGaussianSampler(prng, params.RingQ(), params.Sigma(), uint64(6*params.Sigma()))
@@ -79,21 +90,13 @@ func (keygen *KeyGenerator) GenSecretKeyGaussian(id string) (sk *SecretKey) {
 
 // GenSecretKeyWithDistrib generates a new SecretKey with the distribution [(p-1)/2, p, (p-1)/2].
 func (keygen *KeyGenerator) GenSecretKeyWithDistrib(p float64, id string) (sk *SecretKey) {
-	prng, err := utils.NewPRNG()
-	if err != nil {
-		panic(err)
-	}
-	ternarySamplerMontgomery := ring.NewTernarySampler(prng, keygen.params.RingQ(), p, false)
+	ternarySamplerMontgomery := ring.NewTernarySampler(keygen.prng, keygen.params.RingQ(), p, false)
 	return keygen.genSecretKeyFromSampler(ternarySamplerMontgomery, id)
 }
 
 // GenSecretKeySparse generates a new SecretKey with exactly hw non-zero coefficients.
 func (keygen *KeyGenerator) GenSecretKeySparse(hw int, id string) (sk *SecretKey) {
-	prng, err := utils.NewPRNG()
-	if err != nil {
-		panic(err)
-	}
-	ternarySamplerMontgomery := ring.NewTernarySamplerSparse(prng, keygen.params.RingQ(), hw, false)
+	ternarySamplerMontgomery := ring.NewTernarySamplerSparse(keygen.prng, keygen.params.RingQ(), hw, false)
 	return keygen.genSecretKeyFromSampler(ternarySamplerMontgomery, id)
 }
 
@@ -128,6 +131,7 @@ func (keygen *KeyGenerator) GenPublicKey(sk *SecretKey) (pk *PublicKey) {
 	// pk.Value[1].P.Copy(keygen.params.CRS[0].Value[0].P)
 
 	ringQP.MulCoeffsMontgomeryAndSubLvl(levelQ, levelP, sk.Value, pk.Value[1], pk.Value[0])
+	pk.Epoch = sk.Epoch
 	return pk
 }
 
@@ -160,7 +164,7 @@ func (keygen *KeyGenerator) GenGaussianError(e rlwe.PolyQP) {
 func (keygen *KeyGenerator) GenRelinearizationKey(sk *SecretKey) (rlk *RelinearizationKey) {
 
 	if keygen.params.PCount() == 0 {
-		panic("modulus P is empty")
+		panic(NewError(ErrInvalidInput, "modulus P is empty"))
 	}
 	params := keygen.params
 	levelQ := params.QCount() - 1
@@ -173,6 +177,7 @@ func (keygen *KeyGenerator) GenRelinearizationKey(sk *SecretKey) (rlk *Relineari
 
 	//rlk = (b, d, v)
 	rlk = NewRelinearizationKey(keygen.params, id)
+	rlk.Epoch = sk.Epoch
 	beta := params.Beta(levelQ)
 
 	//set CRS
@@ -210,6 +215,20 @@ func (keygen *KeyGenerator) GenRelinearizationKey(sk *SecretKey) (rlk *Relineari
 	return
 }
 
+// TryGenRotationKey is GenRotationKey returning an error instead of panicking on an invalid request,
+// e.g. if the CRS for rotidx is not generated (ErrMissingCRS).
+func (keygen *KeyGenerator) TryGenRotationKey(rotidx int, sk *SecretKey) (rk *RotationKey, err error) {
+	if err = checkCRS(keygen.params, "GenRotationKey", rotidx); err != nil {
+		return nil, err
+	}
+
+	if err = CheckSecretKey(keygen.params, sk); err != nil || !checkPoly(keygen.params, sk.Value.P, keygen.params.PCount()) {
+		return nil, NewError(ErrInvalidInput, "Cannot GenRotationKey: secret key does not match the parameters")
+	}
+
+	return keygen.GenRotationKey(rotidx, sk), nil
+}
+
 // GenRotationKeys generates a RotationKeySet from a list of galois element corresponding to the desired rotations
 func (keygen *KeyGenerator) GenRotationKey(rotidx int, sk *SecretKey) (rk *RotationKey) {
 	skIn := sk
@@ -225,7 +244,7 @@ func (keygen *KeyGenerator) GenRotationKey(rotidx int, sk *SecretKey) (rk *Rotat
 	// check CRS for given rot idx exists
 	_, in := params.CRS[rotidx]
 	if !in {
-		panic("Cannot GenRotationKey: CRS for given rot idx is not generated")
+		panic(NewError(ErrMissingCRS, "Cannot GenRotationKey: CRS for rot idx %d is not generated", rotidx))
 	}
 
 	// adjust rotidx
@@ -241,6 +260,7 @@ func (keygen *KeyGenerator) GenRotationKey(rotidx int, sk *SecretKey) (rk *Rotat
 
 	// rk  = Ps + e
 	rk = NewRotationKey(params, uint(rotidx), id)
+	rk.Epoch = sk.Epoch
 	keygen.GenSwitchingKey(skIn, rk.Value)
 	a := params.CRS[rotidx]
 
@@ -283,6 +303,7 @@ func (keygen *KeyGenerator) GenConjugationKey(sk *SecretKey) (cjk *ConjugationKe
 
 	// rk  = Ps' + e
 	cjk = NewConjugationKey(params, id)
+	cjk.Epoch = sk.Epoch
 	keygen.GenSwitchingKey(skOut, cjk.Value)
 	a := params.CRS[-2]
 
@@ -293,6 +314,36 @@ func (keygen *KeyGenerator) GenConjugationKey(sk *SecretKey) (cjk *ConjugationKe
 	return cjk
 }
 
+// invMFormSecretKey returns a copy of sk taken out of Montgomery form, leaving sk untouched.
+func (keygen *KeyGenerator) invMFormSecretKey(sk *SecretKey) (skOut *SecretKey) {
+	levelQ, levelP := keygen.params.QCount()-1, keygen.params.PCount()-1
+
+	skOut = NewSecretKey(keygen.params, sk.ID)
+	skOut.Epoch = sk.Epoch
+	keygen.params.RingQP().InvMFormLvl(levelQ, levelP, sk.Value, skOut.Value)
+
+	return skOut
+}
+
+// genSwitchingKeyInvMForm is GenSwitchingKey returning gs + e out of MForm,
+// for the keys which are combined with other terms before being put in MForm.
+func (keygen *KeyGenerator) genSwitchingKeyInvMForm(skIn *SecretKey, swk *SwitchingKey) {
+	levelQ, levelP := keygen.params.QCount()-1, keygen.params.PCount()-1
+	ringQP := keygen.params.RingQP()
+
+	keygen.GenSwitchingKey(skIn, swk)
+	for i := range swk.Value {
+		ringQP.InvMFormLvl(levelQ, levelP, swk.Value[i], swk.Value[i])
+	}
+}
+
+// SetInsecureErrorFreeSwitchingKeys makes GenSwitchingKey, and therefore the relinearization, rotation, conjugation
+// and SWK keys built on it, omit their RLWE error if errorFree is set. Such keys are insecure:
+// this is only meant to isolate the other noise sources when debugging the precision of the evaluation.
+func (keygen *KeyGenerator) SetInsecureErrorFreeSwitchingKeys(errorFree bool) {
+	keygen.insecureErrorFreeSwitchingKeys = errorFree
+}
+
 // For an input secretkey s, gen gs + e in MForm
 func (keygen *KeyGenerator) GenSwitchingKey(skIn *SecretKey, swk *SwitchingKey) {
 	params := keygen.params
@@ -324,7 +375,9 @@ func (keygen *KeyGenerator) GenSwitchingKey(skIn *SecretKey, swk *SwitchingKey)
 		ringQP := params.RingQP()
 
 		keygen.gaussianSamplerQ.ReadLvl(levelQ, swk.Value[i].Q)
-		swk.Value[i].Q.Zero() // 에러 없애기
+		if keygen.insecureErrorFreeSwitchingKeys {
+			swk.Value[i].Q.Zero()
+		}
 		ringQP.ExtendBasisSmallNormAndCenter(swk.Value[i].Q, levelP, nil, swk.Value[i].P)
 
 		ringQP.NTTLvl(levelQ, levelP, swk.Value[i], swk.Value[i])
@@ -356,75 +409,92 @@ func (keygen *KeyGenerator) GenSwitchingKey(skIn *SecretKey, swk *SwitchingKey)
 	}
 }
 
-// GenConjugationKeys generates a ConjugationKeySet from a list of galois element corresponding to the desired conjugation
+// GenSWKTest generates a SWK switching a component from sk to sk2 of the same id, knowing both secrets:
+// swk = g*sk - a*sk2 + e with a uniform head a, so that swk + swkhead*sk2 = g*sk + e.
 func (keygen *KeyGenerator) GenSWKTest(sk *SecretKey, sk2 *SecretKey) (swk *SWK, swkhead *SWK) {
-	// skIn := sk
-	id := sk.ID
-	// skOut := NewSecretKey(keygen.params, id)
+	swk, seeded := keygen.GenSWKTestSeeded(sk, sk2)
+
+	swkhead, err := seeded.Expand(keygen.params)
+	if err != nil {
+		panic(err)
+	}
+
+	return swk, swkhead
+}
 
+// GenSWKTestSeeded is GenSWKTest returning the head as the seed it is expanded from.
+func (keygen *KeyGenerator) GenSWKTestSeeded(sk *SecretKey, sk2 *SecretKey) (swk *SWK, swkhead *SeededSWK) {
 	params := keygen.params
-	levelQ := params.QCount() - 1
-	levelP := params.PCount() - 1
-	beta := params.Beta(levelQ)
+	levelQ, levelP := params.QCount()-1, params.PCount()-1
 	ringQP := params.RingQP()
 
-	// Reset sk to zero
-	// sk = NewSecretKey(params, "group0")
-	ringQP.InvMFormLvl(levelQ, levelP, sk.Value, sk.Value)
+	swkhead = &SeededSWK{ID: sk.ID, Epoch: sk2.Epoch, From: sk.Epoch, Seed: make([]byte, SWKSeedSize)}
+	keygen.prng.Clock(swkhead.Seed)
+	head := expandSWKHead(params, swkhead.Seed)
 
-	// rk  = Ps' + e
-	swk = NewSWK(params, id)
-	swkhead = NewSWK(params, id)
+	// swk = g*sk + e - a*sk2
+	swk = NewSWK(params, sk.ID)
+	swk.Epoch, swk.From = sk2.Epoch, sk.Epoch
 	keygen.GenSwitchingKey(sk, swk.Value)
 
-	for i := 0; i < beta; i++ {
-		r0 := ringQP.NewPoly()
-		keygen.gaussianSamplerQ.ReadLvl(levelQ, r0.Q)
-		ringQP.ExtendBasisSmallNormAndCenter(r0.Q, levelP, r0.Q, r0.P)
-		ringQP.NTTLvl(levelQ, levelP, r0, r0)
+	tmp := ringQP.NewPoly()
+	for i := range swk.Value.Value {
+		ringQP.MulCoeffsMontgomeryLvl(levelQ, levelP, head.Value[i], sk2.Value, tmp)
+		ringQP.SubLvl(levelQ, levelP, swk.Value.Value[i], tmp, swk.Value.Value[i])
+	}
 
-		r1 := ringQP.NewPoly()
-		keygen.gaussianSamplerQ.ReadLvl(levelQ, r1.Q)
-		ringQP.ExtendBasisSmallNormAndCenter(r1.Q, levelP, r1.Q, r1.P)
-		ringQP.NTTLvl(levelQ, levelP, r1, r1)
+	return swk, swkhead
+}
 
-		r2 := ringQP.NewPoly()
-		keygen.ternarySampler.ReadLvl(levelQ, r2.Q)
-		ringQP.ExtendBasisSmallNormAndCenter(r2.Q, levelP, r2.Q, r2.P)
-		ringQP.NTTLvl(levelQ, levelP, r2, r2)
+// SWKSeedSize is the size in bytes of the seed of the head of a SWK generated by GenSWKTestSeeded.
+const SWKSeedSize = 32
 
-		a := ringQP.NewPoly()
-		keygen.uniformSamplerQ.ReadLvl(levelQ, a.Q)
-		keygen.uniformSamplerP.ReadLvl(levelP, a.P)
-		ringQP.NTTLvl(levelQ, levelP, a, a)
+// Expand returns the head represented by el.
+// It returns an error of kind ErrInvalidInput if its seed or id is invalid.
+func (el *SeededSWK) Expand(params Parameters) (*SWK, error) {
+	if len(el.Seed) != SWKSeedSize || el.ID == "" || el.ID == "0" {
+		return nil, NewError(ErrInvalidInput, "Cannot Expand: invalid seed or id")
+	}
 
-		e1 := ringQP.NewPoly()
-		keygen.gaussianSamplerQ.ReadLvl(levelQ, e1.Q)
-		ringQP.ExtendBasisSmallNormAndCenter(e1.Q, levelP, e1.Q, e1.P) // 필요!
-		ringQP.NTTLvl(levelQ, levelP, e1, e1)                          // 필요!
+	return &SWK{Value: expandSWKHead(params, el.Seed), ID: el.ID, Epoch: el.Epoch, From: el.From}, nil
+}
 
-		b := ringQP.NewPoly()
-		ringQP.MulCoeffsMontgomeryLvl(levelQ, levelP, a, sk2.Value, b)
-		ringQP.SubLvl(levelQ, levelP, e1, b, b)
+// expandSWKHead returns the head expanded from seed: uniform values in the NTT domain, in MForm, as the CRS.
+func expandSWKHead(params Parameters, seed []byte) *SwitchingKey {
+	prng, err := utils.NewKeyedPRNG(seed)
+	if err != nil {
+		panic(err)
+	}
+	uniformSamplerQ := ring.NewUniformSampler(prng, params.RingQ())
+	uniformSamplerP := ring.NewUniformSampler(prng, params.RingP())
 
-		ringQP.MFormLvl(levelQ, levelP, a, swkhead.Value.Value[i])
-		ringQP.MulCoeffsMontgomeryLvl(levelQ, levelP, swkhead.Value.Value[i], r2, swkhead.Value.Value[i])
-		ringQP.AddLvl(levelQ, levelP, swkhead.Value.Value[i], r1, swkhead.Value.Value[i])
+	ringQP := params.RingQP()
+	levelQ, levelP := params.QCount()-1, params.PCount()-1
 
-		ringQP.MFormLvl(levelQ, levelP, b, b)
-		ringQP.MulCoeffsMontgomeryLvl(levelQ, levelP, b, r2, b)
-		ringQP.AddLvl(levelQ, levelP, swk.Value.Value[i], b, swk.Value.Value[i])
-		ringQP.AddLvl(levelQ, levelP, swk.Value.Value[i], r0, swk.Value.Value[i])
+	head := NewSwitchingKey(params)
+	for i := range head.Value {
+		uniformSamplerQ.Read(head.Value[i].Q)
+		uniformSamplerP.Read(head.Value[i].P)
+		ringQP.MFormLvl(levelQ, levelP, head.Value[i], head.Value[i])
+	}
 
-		ringQP.MFormLvl(levelQ, levelP, swk.Value.Value[i], swk.Value.Value[i])
-		ringQP.MFormLvl(levelQ, levelP, swkhead.Value.Value[i], swkhead.Value.Value[i])
+	return head
+}
+
+// TryUAuxKeyGen is UAuxKeyGen returning an error instead of panicking on an invalid request.
+func (keygen *KeyGenerator) TryUAuxKeyGen(swkheadsum *SWK, sk *SecretKey) (uaux *SWK, uauxhead *SWK, err error) {
+	if err = keygen.CheckUAuxKeyGen(swkheadsum, sk); err != nil {
+		return nil, nil, err
 	}
-	// fmt.Print("swkhead = ", swkhead.Value.Value[0].Q.Coeffs[4][5], "\n")
-	ringQP.MFormLvl(levelQ, levelP, sk.Value, sk.Value)
-	return swk, swkhead
+
+	uaux, uauxhead = keygen.UAuxKeyGen(swkheadsum, sk)
+	return uaux, uauxhead, nil
 }
 
 func (keygen *KeyGenerator) UAuxKeyGen(swkheadsum *SWK, sk *SecretKey) (uaux *SWK, uauxhead *SWK) {
+	if err := keygen.CheckUAuxKeyGen(swkheadsum, sk); err != nil {
+		panic(err)
+	}
 
 	id := sk.ID
 
@@ -436,8 +506,9 @@ func (keygen *KeyGenerator) UAuxKeyGen(swkheadsum *SWK, sk *SecretKey) (uaux *SW
 
 	uaux = NewSWK(params, id)
 	uauxhead = NewSWK(params, id)
+	uaux.Epoch, uauxhead.Epoch = swkheadsum.Epoch, swkheadsum.Epoch
 
-	ringQP.InvMFormLvl(levelQ, levelP, sk.Value, sk.Value)
+	sk = keygen.invMFormSecretKey(sk)
 
 	for i := 0; i < beta; i++ {
 		e := ringQP.NewPoly()
@@ -456,12 +527,102 @@ func (keygen *KeyGenerator) UAuxKeyGen(swkheadsum *SWK, sk *SecretKey) (uaux *SW
 		ringQP.MFormLvl(levelQ, levelP, uauxhead.Value.Value[i], uauxhead.Value.Value[i])
 	}
 	// fmt.Print("swkhead = ", swkhead.Value.Value[0].Q.Coeffs[4][5], "\n")
-	ringQP.MFormLvl(levelQ, levelP, sk.Value, sk.Value)
 	return uaux, uauxhead
 }
 
+// TryLAuxKeyGen is LAuxKeyGen returning an error instead of panicking on an invalid request.
+func (keygen *KeyGenerator) TryLAuxKeyGen(swkheadsum *SWK, sk *SecretKey) (laux *SWK, err error) {
+	if err = keygen.CheckLAuxKeyGen(swkheadsum, sk); err != nil {
+		return nil, err
+	}
+
+	return keygen.LAuxKeyGen(swkheadsum, sk), nil
+}
+
+// LAuxKeyGen generates the auxiliary key of a party leaving its group.
+// laux = swkheadsum * s - P * s * g + e, so that swksum + laux is the switching key sum of the remaining members.
+func (keygen *KeyGenerator) LAuxKeyGen(swkheadsum *SWK, sk *SecretKey) (laux *SWK) {
+	if err := keygen.CheckLAuxKeyGen(swkheadsum, sk); err != nil {
+		panic(err)
+	}
+
+	id := sk.ID
+
+	params := keygen.params
+	levelQ := params.QCount() - 1
+	levelP := params.PCount() - 1
+	beta := params.Beta(levelQ)
+	ringQP := params.RingQP()
+
+	laux = NewSWK(params, id)
+	laux.Epoch = swkheadsum.Epoch
+
+	// laux = Ps + e
+	keygen.genSwitchingKeyInvMForm(sk, laux.Value)
+	sk = keygen.invMFormSecretKey(sk)
+
+	for i := 0; i < beta; i++ {
+		e := ringQP.NewPoly()
+		keygen.NFgaussianSamplerQ.ReadLvl(levelQ, e.Q)
+		ringQP.ExtendBasisSmallNormAndCenter(e.Q, levelP, e.Q, e.P)
+		ringQP.NTTLvl(levelQ, levelP, e, e)
+
+		b := ringQP.NewPoly()
+		ringQP.MulCoeffsMontgomeryLvl(levelQ, levelP, swkheadsum.Value.Value[i], sk.Value, b)
+		ringQP.SubLvl(levelQ, levelP, b, laux.Value.Value[i], laux.Value.Value[i])
+		ringQP.AddLvl(levelQ, levelP, laux.Value.Value[i], e, laux.Value.Value[i])
+
+		ringQP.MFormLvl(levelQ, levelP, laux.Value.Value[i], laux.Value.Value[i])
+	}
+
+	return laux
+}
+
+// TryRAuxKeyGen is RAuxKeyGen returning an error instead of panicking on an invalid request.
+func (keygen *KeyGenerator) TryRAuxKeyGen(swkheadsum *SWK, skOld, skNew *SecretKey) (raux *SWK, err error) {
+	if err = keygen.CheckRAuxKeyGen(swkheadsum, skOld, skNew); err != nil {
+		return nil, err
+	}
+
+	return keygen.RAuxKeyGen(swkheadsum, skOld, skNew), nil
+}
+
+// RAuxKeyGen generates the auxiliary key of a party refreshing its secret key from skOld to skNew.
+// raux = swkheadsum * (s - s') + e, so that swksum + sum(raux) encrypts the previous group secret under the refreshed one.
+func (keygen *KeyGenerator) RAuxKeyGen(swkheadsum *SWK, skOld, skNew *SecretKey) (raux *SWK) {
+
+	if err := keygen.CheckRAuxKeyGen(swkheadsum, skOld, skNew); err != nil {
+		panic(err)
+	}
+
+	params := keygen.params
+	levelQ := params.QCount() - 1
+	levelP := params.PCount() - 1
+
+	// delta = s' - s, so that uaux = -swkheadsum * delta + e
+	delta := NewSecretKey(params, skNew.ID)
+	params.RingQP().SubLvl(levelQ, levelP, skNew.Value, skOld.Value, delta.Value)
+
+	raux, _ = keygen.UAuxKeyGen(swkheadsum, delta)
+	return raux
+}
+
+// TryGenSWK is GenSWK returning an error instead of panicking on an invalid request.
+func (keygen *KeyGenerator) TryGenSWK(sk *SecretKey, pk *PublicKey) (swk *SWK, swkhead *SWK, err error) {
+	if err = keygen.CheckGenSWK(sk, pk); err != nil {
+		return nil, nil, err
+	}
+
+	swk, swkhead = keygen.GenSWK(sk, pk)
+	return swk, swkhead, nil
+}
+
 // GenPublicKey generates a new public key from the provided SecretKey.
 func (keygen *KeyGenerator) GenSWK(sk *SecretKey, pk *PublicKey) (swk *SWK, swkhead *SWK) {
+	if err := keygen.CheckGenSWK(sk, pk); err != nil {
+		panic(err)
+	}
+
 	// skIn := sk
 	id := sk.ID
 	// skOut := NewSecretKey(keygen.params, id)
@@ -475,14 +636,12 @@ func (keygen *KeyGenerator) GenSWK(sk *SecretKey, pk *PublicKey) (swk *SWK, swkh
 	// ringQ := params.RingQ()
 	// ringP := params.RingP()
 
-	// Reset sk to zero
-	// zsk := NewSecretKey(params, "group0")
-	ringQP.InvMFormLvl(levelQ, levelP, sk.Value, sk.Value)
-
 	// rk  = Ps' + e
 	swk = NewSWK(params, id)
 	swkhead = NewSWK(params, id)
-	keygen.GenSwitchingKey(sk, swk.Value)
+	swk.Epoch, swkhead.Epoch = pk.Epoch, pk.Epoch
+	swk.From, swkhead.From = sk.Epoch, sk.Epoch
+	keygen.genSwitchingKeyInvMForm(sk, swk.Value)
 
 	a := ringQP.NewPoly()
 	b := ringQP.NewPoly()
@@ -540,7 +699,6 @@ func (keygen *KeyGenerator) GenSWK(sk *SecretKey, pk *PublicKey) (swk *SWK, swkh
 
 		// ringQP.MulCoeffsMontgomeryLvl(levelQ, levelP, pk.Value[1], r0, swkhead.Value.Value[i])
 	}
-	ringQP.MFormLvl(levelQ, levelP, sk.Value, sk.Value)
 	// fmt.Print("pk0 after = ", pk.Value[0].Q.Coeffs[2][3], "\n")
 	// fmt.Print("pk1 after = ", pk.Value[1].Q.Coeffs[2][3], "\n")
 
@@ -556,7 +714,7 @@ func (keygen *KeyGenerator) GenSWK(sk *SecretKey, pk *PublicKey) (swk *SWK, swkh
 func (keygen *KeyGenerator) GenGroupSecretKey(skList []*SecretKey) (skOut *SecretKey) {
 
 	if len(skList) == 0 {
-		panic("invalid input: empty secretkey list")
+		panic(NewError(ErrInvalidInput, "invalid input: empty secretkey list"))
 	}
 
 	params := keygen.params
@@ -565,10 +723,11 @@ func (keygen *KeyGenerator) GenGroupSecretKey(skList []*SecretKey) (skOut *Secre
 
 	id := skList[0].ID
 	skOut = NewSecretKey(params, id)
+	skOut.Epoch = skList[0].Epoch
 
 	for _, sk := range skList {
 		if id != sk.ID {
-			panic("invalid input: IDs are not same")
+			panic(NewError(ErrInvalidInput, "invalid input: IDs are not same"))
 		}
 
 		params.RingQP().AddLvl(levelQ, levelP, skOut.Value, sk.Value, skOut.Value)
@@ -581,7 +740,7 @@ func (keygen *KeyGenerator) GenGroupSecretKey(skList []*SecretKey) (skOut *Secre
 func (keygen *KeyGenerator) GenGroupPublicKey(pkList []*PublicKey) (pkOut *PublicKey) {
 
 	if len(pkList) == 0 {
-		panic("invalid input: empty publickey list")
+		panic(NewError(ErrInvalidInput, "invalid input: empty publickey list"))
 	}
 
 	params := keygen.params
@@ -590,10 +749,11 @@ func (keygen *KeyGenerator) GenGroupPublicKey(pkList []*PublicKey) (pkOut *Publi
 
 	id := pkList[0].ID
 	pkOut = NewPublicKey(params, id)
+	pkOut.Epoch = pkList[0].Epoch
 
 	for _, pk := range pkList {
 		if id != pk.ID {
-			panic("invalid input: IDs are not same")
+			panic(NewError(ErrInvalidInput, "invalid input: IDs are not same"))
 		}
 
 		params.RingQP().AddLvl(levelQ, levelP, pkOut.Value[0], pk.Value[0], pkOut.Value[0])
@@ -607,7 +767,7 @@ func (keygen *KeyGenerator) GenGroupPublicKey(pkList []*PublicKey) (pkOut *Publi
 func (keygen *KeyGenerator) GenGroupRotKey(rtkList []*RotationKey) (rtkOut *RotationKey) {
 
 	if len(rtkList) == 0 {
-		panic("invalid input: empty rotkey list")
+		panic(NewError(ErrInvalidInput, "invalid input: empty rotkey list"))
 	}
 
 	params := keygen.params
@@ -618,14 +778,15 @@ func (keygen *KeyGenerator) GenGroupRotKey(rtkList []*RotationKey) (rtkOut *Rota
 	id := rtkList[0].ID
 	idx := rtkList[0].RotIdx
 	rtkOut = NewRotationKey(params, idx, id)
+	rtkOut.Epoch = rtkList[0].Epoch
 
 	for _, rtk := range rtkList {
 		if id != rtk.ID {
-			panic("invalid input: IDs are not same")
+			panic(NewError(ErrInvalidInput, "invalid input: IDs are not same"))
 		}
 
 		if idx != rtk.RotIdx {
-			panic("invalid input: rotation indexes are not same")
+			panic(NewError(ErrInvalidInput, "invalid input: rotation indexes are not same"))
 		}
 
 		for i := 0; i < beta; i++ {
@@ -639,7 +800,7 @@ func (keygen *KeyGenerator) GenGroupRotKey(rtkList []*RotationKey) (rtkOut *Rota
 func (keygen *KeyGenerator) GenGroupConjKey(cjkList []*ConjugationKey) (cjkOut *ConjugationKey) {
 
 	if len(cjkList) == 0 {
-		panic("invalid input: empty conjkey list")
+		panic(NewError(ErrInvalidInput, "invalid input: empty conjkey list"))
 	}
 
 	params := keygen.params
@@ -649,10 +810,11 @@ func (keygen *KeyGenerator) GenGroupConjKey(cjkList []*ConjugationKey) (cjkOut *
 
 	id := cjkList[0].ID
 	cjkOut = NewConjugationKey(params, id)
+	cjkOut.Epoch = cjkList[0].Epoch
 
 	for _, cjk := range cjkList {
 		if id != cjk.ID {
-			panic("invalid input: IDs are not same")
+			panic(NewError(ErrInvalidInput, "invalid input: IDs are not same"))
 		}
 
 		for i := 0; i < beta; i++ {
@@ -665,7 +827,7 @@ func (keygen *KeyGenerator) GenGroupConjKey(cjkList []*ConjugationKey) (cjkOut *
 func (keygen *KeyGenerator) GenGroupSWK(swkList []*SWK) (swkOut *SWK) {
 
 	if len(swkList) == 0 {
-		panic("invalid input: empty conjkey list")
+		panic(NewError(ErrInvalidInput, "invalid input: empty conjkey list"))
 	}
 
 	params := keygen.params
@@ -675,10 +837,11 @@ func (keygen *KeyGenerator) GenGroupSWK(swkList []*SWK) (swkOut *SWK) {
 
 	id := swkList[0].ID
 	swkOut = NewSWK(params, id)
+	swkOut.Epoch, swkOut.From = swkList[0].Epoch, swkList[0].From
 
 	for _, swk := range swkList {
 		if id != swk.ID {
-			panic("invalid input: IDs are not same")
+			panic(NewError(ErrInvalidInput, "invalid input: IDs are not same"))
 		}
 
 		for i := 0; i < beta; i++ {
@@ -690,7 +853,7 @@ func (keygen *KeyGenerator) GenGroupSWK(swkList []*SWK) (swkOut *SWK) {
 
 func (keygen *KeyGenerator) GenGroupRelinKey(rlkList []*RelinearizationKey) (rlkOut *RelinearizationKey) {
 	if len(rlkList) == 0 {
-		panic("invalid input: empty relinkey list")
+		panic(NewError(ErrInvalidInput, "invalid input: empty relinkey list"))
 	}
 
 	params := keygen.params
@@ -700,10 +863,11 @@ func (keygen *KeyGenerator) GenGroupRelinKey(rlkList []*RelinearizationKey) (rlk
 
 	id := rlkList[0].ID
 	rlkOut = NewRelinearizationKey(params, id)
+	rlkOut.Epoch = rlkList[0].Epoch
 
 	for _, rlk := range rlkList {
 		if id != rlk.ID {
-			panic("invalid input: IDs are not same")
+			panic(NewError(ErrInvalidInput, "invalid input: IDs are not same"))
 		}
 
 		for i := 0; i < beta; i++ {
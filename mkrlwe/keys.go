@@ -1,11 +1,17 @@
 package mkrlwe
 
-import "github.com/ldsec/lattigo/v2/rlwe"
+import (
+	"sync"
+
+	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/ldsec/lattigo/v2/rlwe"
+)
 
 // SecretKeySet is a type for generic Multikey RLWE secret keys.
 type SecretKey struct {
 	rlwe.SecretKey
-	ID string
+	ID    string
+	Epoch int
 }
 
 // SecretKeySet is a type for a set of multikey RLWE secret keys.
@@ -16,7 +22,8 @@ type SecretKeySet struct {
 // PublicKey is a type for generic RLWE public keys.
 type PublicKey struct {
 	rlwe.PublicKey
-	ID string
+	ID    string
+	Epoch int
 }
 
 // SwitchingKey is a type for generic RLWE switching keys.
@@ -34,6 +41,7 @@ type PublicKeySet struct {
 type RelinearizationKey struct {
 	Value [3]*SwitchingKey
 	ID    string
+	Epoch int
 }
 
 // RotationKey is a type for storing generic RLWE public rotation keys.
@@ -41,25 +49,74 @@ type RotationKey struct {
 	Value  *SwitchingKey
 	ID     string
 	RotIdx uint
+	Epoch  int
 }
 
 // CojugationKey is a type for storing generic RLWE public conjugation keys
 type ConjugationKey struct {
 	Value *SwitchingKey
 	ID    string
+	Epoch int
 }
 
 // CojugationKey is a type for storing generic RLWE public conjugation keys
+// From is the epoch of the keys an SWK switches from: the epoch of the secret key it is generated from,
+// or the epoch of the group before the update which returned it.
 type SWK struct {
 	Value *SwitchingKey
 	ID    string
+	Epoch int
+	From  int
+}
+
+// SeededSWK is the head of a SWK generated by GenSWKTestSeeded, represented by the seed its uniform
+// values are expanded from. The receiver recovers the head with Expand.
+type SeededSWK struct {
+	ID    string
+	Epoch int
+	From  int
+	Seed  []byte
+}
+
+// JoinKey is a type for the pair (jk, jkhead) returned by a group update,
+// which switches the component of a group from the epoch From to the epoch of jk.
+type JoinKey struct {
+	Value *SWK
+	Head  *SWK
+	From  int
+}
+
+// ComposedJoinKey is a type for a chain of join keys of a group composed into a single key by KeySwitcher.ComposeChain,
+// which switches the component of the group from the epoch From to the epoch Epoch with one KSComposed.
+// Its rows (Value[i], Head[i]) encrypt e_i * s under the group secret of epoch Epoch, modulo Q, where s is the group secret
+// of epoch From and e_i the i-th CRT unit of Q. The moduli of Q above the rows take the role of the special modulus P,
+// so that the key only switches components up to the level MaxLevel.
+type ComposedJoinKey struct {
+	Value []*ring.Poly
+	Head  []*ring.Poly
+	ID    string
+	From  int
+	Epoch int
+}
+
+// MaxLevel returns the maximum level of the components the ComposedJoinKey switches.
+func (cjk *ComposedJoinKey) MaxLevel() int {
+	return len(cjk.Value) - 1
+}
+
+// JoinKeySet is a type for the chains of join keys of groups, indexed by group id and source epoch.
+// It also caches the chains composed by KeySwitcher.ComposeChain, which are not marshaled.
+type JoinKeySet struct {
+	Value map[string]map[int]*JoinKey
+
+	composed   map[string]map[int]*ComposedJoinKey
+	composedMu sync.Mutex
 }
 
 // RelinearizationKeySet is a type for a set of multikey RLWE relinearization keys.
 type RelinearizationKeySet struct {
-	params    Parameters
-	Value     map[string]*RelinearizationKey
-	HoistPool [2]*HoistedCiphertext
+	params Parameters
+	Value  map[string]*RelinearizationKey
 }
 
 // RotationKeysSet is a type for a set of multikey RLWE rotation keys.
@@ -96,12 +153,30 @@ func (skSet *SecretKeySet) DelSecretKey(id string) {
 
 // GetSecretKey returns a secretkey of given id from SecretKeySet
 func (skSet *SecretKeySet) GetSecretKey(id string) *SecretKey {
+	ret, err := skSet.LookupSecretKey(id)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// LookupSecretKey returns a secretkey of given id from SecretKeySet, or an error of kind ErrMissingKey
+func (skSet *SecretKeySet) LookupSecretKey(id string) (*SecretKey, error) {
 	ret, in := skSet.Value[id]
 
 	if !in {
-		panic("cannot GetPublicKey: there is no public key with given id")
+		return nil, NewError(ErrMissingKey, "cannot GetSecretKey: there is no secret key with id %s", id)
 	}
-	return ret
+	return ret, nil
+}
+
+// IDSet returns the ids of the secret keys in SecretKeySet
+func (skSet *SecretKeySet) IDSet() *IDSet {
+	idset := NewIDSet()
+	for id := range skSet.Value {
+		idset.Add(id)
+	}
+	return idset
 }
 
 // NewPublicKeySet returns a new empty PublicKeySet
@@ -123,13 +198,31 @@ func (pkSet *PublicKeySet) DelPublicKey(id string) {
 
 // GetPublicKey returns a publickey of given id from PublicKeySet
 func (pkSet *PublicKeySet) GetPublicKey(id string) *PublicKey {
+	ret, err := pkSet.LookupPublicKey(id)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// LookupPublicKey returns a publickey of given id from PublicKeySet, or an error of kind ErrMissingKey
+func (pkSet *PublicKeySet) LookupPublicKey(id string) (*PublicKey, error) {
 	ret, in := pkSet.Value[id]
 
 	if !in {
-		panic("cannot GetPublicKey: there is no public key with given id")
+		return nil, NewError(ErrMissingKey, "cannot GetPublicKey: there is no public key with id %s", id)
 	}
 
-	return ret
+	return ret, nil
+}
+
+// IDSet returns the ids of the public keys in PublicKeySet
+func (pkSet *PublicKeySet) IDSet() *IDSet {
+	idset := NewIDSet()
+	for id := range pkSet.Value {
+		idset.Add(id)
+	}
+	return idset
 }
 
 // NewRotationKeysSet returns a new empty RotationKeysSet
@@ -160,26 +253,40 @@ func (rkSet *RotationKeySet) DelRotationKey(id string, rotidx uint) {
 
 // GetRotationKeys returns a rotation keys of given id from RotationKeysSet
 func (rkSet *RotationKeySet) GetRotationKey(id string, rotidx uint) *RotationKey {
+	ret, err := rkSet.LookupRotationKey(id, rotidx)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// LookupRotationKey returns a rotation key of given id and rotidx from RotationKeysSet, or an error of kind ErrMissingKey
+func (rkSet *RotationKeySet) LookupRotationKey(id string, rotidx uint) (*RotationKey, error) {
 	_, in := rkSet.Value[id]
 	if !in {
-		panic("cannot GetRotationKeys: there is no rotation key with given id")
+		return nil, NewError(ErrMissingKey, "cannot GetRotationKeys: there is no rotation key with id %s", id)
 	}
 
-	_, in = rkSet.Value[id][rotidx]
+	ret, in := rkSet.Value[id][rotidx]
 	if !in {
-		panic("cannot GetRotationKeys: there is no rotation key with given id")
+		return nil, NewError(ErrMissingKey, "cannot GetRotationKeys: there is no rotation key with id %s and rot idx %d", id, rotidx)
+	}
+	return ret, nil
+}
+
+// IDSet returns the ids of the rotation keys in RotationKeySet
+func (rkSet *RotationKeySet) IDSet() *IDSet {
+	idset := NewIDSet()
+	for id := range rkSet.Value {
+		idset.Add(id)
 	}
-	return rkSet.Value[id][rotidx]
+	return idset
 }
 
 // NewRelinearizationKeySet returns a new empty RelinearizationKeySet
 func NewRelinearizationKeySet(params Parameters) *RelinearizationKeySet {
 	rlkSet := new(RelinearizationKeySet)
 	rlkSet.Value = make(map[string]*RelinearizationKey)
-
-	rlkSet.HoistPool[0] = NewHoistedCiphertext()
-	rlkSet.HoistPool[1] = NewHoistedCiphertext()
-
 	rlkSet.params = params
 
 	return rlkSet
@@ -188,8 +295,6 @@ func NewRelinearizationKeySet(params Parameters) *RelinearizationKeySet {
 // AddRelinearizationKey insert new publickey into RelinearizationKeySet with its id
 func (rlkSet *RelinearizationKeySet) AddRelinearizationKey(rlk *RelinearizationKey) {
 	rlkSet.Value[rlk.ID] = rlk
-	rlkSet.HoistPool[0].Value[rlk.ID] = NewSwitchingKey(rlkSet.params)
-	rlkSet.HoistPool[1].Value[rlk.ID] = NewSwitchingKey(rlkSet.params)
 }
 
 // DelRelinearizationKey delete publickey of given id from SecretKeySet
@@ -199,13 +304,31 @@ func (rlkSet *RelinearizationKeySet) DelRelinearizationKey(id string) {
 
 // GetRelinearizationKey returns a publickey of given id from RelinearizationKeySet
 func (rlkSet *RelinearizationKeySet) GetRelinearizationKey(id string) *RelinearizationKey {
+	ret, err := rlkSet.LookupRelinearizationKey(id)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// LookupRelinearizationKey returns a relinearization key of given id from RelinearizationKeySet, or an error of kind ErrMissingKey
+func (rlkSet *RelinearizationKeySet) LookupRelinearizationKey(id string) (*RelinearizationKey, error) {
 	ret, in := rlkSet.Value[id]
 
 	if !in {
-		panic("cannot GetRelinearizationKey: there is no relinearization key with given id")
+		return nil, NewError(ErrMissingKey, "cannot GetRelinearizationKey: there is no relinearization key with id %s", id)
 	}
 
-	return ret
+	return ret, nil
+}
+
+// IDSet returns the ids of the relinearization keys in RelinearizationKeySet
+func (rlkSet *RelinearizationKeySet) IDSet() *IDSet {
+	idset := NewIDSet()
+	for id := range rlkSet.Value {
+		idset.Add(id)
+	}
+	return idset
 }
 
 // NewConjugationKeySet returns a new empty PublicKeySet
@@ -227,13 +350,143 @@ func (cjkSet *ConjugationKeySet) DelConjugationKey(id string) {
 
 // GetConjugationKey returns a publickey of given id from PublicKeySet
 func (cjkSet *ConjugationKeySet) GetConjugationKey(id string) *ConjugationKey {
+	ret, err := cjkSet.LookupConjugationKey(id)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// LookupConjugationKey returns a conjugation key of given id from ConjugationKeySet, or an error of kind ErrMissingKey
+func (cjkSet *ConjugationKeySet) LookupConjugationKey(id string) (*ConjugationKey, error) {
 	ret, in := cjkSet.Value[id]
 
 	if !in {
-		panic("cannot GetConjugationKey: there is no conjugation key with given id")
+		return nil, NewError(ErrMissingKey, "cannot GetConjugationKey: there is no conjugation key with id %s", id)
 	}
 
-	return ret
+	return ret, nil
+}
+
+// IDSet returns the ids of the conjugation keys in ConjugationKeySet
+func (cjkSet *ConjugationKeySet) IDSet() *IDSet {
+	idset := NewIDSet()
+	for id := range cjkSet.Value {
+		idset.Add(id)
+	}
+	return idset
+}
+
+// NewJoinKeySet returns a new empty JoinKeySet
+func NewJoinKeySet() *JoinKeySet {
+	jkSet := new(JoinKeySet)
+	jkSet.Value = make(map[string]map[int]*JoinKey)
+	return jkSet
+}
+
+// AddJoinKey inserts the pair (jk, jkhead) returned by Group.Join, Group.BatchJoin, Group.Leave or Group.Refresh into JoinKeySet,
+// as the key from the epoch jk.From.
+func (jkSet *JoinKeySet) AddJoinKey(jk, jkhead *SWK) {
+	if jk.ID != jkhead.ID || jk.Epoch != jkhead.Epoch || jk.From != jkhead.From {
+		panic(NewError(ErrInvalidInput, "invalid input: jk and jkhead are not from the same group update"))
+	}
+
+	if _, in := jkSet.Value[jk.ID]; !in {
+		jkSet.Value[jk.ID] = make(map[int]*JoinKey)
+	}
+
+	jkSet.Value[jk.ID][jk.From] = &JoinKey{Value: jk, Head: jkhead, From: jk.From}
+
+	// the composed chains going through the replaced key are no longer valid
+	jkSet.composedMu.Lock()
+	defer jkSet.composedMu.Unlock()
+
+	for from, cjk := range jkSet.composed[jk.ID] {
+		if cjk.From <= jk.From && jk.From < cjk.Epoch {
+			delete(jkSet.composed[jk.ID], from)
+		}
+	}
+}
+
+// DelJoinKeys deletes the join keys of given id from JoinKeySet, with their composed chains
+func (jkSet *JoinKeySet) DelJoinKeys(id string) {
+	delete(jkSet.Value, id)
+
+	jkSet.composedMu.Lock()
+	defer jkSet.composedMu.Unlock()
+
+	delete(jkSet.composed, id)
+}
+
+// GetJoinKey returns the join key of given id from the given epoch, and whether it is in JoinKeySet
+func (jkSet *JoinKeySet) GetJoinKey(id string, from int) (jk *JoinKey, in bool) {
+	jk, in = jkSet.Value[id][from]
+	return
+}
+
+// GetChain returns the join keys of given id from the given epoch to the last epoch of JoinKeySet, in order
+func (jkSet *JoinKeySet) GetChain(id string, from int) (chain []*JoinKey) {
+	for jk, in := jkSet.GetJoinKey(id, from); in; jk, in = jkSet.GetJoinKey(id, jk.Value.Epoch) {
+		chain = append(chain, jk)
+	}
+	return
+}
+
+// GetComposedKey returns the composed chain of given id from the given epoch cached in JoinKeySet by KeySwitcher.ComposeChain,
+// and whether it is in JoinKeySet and reaches the last epoch of the chain.
+func (jkSet *JoinKeySet) GetComposedKey(id string, from int) (cjk *ComposedJoinKey, in bool) {
+	jkSet.composedMu.Lock()
+	defer jkSet.composedMu.Unlock()
+
+	if cjk, in = jkSet.getComposedKey(id, from); !in {
+		return nil, false
+	}
+
+	return cjk, jkSet.isLastEpoch(id, cjk.Epoch)
+}
+
+// getComposedKey returns the cached composed chain of given id from the given epoch, up to date or not,
+// for a caller holding composedMu
+func (jkSet *JoinKeySet) getComposedKey(id string, from int) (cjk *ComposedJoinKey, in bool) {
+	cjk, in = jkSet.composed[id][from]
+	return
+}
+
+// isLastEpoch returns whether the chain of given id has no join key from the given epoch
+func (jkSet *JoinKeySet) isLastEpoch(id string, epoch int) bool {
+	_, in := jkSet.GetJoinKey(id, epoch)
+	return !in
+}
+
+// IDSet returns the ids of the join keys in JoinKeySet
+func (jkSet *JoinKeySet) IDSet() *IDSet {
+	idset := NewIDSet()
+	for id := range jkSet.Value {
+		idset.Add(id)
+	}
+	return idset
+}
+
+// ExtendEpochs returns a shallow copy of ct tagged with the epochs KeySwitcher.Extend switches its components to,
+// so that an operation on the extension of ct can be checked without extending it. A nil jkSet returns ct.
+// The epochs of ct are read after any extension of ct in progress, see KeySwitcher.Extend.
+func (jkSet *JoinKeySet) ExtendEpochs(ct *Ciphertext) *Ciphertext {
+	if jkSet == nil {
+		return ct
+	}
+
+	ct.extendMu.Lock()
+	defer ct.extendMu.Unlock()
+
+	ctOut := &Ciphertext{Value: ct.Value}
+	ctOut.CopyEpochs(ct)
+	for _, id := range ct.IDSet().Slice() {
+		if chain := jkSet.GetChain(id, ct.GetEpoch(id)); len(chain) > 0 {
+			ctOut.SetEpoch(id, chain[len(chain)-1].Value.Epoch)
+		}
+	}
+
+	return ctOut
 }
 
 // NewConjugationKeySet returns a new empty PublicKeySet
@@ -248,6 +501,15 @@ func (swkSet *SWKSet) AddSWK(swk *SWK) {
 	swkSet.Value[swk.ID] = swk
 }
 
+// IDSet returns the ids of the SWKs in SWKSet
+func (swkSet *SWKSet) IDSet() *IDSet {
+	idset := NewIDSet()
+	for id := range swkSet.Value {
+		idset.Add(id)
+	}
+	return idset
+}
+
 // // DelConjugationKey delete publickey of given id from SecretKeySet
 // func (cjkSet *ConjugationKeySet) DelConjugationKey(id string) {
 // 	delete(cjkSet.Value, id)
@@ -339,6 +601,7 @@ func (sk *SecretKey) CopyNew() *SecretKey {
 	ret := new(SecretKey)
 	ret.Value = sk.Value.CopyNew()
 	ret.ID = sk.ID
+	ret.Epoch = sk.Epoch
 
 	return ret
 }
@@ -353,6 +616,7 @@ func (pk *PublicKey) CopyNew() *PublicKey {
 	ret.Value[0] = pk.Value[0].CopyNew()
 	ret.Value[1] = pk.Value[1].CopyNew()
 	ret.ID = pk.ID
+	ret.Epoch = pk.Epoch
 
 	return ret
 }
@@ -49,6 +49,23 @@ func NewKeySwitcher(params Parameters) *KeySwitcher {
 	return ks
 }
 
+// ShallowCopy creates a copy of a KeySwitcher, only reallocating the memory pools.
+// KeySwitchers returned by ShallowCopy can be used concurrently.
+func (ks *KeySwitcher) ShallowCopy() *KeySwitcher {
+	params := ks.Parameters
+	ringQ := params.RingQ()
+
+	return &KeySwitcher{
+		KeySwitcher: *ks.KeySwitcher.ShallowCopy(),
+		Parameters:  params,
+		Decomposer:  ks.Decomposer,
+		polyQPool:   [4]*ring.Poly{ringQ.NewPoly(), ringQ.NewPoly(), ringQ.NewPoly(), ringQ.NewPoly()},
+		swkPool1:    NewSwitchingKey(params),
+		swkPool2:    NewSwitchingKey(params),
+		swkPool3:    NewSwitchingKey(params),
+	}
+}
+
 func (ks *KeySwitcher) Decompose(levelQ int, a *ring.Poly, ad *SwitchingKey) {
 
 	params := ks.Parameters
@@ -125,13 +142,11 @@ func (ks *KeySwitcher) ExternalProduct(levelQ int, a *ring.Poly, bg *SwitchingKe
 func (ks *KeySwitcher) PrevMulAndRelin(op0, op1 *Ciphertext, rlkSet *RelinearizationKeySet, ctOut *Ciphertext) {
 	level := ctOut.Level()
 
-	if op0.Level() < level {
-		panic("Cannot MulAndRelin: op0 and op1 have different levels")
+	if err := ks.CheckMulAndRelin(op0, op1, rlkSet, level); err != nil {
+		panic(err)
 	}
 
-	if ctOut.Level() < level {
-		panic("Cannot MulAndRelin: op0 and ctOut have different levels")
-	}
+	ctOut.MergeEpochs(op0, op1)
 
 	idset0 := op0.IDSet()
 	idset1 := op1.IDSet()
@@ -148,11 +163,11 @@ func (ks *KeySwitcher) PrevMulAndRelin(op0, op1 *Ciphertext, rlkSet *Relineariza
 
 	//ctOut_j <- op0_0 * op1_j + op0_j * op1_0
 	ringQ.MFormLvl(level, ks.polyQPool[1], ks.polyQPool[1])
-	for id := range idset0.Value { // id: j
+	for _, id := range idset0.Slice() { // id: j
 		ringQ.NTTLvl(level, op0.Value[id], ks.polyQPool[2]) //pool[2]: op0_j
 		ringQ.MulCoeffsMontgomeryLvl(level, ks.polyQPool[1], ks.polyQPool[2], ctOut.Value[id])
 	}
-	for id := range idset1.Value { //id:j
+	for _, id := range idset1.Slice() { //id:j
 		ringQ.NTTLvl(level, op1.Value[id], ks.polyQPool[2]) //pool[2]: op1_j
 		if idset0.Has(id) {
 			ringQ.MulCoeffsMontgomeryAndAddLvl(level, ks.polyQPool[0], ks.polyQPool[2], ctOut.Value[id])
@@ -161,13 +176,13 @@ func (ks *KeySwitcher) PrevMulAndRelin(op0, op1 *Ciphertext, rlkSet *Relineariza
 		}
 	}
 
-	for id := range ctOut.Value {
+	for _, id := range ctOut.IDs() {
 		ringQ.InvNTTLvl(level, ctOut.Value[id], ctOut.Value[id])
 	}
 
 	//ctOut_j <- ctOut_j +  SUM_i(Inter(op0_i * op1_j , d_i))
-	for id0 := range idset0.Value { // id0: i
-		for id1 := range idset1.Value { // id1: j
+	for _, id0 := range idset0.Slice() { // id0: i
+		for _, id1 := range idset1.Slice() { // id1: j
 			if id0 > id1 {
 				if idset1.Has(id0) {
 					continue
@@ -229,13 +244,11 @@ func (ks *KeySwitcher) MulAndRelin(op0, op1 *Ciphertext, rlkSet *Relinearization
 
 	level := ctOut.Level()
 
-	if op0.Level() < level {
-		panic("Cannot MulAndRelin: op0 and op1 have different levels")
+	if err := ks.CheckMulAndRelin(op0, op1, rlkSet, level); err != nil {
+		panic(err)
 	}
 
-	if ctOut.Level() < level {
-		panic("Cannot MulAndRelin: op0 and ctOut have different levels")
-	}
+	ctOut.MergeEpochs(op0, op1)
 
 	idset0 := op0.IDSet()
 	idset1 := op1.IDSet()
@@ -260,7 +273,7 @@ func (ks *KeySwitcher) MulAndRelin(op0, op1 *Ciphertext, rlkSet *Relinearization
 	}
 
 	//gen x vector
-	for id := range idset0.Value {
+	for _, id := range idset0.Slice() {
 		ks.Decompose(level, op0.Value[id], ks.swkPool3)
 		d := rlkSet.Value[id].Value[1]
 		for i := 0; i < beta; i++ {
@@ -273,7 +286,7 @@ func (ks *KeySwitcher) MulAndRelin(op0, op1 *Ciphertext, rlkSet *Relinearization
 	}
 
 	//gen y vector
-	for id := range idset1.Value {
+	for _, id := range idset1.Slice() {
 		ks.Decompose(level, op1.Value[id], ks.swkPool3)
 		b := rlkSet.Value[id].Value[0]
 		for i := 0; i < beta; i++ {
@@ -294,12 +307,12 @@ func (ks *KeySwitcher) MulAndRelin(op0, op1 *Ciphertext, rlkSet *Relinearization
 
 	//ctOut_j <- op0_0 * op1_j + op0_j * op1_0
 	ringQ.MFormLvl(level, ks.polyQPool[1], ks.polyQPool[1])
-	for id := range idset0.Value {
+	for _, id := range idset0.Slice() {
 		ringQ.NTTLvl(level, op0.Value[id], ks.polyQPool[2])
 		ringQ.MulCoeffsMontgomeryLvl(level, ks.polyQPool[1], ks.polyQPool[2], ctOut.Value[id])
 	}
 
-	for id := range idset1.Value {
+	for _, id := range idset1.Slice() {
 		ringQ.NTTLvl(level, op1.Value[id], ks.polyQPool[2])
 		if idset0.Has(id) {
 			ringQ.MulCoeffsMontgomeryAndAddLvl(level, ks.polyQPool[0], ks.polyQPool[2], ctOut.Value[id])
@@ -308,12 +321,12 @@ func (ks *KeySwitcher) MulAndRelin(op0, op1 *Ciphertext, rlkSet *Relinearization
 		}
 	}
 
-	for id := range ctOut.Value {
+	for _, id := range ctOut.IDs() {
 		ringQ.InvNTTLvl(level, ctOut.Value[id], ctOut.Value[id])
 	}
 
 	//ctOut_j <- ctOut_j +  Inter(op1_j, x)
-	for id := range idset1.Value {
+	for _, id := range idset1.Slice() {
 		ks.ExternalProduct(level, op1.Value[id], x, ks.polyQPool[0])
 		ringQ.AddLvl(level, ctOut.Value[id], ks.polyQPool[0], ctOut.Value[id])
 	}
@@ -323,7 +336,7 @@ func (ks *KeySwitcher) MulAndRelin(op0, op1 *Ciphertext, rlkSet *Relinearization
 
 	u := params.CRS[-1]
 
-	for id := range idset0.Value {
+	for _, id := range idset0.Slice() {
 		v := rlkSet.Value[id].Value[2]
 		ks.ExternalProduct(level, op0.Value[id], y, ks.polyQPool[0])
 
@@ -344,9 +357,8 @@ func (ks *KeySwitcher) Rotate(ctIn *Ciphertext, rotidx int, rkSet *RotationKeySe
 	params := ks.Parameters
 	ringQ := params.RingQ()
 
-	// check ctIn level
-	if ctIn.Level() < level {
-		panic("Cannot Rotate: ctIn and ctOut have different levels")
+	if err := ks.CheckRotate(ctIn, rotidx, rkSet, level); err != nil {
+		panic(err)
 	}
 
 	// adjust rotidx
@@ -361,7 +373,7 @@ func (ks *KeySwitcher) Rotate(ctIn *Ciphertext, rotidx int, rkSet *RotationKeySe
 
 	ctOut.Value["0"].Copy(ctIn.Value["0"])
 
-	for id := range idset.Value {
+	for _, id := range idset.Slice() {
 		rk := rkSet.GetRotationKey(id, uint(rotidx))
 		ks.ExternalProduct(level, ctIn.Value[id], rk.Value, ks.polyQPool[0])
 		ringQ.AddLvl(level, ctOut.Value["0"], ks.polyQPool[0], ctOut.Value["0"])
@@ -371,7 +383,7 @@ func (ks *KeySwitcher) Rotate(ctIn *Ciphertext, rotidx int, rkSet *RotationKeySe
 
 	// permute ctOut
 	galEl := params.GaloisElementForColumnRotationBy(rotidx)
-	for id := range ctIn.Value {
+	for _, id := range ctIn.IDs() {
 
 		var mask, index, indexRaw, logN, tmp uint64
 
@@ -401,6 +413,7 @@ func (ks *KeySwitcher) Rotate(ctIn *Ciphertext, rotidx int, rkSet *RotationKeySe
 
 	}
 
+	ctOut.CopyEpochs(ctIn)
 }
 
 // Conjugate conjugate ctIn with ctOut with ConjugationKeySet and returns the result in ctOut.
@@ -412,18 +425,19 @@ func (ks *KeySwitcher) Conjugate(ctIn *Ciphertext, ckSet *ConjugationKeySet, ctO
 	ringQ := params.RingQ()
 	galEl := params.GaloisElementForRowRotation()
 
-	// check ctIn level
-	if ctIn.Level() < level {
-		panic("Cannot Conjugate: ctIn and ctOut have different levels")
+	if err := ks.CheckConjugate(ctIn, ckSet, level); err != nil {
+		panic(err)
 	}
 
 	// permute ctIn and put it to ctOut
-	for id := range ctIn.Value {
+	for _, id := range ctIn.IDs() {
 		ringQ.Permute(ctIn.Value[id], galEl, ctOut.Value[id])
 	}
 
+	ctOut.CopyEpochs(ctIn)
+
 	// c0 <- c0 + IP(c_i, rk_i)
-	for id := range idset.Value {
+	for _, id := range idset.Slice() {
 		ck := ckSet.GetConjugationKey(id)
 		ks.ExternalProduct(level, ctOut.Value[id], ck.Value, ks.polyQPool[0])
 		ringQ.AddLvl(level, ctOut.Value["0"], ks.polyQPool[0], ctOut.Value["0"])
@@ -431,47 +445,534 @@ func (ks *KeySwitcher) Conjugate(ctIn *Ciphertext, ckSet *ConjugationKeySet, ctO
 
 	// c_i <- IP(c_i, a)
 	a := params.CRS[-2]
-	for id := range idset.Value {
+	for _, id := range idset.Slice() {
 		ks.ExternalProduct(level, ctOut.Value[id], a, ks.polyQPool[0])
 		ctOut.Value[id].Copy(ks.polyQPool[0])
 	}
 }
 
-// Conjugate conjugate ctIn with ctOut with ConjugationKeySet and returns the result in ctOut.
-// Input ciphertext should be in NTT form
+// KS switches every component of ctIn but c0 with the pair (swk, swkhead) and returns the result in ctOut.
+// The level of the output is ctOut.Level(), and ctIn must be at this level or above.
+// ctIn is left unchanged unless ctOut is ctIn, in which case the switch is done in place.
 func (ks *KeySwitcher) KS(ctIn *Ciphertext, swk *SWK, swkhead *SWK, ctOut *Ciphertext) {
 	level := ctOut.Level()
 	idset := ctIn.IDSet()
 	params := ks.Parameters
 	ringQ := params.RingQ()
-	// galEl := params.GaloisElementForRowRotation()
 
-	// check ctIn level
-	if ctIn.Level() < level {
-		panic("Cannot KS: ctIn and ctOut have different levels")
+	if err := ks.CheckKS(ctIn, swk, swkhead, level); err != nil {
+		panic(err)
 	}
 
-	// permute ctIn and put it to ctOut
-	for id := range ctIn.Value {
-		ctOut.Value[id] = ctIn.Value[id]
+	c0 := ks.polyQPool[1]
+	ring.CopyLvl(level, ctIn.Value["0"], c0)
+
+	// c0 <- c0 + IP(c_i, swk)
+	for _, id := range idset.Slice() {
+		ks.ExternalProduct(level, ctIn.Value[id], swk.Value, ks.polyQPool[0])
+		ringQ.AddLvl(level, c0, ks.polyQPool[0], c0)
 	}
 
-	// var ksk *SwitchingKey
-	// var a *SwitchingKey
-	ksk := swk
-	a := swkhead
-	// fmt.Print("a! = ", ctIn.Value["0"].Coeffs[0][0], "\n")
-	// c0 <- c0 + IP(c_i, rk_i)
-	for id := range idset.Value {
-		ks.ExternalProduct(level, ctOut.Value[id], ksk.Value, ks.polyQPool[0])
-		ringQ.AddLvl(level, ctOut.Value["0"], ks.polyQPool[0], ctOut.Value["0"])
+	// c_i <- IP(c_i, swkhead)
+	for _, id := range idset.Slice() {
+		ks.ExternalProduct(level, ctIn.Value[id], swkhead.Value, ks.polyQPool[0])
+
+		if _, in := ctOut.Value[id]; !in {
+			ctOut.Value[id] = ring.NewPoly(params.N(), level+1)
+		}
+		ring.CopyLvl(level, ks.polyQPool[0], ctOut.Value[id])
 	}
-	// fmt.Print("d! = ", ctOut.Value["0"].Coeffs[0][0], "\n")
-	// c_i <- IP(c_i, a)
-	for id := range idset.Value {
-		ks.ExternalProduct(level, ctOut.Value[id], a.Value, ks.polyQPool[0])
-		ctOut.Value[id].Copy(ks.polyQPool[0])
+
+	ring.CopyLvl(level, c0, ctOut.Value["0"])
+
+	// the switched components are under the keys of swk
+	ctOut.CopyEpochs(ctIn)
+	for _, id := range idset.Slice() {
+		ctOut.SetEpoch(id, swk.Epoch)
+	}
+}
+
+// Extend switches in place every component of ct through the chain of join keys of its group in jkSet,
+// from the epoch of the component to the last epoch of the chain, see KSChain.
+// Components without join key from their epoch are left unchanged.
+// Extend can be called concurrently on the same ciphertext from KeySwitchers returned by ShallowCopy:
+// the first call switches it and the others wait for it to find it up to date, so that ct is written once.
+func (ks *KeySwitcher) Extend(ct *Ciphertext, jkSet *JoinKeySet) {
+	ct.extendMu.Lock()
+	defer ct.extendMu.Unlock()
+
+	for _, id := range ct.IDSet().Slice() {
+		if chain := jkSet.GetChain(id, ct.GetEpoch(id)); len(chain) > 0 {
+			ks.switchChain(ct, id, chain, jkSet, ct)
+		}
+	}
+}
+
+// ExtendNew is Extend leaving ct unchanged: it returns ct itself if none of its components has a join key
+// from its epoch in jkSet, and a newly created extended copy of ct otherwise. A nil jkSet extends nothing.
+func (ks *KeySwitcher) ExtendNew(ct *Ciphertext, jkSet *JoinKeySet) (ctOut *Ciphertext) {
+	ctOut = ct
+	if jkSet == nil {
+		return
+	}
+
+	ct.extendMu.Lock()
+	defer ct.extendMu.Unlock()
+
+	for _, id := range ct.IDSet().Slice() {
+		if chain := jkSet.GetChain(id, ct.GetEpoch(id)); len(chain) > 0 {
+			if ctOut == ct {
+				ctOut = ct.CopyNew()
+			}
+			ks.switchChain(ctOut, id, chain, jkSet, ctOut)
+		}
+	}
+
+	return
+}
+
+// switchChain switches the component of ctIn under id through chain, with the composition of chain cached in jkSet
+// if the chain has several keys and the component is at a level ComposeChain composes at, and with KSChain otherwise.
+func (ks *KeySwitcher) switchChain(ctIn *Ciphertext, id string, chain []*JoinKey, jkSet *JoinKeySet, ctOut *Ciphertext) {
+	if len(chain) > 1 && ctOut.Level() <= ks.maxComposedLevel() {
+		ks.KSComposed(ctIn, ks.ComposeChain(jkSet, id, ctIn.GetEpoch(id)), ctOut)
+		return
+	}
+
+	ks.KSChain(ctIn, id, chain, ctOut)
+}
+
+// maxComposedLevel returns the maximum level of the keys composed by ComposeChain,
+// which leaves as many moduli of Q above it as P has. It is negative if there is no such level.
+func (ks *KeySwitcher) maxComposedLevel() int {
+	return ks.Parameters.QCount() - ks.Parameters.PCount() - 1
+}
+
+// ComposeChain composes the chain of join keys of the group id in jkSet, from the given epoch to its last epoch,
+// into a single ComposedJoinKey, which is cached in jkSet and returned. Its i-th row is the output of KSChain on the
+// component (0, e_i), so that the key is composed by switching the heads under every following join key and summing
+// the results into the values. A cached key to an earlier epoch is only switched through the rest of the chain.
+// It returns nil if jkSet has no join key of id from the given epoch, or if Q has no more moduli than P.
+// Concurrent callers wait for a single composition.
+func (ks *KeySwitcher) ComposeChain(jkSet *JoinKeySet, id string, from int) (cjk *ComposedJoinKey) {
+	params := ks.Parameters
+	ringQ := params.RingQ()
+	levelQ := params.QCount() - 1
+
+	jkSet.composedMu.Lock()
+	defer jkSet.composedMu.Unlock()
+
+	cached, in := jkSet.getComposedKey(id, from)
+
+	epoch := from
+	if in {
+		epoch = cached.Epoch
+	}
+
+	chain := jkSet.GetChain(id, epoch)
+	if len(chain) == 0 {
+		return cached
+	}
+
+	if ks.maxComposedLevel() < 0 {
+		return nil
+	}
+
+	cjk = &ComposedJoinKey{ID: id, From: from, Epoch: chain[len(chain)-1].Value.Epoch}
+	cjk.Value = make([]*ring.Poly, ks.maxComposedLevel()+1)
+	cjk.Head = make([]*ring.Poly, ks.maxComposedLevel()+1)
+
+	idset := NewIDSet()
+	idset.Add(id)
+	ct := NewCiphertext(params, idset, levelQ)
+
+	for i := range cjk.Value {
+		if in {
+			// (Value[i], Head[i]) is an encryption of e_i * s under the group secret of the epoch of cached
+			ringQ.InvMFormLvl(levelQ, cached.Value[i], ct.Value["0"])
+			ringQ.InvNTTLvl(levelQ, ct.Value["0"], ct.Value["0"])
+			ringQ.InvMFormLvl(levelQ, cached.Head[i], ct.Value[id])
+			ringQ.InvNTTLvl(levelQ, ct.Value[id], ct.Value[id])
+		} else {
+			ct.Value["0"].Zero()
+			ct.Value[id].Zero()
+			ct.Value[id].Coeffs[i][0] = 1
+		}
+		ct.Value[id].IsNTT = false
+		ct.SetEpoch(id, epoch)
+
+		ks.KSChain(ct, id, chain, ct)
+
+		cjk.Value[i] = ringQ.NewPoly()
+		ringQ.NTTLvl(levelQ, ct.Value["0"], cjk.Value[i])
+		ringQ.MFormLvl(levelQ, cjk.Value[i], cjk.Value[i])
+
+		cjk.Head[i] = ringQ.NewPoly()
+		ringQ.NTTLvl(levelQ, ct.Value[id], cjk.Head[i])
+		ringQ.MFormLvl(levelQ, cjk.Head[i], cjk.Head[i])
+	}
+
+	if jkSet.composed == nil {
+		jkSet.composed = make(map[string]map[int]*ComposedJoinKey)
+	}
+
+	if _, in := jkSet.composed[id]; !in {
+		jkSet.composed[id] = make(map[int]*ComposedJoinKey)
+	}
+
+	jkSet.composed[id][from] = cjk
+
+	return cjk
+}
+
+// KSComposed switches the component of ctIn under cjk.ID with the composed chain cjk in a single pass,
+// and returns the result in ctOut. The other components of ctIn are copied to ctOut. ctOut can be ctIn.
+// c_id is multiplied by the product Q' of the moduli of Q above its level and decomposed modulo each modulus of its level,
+// and the inner products of the digits with the rows of cjk are divided by Q', which takes the role of P in KS.
+// The cost does not depend on the length of the chain, and c0 and c_id get a single rounding error each.
+func (ks *KeySwitcher) KSComposed(ctIn *Ciphertext, cjk *ComposedJoinKey, ctOut *Ciphertext) {
+	level := ctOut.Level()
+	params := ks.Parameters
+	ringQ := params.RingQ()
+	levelQ := params.QCount() - 1
+
+	if err := ks.CheckKSComposed(ctIn, cjk, level); err != nil {
+		panic(err)
+	}
+
+	id := cjk.ID
+	c := ctIn.Value[id]
+
+	digit := ks.polyQPool[0]
+	c0 := ks.polyQPool[1]
+	cId := ks.polyQPool[2]
+	cScaled := ks.polyQPool[3]
+
+	if c.IsNTT {
+		ringQ.InvNTTLvl(level, c, cScaled)
+	} else {
+		ring.CopyLvl(level, c, cScaled)
 	}
-	// fmt.Print("h! = ", ctOut.Value["group0"].Coeffs[0][0], "\n")
-	// fmt.Print("a, d = ", ctIn.Value["0"].Coeffs[0][0], ctOut.Value["0"].Coeffs[0][0], "\n")
+
+	// c_id <- Q' * c_id, so that sum_i [c_id]_qi * e_i = Q' * c_id modulo Q
+	qTop := ring.NewUint(1)
+	for _, qi := range ringQ.Modulus[level+1:] {
+		qTop.Mul(qTop, ring.NewUint(qi))
+	}
+	ringQ.MulScalarBigintLvl(level, cScaled, qTop, cScaled)
+
+	// c0 <- sum [c_id]_qi * Value[i], c_id <- sum [c_id]_qi * Head[i]
+	for i := 0; i <= level; i++ {
+		ks.decomposeSingleModulus(levelQ, i, cScaled, digit)
+
+		if i == 0 {
+			ringQ.MulCoeffsMontgomeryLvl(levelQ, cjk.Value[i], digit, c0)
+			ringQ.MulCoeffsMontgomeryLvl(levelQ, cjk.Head[i], digit, cId)
+		} else {
+			ringQ.MulCoeffsMontgomeryAndAddLvl(levelQ, cjk.Value[i], digit, c0)
+			ringQ.MulCoeffsMontgomeryAndAddLvl(levelQ, cjk.Head[i], digit, cId)
+		}
+	}
+
+	// divide by Q'
+	ringQ.InvNTTLvl(levelQ, c0, c0)
+	ringQ.InvNTTLvl(levelQ, cId, cId)
+	ringQ.DivRoundByLastModulusManyLvl(levelQ, levelQ-level, c0, c0, c0)
+	ringQ.DivRoundByLastModulusManyLvl(levelQ, levelQ-level, cId, cId, cId)
+
+	ringQ.AddLvl(level, ctIn.Value["0"], c0, c0)
+
+	for _, i := range ctIn.IDs() {
+		if i == "0" || i == id {
+			continue
+		}
+
+		if _, in := ctOut.Value[i]; !in {
+			ctOut.Value[i] = ring.NewPoly(params.N(), level+1)
+		}
+		ring.CopyLvl(level, ctIn.Value[i], ctOut.Value[i])
+	}
+
+	if _, in := ctOut.Value[id]; !in {
+		ctOut.Value[id] = ring.NewPoly(params.N(), level+1)
+	}
+
+	ring.CopyLvl(level, c0, ctOut.Value["0"])
+	ring.CopyLvl(level, cId, ctOut.Value[id])
+	ctOut.Value[id].IsNTT = false
+
+	ctOut.CopyEpochs(ctIn)
+	ctOut.SetEpoch(id, cjk.Epoch)
+}
+
+// decomposeSingleModulus sets digit to the i-th CRT digit of c, centered and extended to the moduli of Q up to levelQ, in the NTT domain
+func (ks *KeySwitcher) decomposeSingleModulus(levelQ, i int, c, digit *ring.Poly) {
+	ringQ := ks.Parameters.RingQ()
+	qi := ringQ.Modulus[i]
+
+	for j := 0; j <= levelQ; j++ {
+		qj := ringQ.Modulus[j]
+
+		if j == i {
+			copy(digit.Coeffs[j], c.Coeffs[i])
+			continue
+		}
+
+		for k, x := range c.Coeffs[i] {
+			if x > qi>>1 {
+				if x = (qi - x) % qj; x != 0 {
+					x = qj - x
+				}
+			} else {
+				x %= qj
+			}
+			digit.Coeffs[j][k] = x
+		}
+	}
+
+	ringQ.NTTLvl(levelQ, digit, digit)
+}
+
+// KSChain switches the component of ctIn under id through the join keys of chain in one pass,
+// and returns the result in ctOut. The other components of ctIn are copied to ctOut. ctOut can be ctIn.
+// The chain must start at the epoch of the component, and every key at the epoch of the previous one.
+// The keys are not composed: the cost is O(k) for a chain of k keys, as c_id is decomposed, multiplied
+// and divided by P once per key. Only the contributions to c0 are accumulated modulo QP and divided by P once,
+// so that c0 gets a single rounding error, whereas c_id gets k of them, which are multiplied by the group
+// secret at decryption, on top of the error of the k keys. See ComposeChain and KSComposed for a single key-switch.
+func (ks *KeySwitcher) KSChain(ctIn *Ciphertext, id string, chain []*JoinKey, ctOut *Ciphertext) {
+	level := ctOut.Level()
+	params := ks.Parameters
+	ringQ := params.RingQ()
+	ringP := params.RingP()
+	ringQP := params.RingQP()
+
+	if err := ks.CheckKSChain(ctIn, id, chain, level); err != nil {
+		panic(err)
+	}
+
+	c := ctIn.Value[id]
+	epoch := chain[len(chain)-1].Value.Epoch
+
+	alpha := params.Alpha()
+	levelP := params.PCount() - 1
+	beta := params.Beta(level)
+
+	c0 := ks.polyQPool[1]
+	cId := ks.polyQPool[2]
+
+	if c.IsNTT {
+		ringQ.InvNTTLvl(level, c, cId)
+	} else {
+		ring.CopyLvl(level, c, cId)
+	}
+
+	cDecompQP := ks.Pool[0]
+	c0QP := ks.Pool[2]
+	cIdQP := ks.Pool[3]
+
+	// c0QP <- sum IP(c_id, jk) over the chain, c_id <- IP(c_id, jkhead) for each key of the chain
+	for k, jk := range chain {
+		for i := 0; i < beta; i++ {
+			ks.DecomposeSingleNTT(level, levelP, alpha, i, params.Gamma(), cId, cDecompQP.Q, cDecompQP.P)
+
+			if k == 0 && i == 0 {
+				ringQP.MulCoeffsMontgomeryLvl(level, levelP, jk.Value.Value.Value[i], cDecompQP, c0QP)
+			} else {
+				ringQP.MulCoeffsMontgomeryAndAddLvl(level, levelP, jk.Value.Value.Value[i], cDecompQP, c0QP)
+			}
+
+			if i == 0 {
+				ringQP.MulCoeffsMontgomeryLvl(level, levelP, jk.Head.Value.Value[i], cDecompQP, cIdQP)
+			} else {
+				ringQP.MulCoeffsMontgomeryAndAddLvl(level, levelP, jk.Head.Value.Value[i], cDecompQP, cIdQP)
+			}
+		}
+
+		ringQ.InvNTTLazyLvl(level, cIdQP.Q, cIdQP.Q)
+		ringP.InvNTTLazyLvl(levelP, cIdQP.P, cIdQP.P)
+		ks.Baseconverter.ModDownQPtoQ(level, levelP, cIdQP.Q, cIdQP.P, cId)
+	}
+
+	ringQ.InvNTTLazyLvl(level, c0QP.Q, c0QP.Q)
+	ringP.InvNTTLazyLvl(levelP, c0QP.P, c0QP.P)
+	ks.Baseconverter.ModDownQPtoQ(level, levelP, c0QP.Q, c0QP.P, c0)
+	ringQ.AddLvl(level, ctIn.Value["0"], c0, c0)
+
+	for _, i := range ctIn.IDs() {
+		if i == "0" || i == id {
+			continue
+		}
+
+		if _, in := ctOut.Value[i]; !in {
+			ctOut.Value[i] = ring.NewPoly(params.N(), level+1)
+		}
+		ring.CopyLvl(level, ctIn.Value[i], ctOut.Value[i])
+	}
+
+	if _, in := ctOut.Value[id]; !in {
+		ctOut.Value[id] = ring.NewPoly(params.N(), level+1)
+	}
+
+	ring.CopyLvl(level, c0, ctOut.Value["0"])
+	ring.CopyLvl(level, cId, ctOut.Value[id])
+	ctOut.Value[id].IsNTT = false
+
+	ctOut.CopyEpochs(ctIn)
+	ctOut.SetEpoch(id, epoch)
+}
+
+// MergeKS folds the components of ctIn under id0 and id1 into a single component under id0,
+// using the pairs (swk0, swkhead0) and (swk1, swkhead1) returned by Group.Merge for the groups id0 and id1.
+// The other components of ctIn are copied to ctOut. ctOut can be ctIn.
+func (ks *KeySwitcher) MergeKS(ctIn *Ciphertext, id0, id1 string, swk0, swkhead0, swk1, swkhead1 *SWK, ctOut *Ciphertext) {
+	level := ctOut.Level()
+	params := ks.Parameters
+	ringQ := params.RingQ()
+
+	if err := ks.CheckMergeKS(ctIn, id0, id1, swk0, swkhead0, swk1, swkhead1, level); err != nil {
+		panic(err)
+	}
+
+	c0 := ks.polyQPool[1]
+	cId := ks.polyQPool[2]
+
+	ring.CopyLvl(level, ctIn.Value["0"], c0)
+	cId.Zero()
+
+	swks := []*SWK{swk0, swk1}
+	swkheads := []*SWK{swkhead0, swkhead1}
+
+	// c0 <- c0 + IP(c_id, swk), c_id0 <- sum IP(c_id, swkhead)
+	for k, id := range []string{id0, id1} {
+		c, in := ctIn.Value[id]
+		if !in {
+			continue
+		}
+
+		ks.ExternalProduct(level, c, swks[k].Value, ks.polyQPool[0])
+		ringQ.AddLvl(level, c0, ks.polyQPool[0], c0)
+
+		ks.ExternalProduct(level, c, swkheads[k].Value, ks.polyQPool[0])
+		ringQ.AddLvl(level, cId, ks.polyQPool[0], cId)
+	}
+
+	for _, id := range ctIn.IDs() {
+		if id == "0" || id == id0 || id == id1 {
+			continue
+		}
+
+		if _, in := ctOut.Value[id]; !in {
+			ctOut.Value[id] = ring.NewPoly(params.N(), level+1)
+		}
+		ring.CopyLvl(level, ctIn.Value[id], ctOut.Value[id])
+	}
+
+	if _, in := ctOut.Value[id0]; !in {
+		ctOut.Value[id0] = ring.NewPoly(params.N(), level+1)
+	}
+
+	ring.CopyLvl(level, c0, ctOut.Value["0"])
+	ring.CopyLvl(level, cId, ctOut.Value[id0])
+	delete(ctOut.Value, id1)
+
+	ctOut.CopyEpochs(ctIn)
+	ctOut.SetEpoch(id0, swk0.Epoch)
+	delete(ctOut.Epoch, id1)
+}
+
+// Split carries the component of ctIn under the group id over to its two child groups id0 and id1.
+// Since the group secret is the sum of the child group secrets, no key-switching is required.
+// The other components of ctIn are copied to ctOut. ctOut can be ctIn.
+func (ks *KeySwitcher) Split(ctIn *Ciphertext, id, id0, id1 string, ctOut *Ciphertext) {
+	level := ctOut.Level()
+	params := ks.Parameters
+	ringQ := params.RingQ()
+
+	if err := ks.CheckSplit(ctIn, id, id0, id1, level); err != nil {
+		panic(err)
+	}
+
+	c := ctIn.Value[id]
+
+	for _, idIn := range ctIn.IDs() {
+		if idIn == id || idIn == id0 || idIn == id1 {
+			continue
+		}
+
+		if _, in := ctOut.Value[idIn]; !in {
+			ctOut.Value[idIn] = ring.NewPoly(params.N(), level+1)
+		}
+		ring.CopyLvl(level, ctIn.Value[idIn], ctOut.Value[idIn])
+	}
+
+	// c_id0 <- c_id0 + c_id, c_id1 <- c_id1 + c_id
+	for _, idOut := range []string{id0, id1} {
+		if _, in := ctOut.Value[idOut]; !in {
+			ctOut.Value[idOut] = ring.NewPoly(params.N(), level+1)
+		}
+
+		if cOut, in := ctIn.Value[idOut]; in {
+			ringQ.AddLvl(level, cOut, c, ctOut.Value[idOut])
+		} else {
+			ring.CopyLvl(level, c, ctOut.Value[idOut])
+		}
+	}
+
+	delete(ctOut.Value, id)
+
+	ctOut.CopyEpochs(ctIn)
+	delete(ctOut.Epoch, id)
+}
+
+// SplitKS carries the component of ctIn under the group id over to its child group id0 alone.
+// (swk, swkhead) is the pair returned by Group.SplitSWK, the sum of the SWK pairs generated by the members of the other child group
+// against the public key of id0. The other components of ctIn are copied to ctOut. ctOut can be ctIn.
+func (ks *KeySwitcher) SplitKS(ctIn *Ciphertext, id, id0 string, swk, swkhead *SWK, ctOut *Ciphertext) {
+	level := ctOut.Level()
+	params := ks.Parameters
+	ringQ := params.RingQ()
+
+	if err := ks.CheckSplitKS(ctIn, id, id0, swk, swkhead, level); err != nil {
+		panic(err)
+	}
+
+	c := ctIn.Value[id]
+
+	c0 := ks.polyQPool[1]
+	cId := ks.polyQPool[2]
+
+	// c0 <- c0 + IP(c_id, swk), c_id0 <- c_id0 + c_id + IP(c_id, swkhead)
+	ks.ExternalProduct(level, c, swk.Value, ks.polyQPool[0])
+	ringQ.AddLvl(level, ctIn.Value["0"], ks.polyQPool[0], c0)
+
+	ks.ExternalProduct(level, c, swkhead.Value, ks.polyQPool[0])
+	ringQ.AddLvl(level, c, ks.polyQPool[0], cId)
+
+	if cOut, in := ctIn.Value[id0]; in {
+		ringQ.AddLvl(level, cOut, cId, cId)
+	}
+
+	for _, idIn := range ctIn.IDs() {
+		if idIn == "0" || idIn == id || idIn == id0 {
+			continue
+		}
+
+		if _, in := ctOut.Value[idIn]; !in {
+			ctOut.Value[idIn] = ring.NewPoly(params.N(), level+1)
+		}
+		ring.CopyLvl(level, ctIn.Value[idIn], ctOut.Value[idIn])
+	}
+
+	if _, in := ctOut.Value[id0]; !in {
+		ctOut.Value[id0] = ring.NewPoly(params.N(), level+1)
+	}
+
+	ring.CopyLvl(level, c0, ctOut.Value["0"])
+	ring.CopyLvl(level, cId, ctOut.Value[id0])
+	delete(ctOut.Value, id)
+
+	ctOut.CopyEpochs(ctIn)
+	ctOut.SetEpoch(id0, swk.Epoch)
+	delete(ctOut.Epoch, id)
 }
@@ -45,13 +45,11 @@ func (ks *KeySwitcher) MulAndRelinHoisted(op0, op1 *Ciphertext, op0Hoisted, op1H
 
 	level := ctOut.Level()
 
-	if op0.Level() < level {
-		panic("Cannot MulAndRelin: op0 and op1 have different levels")
+	if err := ks.CheckMulAndRelinHoisted(op0, op1, op0Hoisted, op1Hoisted, rlkSet, level); err != nil {
+		panic(err)
 	}
 
-	if ctOut.Level() < level {
-		panic("Cannot MulAndRelin: op0 and ctOut have different levels")
-	}
+	ctOut.MergeEpochs(op0, op1)
 
 	idset0 := op0.IDSet()
 	idset1 := op1.IDSet()
@@ -76,7 +74,7 @@ func (ks *KeySwitcher) MulAndRelinHoisted(op0, op1 *Ciphertext, op0Hoisted, op1H
 	}
 
 	//gen x vector
-	for id := range idset0.Value {
+	for _, id := range idset0.Slice() {
 		if op0Hoisted == nil {
 			ks.Decompose(level, op0.Value[id], ks.swkPool3)
 			d := rlkSet.Value[id].Value[1]
@@ -96,7 +94,7 @@ func (ks *KeySwitcher) MulAndRelinHoisted(op0, op1 *Ciphertext, op0Hoisted, op1H
 	}
 
 	//gen y vector
-	for id := range idset1.Value {
+	for _, id := range idset1.Slice() {
 		if op1Hoisted == nil {
 			ks.Decompose(level, op1.Value[id], ks.swkPool3)
 			b := rlkSet.Value[id].Value[0]
@@ -125,12 +123,12 @@ func (ks *KeySwitcher) MulAndRelinHoisted(op0, op1 *Ciphertext, op0Hoisted, op1H
 
 	//ctOut_j <- op0_0 * op1_j + op0_j * op1_0
 	ringQ.MFormLvl(level, ks.polyQPool[1], ks.polyQPool[1])
-	for id := range idset0.Value {
+	for _, id := range idset0.Slice() {
 		ringQ.NTTLvl(level, op0.Value[id], ks.polyQPool[2])
 		ringQ.MulCoeffsMontgomeryLvl(level, ks.polyQPool[1], ks.polyQPool[2], ctOut.Value[id])
 	}
 
-	for id := range idset1.Value {
+	for _, id := range idset1.Slice() {
 		ringQ.NTTLvl(level, op1.Value[id], ks.polyQPool[2])
 		if idset0.Has(id) {
 			ringQ.MulCoeffsMontgomeryAndAddLvl(level, ks.polyQPool[0], ks.polyQPool[2], ctOut.Value[id])
@@ -139,12 +137,12 @@ func (ks *KeySwitcher) MulAndRelinHoisted(op0, op1 *Ciphertext, op0Hoisted, op1H
 		}
 	}
 
-	for id := range ctOut.Value {
+	for _, id := range ctOut.IDs() {
 		ringQ.InvNTTLvl(level, ctOut.Value[id], ctOut.Value[id])
 	}
 
 	//ctOut_j <- ctOut_j +  Ext(op1_j, x)
-	for id := range idset1.Value {
+	for _, id := range idset1.Slice() {
 		if op1Hoisted == nil {
 			ks.ExternalProduct(level, op1.Value[id], x, ks.polyQPool[0])
 		} else {
@@ -158,7 +156,7 @@ func (ks *KeySwitcher) MulAndRelinHoisted(op0, op1 *Ciphertext, op0Hoisted, op1H
 
 	u := params.CRS[-1]
 
-	for id := range idset0.Value {
+	for _, id := range idset0.Slice() {
 
 		v := rlkSet.Value[id].Value[2]
 
@@ -182,13 +180,11 @@ func (ks *KeySwitcher) PrevMulAndRelinHoisted(op0, op1 *Ciphertext, rlkSet *Reli
 
 	level := ctOut.Level()
 
-	if op0.Level() < level {
-		panic("Cannot MulAndRelin: op0 and op1 have different levels")
+	if err := ks.CheckMulAndRelin(op0, op1, rlkSet, level); err != nil {
+		panic(err)
 	}
 
-	if ctOut.Level() < level {
-		panic("Cannot MulAndRelin: op0 and ctOut have different levels")
-	}
+	ctOut.MergeEpochs(op0, op1)
 
 	idset0 := op0.IDSet()
 	idset1 := op1.IDSet()
@@ -205,12 +201,12 @@ func (ks *KeySwitcher) PrevMulAndRelinHoisted(op0, op1 *Ciphertext, rlkSet *Reli
 	ringQ.MFormLvl(level, ks.polyQPool[1], ks.polyQPool[1])
 
 	//ctOut_j <- op0_0 * op1_j + op0_j * op1_0
-	for id := range idset0.Value { // id: j
+	for _, id := range idset0.Slice() { // id: j
 		ringQ.NTTLvl(level, op0.Value[id], ks.polyQPool[2]) //pool[2]: op0_j
 		ringQ.MulCoeffsMontgomeryLvl(level, ks.polyQPool[1], ks.polyQPool[2], ctOut.Value[id])
 	}
 
-	for id := range idset1.Value { //id:j
+	for _, id := range idset1.Slice() { //id:j
 		ringQ.NTTLvl(level, op1.Value[id], ks.polyQPool[2]) //pool[2]: op1_j
 		if idset0.Has(id) {
 			ringQ.MulCoeffsMontgomeryAndAddLvl(level, ks.polyQPool[0], ks.polyQPool[2], ctOut.Value[id])
@@ -219,15 +215,15 @@ func (ks *KeySwitcher) PrevMulAndRelinHoisted(op0, op1 *Ciphertext, rlkSet *Reli
 		}
 	}
 
-	for id := range ctOut.Value {
+	for _, id := range ctOut.IDs() {
 		ringQ.InvNTTLvl(level, ctOut.Value[id], ctOut.Value[id])
 	}
 
 	// c_i,j : pool[2]
 	// x_i: pool[3]
-	for id0 := range idset0.Value { // id0: i
+	for _, id0 := range idset0.Slice() { // id0: i
 		ks.polyQPool[3].Zero()
-		for id1 := range idset1.Value { // id1: j
+		for _, id1 := range idset1.Slice() { // id1: j
 			if id0 > id1 {
 				if idset1.Has(id0) {
 					continue
@@ -320,9 +316,8 @@ func (ks *KeySwitcher) RotateHoisted(ctIn *Ciphertext, rotidx int, ctInHoisted *
 	params := ks.Parameters
 	ringQ := params.RingQ()
 
-	// check ctIn level
-	if ctIn.Level() < level {
-		panic("Cannot Rotate: ctIn and ctOut have different levels")
+	if err := ks.CheckRotateHoisted(ctIn, rotidx, ctInHoisted, rkSet, level); err != nil {
+		panic(err)
 	}
 
 	// adjust rotidx
@@ -337,7 +332,7 @@ func (ks *KeySwitcher) RotateHoisted(ctIn *Ciphertext, rotidx int, ctInHoisted *
 
 	ctOut.Value["0"].Copy(ctIn.Value["0"])
 
-	for id := range idset.Value {
+	for _, id := range idset.Slice() {
 		rk := rkSet.GetRotationKey(id, uint(rotidx))
 		ks.ExternalProductHoisted(level, ctInHoisted.Value[id], rk.Value, ks.polyQPool[0])
 		ringQ.AddLvl(level, ctOut.Value["0"], ks.polyQPool[0], ctOut.Value["0"])
@@ -347,7 +342,7 @@ func (ks *KeySwitcher) RotateHoisted(ctIn *Ciphertext, rotidx int, ctInHoisted *
 
 	// permute ctOut
 	galEl := params.GaloisElementForColumnRotationBy(rotidx)
-	for id := range ctIn.Value {
+	for _, id := range ctIn.IDs() {
 
 		var mask, index, indexRaw, logN, tmp uint64
 
@@ -377,4 +372,5 @@ func (ks *KeySwitcher) RotateHoisted(ctIn *Ciphertext, rotidx int, ctInHoisted *
 
 	}
 
+	ctOut.CopyEpochs(ctIn)
 }
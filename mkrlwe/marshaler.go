@@ -0,0 +1,1031 @@
+package mkrlwe
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+
+	"github.com/ldsec/lattigo/v2/ring"
+	"github.com/ldsec/lattigo/v2/rlwe"
+)
+
+// GetDataLen returns the length in bytes of the target Ciphertext.
+func (el *Ciphertext) GetDataLen(WithMetaData bool) (dataLen int) {
+	// MetaData is :
+	// 1 byte : level
+	// 1 byte : isNTT
+	// 2 byte : number of components
+	// per component, 1 byte + len(id) : id and 4 byte : epoch
+	if WithMetaData {
+		dataLen += 4
+		for id := range el.Value {
+			dataLen += 5 + len(id)
+		}
+	}
+
+	for _, poly := range el.Value {
+		dataLen += poly.GetDataLen(WithMetaData)
+	}
+
+	return dataLen
+}
+
+// MarshalBinary encodes a Ciphertext on a byte slice.
+// The components are written in a stable order: "0" first and then the ids in increasing order.
+func (el *Ciphertext) MarshalBinary() (data []byte, err error) {
+	return marshal(el.GetDataLen(true), el.encode)
+}
+
+// UnmarshalBinary decodes a previously marshaled Ciphertext on the target Ciphertext.
+func (el *Ciphertext) UnmarshalBinary(data []byte) (err error) {
+	return unmarshal(data, el.decode)
+}
+
+func (el *Ciphertext) encode(pointer int, data []byte) (int, error) {
+
+	if len(el.Value) > 0xffff {
+		return pointer, errors.New("too many components to encode Ciphertext")
+	}
+
+	data[pointer] = uint8(el.Level())
+	if el.Value["0"].IsNTT {
+		data[pointer+1] = 1
+	}
+	binary.BigEndian.PutUint16(data[pointer+2:pointer+4], uint16(len(el.Value)))
+	pointer += 4
+
+	var err error
+	for _, id := range el.IDs() {
+		if pointer, err = encodeID(pointer, data, id); err != nil {
+			return pointer, err
+		}
+
+		binary.BigEndian.PutUint32(data[pointer:pointer+4], uint32(el.GetEpoch(id)))
+		pointer += 4
+
+		if pointer, err = encodePoly(pointer, data, el.Value[id]); err != nil {
+			return pointer, err
+		}
+	}
+
+	return pointer, nil
+}
+
+func (el *Ciphertext) decode(pointer int, data []byte) (ptr int, err error) {
+
+	if len(data) < pointer+4 {
+		return pointer, errors.New("too small bytearray")
+	}
+
+	level := int(data[pointer])
+	isNTT := data[pointer+1] == 1
+	numComponents := int(binary.BigEndian.Uint16(data[pointer+2 : pointer+4]))
+	pointer += 4
+
+	el.Value = make(map[string]*ring.Poly)
+	el.Epoch = make(map[string]int)
+
+	var id string
+	for i := 0; i < numComponents; i++ {
+		if id, pointer, err = decodeID(pointer, data); err != nil {
+			return pointer, err
+		}
+
+		if _, ok := el.Value[id]; ok {
+			return pointer, errors.New("duplicated id " + id)
+		}
+
+		if len(data) < pointer+4 {
+			return pointer, errors.New("too small bytearray")
+		}
+		epoch := int(binary.BigEndian.Uint32(data[pointer : pointer+4]))
+		pointer += 4
+
+		if el.Value[id], pointer, err = decodePoly(pointer, data); err != nil {
+			return pointer, err
+		}
+
+		if el.Value[id].Level() != level || el.Value[id].IsNTT != isNTT {
+			return pointer, errors.New("inconsistent level or NTT flag of component " + id)
+		}
+
+		if id != "0" {
+			el.SetEpoch(id, epoch)
+		}
+	}
+
+	if _, ok := el.Value["0"]; !ok {
+		return pointer, errors.New("missing component 0")
+	}
+
+	return pointer, nil
+}
+
+// GetDataLen returns the length in bytes of the target SeededCiphertext.
+func (el *SeededCiphertext) GetDataLen(WithMetaData bool) (dataLen int) {
+	if WithMetaData {
+		dataLen += headerLen(el.ID)
+	}
+
+	return dataLen + len(el.Seed) + el.Value.GetDataLen(WithMetaData)
+}
+
+// MarshalBinary encodes a SeededCiphertext on a byte slice: its id and epoch, its seed and its component "0".
+func (el *SeededCiphertext) MarshalBinary() (data []byte, err error) {
+	if len(el.Seed) != CiphertextSeedSize {
+		return nil, errors.New("cannot encode SeededCiphertext: invalid seed")
+	}
+
+	return marshal(el.GetDataLen(true), func(pointer int, data []byte) (int, error) {
+		var err error
+		if pointer, err = encodeHeader(pointer, data, el.ID, el.Epoch); err != nil {
+			return pointer, err
+		}
+
+		pointer += copy(data[pointer:], el.Seed)
+
+		return encodePoly(pointer, data, el.Value)
+	})
+}
+
+// UnmarshalBinary decodes a previously marshaled SeededCiphertext on the target SeededCiphertext.
+// The result is checked against the parameters by Expand.
+func (el *SeededCiphertext) UnmarshalBinary(data []byte) (err error) {
+	return unmarshal(data, func(pointer int, data []byte) (int, error) {
+		id, epoch, pointer, err := decodeHeader(pointer, data)
+		if err != nil {
+			return pointer, err
+		}
+
+		if len(data) < pointer+CiphertextSeedSize {
+			return pointer, errors.New("too small bytearray")
+		}
+
+		el.ID = id
+		el.Epoch = epoch
+		el.Seed = append([]byte{}, data[pointer:pointer+CiphertextSeedSize]...)
+		pointer += CiphertextSeedSize
+
+		el.Value, pointer, err = decodePoly(pointer, data)
+
+		return pointer, err
+	})
+}
+
+// encodeID writes id on data at pointer, prefixed by its length on 1 byte
+func encodeID(pointer int, data []byte, id string) (int, error) {
+	if len(id) > 0xff {
+		return pointer, errors.New("id is too long to be encoded: " + id)
+	}
+
+	data[pointer] = uint8(len(id))
+	pointer++
+	pointer += copy(data[pointer:], id)
+
+	return pointer, nil
+}
+
+// decodeID reads an id written by encodeID on data at pointer
+func decodeID(pointer int, data []byte) (string, int, error) {
+	if len(data) < pointer+1 || len(data) < pointer+1+int(data[pointer]) {
+		return "", pointer, errors.New("too small bytearray")
+	}
+
+	idLen := int(data[pointer])
+	pointer++
+
+	return string(data[pointer : pointer+idLen]), pointer + idLen, nil
+}
+
+// encodePoly writes poly with its metadata on data at pointer
+func encodePoly(pointer int, data []byte, poly *ring.Poly) (int, error) {
+	inc, err := poly.WriteTo(data[pointer : pointer+poly.GetDataLen(true)])
+	return pointer + inc, err
+}
+
+// decodePoly reads a poly written by encodePoly on data at pointer,
+// checking that data is large enough beforehand
+func decodePoly(pointer int, data []byte) (*ring.Poly, int, error) {
+	if len(data) < pointer+4 {
+		return nil, pointer, errors.New("too small bytearray")
+	}
+
+	if data[pointer] > 30 {
+		return nil, pointer, errors.New("invalid polynomial encoding")
+	}
+
+	N := 1 << data[pointer]
+	numberModuli := int(data[pointer+1])
+	if numberModuli == 0 || len(data) < pointer+4+((N*numberModuli)<<3) {
+		return nil, pointer, errors.New("too small bytearray")
+	}
+
+	poly := new(ring.Poly)
+	inc, err := poly.DecodePolyNew(data[pointer:])
+
+	return poly, pointer + inc, err
+}
+
+// headerLen returns the length in bytes of the id and epoch of a key.
+func headerLen(id string) int {
+	return 1 + len(id) + 4
+}
+
+// encodeHeader writes the id and epoch of a key on data at pointer
+func encodeHeader(pointer int, data []byte, id string, epoch int) (int, error) {
+	var err error
+	if pointer, err = encodeID(pointer, data, id); err != nil {
+		return pointer, err
+	}
+
+	binary.BigEndian.PutUint32(data[pointer:pointer+4], uint32(epoch))
+
+	return pointer + 4, nil
+}
+
+// decodeHeader reads the id and epoch of a key written by encodeHeader on data at pointer
+func decodeHeader(pointer int, data []byte) (string, int, int, error) {
+	id, pointer, err := decodeID(pointer, data)
+	if err != nil {
+		return "", 0, pointer, err
+	}
+
+	epoch, pointer, err := decodeUint32(pointer, data)
+	if err != nil {
+		return "", 0, pointer, err
+	}
+
+	return id, epoch, pointer, nil
+}
+
+// decodeUint32 reads an uint32 on data at pointer
+func decodeUint32(pointer int, data []byte) (int, int, error) {
+	if len(data) < pointer+4 {
+		return 0, pointer, errors.New("too small bytearray")
+	}
+
+	return int(binary.BigEndian.Uint32(data[pointer : pointer+4])), pointer + 4, nil
+}
+
+// polyQPDataLen returns the length in bytes of a PolyQP.
+func polyQPDataLen(p rlwe.PolyQP, WithMetadata bool) int {
+	return p.Q.GetDataLen(WithMetadata) + p.P.GetDataLen(WithMetadata)
+}
+
+// encodePolyQP writes p with its metadata on data at pointer
+func encodePolyQP(pointer int, data []byte, p rlwe.PolyQP) (int, error) {
+	var err error
+	if pointer, err = encodePoly(pointer, data, p.Q); err != nil {
+		return pointer, err
+	}
+
+	return encodePoly(pointer, data, p.P)
+}
+
+// decodePolyQP reads a PolyQP written by encodePolyQP on data at pointer
+func decodePolyQP(pointer int, data []byte) (p rlwe.PolyQP, ptr int, err error) {
+	if p.Q, pointer, err = decodePoly(pointer, data); err != nil {
+		return p, pointer, err
+	}
+
+	if p.P, pointer, err = decodePoly(pointer, data); err != nil {
+		return p, pointer, err
+	}
+
+	return p, pointer, nil
+}
+
+// marshal allocates a byte slice of length dataLen, encodes on it with encode
+// and checks that the whole slice was written.
+func marshal(dataLen int, encode func(int, []byte) (int, error)) (data []byte, err error) {
+	data = make([]byte, dataLen)
+
+	var pointer int
+	if pointer, err = encode(0, data); err != nil {
+		return nil, err
+	}
+
+	if pointer != dataLen {
+		return nil, errors.New("invalid data length")
+	}
+
+	return data, nil
+}
+
+// unmarshal decodes data with decode and checks that the whole slice was read.
+func unmarshal(data []byte, decode func(int, []byte) (int, error)) (err error) {
+	var pointer int
+	if pointer, err = decode(0, data); err != nil {
+		return err
+	}
+
+	if pointer != len(data) {
+		return errors.New("remaining unparsed data")
+	}
+
+	return nil
+}
+
+// GetDataLen returns the length in bytes of the target SwitchingKey.
+func (swk *SwitchingKey) GetDataLen(WithMetadata bool) (dataLen int) {
+	if WithMetadata {
+		dataLen++
+	}
+
+	for _, p := range swk.Value {
+		dataLen += polyQPDataLen(p, WithMetadata)
+	}
+
+	return dataLen
+}
+
+// MarshalBinary encodes a SwitchingKey on a byte slice.
+func (swk *SwitchingKey) MarshalBinary() (data []byte, err error) {
+	return marshal(swk.GetDataLen(true), swk.encode)
+}
+
+// UnmarshalBinary decodes a previously marshaled SwitchingKey on the target SwitchingKey.
+func (swk *SwitchingKey) UnmarshalBinary(data []byte) (err error) {
+	return unmarshal(data, swk.decode)
+}
+
+func (swk *SwitchingKey) encode(pointer int, data []byte) (int, error) {
+	if len(swk.Value) > 0xff {
+		return pointer, errors.New("too many polynomials to encode SwitchingKey")
+	}
+
+	data[pointer] = uint8(len(swk.Value))
+	pointer++
+
+	var err error
+	for _, p := range swk.Value {
+		if pointer, err = encodePolyQP(pointer, data, p); err != nil {
+			return pointer, err
+		}
+	}
+
+	return pointer, nil
+}
+
+func (swk *SwitchingKey) decode(pointer int, data []byte) (int, error) {
+	if len(data) < pointer+1 {
+		return pointer, errors.New("too small bytearray")
+	}
+
+	beta := int(data[pointer])
+	pointer++
+
+	swk.Value = make([]rlwe.PolyQP, beta)
+
+	var err error
+	for i := range swk.Value {
+		if swk.Value[i], pointer, err = decodePolyQP(pointer, data); err != nil {
+			return pointer, err
+		}
+	}
+
+	return pointer, nil
+}
+
+// GetDataLen returns the length in bytes of the target SecretKey.
+func (sk *SecretKey) GetDataLen(WithMetadata bool) (dataLen int) {
+	return headerLen(sk.ID) + polyQPDataLen(sk.Value, WithMetadata)
+}
+
+// MarshalBinary encodes a SecretKey on a byte slice.
+func (sk *SecretKey) MarshalBinary() (data []byte, err error) {
+	return marshal(sk.GetDataLen(true), sk.encode)
+}
+
+// UnmarshalBinary decodes a previously marshaled SecretKey on the target SecretKey.
+func (sk *SecretKey) UnmarshalBinary(data []byte) (err error) {
+	return unmarshal(data, sk.decode)
+}
+
+func (sk *SecretKey) encode(pointer int, data []byte) (int, error) {
+	var err error
+	if pointer, err = encodeHeader(pointer, data, sk.ID, sk.Epoch); err != nil {
+		return pointer, err
+	}
+
+	return encodePolyQP(pointer, data, sk.Value)
+}
+
+func (sk *SecretKey) decode(pointer int, data []byte) (ptr int, err error) {
+	if sk.ID, sk.Epoch, pointer, err = decodeHeader(pointer, data); err != nil {
+		return pointer, err
+	}
+
+	sk.Value, pointer, err = decodePolyQP(pointer, data)
+	return pointer, err
+}
+
+// GetDataLen returns the length in bytes of the target PublicKey.
+func (pk *PublicKey) GetDataLen(WithMetadata bool) (dataLen int) {
+	return headerLen(pk.ID) + polyQPDataLen(pk.Value[0], WithMetadata) + polyQPDataLen(pk.Value[1], WithMetadata)
+}
+
+// MarshalBinary encodes a PublicKey on a byte slice.
+func (pk *PublicKey) MarshalBinary() (data []byte, err error) {
+	return marshal(pk.GetDataLen(true), pk.encode)
+}
+
+// UnmarshalBinary decodes a previously marshaled PublicKey on the target PublicKey.
+func (pk *PublicKey) UnmarshalBinary(data []byte) (err error) {
+	return unmarshal(data, pk.decode)
+}
+
+func (pk *PublicKey) encode(pointer int, data []byte) (int, error) {
+	var err error
+	if pointer, err = encodeHeader(pointer, data, pk.ID, pk.Epoch); err != nil {
+		return pointer, err
+	}
+
+	for i := range pk.Value {
+		if pointer, err = encodePolyQP(pointer, data, pk.Value[i]); err != nil {
+			return pointer, err
+		}
+	}
+
+	return pointer, nil
+}
+
+func (pk *PublicKey) decode(pointer int, data []byte) (ptr int, err error) {
+	if pk.ID, pk.Epoch, pointer, err = decodeHeader(pointer, data); err != nil {
+		return pointer, err
+	}
+
+	for i := range pk.Value {
+		if pk.Value[i], pointer, err = decodePolyQP(pointer, data); err != nil {
+			return pointer, err
+		}
+	}
+
+	return pointer, nil
+}
+
+// MarshalBinaryCompressed encodes a PublicKey on a byte slice without its uniform half,
+// which is the CRS of index 0 and is expanded again from the CRS seed by the receiver.
+// The other uniform-looking halves, such as the head of a SWK, are masked by secret randomness
+// and cannot be compressed this way.
+func (pk *PublicKey) MarshalBinaryCompressed(params Parameters) (data []byte, err error) {
+	crs := params.CRS[0].Value[0]
+	if !pk.Value[1].Q.Equals(crs.Q) || !pk.Value[1].P.Equals(crs.P) {
+		return nil, errors.New("cannot compress PublicKey: uniform half is not the CRS of the parameters")
+	}
+
+	return marshal(headerLen(pk.ID)+polyQPDataLen(pk.Value[0], true), func(pointer int, data []byte) (int, error) {
+		var err error
+		if pointer, err = encodeHeader(pointer, data, pk.ID, pk.Epoch); err != nil {
+			return pointer, err
+		}
+
+		return encodePolyQP(pointer, data, pk.Value[0])
+	})
+}
+
+// UnmarshalBinaryCompressed decodes a PublicKey encoded with MarshalBinaryCompressed on the target PublicKey,
+// setting its uniform half to the CRS of index 0 of params.
+func (pk *PublicKey) UnmarshalBinaryCompressed(params Parameters, data []byte) (err error) {
+	return unmarshal(data, func(pointer int, data []byte) (int, error) {
+		var err error
+		if pk.ID, pk.Epoch, pointer, err = decodeHeader(pointer, data); err != nil {
+			return pointer, err
+		}
+
+		if pk.Value[0], pointer, err = decodePolyQP(pointer, data); err != nil {
+			return pointer, err
+		}
+
+		if pk.Value[0].Q.Level() != params.QCount()-1 || pk.Value[0].P.Level() != params.PCount()-1 {
+			return pointer, errors.New("invalid PublicKey encoding: level does not match the parameters")
+		}
+
+		pk.Value[1] = params.RingQP().NewPoly()
+		pk.Value[1].Q.Copy(params.CRS[0].Value[0].Q)
+		pk.Value[1].P.Copy(params.CRS[0].Value[0].P)
+
+		return pointer, nil
+	})
+}
+
+// GetDataLen returns the length in bytes of the target RelinearizationKey.
+func (rlk *RelinearizationKey) GetDataLen(WithMetadata bool) (dataLen int) {
+	dataLen = headerLen(rlk.ID)
+	for _, swk := range rlk.Value {
+		dataLen += swk.GetDataLen(WithMetadata)
+	}
+
+	return dataLen
+}
+
+// MarshalBinary encodes a RelinearizationKey on a byte slice.
+func (rlk *RelinearizationKey) MarshalBinary() (data []byte, err error) {
+	return marshal(rlk.GetDataLen(true), rlk.encode)
+}
+
+// UnmarshalBinary decodes a previously marshaled RelinearizationKey on the target RelinearizationKey.
+func (rlk *RelinearizationKey) UnmarshalBinary(data []byte) (err error) {
+	return unmarshal(data, rlk.decode)
+}
+
+func (rlk *RelinearizationKey) encode(pointer int, data []byte) (int, error) {
+	var err error
+	if pointer, err = encodeHeader(pointer, data, rlk.ID, rlk.Epoch); err != nil {
+		return pointer, err
+	}
+
+	for _, swk := range rlk.Value {
+		if pointer, err = swk.encode(pointer, data); err != nil {
+			return pointer, err
+		}
+	}
+
+	return pointer, nil
+}
+
+func (rlk *RelinearizationKey) decode(pointer int, data []byte) (ptr int, err error) {
+	if rlk.ID, rlk.Epoch, pointer, err = decodeHeader(pointer, data); err != nil {
+		return pointer, err
+	}
+
+	for i := range rlk.Value {
+		rlk.Value[i] = new(SwitchingKey)
+		if pointer, err = rlk.Value[i].decode(pointer, data); err != nil {
+			return pointer, err
+		}
+	}
+
+	return pointer, nil
+}
+
+// GetDataLen returns the length in bytes of the target RotationKey.
+func (rtk *RotationKey) GetDataLen(WithMetadata bool) (dataLen int) {
+	return headerLen(rtk.ID) + 4 + rtk.Value.GetDataLen(WithMetadata)
+}
+
+// MarshalBinary encodes a RotationKey on a byte slice.
+func (rtk *RotationKey) MarshalBinary() (data []byte, err error) {
+	return marshal(rtk.GetDataLen(true), rtk.encode)
+}
+
+// UnmarshalBinary decodes a previously marshaled RotationKey on the target RotationKey.
+func (rtk *RotationKey) UnmarshalBinary(data []byte) (err error) {
+	return unmarshal(data, rtk.decode)
+}
+
+func (rtk *RotationKey) encode(pointer int, data []byte) (int, error) {
+	var err error
+	if pointer, err = encodeHeader(pointer, data, rtk.ID, rtk.Epoch); err != nil {
+		return pointer, err
+	}
+
+	binary.BigEndian.PutUint32(data[pointer:pointer+4], uint32(rtk.RotIdx))
+	pointer += 4
+
+	return rtk.Value.encode(pointer, data)
+}
+
+func (rtk *RotationKey) decode(pointer int, data []byte) (ptr int, err error) {
+	if rtk.ID, rtk.Epoch, pointer, err = decodeHeader(pointer, data); err != nil {
+		return pointer, err
+	}
+
+	var rotidx int
+	if rotidx, pointer, err = decodeUint32(pointer, data); err != nil {
+		return pointer, err
+	}
+	rtk.RotIdx = uint(rotidx)
+
+	rtk.Value = new(SwitchingKey)
+	return rtk.Value.decode(pointer, data)
+}
+
+// GetDataLen returns the length in bytes of the target ConjugationKey.
+func (cjk *ConjugationKey) GetDataLen(WithMetadata bool) (dataLen int) {
+	return headerLen(cjk.ID) + cjk.Value.GetDataLen(WithMetadata)
+}
+
+// MarshalBinary encodes a ConjugationKey on a byte slice.
+func (cjk *ConjugationKey) MarshalBinary() (data []byte, err error) {
+	return marshal(cjk.GetDataLen(true), cjk.encode)
+}
+
+// UnmarshalBinary decodes a previously marshaled ConjugationKey on the target ConjugationKey.
+func (cjk *ConjugationKey) UnmarshalBinary(data []byte) (err error) {
+	return unmarshal(data, cjk.decode)
+}
+
+func (cjk *ConjugationKey) encode(pointer int, data []byte) (int, error) {
+	var err error
+	if pointer, err = encodeHeader(pointer, data, cjk.ID, cjk.Epoch); err != nil {
+		return pointer, err
+	}
+
+	return cjk.Value.encode(pointer, data)
+}
+
+func (cjk *ConjugationKey) decode(pointer int, data []byte) (ptr int, err error) {
+	if cjk.ID, cjk.Epoch, pointer, err = decodeHeader(pointer, data); err != nil {
+		return pointer, err
+	}
+
+	cjk.Value = new(SwitchingKey)
+	return cjk.Value.decode(pointer, data)
+}
+
+// GetDataLen returns the length in bytes of the target SWK.
+func (swk *SWK) GetDataLen(WithMetadata bool) (dataLen int) {
+	return headerLen(swk.ID) + 4 + swk.Value.GetDataLen(WithMetadata)
+}
+
+// MarshalBinary encodes a SWK on a byte slice.
+func (swk *SWK) MarshalBinary() (data []byte, err error) {
+	return marshal(swk.GetDataLen(true), swk.encode)
+}
+
+// UnmarshalBinary decodes a previously marshaled SWK on the target SWK.
+func (swk *SWK) UnmarshalBinary(data []byte) (err error) {
+	return unmarshal(data, swk.decode)
+}
+
+func (swk *SWK) encode(pointer int, data []byte) (int, error) {
+	var err error
+	if pointer, err = encodeHeader(pointer, data, swk.ID, swk.Epoch); err != nil {
+		return pointer, err
+	}
+
+	binary.BigEndian.PutUint32(data[pointer:pointer+4], uint32(swk.From))
+	pointer += 4
+
+	return swk.Value.encode(pointer, data)
+}
+
+func (swk *SWK) decode(pointer int, data []byte) (ptr int, err error) {
+	if swk.ID, swk.Epoch, pointer, err = decodeHeader(pointer, data); err != nil {
+		return pointer, err
+	}
+
+	if swk.From, pointer, err = decodeUint32(pointer, data); err != nil {
+		return pointer, err
+	}
+
+	swk.Value = new(SwitchingKey)
+	return swk.Value.decode(pointer, data)
+}
+
+// GetDataLen returns the length in bytes of the target SeededSWK.
+func (el *SeededSWK) GetDataLen(WithMetadata bool) (dataLen int) {
+	return headerLen(el.ID) + 4 + len(el.Seed)
+}
+
+// MarshalBinary encodes a SeededSWK on a byte slice: its id and epochs, and its seed.
+func (el *SeededSWK) MarshalBinary() (data []byte, err error) {
+	if len(el.Seed) != SWKSeedSize {
+		return nil, errors.New("cannot encode SeededSWK: invalid seed")
+	}
+
+	return marshal(el.GetDataLen(true), func(pointer int, data []byte) (int, error) {
+		var err error
+		if pointer, err = encodeHeader(pointer, data, el.ID, el.Epoch); err != nil {
+			return pointer, err
+		}
+
+		binary.BigEndian.PutUint32(data[pointer:pointer+4], uint32(el.From))
+		pointer += 4
+
+		return pointer + copy(data[pointer:], el.Seed), nil
+	})
+}
+
+// UnmarshalBinary decodes a previously marshaled SeededSWK on the target SeededSWK.
+// The result is checked by Expand.
+func (el *SeededSWK) UnmarshalBinary(data []byte) (err error) {
+	return unmarshal(data, func(pointer int, data []byte) (int, error) {
+		id, epoch, pointer, err := decodeHeader(pointer, data)
+		if err != nil {
+			return pointer, err
+		}
+
+		from, pointer, err := decodeUint32(pointer, data)
+		if err != nil {
+			return pointer, err
+		}
+
+		if len(data) < pointer+SWKSeedSize {
+			return pointer, errors.New("too small bytearray")
+		}
+
+		el.ID = id
+		el.Epoch = epoch
+		el.From = from
+		el.Seed = append([]byte{}, data[pointer:pointer+SWKSeedSize]...)
+
+		return pointer + SWKSeedSize, nil
+	})
+}
+
+// GetDataLen returns the length in bytes of the target JoinKey.
+func (jk *JoinKey) GetDataLen(WithMetadata bool) (dataLen int) {
+	return 4 + jk.Value.GetDataLen(WithMetadata) + jk.Head.GetDataLen(WithMetadata)
+}
+
+// MarshalBinary encodes a JoinKey on a byte slice.
+func (jk *JoinKey) MarshalBinary() (data []byte, err error) {
+	return marshal(jk.GetDataLen(true), jk.encode)
+}
+
+// UnmarshalBinary decodes a previously marshaled JoinKey on the target JoinKey.
+func (jk *JoinKey) UnmarshalBinary(data []byte) (err error) {
+	return unmarshal(data, jk.decode)
+}
+
+func (jk *JoinKey) encode(pointer int, data []byte) (int, error) {
+	binary.BigEndian.PutUint32(data[pointer:pointer+4], uint32(jk.From))
+	pointer += 4
+
+	var err error
+	if pointer, err = jk.Value.encode(pointer, data); err != nil {
+		return pointer, err
+	}
+
+	return jk.Head.encode(pointer, data)
+}
+
+func (jk *JoinKey) decode(pointer int, data []byte) (ptr int, err error) {
+	if jk.From, pointer, err = decodeUint32(pointer, data); err != nil {
+		return pointer, err
+	}
+
+	jk.Value = new(SWK)
+	if pointer, err = jk.Value.decode(pointer, data); err != nil {
+		return pointer, err
+	}
+
+	jk.Head = new(SWK)
+	return jk.Head.decode(pointer, data)
+}
+
+// keySetDataLen returns the length in bytes of a key set with the given keys.
+func keySetDataLen(keys []keyEncoder) (dataLen int) {
+	dataLen = 4
+	for _, key := range keys {
+		dataLen += key.GetDataLen(true)
+	}
+
+	return dataLen
+}
+
+// keyEncoder is the interface of the keys stored in the key sets.
+type keyEncoder interface {
+	GetDataLen(WithMetadata bool) int
+	encode(pointer int, data []byte) (int, error)
+}
+
+// marshalKeySet encodes the number of keys followed by the keys, in the given order.
+func marshalKeySet(keys []keyEncoder) (data []byte, err error) {
+	return marshal(keySetDataLen(keys), func(pointer int, data []byte) (int, error) {
+		binary.BigEndian.PutUint32(data[pointer:pointer+4], uint32(len(keys)))
+		pointer += 4
+
+		var err error
+		for _, key := range keys {
+			if pointer, err = key.encode(pointer, data); err != nil {
+				return pointer, err
+			}
+		}
+
+		return pointer, nil
+	})
+}
+
+// marshalKeySetByID encodes a key set with the ids of idset, in increasing order,
+// where keysOf returns the keys of each id in the order they are encoded.
+func marshalKeySetByID(idset *IDSet, keysOf func(id string) []keyEncoder) (data []byte, err error) {
+	keys := make([]keyEncoder, 0, idset.Size())
+	for _, id := range idset.Slice() {
+		keys = append(keys, keysOf(id)...)
+	}
+
+	return marshalKeySet(keys)
+}
+
+// unmarshalKeySet decodes the number of keys of a key set and calls decodeKey for each of them.
+func unmarshalKeySet(data []byte, decodeKey func(int, []byte) (int, error)) (err error) {
+	return unmarshal(data, func(pointer int, data []byte) (int, error) {
+		var numKeys int
+		var err error
+		if numKeys, pointer, err = decodeUint32(pointer, data); err != nil {
+			return pointer, err
+		}
+
+		for i := 0; i < numKeys; i++ {
+			if pointer, err = decodeKey(pointer, data); err != nil {
+				return pointer, err
+			}
+		}
+
+		return pointer, nil
+	})
+}
+
+// MarshalBinary encodes a SecretKeySet on a byte slice, with the keys in increasing order of id.
+func (skSet *SecretKeySet) MarshalBinary() (data []byte, err error) {
+	return marshalKeySetByID(skSet.IDSet(), func(id string) []keyEncoder {
+		return []keyEncoder{skSet.Value[id]}
+	})
+}
+
+// UnmarshalBinary decodes a previously marshaled SecretKeySet on the target SecretKeySet.
+func (skSet *SecretKeySet) UnmarshalBinary(data []byte) (err error) {
+	skSet.Value = make(map[string]*SecretKey)
+
+	return unmarshalKeySet(data, func(pointer int, data []byte) (int, error) {
+		sk := new(SecretKey)
+
+		var err error
+		if pointer, err = sk.decode(pointer, data); err != nil {
+			return pointer, err
+		}
+
+		skSet.AddSecretKey(sk)
+		return pointer, nil
+	})
+}
+
+// MarshalBinary encodes a PublicKeySet on a byte slice, with the keys in increasing order of id.
+func (pkSet *PublicKeySet) MarshalBinary() (data []byte, err error) {
+	return marshalKeySetByID(pkSet.IDSet(), func(id string) []keyEncoder {
+		return []keyEncoder{pkSet.Value[id]}
+	})
+}
+
+// UnmarshalBinary decodes a previously marshaled PublicKeySet on the target PublicKeySet.
+func (pkSet *PublicKeySet) UnmarshalBinary(data []byte) (err error) {
+	pkSet.Value = make(map[string]*PublicKey)
+
+	return unmarshalKeySet(data, func(pointer int, data []byte) (int, error) {
+		pk := new(PublicKey)
+
+		var err error
+		if pointer, err = pk.decode(pointer, data); err != nil {
+			return pointer, err
+		}
+
+		pkSet.AddPublicKey(pk)
+		return pointer, nil
+	})
+}
+
+// MarshalBinary encodes a RelinearizationKeySet on a byte slice, with the keys in increasing order of id.
+func (rlkSet *RelinearizationKeySet) MarshalBinary() (data []byte, err error) {
+	return marshalKeySetByID(rlkSet.IDSet(), func(id string) []keyEncoder {
+		return []keyEncoder{rlkSet.Value[id]}
+	})
+}
+
+// UnmarshalBinary decodes a previously marshaled RelinearizationKeySet on the target RelinearizationKeySet,
+// which must have been created with NewRelinearizationKeySet.
+func (rlkSet *RelinearizationKeySet) UnmarshalBinary(data []byte) (err error) {
+	if rlkSet.Value == nil {
+		return errors.New("RelinearizationKeySet must be created with NewRelinearizationKeySet")
+	}
+
+	rlkSet.Value = make(map[string]*RelinearizationKey)
+
+	return unmarshalKeySet(data, func(pointer int, data []byte) (int, error) {
+		rlk := new(RelinearizationKey)
+
+		var err error
+		if pointer, err = rlk.decode(pointer, data); err != nil {
+			return pointer, err
+		}
+		rlkSet.AddRelinearizationKey(rlk)
+		return pointer, nil
+	})
+}
+
+// MarshalBinary encodes a RotationKeySet on a byte slice,
+// with the keys in increasing order of id and then of rotation index.
+func (rkSet *RotationKeySet) MarshalBinary() (data []byte, err error) {
+	return marshalKeySetByID(rkSet.IDSet(), func(id string) (keys []keyEncoder) {
+		rotidxs := make([]int, 0, len(rkSet.Value[id]))
+		for rotidx := range rkSet.Value[id] {
+			rotidxs = append(rotidxs, int(rotidx))
+		}
+		sort.Ints(rotidxs)
+
+		for _, rotidx := range rotidxs {
+			keys = append(keys, rkSet.Value[id][uint(rotidx)])
+		}
+
+		return keys
+	})
+}
+
+// UnmarshalBinary decodes a previously marshaled RotationKeySet on the target RotationKeySet.
+func (rkSet *RotationKeySet) UnmarshalBinary(data []byte) (err error) {
+	rkSet.Value = make(map[string]map[uint]*RotationKey)
+
+	return unmarshalKeySet(data, func(pointer int, data []byte) (int, error) {
+		rtk := new(RotationKey)
+
+		var err error
+		if pointer, err = rtk.decode(pointer, data); err != nil {
+			return pointer, err
+		}
+		rkSet.AddRotationKey(rtk)
+		return pointer, nil
+	})
+}
+
+// MarshalBinary encodes a ConjugationKeySet on a byte slice, with the keys in increasing order of id.
+func (cjkSet *ConjugationKeySet) MarshalBinary() (data []byte, err error) {
+	return marshalKeySetByID(cjkSet.IDSet(), func(id string) []keyEncoder {
+		return []keyEncoder{cjkSet.Value[id]}
+	})
+}
+
+// UnmarshalBinary decodes a previously marshaled ConjugationKeySet on the target ConjugationKeySet.
+func (cjkSet *ConjugationKeySet) UnmarshalBinary(data []byte) (err error) {
+	cjkSet.Value = make(map[string]*ConjugationKey)
+
+	return unmarshalKeySet(data, func(pointer int, data []byte) (int, error) {
+		cjk := new(ConjugationKey)
+
+		var err error
+		if pointer, err = cjk.decode(pointer, data); err != nil {
+			return pointer, err
+		}
+
+		cjkSet.AddConjugationKey(cjk)
+		return pointer, nil
+	})
+}
+
+// MarshalBinary encodes a SWKSet on a byte slice, with the keys in increasing order of id.
+func (swkSet *SWKSet) MarshalBinary() (data []byte, err error) {
+	return marshalKeySetByID(swkSet.IDSet(), func(id string) []keyEncoder {
+		return []keyEncoder{swkSet.Value[id]}
+	})
+}
+
+// UnmarshalBinary decodes a previously marshaled SWKSet on the target SWKSet.
+func (swkSet *SWKSet) UnmarshalBinary(data []byte) (err error) {
+	swkSet.Value = make(map[string]*SWK)
+
+	return unmarshalKeySet(data, func(pointer int, data []byte) (int, error) {
+		swk := new(SWK)
+
+		var err error
+		if pointer, err = swk.decode(pointer, data); err != nil {
+			return pointer, err
+		}
+
+		swkSet.AddSWK(swk)
+		return pointer, nil
+	})
+}
+
+// MarshalBinary encodes a JoinKeySet on a byte slice,
+// with the keys in increasing order of group id and then of source epoch.
+func (jkSet *JoinKeySet) MarshalBinary() (data []byte, err error) {
+	return marshalKeySetByID(jkSet.IDSet(), func(id string) (keys []keyEncoder) {
+		froms := make([]int, 0, len(jkSet.Value[id]))
+		for from := range jkSet.Value[id] {
+			froms = append(froms, from)
+		}
+		sort.Ints(froms)
+
+		for _, from := range froms {
+			keys = append(keys, jkSet.Value[id][from])
+		}
+
+		return keys
+	})
+}
+
+// UnmarshalBinary decodes a previously marshaled JoinKeySet on the target JoinKeySet, dropping its composed chains.
+func (jkSet *JoinKeySet) UnmarshalBinary(data []byte) (err error) {
+	jkSet.Value = make(map[string]map[int]*JoinKey)
+
+	jkSet.composedMu.Lock()
+	jkSet.composed = nil
+	jkSet.composedMu.Unlock()
+
+	return unmarshalKeySet(data, func(pointer int, data []byte) (int, error) {
+		jk := new(JoinKey)
+
+		var err error
+		if pointer, err = jk.decode(pointer, data); err != nil {
+			return pointer, err
+		}
+
+		if jk.Value.ID != jk.Head.ID || jk.Value.Epoch != jk.Head.Epoch || jk.From != jk.Value.From || jk.From != jk.Head.From {
+			return pointer, errors.New("inconsistent join key of id " + jk.Value.ID)
+		}
+
+		jkSet.AddJoinKey(jk.Value, jk.Head)
+		return pointer, nil
+	})
+}
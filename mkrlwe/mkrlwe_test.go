@@ -2,15 +2,18 @@ package mkrlwe
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"math"
 	"math/big"
 	"math/bits"
+	"sync"
 	"testing"
 
 	"github.com/ldsec/lattigo/v2/ring"
 	"github.com/ldsec/lattigo/v2/rlwe"
+	"github.com/ldsec/lattigo/v2/utils"
 	//"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -84,6 +87,29 @@ func TestMKRLWE(t *testing.T) {
 		testDecompose(kgen, t)
 		testExternalProduct(kgen, t)
 		testHadamardProduct(kgen, t)
+
+		testGroupJoin(kgen, t)
+		testGroupLeave(kgen, t)
+		testGroupBatchJoin(kgen, t)
+		testGroupMerge(kgen, t)
+		testGroupSplit(kgen, t)
+		testGroupRefresh(kgen, t)
+		testGroupRevoke(kgen, t)
+		testGroupEpoch(kgen, t)
+		testGroupErrors(kgen, t)
+		testJoinKeySets(kgen, t)
+		testGroupExtend(kgen, t)
+		testGroupKSChain(kgen, t)
+		testCRSSeed(kgen, t)
+		testMarshaller(kgen, t)
+		testKeyMarshaller(kgen, t)
+		testErrors(kgen, t)
+		testIDSet(kgen, t)
+		testPRNG(kgen, t)
+		testReadOnlyInputs(kgen, t)
+		testKSInOut(kgen, t)
+		testSmudging(kgen, t)
+		testSwitchingKeyError(kgen, t)
 	}
 
 }
@@ -677,3 +703,1890 @@ func testHadamardProduct(kgen *KeyGenerator, t *testing.T) {
 	})
 
 }
+
+func testGroupJoin(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks that a ciphertext of the group survives two successive joins
+	// 1) aggregate the keys of the founding members
+	// 2) encrypt under the group public key
+	// 3) join two parties one by one and switch the ciphertext with each join key
+
+	params := kgen.params
+
+	t.Run(testString(params, "GroupJoin/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		id := "group0"
+		users := NewIDSet()
+		users.Add(id)
+
+		ringQ := params.RingQ()
+		numParties := 3
+
+		group := NewGroup(params, id)
+		skList := make([]*SecretKey, 0)
+
+		for p := 0; p < numParties; p++ {
+			sk := kgen.GenSecretKey(id)
+			skList = append(skList, sk)
+			group.AddShares(kgen.GenPartyShares(sk, []int{1}))
+		}
+
+		for _, sk := range skList {
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		encryptor := NewEncryptor(params)
+		decryptor := NewDecryptor(params)
+		ks := NewKeySwitcher(params)
+
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		ciphertext := NewCiphertext(params, users, plaintext.Level())
+		encryptor.Encrypt(plaintext, group.PublicKey, ciphertext)
+
+		for j := 0; j < 2; j++ {
+			sk := kgen.GenSecretKey(id)
+			skList = append(skList, sk)
+
+			uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+			jk, jkhead := group.Join(kgen.GenPartyShares(sk, []int{1}), uaux)
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+
+			ctOut := NewCiphertext(params, users, ciphertext.Level())
+			ks.KS(ciphertext, jk, jkhead, ctOut)
+			ciphertext = ctOut
+		}
+
+		require.Equal(t, numParties+2, group.Size)
+		require.Equal(t, 1, len(group.RotationKeys))
+
+		skSet := NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+		decryptor.Decrypt(ciphertext, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ciphertext.Level(), ringQ, plaintext.Value))
+
+		// fresh encryptions under the updated group public key decrypt with the new group secret
+		ciphertext = NewCiphertext(params, users, plaintext.Level())
+		encryptor.Encrypt(plaintext, group.PublicKey, ciphertext)
+		decryptor.Decrypt(ciphertext, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ciphertext.Level(), ringQ, plaintext.Value))
+	})
+}
+
+func testGroupLeave(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks that a ciphertext of the group survives a leave followed by a join
+	// 1) aggregate the keys of the founding members
+	// 2) encrypt under the group public key
+	// 3) remove a member, then admit a new one, and switch the ciphertext with each key
+
+	params := kgen.params
+
+	t.Run(testString(params, "GroupLeave/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		id := "group0"
+		users := NewIDSet()
+		users.Add(id)
+
+		ringQ := params.RingQ()
+		numParties := 3
+
+		group := NewGroup(params, id)
+		skList := make([]*SecretKey, numParties)
+		sharesList := make([]*PartyShares, numParties)
+
+		for p := 0; p < numParties; p++ {
+			skList[p] = kgen.GenSecretKey(id)
+			sharesList[p] = kgen.GenPartyShares(skList[p], []int{1})
+			group.AddShares(sharesList[p])
+		}
+
+		for _, sk := range skList {
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		encryptor := NewEncryptor(params)
+		decryptor := NewDecryptor(params)
+		ks := NewKeySwitcher(params)
+
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		ciphertext := NewCiphertext(params, users, plaintext.Level())
+		encryptor.Encrypt(plaintext, group.PublicKey, ciphertext)
+
+		// the second member leaves
+		sk := skList[1]
+		laux := kgen.LAuxKeyGen(group.SWKHeadSum, sk)
+		swk, swkhead := kgen.GenSWK(sk, group.PublicKeyWithout(sharesList[1].PublicKey))
+		jk, jkhead := group.Leave(sharesList[1], laux, swk, swkhead)
+		skList = append(skList[:1], skList[2:]...)
+
+		ctOut := NewCiphertext(params, users, ciphertext.Level())
+		ks.KS(ciphertext, jk, jkhead, ctOut)
+		ciphertext = ctOut
+
+		require.Equal(t, numParties-1, group.Size)
+
+		skSet := NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+		decryptor.Decrypt(ciphertext, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ciphertext.Level(), ringQ, plaintext.Value))
+
+		// a new member joins the remaining ones
+		sk = kgen.GenSecretKey(id)
+		skList = append(skList, sk)
+
+		uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+		jk, jkhead = group.Join(kgen.GenPartyShares(sk, []int{1}), uaux)
+		group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+
+		ctOut = NewCiphertext(params, users, ciphertext.Level())
+		ks.KS(ciphertext, jk, jkhead, ctOut)
+		ciphertext = ctOut
+
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+		decryptor.Decrypt(ciphertext, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ciphertext.Level(), ringQ, plaintext.Value))
+
+		ciphertext = NewCiphertext(params, users, plaintext.Level())
+		encryptor.Encrypt(plaintext, group.PublicKey, ciphertext)
+		decryptor.Decrypt(ciphertext, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ciphertext.Level(), ringQ, plaintext.Value))
+	})
+}
+
+func testGroupBatchJoin(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks that several parties can join with a single key-switch
+	// 1) aggregate the keys of the founding members
+	// 2) encrypt under the group public key
+	// 3) admit three parties at once and switch the ciphertext with the combined join key
+
+	params := kgen.params
+
+	t.Run(testString(params, "GroupBatchJoin/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		id := "group0"
+		users := NewIDSet()
+		users.Add(id)
+
+		ringQ := params.RingQ()
+		numParties := 2
+		joiningParties := 3
+
+		group := NewGroup(params, id)
+		skList := make([]*SecretKey, 0)
+
+		for p := 0; p < numParties; p++ {
+			sk := kgen.GenSecretKey(id)
+			skList = append(skList, sk)
+			group.AddShares(kgen.GenPartyShares(sk, nil))
+		}
+
+		for _, sk := range skList {
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		encryptor := NewEncryptor(params)
+		decryptor := NewDecryptor(params)
+		ks := NewKeySwitcher(params)
+
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		ciphertext := NewCiphertext(params, users, plaintext.Level())
+		encryptor.Encrypt(plaintext, group.PublicKey, ciphertext)
+
+		sharesList := make([]*PartyShares, joiningParties)
+		uauxList := make([]*SWK, joiningParties)
+		for p := 0; p < joiningParties; p++ {
+			sk := kgen.GenSecretKey(id)
+			skList = append(skList, sk)
+			sharesList[p] = kgen.GenPartyShares(sk, nil)
+			uauxList[p], _ = kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+		}
+
+		jk, jkhead := group.BatchJoin(sharesList, uauxList)
+
+		for _, sk := range skList[numParties:] {
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		ctOut := NewCiphertext(params, users, ciphertext.Level())
+		ks.KS(ciphertext, jk, jkhead, ctOut)
+		ciphertext = ctOut
+
+		require.Equal(t, numParties+joiningParties, group.Size)
+
+		skSet := NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+		decryptor.Decrypt(ciphertext, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ciphertext.Level(), ringQ, plaintext.Value))
+
+		// the sums stay consistent for a following join
+		sk := kgen.GenSecretKey(id)
+		skList = append(skList, sk)
+
+		uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+		jk, jkhead = group.Join(kgen.GenPartyShares(sk, nil), uaux)
+
+		ctOut = NewCiphertext(params, users, ciphertext.Level())
+		ks.KS(ciphertext, jk, jkhead, ctOut)
+		ciphertext = ctOut
+
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+		decryptor.Decrypt(ciphertext, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ciphertext.Level(), ringQ, plaintext.Value))
+	})
+}
+
+// newTestGroup returns a group of numParties members, with their SWK pairs aggregated, and the members' secret keys
+func newTestGroup(kgen *KeyGenerator, id string, numParties int) (group *Group, skList []*SecretKey) {
+	group = NewGroup(kgen.params, id)
+	skList = make([]*SecretKey, numParties)
+
+	for p := 0; p < numParties; p++ {
+		skList[p] = kgen.GenSecretKey(id)
+		group.AddShares(kgen.GenPartyShares(skList[p], nil))
+	}
+
+	for _, sk := range skList {
+		group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+	}
+
+	return group, skList
+}
+
+func testGroupMerge(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks that the two components of a ciphertext are folded into one after a merge
+	// 1) generate two groups and a ciphertext under both of them
+	// 2) merge the second group into the first one
+	// 3) fold the ciphertext into the merged group
+
+	params := kgen.params
+
+	t.Run(testString(params, "GroupMerge/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		id0, id1 := "group0", "group1"
+		ringQ := params.RingQ()
+
+		group0, skList0 := newTestGroup(kgen, id0, 2)
+		group1, skList1 := newTestGroup(kgen, id1, 2)
+
+		encryptor := NewEncryptor(params)
+		decryptor := NewDecryptor(params)
+		ks := NewKeySwitcher(params)
+
+		idset0 := NewIDSet()
+		idset0.Add(id0)
+		idset1 := NewIDSet()
+		idset1.Add(id1)
+
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		level := plaintext.Level()
+
+		ct0 := NewCiphertext(params, idset0, level)
+		ct1 := NewCiphertext(params, idset1, level)
+		encryptor.Encrypt(plaintext, group0.PublicKey, ct0)
+		encryptor.Encrypt(plaintext, group1.PublicKey, ct1)
+
+		ciphertext := NewCiphertext(params, idset0.Union(idset1), level)
+		ringQ.AddLvl(level, ct0.Value["0"], ct1.Value["0"], ciphertext.Value["0"])
+		ciphertext.Value[id0].Copy(ct0.Value[id0])
+		ciphertext.Value[id1].Copy(ct1.Value[id1])
+
+		uaux := make([]*SWK, 0)
+		for _, sk := range skList1 {
+			aux, _ := kgen.UAuxKeyGen(group0.SWKHeadSum, sk)
+			uaux = append(uaux, aux)
+		}
+
+		uauxOther := make([]*SWK, 0)
+		for _, sk := range skList0 {
+			aux, _ := kgen.UAuxKeyGen(group1.SWKHeadSum, sk)
+			uauxOther = append(uauxOther, aux)
+		}
+
+		jk0, jkhead0, jk1, jkhead1 := group0.Merge(group1, uaux, uauxOther)
+
+		require.Equal(t, 4, group0.Size)
+
+		skList := append(skList0, skList1...)
+		for _, sk := range skList {
+			sk.ID = id0
+		}
+
+		skSet := NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+		for _, ct := range []*Ciphertext{ciphertext, ct0, ct1} {
+			ctOut := NewCiphertext(params, idset0, level)
+			ks.MergeKS(ct, id0, id1, jk0, jkhead0, jk1, jkhead1, ctOut)
+
+			require.Equal(t, 1, ctOut.IDSet().Size())
+			require.True(t, ctOut.IDSet().Has(id0))
+
+			decryptor.Decrypt(ctOut, skSet, plaintext)
+			require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(level, ringQ, plaintext.Value))
+		}
+
+		// the merged sums stay consistent for a following join
+		sk := kgen.GenSecretKey(id0)
+		skList = append(skList, sk)
+
+		uauxJoin, _ := kgen.UAuxKeyGen(group0.SWKHeadSum, sk)
+		jk, jkhead := group0.Join(kgen.GenPartyShares(sk, nil), uauxJoin)
+
+		ctOut := NewCiphertext(params, idset0, level)
+		ks.MergeKS(ciphertext, id0, id1, jk0, jkhead0, jk1, jkhead1, ctOut)
+
+		// the join key only switches from the epoch of the merged group
+		require.True(t, errors.Is(ks.CheckKS(ciphertext, jk, jkhead, level), ErrEpochMismatch))
+		require.True(t, errors.Is(ks.CheckKS(ctOut, jk, jkhead0, level), ErrEpochMismatch))
+
+		ks.KS(ctOut, jk, jkhead, ctOut)
+
+		require.True(t, errors.Is(ks.CheckKS(ctOut, jk, jkhead, level), ErrEpochMismatch))
+		require.True(t, errors.Is(ks.CheckMergeKS(ctOut, id0, id1, jk0, jkhead0, jk1, jkhead1, level), ErrEpochMismatch))
+
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+		decryptor.Decrypt(ctOut, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(level, ringQ, plaintext.Value))
+	})
+}
+
+func testGroupSplit(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks that a ciphertext of a group can be carried over to the child groups after a split
+	// 1) split a group of three members into two child groups
+	// 2) carry the ciphertext over to both child groups
+	// 3) carry the ciphertext over to the first child group alone
+
+	params := kgen.params
+
+	t.Run(testString(params, "GroupSplit/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		id, id0, id1 := "group", "group0", "group1"
+		ringQ := params.RingQ()
+
+		group, skList := newTestGroup(kgen, id, 3)
+
+		encryptor := NewEncryptor(params)
+		decryptor := NewDecryptor(params)
+		ks := NewKeySwitcher(params)
+
+		idset := NewIDSet()
+		idset.Add(id)
+
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		level := plaintext.Level()
+
+		ciphertext := NewCiphertext(params, idset, level)
+		encryptor.Encrypt(plaintext, group.PublicKey, ciphertext)
+
+		skList0 := make([]*SecretKey, 0)
+		skList1 := make([]*SecretKey, 0)
+		shares0 := make([]*PartyShares, 0)
+		shares1 := make([]*PartyShares, 0)
+
+		for i, sk := range skList {
+			skc := sk.CopyNew()
+			if i == 0 {
+				skc.ID = id0
+				skList0 = append(skList0, skc)
+				shares0 = append(shares0, kgen.GenPartyShares(skc, nil))
+			} else {
+				skc.ID = id1
+				skList1 = append(skList1, skc)
+				shares1 = append(shares1, kgen.GenPartyShares(skc, nil))
+			}
+		}
+
+		group0, group1 := group.Split(id0, id1, shares0, shares1)
+
+		require.Equal(t, 1, group0.Size)
+		require.Equal(t, 2, group1.Size)
+
+		skSet := NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList0))
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList1))
+
+		ctOut := NewCiphertext(params, idset, level)
+		ks.Split(ciphertext, id, id0, id1, ctOut)
+
+		require.Equal(t, 2, ctOut.IDSet().Size())
+		require.True(t, ctOut.IDSet().Has(id0))
+		require.True(t, ctOut.IDSet().Has(id1))
+
+		decryptor.Decrypt(ctOut, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(level, ringQ, plaintext.Value))
+
+		swkList := make([]*SWK, 0)
+		swkheadList := make([]*SWK, 0)
+		for _, sk := range skList1 {
+			swk, swkhead := kgen.GenSWK(sk, group0.PublicKey)
+			swkList = append(swkList, swk)
+			swkheadList = append(swkheadList, swkhead)
+		}
+
+		ctOut = NewCiphertext(params, idset, level)
+		swk, swkhead := group.SplitSWK(swkList, swkheadList)
+		ks.SplitKS(ciphertext, id, id0, swk, swkhead, ctOut)
+
+		ctOld := ciphertext.CopyNew()
+		ctOld.SetEpoch(id, group.Epoch+1)
+		require.True(t, errors.Is(ks.CheckSplitKS(ctOld, id, id0, swk, swkhead, level), ErrEpochMismatch))
+
+		require.Equal(t, 1, ctOut.IDSet().Size())
+		require.True(t, ctOut.IDSet().Has(id0))
+
+		skSet = NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList0))
+
+		decryptor.Decrypt(ctOut, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(level, ringQ, plaintext.Value))
+	})
+}
+
+func testGroupRefresh(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks that a ciphertext of a group can be switched to the refreshed group secret
+	// 1) refresh the secret keys of every member of a group
+	// 2) switch the ciphertext to the refreshed group secret
+	// 3) check that the previous group secret no longer decrypts it
+
+	params := kgen.params
+
+	t.Run(testString(params, "GroupRefresh/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		id := "group"
+		ringQ := params.RingQ()
+
+		group, skList := newTestGroup(kgen, id, 3)
+
+		encryptor := NewEncryptor(params)
+		decryptor := NewDecryptor(params)
+		ks := NewKeySwitcher(params)
+
+		idset := NewIDSet()
+		idset.Add(id)
+
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		level := plaintext.Level()
+
+		ciphertext := NewCiphertext(params, idset, level)
+		encryptor.Encrypt(plaintext, group.PublicKey, ciphertext)
+
+		skListNew := make([]*SecretKey, len(skList))
+		sharesList := make([]*PartyShares, len(skList))
+		rauxList := make([]*SWK, len(skList))
+
+		for i, sk := range skList {
+			skListNew[i] = kgen.GenSecretKey(id)
+			sharesList[i] = kgen.GenPartyShares(skListNew[i], nil)
+			rauxList[i] = kgen.RAuxKeyGen(group.SWKHeadSum, sk, skListNew[i])
+		}
+
+		jk, jkhead := group.Refresh(sharesList, rauxList)
+
+		require.Equal(t, 3, group.Size)
+
+		for _, sk := range skListNew {
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		ctOut := NewCiphertext(params, idset, level)
+		ks.KS(ciphertext, jk, jkhead, ctOut)
+
+		skSet := NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skListNew))
+
+		decryptor.Decrypt(ctOut, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(level, ringQ, plaintext.Value))
+
+		skSetOld := NewSecretKeySet()
+		skSetOld.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+		decryptor.Decrypt(ctOut, skSetOld, plaintext)
+		require.Less(t, 12+params.LogN(), log2OfInnerSum(level, ringQ, plaintext.Value))
+
+		// the refreshed sums stay consistent for a following join
+		sk := kgen.GenSecretKey(id)
+		skListNew = append(skListNew, sk)
+
+		uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+		jk, jkhead = group.Join(kgen.GenPartyShares(sk, nil), uaux)
+		ks.KS(ctOut, jk, jkhead, ctOut)
+
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skListNew))
+
+		decryptor.Decrypt(ctOut, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(level, ringQ, plaintext.Value))
+	})
+}
+
+func testGroupRevoke(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks that the remaining members can revoke a member without its secret key
+	// 1) drop the key shares of the revoked member from the group keys
+	// 2) check that the group keys are the ones of the remaining members, and that the revoked member cannot decrypt
+	// 3) re-add the switching keys of the remaining members and refresh their secret keys
+
+	params := kgen.params
+
+	t.Run(testString(params, "GroupRevoke/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		id := "group0"
+		ringQ := params.RingQ()
+		numParties := 3
+
+		group := NewGroup(params, id)
+		skList := make([]*SecretKey, numParties)
+		sharesList := make([]*PartyShares, numParties)
+
+		for p := 0; p < numParties; p++ {
+			skList[p] = kgen.GenSecretKey(id)
+			sharesList[p] = kgen.GenPartyShares(skList[p], []int{1})
+			group.AddShares(sharesList[p])
+		}
+
+		for _, sk := range skList {
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		// the second member is revoked
+		group.Revoke(sharesList[1])
+		skRevoked := skList[1]
+		skList = append(skList[:1], skList[2:]...)
+		sharesList = append(sharesList[:1], sharesList[2:]...)
+
+		require.Equal(t, numParties-1, group.Size)
+		require.Equal(t, 1, group.Epoch)
+		require.Nil(t, group.SWKSum)
+
+		pk := kgen.GenGroupPublicKey([]*PublicKey{sharesList[0].PublicKey, sharesList[1].PublicKey})
+		require.True(t, pk.Value[0].Q.Equals(group.PublicKey.Value[0].Q))
+		rtk := kgen.GenGroupRotKey([]*RotationKey{sharesList[0].RotationKeys[1], sharesList[1].RotationKeys[1]})
+		requireSwitchingKeyEqual(t, rtk.Value, group.RotationKeys[1].Value)
+
+		encryptor := NewEncryptor(params)
+		decryptor := NewDecryptor(params)
+		ks := NewKeySwitcher(params)
+
+		idset := NewIDSet()
+		idset.Add(id)
+
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		level := plaintext.Level()
+
+		ciphertext := NewCiphertext(params, idset, level)
+		encryptor.Encrypt(plaintext, group.PublicKey, ciphertext)
+		require.Equal(t, 1, ciphertext.GetEpoch(id))
+
+		skSet := NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+		decryptor.Decrypt(ciphertext, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(level, ringQ, plaintext.Value))
+
+		skSetOld := NewSecretKeySet()
+		skSetOld.AddSecretKey(kgen.GenGroupSecretKey(append([]*SecretKey{skRevoked}, skList...)))
+		decryptor.Decrypt(ciphertext, skSetOld, plaintext)
+		require.Less(t, 12+params.LogN(), log2OfInnerSum(level, ringQ, plaintext.Value))
+
+		// the remaining members re-add their switching keys, then refresh their secret keys
+		_, _, err := group.TryRefresh(sharesList, nil)
+		require.True(t, errors.Is(err, ErrInvalidInput))
+
+		for _, sk := range skList {
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		skListNew := make([]*SecretKey, len(skList))
+		rauxList := make([]*SWK, len(skList))
+		for i, sk := range skList {
+			skListNew[i] = kgen.GenSecretKey(id)
+			sharesList[i] = kgen.GenPartyShares(skListNew[i], []int{1})
+			rauxList[i] = kgen.RAuxKeyGen(group.SWKHeadSum, sk, skListNew[i])
+		}
+
+		jk, jkhead := group.Refresh(sharesList, rauxList)
+
+		ctOut := NewCiphertext(params, idset, level)
+		ks.KS(ciphertext, jk, jkhead, ctOut)
+
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skListNew))
+		decryptor.Decrypt(ctOut, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(level, ringQ, plaintext.Value))
+
+		// the last member cannot be revoked
+		group.Revoke(sharesList[1])
+		require.True(t, errors.Is(group.TryRevoke(sharesList[0]), ErrInvalidInput))
+		require.Equal(t, 1, group.Size)
+	})
+}
+
+func testGroupEpoch(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks that ciphertexts and keys from before and after a join cannot be mixed
+	// 1) encrypt under the group public key before and after a join
+	// 2) multiplying the two ciphertexts or the stale one with the new keys panics
+	// 3) switching the stale ciphertext with the join key makes it usable again
+
+	params := kgen.params
+
+	t.Run(testString(params, "GroupEpoch/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		id := "group0"
+		users := NewIDSet()
+		users.Add(id)
+
+		group, _ := newTestGroup(kgen, id, 2)
+		require.Equal(t, 0, group.Epoch)
+
+		encryptor := NewEncryptor(params)
+		ks := NewKeySwitcher(params)
+
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		ctOld := NewCiphertext(params, users, plaintext.Level())
+		encryptor.Encrypt(plaintext, group.PublicKey, ctOld)
+		require.Equal(t, 0, ctOld.GetEpoch(id))
+
+		sk := kgen.GenSecretKey(id)
+		uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+		jk, jkhead := group.Join(kgen.GenPartyShares(sk, nil), uaux)
+
+		// an SWK pair against a public key of the previous epoch is rejected
+		require.Panics(t, func() { group.AddSWK(kgen.GenSWK(sk, kgen.GenPublicKey(sk))) })
+		group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+
+		require.Equal(t, 1, group.Epoch)
+		require.Equal(t, 1, group.PublicKey.Epoch)
+		require.Equal(t, 1, group.RelinearizationKey.Epoch)
+		require.Equal(t, 1, jk.Epoch)
+
+		ctNew := NewCiphertext(params, users, plaintext.Level())
+		encryptor.Encrypt(plaintext, group.PublicKey, ctNew)
+		require.Equal(t, 1, ctNew.GetEpoch(id))
+
+		rlkSet := NewRelinearizationKeySet(params)
+		group.UpdateKeySets(nil, rlkSet, nil, nil)
+
+		ctOut := NewCiphertext(params, users, plaintext.Level())
+		require.Panics(t, func() { ks.MulAndRelin(ctOld, ctNew, rlkSet, ctOut) })
+		require.Panics(t, func() { ks.MulAndRelin(ctOld, ctOld, rlkSet, ctOut) })
+
+		ctSwitched := NewCiphertext(params, users, ctOld.Level())
+		ks.KS(ctOld, jk, jkhead, ctSwitched)
+		require.Equal(t, 1, ctSwitched.GetEpoch(id))
+		require.NotPanics(t, func() { ks.MulAndRelin(ctSwitched, ctNew, rlkSet, ctOut) })
+		require.Equal(t, 1, ctOut.GetEpoch(id))
+	})
+}
+
+func testGroupErrors(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks that invalid group updates are rejected with an error and leave the group as it was
+	// 1) try every update of the group with an invalid input
+	// 2) check the kind of the error and that the group keys are not replaced
+	// 3) try a join with valid inputs
+
+	params := kgen.params
+
+	t.Run(testString(params, "GroupErrors/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		id := "group0"
+		group, skList := newTestGroup(kgen, id, 2)
+		other, _ := newTestGroup(kgen, "group1", 2)
+		pk, epoch, size := group.PublicKey, group.Epoch, group.Size
+
+		requireUnchanged := func() {
+			require.True(t, pk == group.PublicKey)
+			require.Equal(t, epoch, group.Epoch)
+			require.Equal(t, size, group.Size)
+		}
+
+		sk := kgen.GenSecretKey(id)
+		shares := kgen.GenPartyShares(sk, nil)
+		uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+
+		// auxiliary keys
+		_, _, err := kgen.TryUAuxKeyGen(nil, sk)
+		require.True(t, errors.Is(err, ErrInvalidInput))
+		_, err = kgen.TryLAuxKeyGen(group.SWKHeadSum, nil)
+		require.True(t, errors.Is(err, ErrInvalidInput))
+		_, err = kgen.TryRAuxKeyGen(group.SWKHeadSum, skList[0], kgen.GenSecretKey("group1"))
+		require.True(t, errors.Is(err, ErrInvalidInput))
+		_, _, err = kgen.TryGenSWK(sk, nil)
+		require.True(t, errors.Is(err, ErrInvalidInput))
+		require.True(t, errors.Is(kgen.TryJoinKeySets(nil, nil, nil), ErrInvalidInput))
+
+		// shares and switching keys
+		require.True(t, errors.Is(group.TryAddShares(nil), ErrInvalidInput))
+		require.True(t, errors.Is(group.TryAddShares(kgen.GenPartyShares(kgen.GenSecretKey("group1"), nil)), ErrInvalidInput))
+		require.True(t, errors.Is(group.TryAddSWK(kgen.GenSWK(kgen.GenSecretKey("group1"), group.PublicKey)), ErrInvalidInput))
+		requireUnchanged()
+
+		// membership updates
+		_, _, err = group.TryJoin(kgen.GenPartyShares(kgen.GenSecretKey("group1"), nil), uaux)
+		require.True(t, errors.Is(err, ErrInvalidInput))
+		_, _, err = group.TryBatchJoin([]*PartyShares{shares}, nil)
+		require.True(t, errors.Is(err, ErrInvalidInput))
+		_, _, err = group.TryLeave(nil, nil, nil, nil)
+		require.True(t, errors.Is(err, ErrInvalidInput))
+		_, _, err = group.TryRefresh([]*PartyShares{shares}, []*SWK{uaux})
+		require.True(t, errors.Is(err, ErrInvalidInput))
+		_, _, _, _, err = group.TryMerge(group, nil, nil)
+		require.True(t, errors.Is(err, ErrInvalidInput))
+		_, _, _, _, err = group.TryMerge(other, []*SWK{uaux, uaux}, []*SWK{uaux, uaux})
+		require.True(t, errors.Is(err, ErrInvalidInput))
+		_, _, err = group.TrySplit("group1", "group2", []*PartyShares{shares}, nil)
+		require.True(t, errors.Is(err, ErrInvalidInput))
+		_, _, err = group.TrySplitSWK(nil, nil)
+		require.True(t, errors.Is(err, ErrInvalidInput))
+		requireUnchanged()
+
+		jk, _, err := group.TryJoin(shares, uaux)
+		require.NoError(t, err)
+		require.Equal(t, epoch+1, jk.Epoch)
+		require.NoError(t, group.TryAddSWK(kgen.GenSWK(sk, group.PublicKey)))
+
+		// an auxiliary key computed against a previous switching key sum is rejected
+		pk, epoch, size = group.PublicKey, group.Epoch, group.Size
+		_, _, err = group.TryJoin(shares, uaux)
+		require.True(t, errors.Is(err, ErrEpochMismatch))
+		require.Panics(t, func() { group.Join(shares, uaux) })
+		requireUnchanged()
+	})
+}
+
+func testJoinKeySets(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks that a join updates every rotation key of the group and its conjugation key in the key sets
+	// 1) aggregate the rotation and conjugation keys of the founding members into key sets
+	// 2) add the shares of a joining party with JoinKeySets
+	// 3) compare with the keys aggregated from all the members
+
+	params := kgen.params
+
+	t.Run(testString(params, "JoinKeySets/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		id := "group0"
+		rotidx := []int{1, 2, 4}
+		numParties := 3
+
+		sharesList := make([]*PartyShares, numParties)
+		for p := 0; p < numParties; p++ {
+			sharesList[p] = kgen.GenPartyShares(kgen.GenSecretKey(id), rotidx)
+		}
+
+		rtkSet := NewRotationKeySet()
+		cjkSet := NewConjugationKeySet()
+		for _, idx := range rotidx {
+			rtkSet.AddRotationKey(kgen.GenGroupRotKey([]*RotationKey{sharesList[0].RotationKeys[uint(idx)], sharesList[1].RotationKeys[uint(idx)]}))
+		}
+		cjkSet.AddConjugationKey(kgen.GenGroupConjKey([]*ConjugationKey{sharesList[0].ConjugationKey, sharesList[1].ConjugationKey}))
+
+		kgen.JoinKeySets(sharesList[2], rtkSet, cjkSet)
+
+		require.Equal(t, len(rotidx), len(rtkSet.Value[id]))
+		for _, idx := range rotidx {
+			rtkList := make([]*RotationKey, numParties)
+			for p := range sharesList {
+				rtkList[p] = sharesList[p].RotationKeys[uint(idx)]
+			}
+			requireSwitchingKeyEqual(t, kgen.GenGroupRotKey(rtkList).Value, rtkSet.GetRotationKey(id, uint(idx)).Value)
+		}
+
+		cjk := kgen.GenGroupConjKey([]*ConjugationKey{sharesList[0].ConjugationKey, sharesList[1].ConjugationKey, sharesList[2].ConjugationKey})
+		requireSwitchingKeyEqual(t, cjk.Value, cjkSet.GetConjugationKey(id).Value)
+
+		// a joining party must provide a share for every index of the group
+		delete(sharesList[2].RotationKeys, 4)
+		require.Panics(t, func() { kgen.JoinKeySets(sharesList[2], rtkSet, cjkSet) })
+
+		// the key sets of a Group only keep the indexes the group has
+		group := NewGroup(params, id)
+		group.AddShares(kgen.GenPartyShares(kgen.GenSecretKey(id), []int{1}))
+		group.UpdateKeySets(nil, nil, rtkSet, cjkSet)
+		require.Equal(t, 1, len(rtkSet.Value[id]))
+	})
+}
+
+func requireSwitchingKeyEqual(t *testing.T, swk0, swk1 *SwitchingKey) {
+	require.Equal(t, len(swk0.Value), len(swk1.Value))
+	for i := range swk0.Value {
+		require.True(t, swk0.Value[i].Q.Equals(swk1.Value[i].Q))
+		require.True(t, swk0.Value[i].P.Equals(swk1.Value[i].P))
+	}
+}
+
+func testGroupExtend(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks that a ciphertext which missed two joins is extended through the chain of join keys
+	// 1) encrypt under the group public key
+	// 2) join two parties one by one and store the join keys
+	// 3) extend the ciphertext and decrypt with the new group secret
+
+	params := kgen.params
+
+	t.Run(testString(params, "GroupExtend/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		id := "group0"
+		users := NewIDSet()
+		users.Add(id)
+
+		ringQ := params.RingQ()
+		group, skList := newTestGroup(kgen, id, 2)
+
+		encryptor := NewEncryptor(params)
+		decryptor := NewDecryptor(params)
+		ks := NewKeySwitcher(params)
+
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		ciphertext := NewCiphertext(params, users, plaintext.Level())
+		encryptor.Encrypt(plaintext, group.PublicKey, ciphertext)
+
+		jkSet := NewJoinKeySet()
+		for j := 0; j < 2; j++ {
+			sk := kgen.GenSecretKey(id)
+			skList = append(skList, sk)
+
+			uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+			jkSet.AddJoinKey(group.Join(kgen.GenPartyShares(sk, nil), uaux))
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		ks.Extend(ciphertext, jkSet)
+		require.Equal(t, group.Epoch, ciphertext.GetEpoch(id))
+
+		skSet := NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+		decryptor.Decrypt(ciphertext, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ciphertext.Level(), ringQ, plaintext.Value))
+	})
+}
+
+func testGroupKSChain(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks that a chain of join keys is applied in one pass
+	// 1) encrypt under the group public key
+	// 2) join three parties one by one and store the join keys
+	// 3) switch the ciphertext through the chain with KSChain and with successive KS, and decrypt both
+	// 4) compose the chain, switch a ciphertext below the top levels with the composed key, and decrypt
+	// 5) join a fourth party, extend the composed key and decrypt again
+
+	params := kgen.params
+
+	t.Run(testString(params, "GroupKSChain/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		id := "group0"
+		users := NewIDSet()
+		users.Add(id)
+
+		ringQ := params.RingQ()
+		group, skList := newTestGroup(kgen, id, 2)
+
+		encryptor := NewEncryptor(params)
+		decryptor := NewDecryptor(params)
+		ks := NewKeySwitcher(params)
+
+		pk := group.PublicKey
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		ciphertext := NewCiphertext(params, users, plaintext.Level())
+		encryptor.Encrypt(plaintext, pk, ciphertext)
+
+		jkSet := NewJoinKeySet()
+		for j := 0; j < 3; j++ {
+			sk := kgen.GenSecretKey(id)
+			skList = append(skList, sk)
+
+			uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+			jkSet.AddJoinKey(group.Join(kgen.GenPartyShares(sk, nil), uaux))
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		chain := jkSet.GetChain(id, 0)
+		require.Equal(t, 3, len(chain))
+
+		// the chain must start at the epoch of the ciphertext
+		ctOut := NewCiphertext(params, users, ciphertext.Level())
+		require.Panics(t, func() { ks.KSChain(ciphertext, id, chain[1:], ctOut) })
+
+		ks.KSChain(ciphertext, id, chain, ctOut)
+		require.Equal(t, group.Epoch, ctOut.GetEpoch(id))
+
+		ctSeq := ciphertext.CopyNew()
+		for _, jk := range chain {
+			ctTmp := NewCiphertext(params, users, ctSeq.Level())
+			ks.KS(ctSeq, jk.Value, jk.Head, ctTmp)
+			ctSeq = ctTmp
+		}
+
+		skSet := NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+		decryptor.Decrypt(ctOut, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ctOut.Level(), ringQ, plaintext.Value))
+
+		decryptor.Decrypt(ctSeq, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ctSeq.Level(), ringQ, plaintext.Value))
+
+		// the chain composed into a single key switches the ciphertexts below the top levels
+		cjk := ks.ComposeChain(jkSet, id, 0)
+		if cjk == nil {
+			return
+		}
+
+		require.Equal(t, group.Epoch, cjk.Epoch)
+		cached, in := jkSet.GetComposedKey(id, 0)
+		require.True(t, in)
+		require.True(t, cached == cjk)
+
+		level := cjk.MaxLevel()
+		ctLow := NewCiphertext(params, users, level)
+		encryptor.Encrypt(rlwe.NewPlaintext(params.Parameters, level), pk, ctLow)
+
+		ctOut = NewCiphertext(params, users, level)
+		ks.KSComposed(ctLow, cjk, ctOut)
+		require.Equal(t, group.Epoch, ctOut.GetEpoch(id))
+
+		plaintext = rlwe.NewPlaintext(params.Parameters, level)
+		decryptor.Decrypt(ctOut, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(level, ringQ, plaintext.Value))
+
+		// Extend applies the cached composed key
+		ctExt := ctLow.CopyNew()
+		ks.Extend(ctExt, jkSet)
+		require.True(t, ctExt.Value["0"].Equals(ctOut.Value["0"]))
+		require.True(t, ctExt.Value[id].Equals(ctOut.Value[id]))
+
+		require.True(t, errors.Is(ks.CheckKSComposed(ctOut, cjk, level), ErrEpochMismatch))
+		require.True(t, errors.Is(ks.CheckKSComposed(ciphertext, cjk, ciphertext.Level()), ErrLevelMismatch))
+
+		// after one more join, the cached key is stale and only switched through the new join key
+		sk := kgen.GenSecretKey(id)
+		skList = append(skList, sk)
+
+		uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+		jkSet.AddJoinKey(group.Join(kgen.GenPartyShares(sk, nil), uaux))
+
+		_, in = jkSet.GetComposedKey(id, 0)
+		require.False(t, in)
+
+		cjk = ks.ComposeChain(jkSet, id, 0)
+		require.Equal(t, group.Epoch, cjk.Epoch)
+
+		ks.KSComposed(ctLow, cjk, ctOut)
+
+		skSet = NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+		decryptor.Decrypt(ctOut, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(level, ringQ, plaintext.Value))
+	})
+}
+
+func testCRSSeed(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks that the CRSs are expanded deterministically from the CRS seed
+	// 1) build two parameters from the seed of kgen.params and compare their CRSs, also after AddCRS
+	// 2) build parameters from another seed and check that the CRSs differ
+
+	params := kgen.params
+
+	t.Run(testString(params, "CRSSeed/"), func(t *testing.T) {
+
+		seed := params.CRSSeed()
+		params0 := NewParametersFromSeed(params.Parameters, params.Gamma(), seed)
+		params1 := NewParametersFromSeed(params.Parameters, params.Gamma(), seed)
+
+		require.Equal(t, len(params.CRS), len(params0.CRS))
+		for idx := range params.CRS {
+			requireSwitchingKeyEqual(t, params.CRS[idx], params0.CRS[idx])
+			requireSwitchingKeyEqual(t, params0.CRS[idx], params1.CRS[idx])
+		}
+
+		params0.AddCRS(3)
+		params1.AddCRS(3)
+		requireSwitchingKeyEqual(t, params0.CRS[3], params1.CRS[3])
+		require.False(t, params0.CRS[3].Value[0].Q.Equals(params0.CRS[1].Value[0].Q))
+
+		seed[0] ^= 1
+		params2 := NewParametersFromSeed(params.Parameters, params.Gamma(), seed)
+		require.False(t, params0.CRS[0].Value[0].Q.Equals(params2.CRS[0].Value[0].Q))
+
+		require.Panics(t, func() { NewParametersFromSeed(params.Parameters, params.Gamma(), seed[:16]) })
+	})
+}
+
+func testMarshaller(kgen *KeyGenerator, t *testing.T) {
+
+	params := kgen.params
+
+	t.Run(testString(params, "Marshaller/Ciphertext/"), func(t *testing.T) {
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		users := NewIDSet()
+		users.Add("user1")
+		users.Add("user0")
+
+		_, pk := kgen.GenKeyPair("user0")
+
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		plaintext.Value.IsNTT = true
+		encryptor := NewEncryptor(params)
+		ciphertext := NewCiphertextNTT(params, users, plaintext.Level())
+		encryptor.Encrypt(plaintext, pk, ciphertext)
+		ciphertext.PadCiphertext(users)
+		ciphertext.SetEpoch("user1", 3)
+
+		data, err := ciphertext.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, ciphertext.GetDataLen(true), len(data))
+
+		dataRepeat, err := ciphertext.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, data, dataRepeat)
+
+		ciphertextTest := new(Ciphertext)
+		require.NoError(t, ciphertextTest.UnmarshalBinary(data))
+
+		require.Equal(t, len(ciphertext.Value), len(ciphertextTest.Value))
+		require.Equal(t, ciphertext.Level(), ciphertextTest.Level())
+		for id := range ciphertext.Value {
+			require.True(t, ciphertext.Value[id].Equals(ciphertextTest.Value[id]))
+			require.Equal(t, ciphertext.Value[id].IsNTT, ciphertextTest.Value[id].IsNTT)
+			require.Equal(t, ciphertext.GetEpoch(id), ciphertextTest.GetEpoch(id))
+		}
+
+		require.Error(t, ciphertextTest.UnmarshalBinary(data[:len(data)-1]))
+		require.Error(t, ciphertextTest.UnmarshalBinary(append(data, 0)))
+	})
+
+	for _, isNTT := range []bool{true, false} {
+		t.Run(testString(params, fmt.Sprintf("Marshaller/CompressedCiphertext/NTT=%t/", isNTT)), func(t *testing.T) {
+			if params.PCount() == 0 {
+				t.Skip()
+			}
+
+			users := NewIDSet()
+			users.Add("user0")
+
+			sk := kgen.GenSecretKey("user0")
+			ringQ := params.RingQ()
+
+			plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+			plaintext.Value.IsNTT = isNTT
+			encryptor := NewEncryptor(params)
+			ciphertext := NewSeededCiphertext(params, plaintext.Level())
+			ciphertext.Value.IsNTT = isNTT
+			encryptor.EncryptSkSeeded(plaintext, sk, ciphertext)
+
+			data, err := ciphertext.MarshalBinary()
+			require.NoError(t, err)
+
+			expanded, err := ciphertext.Expand(params)
+			require.NoError(t, err)
+			dataExpanded, err := expanded.MarshalBinary()
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(data), len(dataExpanded)/2+64)
+
+			seededTest := new(SeededCiphertext)
+			require.NoError(t, seededTest.UnmarshalBinary(data))
+			ciphertextTest, err := seededTest.Expand(params)
+			require.NoError(t, err)
+			require.Equal(t, expanded.Level(), ciphertextTest.Level())
+			for id := range expanded.Value {
+				require.True(t, expanded.Value[id].Equals(ciphertextTest.Value[id]))
+				require.Equal(t, expanded.Value[id].IsNTT, ciphertextTest.Value[id].IsNTT)
+				require.Equal(t, expanded.GetEpoch(id), ciphertextTest.GetEpoch(id))
+			}
+
+			// the expansion is a copy, which can be evaluated on without altering the seeded ciphertext
+			expanded.Value["0"].Zero()
+			require.False(t, ciphertext.Value.Equals(expanded.Value["0"]))
+
+			c1 := ciphertextTest.Value["user0"]
+			c0 := ciphertextTest.Value["0"]
+			if !isNTT {
+				ringQ.NTTLvl(c1.Level(), c1, c1)
+				ringQ.NTTLvl(c0.Level(), c0, c0)
+			}
+			ringQ.MulCoeffsMontgomeryAndAddLvl(c0.Level(), c1, sk.Value.Q, c0)
+			ringQ.InvNTTLvl(c0.Level(), c0, c0)
+			require.GreaterOrEqual(t, 9+params.LogN(), log2OfInnerSum(c0.Level(), ringQ, c0))
+
+			require.Error(t, seededTest.UnmarshalBinary(data[:len(data)-1]))
+
+			seededTest.Value = ring.NewPoly(params.N()/2, 1)
+			_, err = seededTest.Expand(params)
+			require.True(t, errors.Is(err, ErrInvalidInput))
+		})
+	}
+}
+
+// requireMarshalRoundTrip checks that obj is encoded deterministically, and that decoding it in objTest and encoding again gives the same bytes
+func requireMarshalRoundTrip(t *testing.T, obj, objTest interface {
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary([]byte) error
+}) {
+	data, err := obj.MarshalBinary()
+	require.NoError(t, err)
+
+	dataRepeat, err := obj.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, data, dataRepeat)
+
+	require.NoError(t, objTest.UnmarshalBinary(data))
+
+	dataTest, err := objTest.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, data, dataTest)
+
+	require.Error(t, objTest.UnmarshalBinary(data[:len(data)-1]))
+	require.Error(t, objTest.UnmarshalBinary(append(data, 0)))
+}
+
+func testKeyMarshaller(kgen *KeyGenerator, t *testing.T) {
+
+	params := kgen.params
+
+	t.Run(testString(params, "Marshaller/Keys/"), func(t *testing.T) {
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		sk, pk := kgen.GenKeyPair("user0")
+		sk.Epoch = 2
+		pk.Epoch = 2
+
+		skTest := new(SecretKey)
+		requireMarshalRoundTrip(t, sk, skTest)
+		require.Equal(t, sk.ID, skTest.ID)
+		require.Equal(t, sk.Epoch, skTest.Epoch)
+		require.True(t, sk.Value.Q.Equals(skTest.Value.Q))
+		require.True(t, sk.Value.P.Equals(skTest.Value.P))
+
+		requireMarshalRoundTrip(t, pk, new(PublicKey))
+
+		data, err := pk.MarshalBinary()
+		require.NoError(t, err)
+		dataCompressed, err := pk.MarshalBinaryCompressed(params)
+		require.NoError(t, err)
+		require.Less(t, len(dataCompressed), len(data))
+
+		pkTest := new(PublicKey)
+		require.NoError(t, pkTest.UnmarshalBinaryCompressed(params, dataCompressed))
+		dataTest, err := pkTest.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, data, dataTest)
+
+		paramsOther := NewParameters(params.Parameters, params.Gamma())
+		_, err = pk.MarshalBinaryCompressed(paramsOther)
+		require.Error(t, err)
+		require.Error(t, pkTest.UnmarshalBinaryCompressed(params, dataCompressed[:len(dataCompressed)-1]))
+
+		rlk := kgen.GenRelinearizationKey(sk)
+		rlkTest := new(RelinearizationKey)
+		requireMarshalRoundTrip(t, rlk, rlkTest)
+		for i := range rlk.Value {
+			requireSwitchingKeyEqual(t, rlk.Value[i], rlkTest.Value[i])
+		}
+
+		rtk := kgen.GenRotationKey(4, sk)
+		rtkTest := new(RotationKey)
+		requireMarshalRoundTrip(t, rtk, rtkTest)
+		require.Equal(t, rtk.RotIdx, rtkTest.RotIdx)
+
+		requireMarshalRoundTrip(t, kgen.GenConjugationKey(sk), new(ConjugationKey))
+		requireMarshalRoundTrip(t, rlk.Value[0], new(SwitchingKey))
+
+		swk, _ := kgen.GenSWK(sk, pk)
+		requireMarshalRoundTrip(t, swk, new(SWK))
+	})
+
+	t.Run(testString(params, "Marshaller/SeededSWK/"), func(t *testing.T) {
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		sk := kgen.GenSecretKey("user0")
+		sk2 := kgen.GenSecretKey("user0")
+		sk2.Epoch = 1
+
+		swk, seeded := kgen.GenSWKTestSeeded(sk, sk2)
+		seededTest := new(SeededSWK)
+		requireMarshalRoundTrip(t, seeded, seededTest)
+
+		swkhead, err := seeded.Expand(params)
+		require.NoError(t, err)
+		swkheadTest, err := seededTest.Expand(params)
+		require.NoError(t, err)
+		require.Equal(t, sk.ID, swkheadTest.ID)
+		require.Equal(t, sk2.Epoch, swkheadTest.Epoch)
+		requireSwitchingKeyEqual(t, swkhead.Value, swkheadTest.Value)
+
+		data, err := seeded.MarshalBinary()
+		require.NoError(t, err)
+		dataHead, err := swkhead.MarshalBinary()
+		require.NoError(t, err)
+		require.Less(t, 100*len(data), len(dataHead))
+
+		// the expanded head switches an encryption under sk to sk2
+		encryptor := NewEncryptor(params)
+		decryptor := NewDecryptor(params)
+		ringQ := params.RingQ()
+
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		ciphertext := NewCiphertext(params, NewIDSet(), plaintext.Level())
+		encryptor.EncryptSk(plaintext, sk, ciphertext)
+
+		ctOut := NewCiphertext(params, NewIDSet(), plaintext.Level())
+		NewKeySwitcher(params).KS(ciphertext, swk, swkheadTest, ctOut)
+		require.Equal(t, sk2.Epoch, ctOut.Epoch[sk.ID])
+
+		skSet := NewSecretKeySet()
+		skSet.AddSecretKey(sk2)
+		decryptor.Decrypt(ctOut, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ctOut.Level(), ringQ, plaintext.Value))
+
+		_, err = (&SeededSWK{ID: sk.ID, Seed: seeded.Seed[1:]}).Expand(params)
+		require.True(t, errors.Is(err, ErrInvalidInput))
+	})
+
+	t.Run(testString(params, "Marshaller/KeySets/"), func(t *testing.T) {
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		skSet := NewSecretKeySet()
+		pkSet := NewPublicKeyKeySet()
+		rlkSet := NewRelinearizationKeySet(params)
+		rtkSet := NewRotationKeySet()
+		cjkSet := NewConjugationKeySet()
+		swkSet := NewSWKSet()
+
+		for _, id := range []string{"user1", "user0"} {
+			sk, pk := kgen.GenKeyPair(id)
+			skSet.AddSecretKey(sk)
+			pkSet.AddPublicKey(pk)
+			rlkSet.AddRelinearizationKey(kgen.GenRelinearizationKey(sk))
+			rtkSet.AddRotationKey(kgen.GenRotationKey(1, sk))
+			rtkSet.AddRotationKey(kgen.GenRotationKey(2, sk))
+			cjkSet.AddConjugationKey(kgen.GenConjugationKey(sk))
+
+			swk, _ := kgen.GenSWK(sk, pk)
+			swkSet.AddSWK(swk)
+		}
+
+		requireMarshalRoundTrip(t, skSet, NewSecretKeySet())
+		requireMarshalRoundTrip(t, pkSet, NewPublicKeyKeySet())
+
+		rlkSetTest := NewRelinearizationKeySet(params)
+		requireMarshalRoundTrip(t, rlkSet, rlkSetTest)
+		require.Equal(t, len(rlkSet.Value), len(rlkSetTest.Value))
+		require.Error(t, new(RelinearizationKeySet).UnmarshalBinary([]byte{0, 0, 0, 0}))
+
+		rtkSetTest := NewRotationKeySet()
+		requireMarshalRoundTrip(t, rtkSet, rtkSetTest)
+		requireSwitchingKeyEqual(t, rtkSet.GetRotationKey("user1", 2).Value, rtkSetTest.GetRotationKey("user1", 2).Value)
+
+		requireMarshalRoundTrip(t, cjkSet, NewConjugationKeySet())
+		requireMarshalRoundTrip(t, swkSet, NewSWKSet())
+
+		id := "group0"
+		group, _ := newTestGroup(kgen, id, 2)
+
+		jkSet := NewJoinKeySet()
+		for j := 0; j < 2; j++ {
+			sk := kgen.GenSecretKey(id)
+			uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+			jkSet.AddJoinKey(group.Join(kgen.GenPartyShares(sk, nil), uaux))
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		jkSetTest := NewJoinKeySet()
+		requireMarshalRoundTrip(t, jkSet, jkSetTest)
+		require.Equal(t, len(jkSet.GetChain(id, 0)), len(jkSetTest.GetChain(id, 0)))
+	})
+}
+
+func testErrors(kgen *KeyGenerator, t *testing.T) {
+
+	params := kgen.params
+
+	t.Run(testString(params, "Errors/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		id := "user0"
+		users := NewIDSet()
+		users.Add(id)
+
+		sk, pk := kgen.GenKeyPair(id)
+
+		// missing keys
+		_, err := NewSecretKeySet().LookupSecretKey(id)
+		require.True(t, errors.Is(err, ErrMissingKey))
+		require.Contains(t, err.Error(), "secret key")
+		_, err = NewPublicKeyKeySet().LookupPublicKey(id)
+		require.True(t, errors.Is(err, ErrMissingKey))
+		_, err = NewRotationKeySet().LookupRotationKey(id, 1)
+		require.True(t, errors.Is(err, ErrMissingKey))
+		_, err = NewRelinearizationKeySet(params).LookupRelinearizationKey(id)
+		require.True(t, errors.Is(err, ErrMissingKey))
+		_, err = NewConjugationKeySet().LookupConjugationKey(id)
+		require.True(t, errors.Is(err, ErrMissingKey))
+
+		require.Panics(t, func() { NewSecretKeySet().GetSecretKey(id) })
+
+		// missing CRS
+		_, err = kgen.TryGenRotationKey(3, sk)
+		require.True(t, errors.Is(err, ErrMissingCRS))
+		rk, err := kgen.TryGenRotationKey(1, sk)
+		require.NoError(t, err)
+		require.Equal(t, uint(1), rk.RotIdx)
+
+		// missing secret key
+		encryptor := NewEncryptor(params)
+		decryptor := NewDecryptor(params)
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		ct := NewCiphertext(params, users, plaintext.Level())
+		encryptor.Encrypt(plaintext, pk, ct)
+		err = decryptor.TryDecrypt(ct, NewSecretKeySet(), plaintext)
+		require.True(t, errors.Is(err, ErrMissingKey))
+
+		skSet := NewSecretKeySet()
+		skSet.AddSecretKey(sk)
+		require.NoError(t, decryptor.TryDecrypt(ct, skSet, plaintext))
+
+		// malformed inputs are rejected before being touched
+		ctBad := ct.CopyNew()
+		ctBad.Value[id] = ring.NewPoly(params.N()/2, ct.Level()+1)
+		require.True(t, errors.Is(CheckCiphertext(params, ctBad), ErrInvalidInput))
+		require.True(t, errors.Is(decryptor.TryDecrypt(ctBad, skSet, plaintext), ErrInvalidInput))
+		delete(ctBad.Value, "0")
+		require.True(t, errors.Is(CheckCiphertext(params, ctBad), ErrInvalidInput))
+
+		ctOther := ct.CopyNew()
+		skOther, _ := kgen.GenKeyPair("user1")
+		require.True(t, errors.Is(decryptor.TryPartialDecrypt(ctOther, skOther), ErrInvalidInput))
+		for _, i := range ct.IDs() {
+			require.True(t, ct.Value[i].Equals(ctOther.Value[i]))
+		}
+		require.True(t, errors.Is(decryptor.TryPartialDecrypt(ctOther, sk), ErrNoiseBudget))
+		require.NoError(t, decryptor.PartialDecryptFresh(ctOther, sk))
+
+		// level mismatches
+		if params.MaxLevel() > 0 {
+			ks := NewKeySwitcher(params)
+			rlkSet := NewRelinearizationKeySet(params)
+			rlkSet.AddRelinearizationKey(kgen.GenRelinearizationKey(sk))
+			rkSet := NewRotationKeySet()
+			rkSet.AddRotationKey(rk)
+
+			ctLow := NewCiphertext(params, users, 0)
+			ctOut := NewCiphertext(params, users, params.MaxLevel())
+			err = ks.CheckMulAndRelin(ctLow, ct, rlkSet, ctOut.Level())
+			require.True(t, errors.Is(err, ErrLevelMismatch))
+			require.Panics(t, func() { ks.MulAndRelin(ctLow, ct, rlkSet, ctOut) })
+			err = ks.CheckRotate(ctLow, 1, rkSet, ctOut.Level())
+			require.True(t, errors.Is(err, ErrLevelMismatch))
+			require.NoError(t, ks.CheckRotate(ct, 1, rkSet, ctOut.Level()))
+			err = ks.CheckRotate(ct, 3, rkSet, ctOut.Level())
+			require.True(t, errors.Is(err, ErrMissingCRS))
+		}
+	})
+}
+
+func testIDSet(kgen *KeyGenerator, t *testing.T) {
+
+	params := kgen.params
+
+	t.Run(testString(params, "IDSet/"), func(t *testing.T) {
+
+		s0 := NewIDSet()
+		for _, id := range []string{"user2", "user0", "user10", "user1"} {
+			s0.Add(id)
+		}
+
+		s1 := NewIDSet()
+		for _, id := range []string{"user1", "user3", "user0"} {
+			s1.Add(id)
+		}
+
+		require.Equal(t, []string{"user0", "user1", "user10", "user2"}, s0.Slice())
+		require.Equal(t, "{user0, user1, user10, user2}", s0.String())
+		require.Equal(t, "{}", NewIDSet().String())
+
+		require.Equal(t, []string{"user10", "user2"}, s0.Difference(s1).Slice())
+		require.Equal(t, []string{"user3"}, s1.Difference(s0).Slice())
+
+		require.True(t, s0.Equal(s0.CopyNew()))
+		require.False(t, s0.Equal(s1))
+		require.False(t, s0.Equal(s0.Intersection(s1)))
+		require.True(t, s0.Intersection(s1).Equal(s1.Intersection(s0)))
+
+		ct := NewCiphertext(params, s0, params.MaxLevel())
+		require.Equal(t, []string{"0", "user0", "user1", "user10", "user2"}, ct.IDs())
+	})
+}
+
+func testPRNG(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks that a session replays deterministically from a seed
+	// 1) build the parameters, keys and ciphertexts twice from keyed PRNGs with the same seed and compare them
+	// 2) check that another seed gives other keys
+
+	params := kgen.params
+
+	t.Run(testString(params, "PRNG/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		session := func(seed []byte) (data [][]byte) {
+			prng, err := utils.NewKeyedPRNG(seed)
+			require.NoError(t, err)
+
+			params := NewParametersWithPRNG(params.Parameters, params.Gamma(), prng)
+			kgen := NewKeyGeneratorWithPRNG(params, prng)
+			encryptor := NewEncryptorWithPRNG(params, prng)
+
+			sk, pk := kgen.GenKeyPair("user0")
+			rlk := kgen.GenRelinearizationKey(sk)
+			skSparse := kgen.GenSecretKeySparse(64, "user1")
+
+			users := NewIDSet()
+			users.Add("user0")
+			plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+			ct := NewCiphertext(params, users, plaintext.Level())
+			encryptor.Encrypt(plaintext, pk, ct)
+			ctSk := NewSeededCiphertext(params, plaintext.Level())
+			encryptor.EncryptSkSeeded(plaintext, sk, ctSk)
+
+			data = append(data, params.CRSSeed(), ctSk.Seed)
+			for _, el := range []interface{ MarshalBinary() ([]byte, error) }{sk, pk, rlk, skSparse, ct, ctSk} {
+				b, err := el.MarshalBinary()
+				require.NoError(t, err)
+				data = append(data, b)
+			}
+			return
+		}
+
+		seed := []byte("mkrlwe known-answer test seed")
+		require.Equal(t, session(seed), session(seed))
+
+		data0 := session(seed)
+		data1 := session([]byte("another seed"))
+		for i := range data0 {
+			require.NotEqual(t, data0[i], data1[i])
+		}
+	})
+}
+
+func testReadOnlyInputs(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks that key generation and encryption leave their inputs untouched
+	// 1) run the keygen and encryption routines concurrently with the same party keys and compare the keys before and after
+	// 2) check that a second encryption does not overwrite the output of the first one
+
+	params := kgen.params
+
+	t.Run(testString(params, "ReadOnlyInputs/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		id := "user0"
+		users := NewIDSet()
+		users.Add(id)
+
+		sk, pk := kgen.GenKeyPair(id)
+		_, swkheadsum := kgen.GenSWK(kgen.GenSecretKey(id), pk)
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+
+		skData, err := sk.MarshalBinary()
+		require.NoError(t, err)
+		pkData, err := pk.MarshalBinary()
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for g := 0; g < 2; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				kgen := NewKeyGenerator(params)
+				encryptor := NewEncryptor(params)
+
+				kgen.GenSWK(sk, pk)
+				kgen.GenSWKTest(sk, sk)
+				kgen.UAuxKeyGen(swkheadsum, sk)
+				kgen.LAuxKeyGen(swkheadsum, sk)
+				kgen.GenRelinearizationKey(sk)
+				kgen.GenConjugationKey(sk)
+				encryptor.EncryptSk(plaintext, sk, NewCiphertext(params, users, plaintext.Level()))
+				encryptor.Encrypt(plaintext, pk, NewCiphertext(params, users, plaintext.Level()))
+			}()
+		}
+		wg.Wait()
+
+		data, err := sk.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, skData, data)
+		data, err = pk.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, pkData, data)
+
+		encryptor := NewEncryptor(params)
+		ct := NewCiphertext(params, users, plaintext.Level())
+		encryptor.EncryptSk(plaintext, sk, ct)
+		ctData, err := ct.MarshalBinary()
+		require.NoError(t, err)
+
+		encryptor.EncryptSk(plaintext, sk, NewCiphertext(params, users, plaintext.Level()))
+		data, err = ct.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, ctData, data)
+	})
+}
+
+func testKSInOut(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks the input/output semantics of KS
+	// 1) switch a group ciphertext after a join and check that the input still decrypts under the former group key
+	// 2) switch it into an output of lower level
+	// 3) switch it in place and compare with the out of place switch
+
+	params := kgen.params
+
+	t.Run(testString(params, "KSInOut/"), func(t *testing.T) {
+
+		if params.PCount() == 0 || params.MaxLevel() == 0 {
+			t.Skip()
+		}
+
+		id := "group0"
+		users := NewIDSet()
+		users.Add(id)
+
+		ringQ := params.RingQ()
+		numParties := 2
+
+		group := NewGroup(params, id)
+		skList := make([]*SecretKey, 0)
+
+		for p := 0; p < numParties; p++ {
+			sk := kgen.GenSecretKey(id)
+			skList = append(skList, sk)
+			group.AddShares(kgen.GenPartyShares(sk, []int{1}))
+		}
+
+		for _, sk := range skList {
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		encryptor := NewEncryptor(params)
+		decryptor := NewDecryptor(params)
+		ks := NewKeySwitcher(params)
+
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		ciphertext := NewCiphertext(params, users, plaintext.Level())
+		encryptor.Encrypt(plaintext, group.PublicKey, ciphertext)
+
+		skSetOld := NewSecretKeySet()
+		skSetOld.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+		sk := kgen.GenSecretKey(id)
+		skList = append(skList, sk)
+		uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+		jk, jkhead := group.Join(kgen.GenPartyShares(sk, []int{1}), uaux)
+
+		skSetNew := NewSecretKeySet()
+		skSetNew.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+		ctData, err := ciphertext.MarshalBinary()
+		require.NoError(t, err)
+
+		ctOut := NewCiphertext(params, users, ciphertext.Level())
+		ks.KS(ciphertext, jk, jkhead, ctOut)
+
+		data, err := ciphertext.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, ctData, data)
+
+		decryptor.Decrypt(ciphertext, skSetOld, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ciphertext.Level(), ringQ, plaintext.Value))
+		decryptor.Decrypt(ctOut, skSetNew, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ctOut.Level(), ringQ, plaintext.Value))
+
+		ctLow := NewCiphertext(params, users, ciphertext.Level()-1)
+		ks.KS(ciphertext, jk, jkhead, ctLow)
+		require.Equal(t, ciphertext.Level()-1, ctLow.Level())
+		decryptor.Decrypt(ctLow, skSetNew, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ctLow.Level(), ringQ, plaintext.Value))
+
+		ks.KS(ciphertext, jk, jkhead, ciphertext)
+		require.True(t, ciphertext.Value["0"].Equals(ctOut.Value["0"]))
+		require.True(t, ciphertext.Value[id].Equals(ctOut.Value[id]))
+
+		require.Panics(t, func() { ks.KS(ctLow, jk, jkhead, NewCiphertext(params, users, ciphertext.Level())) })
+	})
+}
+
+func testSmudging(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks the smudging noise of partial decryptions
+	// 1) check that the digits of the noise add up to the calibrated standard deviation and fit in the moduli
+	// 2) sample a noise of several digits and compare its empirical standard deviation with the calibrated one
+	// 3) check that a smudging noise beyond the budget of the ciphertext is rejected
+	// 4) check that the default partial decryption is smudged and that it still decrypts
+
+	params := kgen.params
+
+	t.Run(testString(params, "Smudging/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		ringQ := params.RingQ()
+		decryptor := NewDecryptor(params)
+		require.Equal(t, DefaultSmudging(), decryptor.Smudging())
+
+		smudging := DefaultSmudging()
+		logNoise := 12.0
+		var variance, scale float64 = 0, 1
+		sigmas := smudging.sigmas(params.N(), logNoise)
+		for i := len(sigmas) - 1; i >= 0; i-- {
+			variance += sigmas[i] * sigmas[i] * scale * scale
+			scale *= math.Exp2(smudgingLogBase)
+			for _, qi := range ringQ.Modulus {
+				require.Less(t, 6*sigmas[i], float64(qi))
+			}
+		}
+		sigma := smudging.Sigma(params.N(), logNoise)
+		require.InDelta(t, 1, math.Sqrt(variance)/sigma, 1e-9)
+		require.Equal(t, math.Exp2(float64(smudging.Security)/2+logNoise)*math.Sqrt(float64(params.N())/2), sigma)
+
+		// a noise of sigma 2^(logN/2 + 24.5) < q0/2^10 is sampled with two digits
+		smudging = Smudging{Security: 10}
+		logNoise = 20
+		require.Len(t, smudging.sigmas(params.N(), logNoise), 2)
+		decryptor.SetSmudging(smudging)
+
+		e := ringQ.NewPoly()
+		decryptor.readSmudgingLvl(0, e, smudging.sigmas(params.N(), logNoise))
+		q0 := ringQ.Modulus[0]
+		var sum float64
+		for _, c := range e.Coeffs[0] {
+			v := float64(c)
+			if c > q0>>1 {
+				v = -float64(q0 - c)
+			}
+			sum += v * v
+		}
+		require.InDelta(t, 1, math.Sqrt(sum/float64(params.N()))/smudging.Sigma(params.N(), logNoise), 0.05)
+
+		require.Panics(t, func() { decryptor.SetSmudging(Smudging{Security: -1}) })
+
+		_, err := decryptor.SmudgingSigma(SmudgingBound{LogNoise: logNoise, LogBudget: logNoise + 10, Shares: 1})
+		require.True(t, errors.Is(err, ErrNoiseBudget))
+
+		id := "user0"
+		users := NewIDSet()
+		users.Add(id)
+
+		sk, pk := kgen.GenKeyPair(id)
+		encryptor := NewEncryptor(params)
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		ciphertext := NewCiphertext(params, users, plaintext.Level())
+		encryptor.Encrypt(plaintext, pk, ciphertext)
+
+		logNorm := func(level int, poly *ring.Poly) (logNorm int) {
+			coeffs := make([]*big.Int, params.N())
+			for i := range coeffs {
+				coeffs[i] = new(big.Int)
+			}
+			ringQ.PolyToBigintCenteredLvl(level, poly, coeffs)
+			for _, c := range coeffs {
+				logNorm = utils.MaxInt(logNorm, c.BitLen())
+			}
+			return
+		}
+
+		skSet := NewSecretKeySet()
+		skSet.AddSecretKey(sk)
+		decryptor.Decrypt(ciphertext, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ciphertext.Level(), ringQ, plaintext.Value))
+
+		// a share beyond the budget is rejected and leaves the ciphertext unchanged
+		decryptor.SetSmudging(Smudging{Security: 2 * params.LogQ()})
+		ctShare := ciphertext.CopyNew()
+		require.True(t, errors.Is(decryptor.PartialDecryptFresh(ctShare, sk), ErrNoiseBudget))
+		require.True(t, ctShare.Value[id].Equals(ciphertext.Value[id]))
+
+		// without the estimate of its noise, the default decryptor refuses to share a partial decryption
+		decryptor = NewDecryptor(params)
+		_, err = decryptor.SmudgingSigma(decryptor.SmudgingBound(ciphertext, nil))
+		require.True(t, errors.Is(err, ErrNoiseBudget))
+		require.True(t, errors.Is(decryptor.TryPartialDecrypt(ctShare, sk), ErrNoiseBudget))
+		require.Panics(t, func() { decryptor.PartialDecrypt(ctShare, sk) })
+		require.True(t, ctShare.Value[id].Equals(ciphertext.Value[id]))
+
+		// the share of a fresh ciphertext carries a noise of about 2^logSigma
+		est := NewNoiseEstimator(params).Encrypt(ciphertext.Level(), id, 1)
+		require.Equal(t, decryptor.SmudgingBound(ciphertext, est), decryptor.FreshSmudgingBound(ciphertext))
+		require.NoError(t, decryptor.PartialDecryptFresh(ctShare, sk))
+		require.Equal(t, 1, len(ctShare.Value))
+		ringQ.ReduceLvl(ctShare.Level(), ctShare.Value["0"], plaintext.Value)
+
+		sigma, err = decryptor.SmudgingSigma(decryptor.FreshSmudgingBound(ciphertext))
+		require.NoError(t, err)
+		logSigma := int(math.Log2(sigma))
+		require.LessOrEqual(t, logSigma, logNorm(ctShare.Level(), plaintext.Value))
+		require.GreaterOrEqual(t, logSigma+4, logNorm(ctShare.Level(), plaintext.Value))
+	})
+}
+
+func testSwitchingKeyError(kgen *KeyGenerator, t *testing.T) {
+
+	// Checks the RLWE error of the switching keys
+	// 1) check that the error-free keys of the insecure option are exactly P * s * g
+	// 2) check that the default keys carry a small nonzero error on top of them
+	// 3) switch a ciphertext to a group key generated with the error and check the decryption precision
+
+	params := kgen.params
+
+	t.Run(testString(params, "SwitchingKeyError/"), func(t *testing.T) {
+
+		if params.PCount() == 0 {
+			t.Skip()
+		}
+
+		id := "group0"
+		users := NewIDSet()
+		users.Add(id)
+
+		ringQ := params.RingQ()
+		ringP := params.RingP()
+		ringQP := params.RingQP()
+		levelQ, levelP := params.QCount()-1, params.PCount()-1
+
+		kgenErrorFree := NewKeyGenerator(params)
+		kgenErrorFree.SetInsecureErrorFreeSwitchingKeys(true)
+
+		sk := kgen.GenSecretKey(id)
+		swk := NewSwitchingKey(params)
+		kgen.GenSwitchingKey(sk, swk)
+		swkErrorFree := NewSwitchingKey(params)
+		kgenErrorFree.GenSwitchingKey(sk, swkErrorFree)
+		swkErrorFree2 := NewSwitchingKey(params)
+		kgenErrorFree.GenSwitchingKey(sk, swkErrorFree2)
+
+		for i := range swk.Value {
+			require.True(t, swkErrorFree.Value[i].Equals(swkErrorFree2.Value[i]))
+
+			e := ringQP.NewPoly()
+			ringQP.SubLvl(levelQ, levelP, swk.Value[i], swkErrorFree.Value[i], e)
+			ringQP.InvMFormLvl(levelQ, levelP, e, e)
+			ringQP.InvNTTLvl(levelQ, levelP, e, e)
+
+			require.False(t, e.Q.Equals(ringQ.NewPoly()))
+			require.GreaterOrEqual(t, 5+params.LogN(), log2OfInnerSum(levelQ, ringQ, e.Q))
+			require.GreaterOrEqual(t, 5+params.LogN(), log2OfInnerSum(levelP, ringP, e.P))
+		}
+
+		numParties := 2
+		group := NewGroup(params, id)
+		skList := make([]*SecretKey, 0)
+
+		for p := 0; p < numParties; p++ {
+			sk := kgen.GenSecretKey(id)
+			skList = append(skList, sk)
+			group.AddShares(kgen.GenPartyShares(sk, []int{1}))
+		}
+
+		for _, sk := range skList {
+			group.AddSWK(kgen.GenSWK(sk, group.PublicKey))
+		}
+
+		encryptor := NewEncryptor(params)
+		decryptor := NewDecryptor(params)
+		ks := NewKeySwitcher(params)
+
+		plaintext := rlwe.NewPlaintext(params.Parameters, params.MaxLevel())
+		ciphertext := NewCiphertext(params, users, plaintext.Level())
+		encryptor.Encrypt(plaintext, group.PublicKey, ciphertext)
+
+		sk = kgen.GenSecretKey(id)
+		skList = append(skList, sk)
+		uaux, _ := kgen.UAuxKeyGen(group.SWKHeadSum, sk)
+		jk, jkhead := group.Join(kgen.GenPartyShares(sk, []int{1}), uaux)
+
+		skSet := NewSecretKeySet()
+		skSet.AddSecretKey(kgen.GenGroupSecretKey(skList))
+
+		ks.KS(ciphertext, jk, jkhead, ciphertext)
+		decryptor.Decrypt(ciphertext, skSet, plaintext)
+		require.GreaterOrEqual(t, 12+params.LogN(), log2OfInnerSum(ciphertext.Level(), ringQ, plaintext.Value))
+	})
+}
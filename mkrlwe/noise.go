@@ -0,0 +1,292 @@
+package mkrlwe
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/ldsec/lattigo/v2/rlwe"
+	"github.com/ldsec/lattigo/v2/utils"
+)
+
+// secretKeyVariance is the variance of the coefficients of a secret key generated with GenSecretKey,
+// and of the ternary polynomials sampled by the Encryptor and the KeyGenerator.
+const secretKeyVariance = 0.5
+
+// NoiseEstimate is the estimated noise of a ciphertext, that is the difference between its phase
+// c0 + sum c_id * s_id and the encoding of its message. Its coefficients are modeled as independent
+// centered random variables of the same variance, as in the usual central limit heuristic.
+type NoiseEstimate struct {
+	Level    int
+	Members  map[string]int // number of parties sharing the secret key of each id, 1 for an individual key
+	Variance float64        // variance of the coefficients of the noise
+}
+
+// NewNoiseEstimate returns the estimate of a noise of the given variance on a ciphertext of the given level and ids.
+func NewNoiseEstimate(level int, members map[string]int, variance float64) *NoiseEstimate {
+	est := &NoiseEstimate{Level: level, Members: make(map[string]int), Variance: variance}
+	for id, n := range members {
+		est.Members[id] = n
+	}
+
+	return est
+}
+
+// CopyNew returns a deep copy of the estimate.
+func (est *NoiseEstimate) CopyNew() *NoiseEstimate {
+	return NewNoiseEstimate(est.Level, est.Members, est.Variance)
+}
+
+// IDSet returns the ids of the ciphertext of the estimate.
+func (est *NoiseEstimate) IDSet() *IDSet {
+	idset := NewIDSet()
+	for id := range est.Members {
+		idset.Add(id)
+	}
+
+	return idset
+}
+
+// Std returns the standard deviation of the coefficients of the noise.
+func (est *NoiseEstimate) Std() float64 {
+	return math.Sqrt(est.Variance)
+}
+
+// LogStd returns the log2 of the standard deviation of the coefficients of the noise.
+func (est *NoiseEstimate) LogStd() float64 {
+	return math.Log2(est.Variance) / 2
+}
+
+// NoiseEstimator follows the noise of ciphertexts through the operations of the KeySwitcher,
+// for the keys generated by the KeyGenerator with the default secret key distribution.
+// Its methods return new estimates and leave their inputs unchanged.
+// The estimates are averages over the randomness of the keys and ciphertexts:
+// they are meant to size parameters, and are checked against actual noises with Decryptor.MeasureNoise.
+type NoiseEstimator struct {
+	params Parameters
+	sigma2 float64
+}
+
+// NewNoiseEstimator creates a new NoiseEstimator.
+func NewNoiseEstimator(params Parameters) *NoiseEstimator {
+	return &NoiseEstimator{params: params, sigma2: params.Sigma() * params.Sigma()}
+}
+
+// SecretKeyVariance returns the variance of the coefficients of sum c_id * s_id over the ids of est,
+// for components c_id of independent coefficients of unit variance.
+// It grows with the number of ids and with the number of members of each group, whose secret is the sum of theirs.
+func (ne *NoiseEstimator) SecretKeyVariance(est *NoiseEstimate) float64 {
+	var members int
+	for _, n := range est.Members {
+		members += n
+	}
+
+	return float64(ne.params.N()) * float64(members) * secretKeyVariance
+}
+
+// RoundingVariance returns the variance of the noise added by rounding each component of a ciphertext of estimate est,
+// as in a rescaling or at the end of an external product.
+func (ne *NoiseEstimator) RoundingVariance(est *NoiseEstimate) float64 {
+	return (1 + ne.SecretKeyVariance(est)) / 12
+}
+
+// Encrypt returns the estimate of a ciphertext of the given level encrypted with the public key of id,
+// shared by the given number of members.
+func (ne *NoiseEstimator) Encrypt(level int, id string, members int) *NoiseEstimate {
+	// u * e + e0 + e1 * s, where e and s are sums over the members
+	N := float64(ne.params.N())
+	variance := ne.sigma2 * (1 + N*secretKeyVariance*float64(members) + N*secretKeyVariance*float64(members))
+
+	return NewNoiseEstimate(level, map[string]int{id: members}, variance)
+}
+
+// EncryptSk returns the estimate of a ciphertext of the given level encrypted with the secret key of id,
+// shared by the given number of members.
+func (ne *NoiseEstimator) EncryptSk(level int, id string, members int) *NoiseEstimate {
+	return NewNoiseEstimate(level, map[string]int{id: members}, ne.sigma2)
+}
+
+// Add returns the estimate of the sum, or of the difference, of two ciphertexts of independent noises.
+func (ne *NoiseEstimator) Add(est0, est1 *NoiseEstimate) *NoiseEstimate {
+	estOut := NewNoiseEstimate(utils.MinInt(est0.Level, est1.Level), est0.Members, est0.Variance+est1.Variance)
+	for id, n := range est1.Members {
+		estOut.Members[id] = utils.MaxInt(estOut.Members[id], n)
+	}
+
+	return estOut
+}
+
+// Rotate returns the estimate of the output of KeySwitcher.Rotate, which switches every component
+// with the sum of the rotation keys of the members of its group.
+func (ne *NoiseEstimator) Rotate(est *NoiseEstimate) *NoiseEstimate {
+	estOut := est.CopyNew()
+	for _, n := range est.Members {
+		estOut.Variance += ne.externalProductVariance(est.Level, float64(n)*ne.sigma2)
+	}
+	estOut.Variance += ne.externalProductRoundingVariance(est)
+
+	return estOut
+}
+
+// Conjugate returns the estimate of the output of KeySwitcher.Conjugate.
+func (ne *NoiseEstimator) Conjugate(est *NoiseEstimate) *NoiseEstimate {
+	return ne.Rotate(est)
+}
+
+// KS returns the estimate of the output of KeySwitcher.KS, which switches every component of est
+// to the secret key of a group of the given number of members, with the sum of the SWK pairs of the members,
+// or with a join key.
+func (ne *NoiseEstimator) KS(est *NoiseEstimate, members int) *NoiseEstimate {
+	// every member adds e + r0 + r2 * e_pk + r1 * s_pk, whose secret and error are sums over the members
+	N := float64(ne.params.N())
+	n := float64(members)
+	keyVariance := n * ne.sigma2 * (2 + 2*N*secretKeyVariance*n)
+
+	estOut := est.CopyNew()
+	for id := range est.Members {
+		estOut.Members[id] = members
+	}
+
+	for range est.Members {
+		estOut.Variance += ne.externalProductVariance(est.Level, keyVariance)
+	}
+	estOut.Variance += ne.externalProductRoundingVariance(estOut)
+
+	return estOut
+}
+
+// MulAndRelin returns the estimate of the output of a multiplication of two ciphertexts of estimates est0 and est1,
+// whose tensor product has a noise of variance tensorVariance, followed by the relinearization of KeySwitcher.MulAndRelin.
+// The noise of the tensor product depends on the scheme.
+func (ne *NoiseEstimator) MulAndRelin(est0, est1 *NoiseEstimate, tensorVariance float64) *NoiseEstimate {
+	estOut := ne.Add(est0, est1)
+	estOut.Variance = tensorVariance + ne.relinVariance(estOut.Level, est0, est1)
+
+	return estOut
+}
+
+// relinVariance returns the variance of the noise added by the relinearization of KeySwitcher.MulAndRelin.
+// It is dominated, for each pair of ids, by the product of the decompositions of both components with the error
+// of the second key of the first id, which is multiplied by the secret key of the second id.
+// The digits of the decompositions lie in [0, D) and are not centered, so that their product has coefficients
+// of the order of N * D^2 / 4 instead of sqrt(N) * D^2 / 12.
+func (ne *NoiseEstimator) relinVariance(level int, est0, est1 *NoiseEstimate) (variance float64) {
+	N := float64(ne.params.N())
+	P := ne.pBigFloat()
+
+	var digits float64
+	for _, D := range ne.digits(level) {
+		d := new(big.Float).Quo(D, P)
+		d.Mul(d, D)
+		d2, _ := d.Float64()
+		digits += d2 * d2 / 48
+	}
+
+	for _, n0 := range est0.Members {
+		for _, n1 := range est1.Members {
+			// s_j * <g^-1(c'_j), g^-1(c_i) * e_i> / P
+			variance += N * N * N * N * digits * ne.sigma2 * float64(n0) * float64(n1) * secretKeyVariance
+		}
+	}
+
+	for _, n := range est0.Members {
+		variance += ne.externalProductVariance(level, float64(n)*ne.sigma2)
+	}
+
+	variance += ne.externalProductRoundingVariance(est0) + ne.externalProductRoundingVariance(est1)
+
+	return variance
+}
+
+// externalProductRoundingVariance returns the variance of the rounding noise of the switch of every component of est,
+// which adds a rounded external product to c0 and replaces c_id by another one.
+func (ne *NoiseEstimator) externalProductRoundingVariance(est *NoiseEstimate) float64 {
+	return (float64(len(est.Members)) + ne.SecretKeyVariance(est)) / 12
+}
+
+// externalProductVariance returns the variance of the noise of KeySwitcher.ExternalProduct with a key of error variance keyVariance.
+// The digits of the decomposition are uniform in [0, D), of second moment D^2 / 3.
+func (ne *NoiseEstimator) externalProductVariance(level int, keyVariance float64) (variance float64) {
+	N := float64(ne.params.N())
+	P := ne.pBigFloat()
+
+	for _, D := range ne.digits(level) {
+		d, _ := new(big.Float).Quo(D, P).Float64()
+		variance += N * d * d / 3 * keyVariance
+	}
+
+	return variance
+}
+
+// digits returns the moduli of the digits of the decomposition of a polynomial at the given level.
+func (ne *NoiseEstimator) digits(level int) (digits []*big.Float) {
+	params := ne.params
+	alpha := params.Alpha()
+	Q := params.Q()
+
+	for i := 0; i < params.Beta(level); i++ {
+		D := new(big.Float).SetUint64(1)
+		for j := i * alpha; j < utils.MinInt((i+1)*alpha, level+1); j++ {
+			D.Mul(D, new(big.Float).SetUint64(Q[j]))
+		}
+		digits = append(digits, D)
+	}
+
+	return digits
+}
+
+func (ne *NoiseEstimator) pBigFloat() *big.Float {
+	return new(big.Float).SetInt(ne.params.RingP().ModulusBigint)
+}
+
+// MeasureNoise decrypts ciphertext with skSet and returns the log2 of the standard deviation and of the maximum
+// of the coefficients of the difference between its phase and plaintext, that is the actual noise of the ciphertext
+// if plaintext is the encoding of its message. It is a debugging tool: it requires the secret keys of every id.
+func (decryptor *Decryptor) MeasureNoise(ciphertext *Ciphertext, skSet *SecretKeySet, plaintext *rlwe.Plaintext) (logStd, logMax float64) {
+	ringQ := decryptor.ringQ
+	level := utils.MinInt(ciphertext.Level(), plaintext.Level())
+
+	phase := rlwe.NewPlaintext(decryptor.params.Parameters, level)
+	decryptor.Decrypt(ciphertext, skSet, phase)
+	if ciphertext.Value["0"].IsNTT {
+		ringQ.InvNTTLvl(level, phase.Value, phase.Value)
+	}
+
+	if plaintext.Value.IsNTT {
+		pt := ringQ.NewPolyLvl(level)
+		ringQ.InvNTTLvl(level, plaintext.Value, pt)
+		ringQ.SubLvl(level, phase.Value, pt, phase.Value)
+	} else {
+		ringQ.SubLvl(level, phase.Value, plaintext.Value, phase.Value)
+	}
+
+	coeffs := make([]*big.Int, ringQ.N)
+	for i := range coeffs {
+		coeffs[i] = new(big.Int)
+	}
+	ringQ.PolyToBigintCenteredLvl(level, phase.Value, coeffs)
+
+	// the coefficients are normalized by the maximum, so that their squares do not overflow
+	max := new(big.Float)
+	for _, c := range coeffs {
+		if f := new(big.Float).SetInt(c); f.Abs(f).Cmp(max) > 0 {
+			max = f
+		}
+	}
+
+	if max.Sign() == 0 {
+		return math.Inf(-1), math.Inf(-1)
+	}
+
+	var sum float64
+	for _, c := range coeffs {
+		f, _ := new(big.Float).Quo(new(big.Float).SetInt(c), max).Float64()
+		sum += f * f
+	}
+
+	mant := new(big.Float)
+	exp := max.MantExp(mant)
+	m, _ := mant.Float64()
+	logMax = math.Log2(m) + float64(exp)
+
+	return logMax + math.Log2(sum/float64(ringQ.N))/2, logMax
+}
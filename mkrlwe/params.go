@@ -1,6 +1,10 @@
 package mkrlwe
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"math"
 
 	"github.com/ldsec/lattigo/v2/ring"
@@ -8,35 +12,62 @@ import (
 	"github.com/ldsec/lattigo/v2/utils"
 )
 
+// CRSSeedSize is the size in bytes of the public seed the CRSs are expanded from.
+const CRSSeedSize = 32
+
+// CRSSeed is the public seed the CRSs are expanded from, encoded as an hexadecimal string in parameters files.
+type CRSSeed []byte
+
+// MarshalText encodes the seed as an hexadecimal string.
+func (seed CRSSeed) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(seed)), nil
+}
+
+// UnmarshalText decodes a seed encoded as an hexadecimal string.
+func (seed *CRSSeed) UnmarshalText(text []byte) (err error) {
+	*seed, err = hex.DecodeString(string(text))
+	return err
+}
+
 type Parameters struct {
 	rlwe.Parameters
-	CRS   map[int]*SwitchingKey
-	gamma int
+	CRS     map[int]*SwitchingKey
+	gamma   int
+	crsSeed []byte
 }
 
-// NewParameters takes rlwe Parameter as input, generate two CRSs
+// NewParameters takes rlwe Parameter as input, generate CRSs from a fresh random seed
 // and then return mkrlwe parameter
 func NewParameters(params rlwe.Parameters, gamma int) Parameters {
-	ret := new(Parameters)
-	ret.Parameters = params
-	ret.gamma = gamma
+	seed := make([]byte, CRSSeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		panic(err)
+	}
 
-	ringQP := params.RingQP()
-	levelQ := params.QCount() - 1
-	levelP := params.PCount() - 1
+	return NewParametersFromSeed(params, gamma, seed)
+}
 
-	alpha := params.PCount() / gamma
-	beta := int(math.Ceil(float64(params.QCount()) / float64(alpha)))
+// NewParametersWithPRNG takes rlwe Parameter as input, generate CRSs from a seed read from the given PRNG
+// and then return mkrlwe parameter
+func NewParametersWithPRNG(params rlwe.Parameters, gamma int, prng utils.PRNG) Parameters {
+	seed := make([]byte, CRSSeedSize)
+	prng.Clock(seed)
 
-	prng, err := utils.NewPRNG()
-	if err != nil {
-		panic(err)
+	return NewParametersFromSeed(params, gamma, seed)
+}
+
+// NewParametersFromSeed takes rlwe Parameter and a public CRS seed as input,
+// expands the CRSs deterministically from the seed and then return mkrlwe parameter.
+// Parties building Parameters from the same rlwe Parameter and seed agree on the CRSs.
+func NewParametersFromSeed(params rlwe.Parameters, gamma int, seed []byte) Parameters {
+	if len(seed) != CRSSeedSize {
+		panic(NewError(ErrInvalidInput, "invalid input: CRS seed must be 32 bytes"))
 	}
-	uniformSamplerQ := ring.NewUniformSampler(prng, params.RingQ())
-	uniformSamplerP := ring.NewUniformSampler(prng, params.RingP())
 
-	// uniformSamplerQ := ring.NewGaussianSampler(prng, params.RingQ(), params.Sigma(), int(6*params.Sigma()))
-	// uniformSamplerP := ring.NewGaussianSampler(prng, params.RingP(), params.Sigma(), int(6*params.Sigma()))
+	ret := new(Parameters)
+	ret.Parameters = params
+	ret.gamma = gamma
+	ret.crsSeed = append([]byte{}, seed...)
 
 	ret.CRS = make(map[int]*SwitchingKey)
 
@@ -53,14 +84,7 @@ func NewParameters(params rlwe.Parameters, gamma int) Parameters {
 
 	// generate CRS for default indexes
 	for _, idx := range idxs {
-		ret.CRS[idx] = new(SwitchingKey)
-		ret.CRS[idx].Value = make([]rlwe.PolyQP, beta)
-		for i := 0; i < beta; i++ {
-			ret.CRS[idx].Value[i] = ringQP.NewPoly()
-			uniformSamplerQ.Read(ret.CRS[idx].Value[i].Q)
-			uniformSamplerP.Read(ret.CRS[idx].Value[i].P)
-			ringQP.MFormLvl(levelQ, levelP, ret.CRS[idx].Value[i], ret.CRS[idx].Value[i])
-		}
+		ret.CRS[idx] = ret.genCRS(idx)
 	}
 
 	return *ret
@@ -80,26 +104,54 @@ func (params Parameters) Gamma() int {
 	return params.gamma
 }
 
+// CRSSeed returns a copy of the public seed the CRSs are expanded from.
+func (params Parameters) CRSSeed() []byte {
+	return append([]byte{}, params.crsSeed...)
+}
+
+// AddCRS expands the CRS of the given index from the CRS seed.
 func (params *Parameters) AddCRS(idx int) {
+	params.CRS[idx] = params.genCRS(idx)
+}
+
+// genCRS expands the CRS of the given index with a PRNG keyed by the CRS seed,
+// the index and the moduli, so that distinct indexes and rings get independent CRSs.
+func (params Parameters) genCRS(idx int) *SwitchingKey {
+	hash := sha256.New()
+	hash.Write(params.crsSeed)
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(int64(idx)))
+	hash.Write(buf)
+	for _, qi := range params.Q() {
+		binary.LittleEndian.PutUint64(buf, qi)
+		hash.Write(buf)
+	}
+	for _, pi := range params.P() {
+		binary.LittleEndian.PutUint64(buf, pi)
+		hash.Write(buf)
+	}
 
-	prng, err := utils.NewPRNG()
+	prng, err := utils.NewKeyedPRNG(hash.Sum(nil))
 	if err != nil {
 		panic(err)
 	}
 	uniformSamplerQ := ring.NewUniformSampler(prng, params.RingQ())
 	uniformSamplerP := ring.NewUniformSampler(prng, params.RingP())
 
+	ringQP := params.RingQP()
 	levelQ := params.QCount() - 1
 	levelP := params.PCount() - 1
 
 	beta := params.Beta(params.MaxLevel())
-	params.CRS[idx] = new(SwitchingKey)
-	params.CRS[idx].Value = make([]rlwe.PolyQP, beta)
+	crs := new(SwitchingKey)
+	crs.Value = make([]rlwe.PolyQP, beta)
 
 	for i := 0; i < beta; i++ {
-		params.CRS[idx].Value[i] = params.RingQP().NewPoly()
-		uniformSamplerQ.Read(params.CRS[idx].Value[i].Q)
-		uniformSamplerP.Read(params.CRS[idx].Value[i].P)
-		params.RingQP().MFormLvl(levelQ, levelP, params.CRS[idx].Value[i], params.CRS[idx].Value[i])
+		crs.Value[i] = ringQP.NewPoly()
+		uniformSamplerQ.Read(crs.Value[i].Q)
+		uniformSamplerP.Read(crs.Value[i].P)
+		ringQP.MFormLvl(levelQ, levelP, crs.Value[i], crs.Value[i])
 	}
+
+	return crs
 }
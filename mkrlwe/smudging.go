@@ -0,0 +1,209 @@
+package mkrlwe
+
+import (
+	"math"
+
+	"github.com/ldsec/lattigo/v2/ring"
+)
+
+// DefaultSmudgingSecurity is the statistical security, in bits, of the smudging noise of a new Decryptor.
+const DefaultSmudgingSecurity = 40
+
+// smudgingLogBase is the log2 of the base of the digits the smudging noise is sampled with.
+const smudgingLogBase = 20
+
+// Smudging parameterizes the noise flooding added to partial decryptions, which hides the noise of the
+// partial decryptions, and thereby the secret key, from the parties the partial decryption is sent to.
+// The width of the noise is derived from the ciphertext being partially decrypted, see SmudgingBound.
+type Smudging struct {
+	// Security is the statistical security in bits. The smudging noise is disabled if it is 0.
+	Security int
+}
+
+// DefaultSmudging returns the smudging parameters of a new Decryptor.
+func DefaultSmudging() Smudging {
+	return Smudging{Security: DefaultSmudgingSecurity}
+}
+
+// SmudgingBound is the noise of a ciphertext together with the budget its smudged partial decryptions must fit in.
+type SmudgingBound struct {
+	// LogNoise is the log2 of a bound on the coefficients of the noise of the ciphertext.
+	LogNoise float64
+	// LogBudget is the log2 of the bound the coefficients of the noise of the decrypted plaintext must stay below.
+	LogBudget float64
+	// Shares is the number of smudged partial decryptions summed up to decrypt the ciphertext.
+	Shares int
+}
+
+// NewSmudgingBound returns the smudging bound of a ciphertext of noise estimate est and the given budget:
+// its noise is bounded by 6 standard deviations, and every member of each of its ids contributes a share.
+func NewSmudgingBound(est *NoiseEstimate, logBudget float64) SmudgingBound {
+	bound := SmudgingBound{LogNoise: math.Log2(6 * est.Std()), LogBudget: logBudget}
+	for _, n := range est.Members {
+		bound.Shares += n
+	}
+
+	return bound
+}
+
+// Sigma returns the standard deviation of the smudging noise in a ring of degree N for a ciphertext noise
+// bounded by 2^logNoise. sigma = 2^(Security/2) * sqrt(N/2) * 2^logNoise, so that the KL divergence between the
+// partial decryptions of two ciphertexts whose noises differ by at most 2^logNoise in each coefficient is at most
+// 2^-Security, which bounds the advantage of a key recovery from the partial decryption, as in
+// Li, Micciancio, Schultz and Sorrell, "Securing Approximate Homomorphic Encryption Using Differential Privacy".
+func (smudging Smudging) Sigma(N int, logNoise float64) float64 {
+	if smudging.Security == 0 {
+		return 0
+	}
+
+	return math.Exp2(float64(smudging.Security)/2+logNoise) * math.Sqrt(float64(N)/2)
+}
+
+// sigmas returns the standard deviations of the digits of the smudging noise, from the most significant one.
+// The noise is sampled as sum e_j * 2^(smudgingLogBase*j), where the lower digits are wide enough
+// to smooth the multiples of 2^smudgingLogBase, so that the sum is statistically close to a Gaussian
+// of standard deviation Sigma(N, logNoise), which can exceed the moduli of the ring.
+func (smudging Smudging) sigmas(N int, logNoise float64) (sigmas []float64) {
+	sigma := smudging.Sigma(N, logNoise)
+	if sigma == 0 {
+		return nil
+	}
+
+	base := math.Exp2(smudgingLogBase)
+	sigmaLow := base * math.Sqrt(float64(smudging.Security+2)*math.Ln2/math.Pi)
+
+	// variance = sigmaTop^2 * base^(2*(digits-1)) + sigmaLow^2 * sum base^(2*j) for j < digits-1
+	variance := sigma * sigma
+	scale := 1.0
+	for variance/(scale*scale) > 4*sigmaLow*sigmaLow {
+		variance -= sigmaLow * sigmaLow * scale * scale
+		scale *= base
+		sigmas = append(sigmas, sigmaLow)
+	}
+
+	sigmas = append(sigmas, math.Sqrt(math.Max(variance, 0))/scale)
+	for i, j := 0, len(sigmas)-1; i < j; i, j = i+1, j-1 {
+		sigmas[i], sigmas[j] = sigmas[j], sigmas[i]
+	}
+
+	return sigmas
+}
+
+// SetSmudging sets the smudging noise added by PartialDecrypt and PartialDecryptIP.
+// Disabling it with a zero Security is insecure as soon as partial decryptions are shared.
+func (decryptor *Decryptor) SetSmudging(smudging Smudging) {
+	if smudging.Security < 0 {
+		panic(NewError(ErrInvalidInput, "invalid input: negative smudging security"))
+	}
+
+	decryptor.smudging = smudging
+}
+
+// Smudging returns the smudging parameters of the decryptor.
+func (decryptor *Decryptor) Smudging() Smudging {
+	return decryptor.smudging
+}
+
+// SmudgingBound returns the smudging bound of ct for the generic RLWE scheme: its noise is given by est,
+// and the noise of its decryption must stay below half the modulus at its level.
+// If est is nil, the noise of ct is unknown and the bound is infinite, so that no smudging noise fits in it:
+// the noise of a ciphertext which went through evaluation is only known from its estimate.
+func (decryptor *Decryptor) SmudgingBound(ct *Ciphertext, est *NoiseEstimate) SmudgingBound {
+	if est == nil {
+		return SmudgingBound{LogNoise: math.Inf(1), LogBudget: decryptor.logHalfQ(ct.Level()), Shares: len(ct.IDSet().Slice())}
+	}
+
+	return NewSmudgingBound(est, decryptor.logHalfQ(ct.Level()))
+}
+
+// FreshSmudgingBound returns the smudging bound of ct assuming it is a fresh encryption under the public key of each of its ids.
+// It underestimates the noise of a ciphertext which went through evaluation, whose partial decryptions it under-smudges.
+func (decryptor *Decryptor) FreshSmudgingBound(ct *Ciphertext) SmudgingBound {
+	level := ct.Level()
+
+	var est *NoiseEstimate
+	ne := NewNoiseEstimator(decryptor.params)
+	for _, id := range ct.IDSet().Slice() {
+		if est == nil {
+			est = ne.Encrypt(level, id, 1)
+		} else {
+			est = ne.Add(est, ne.Encrypt(level, id, 1))
+		}
+	}
+
+	return decryptor.SmudgingBound(ct, est)
+}
+
+// logHalfQ returns the log2 of half the modulus at the given level.
+func (decryptor *Decryptor) logHalfQ(level int) (logQ float64) {
+	for _, qi := range decryptor.ringQ.Modulus[:level+1] {
+		logQ += math.Log2(float64(qi))
+	}
+
+	return logQ - 1
+}
+
+// SmudgingSigma returns the standard deviation of the smudging noise added to the partial decryptions of a ciphertext
+// of the given bound, or an error of kind ErrNoiseBudget if the smudging security of the decryptor does not fit in its budget.
+func (decryptor *Decryptor) SmudgingSigma(bound SmudgingBound) (float64, error) {
+	if _, err := decryptor.smudgingSigmas(bound); err != nil {
+		return 0, err
+	}
+
+	return decryptor.smudging.Sigma(decryptor.params.N(), bound.LogNoise), nil
+}
+
+// smudgingSigmas returns the standard deviations of the digits of the smudging noise for a ciphertext of the given bound,
+// or an error of kind ErrNoiseBudget if the noise of the decryption exceeds its budget once the shares are smudged.
+func (decryptor *Decryptor) smudgingSigmas(bound SmudgingBound) ([]float64, error) {
+	smudging := decryptor.smudging
+	sigma := smudging.Sigma(decryptor.params.N(), bound.LogNoise)
+	if sigma == 0 {
+		return nil, nil
+	}
+
+	if math.IsInf(bound.LogNoise, 1) {
+		return nil, NewError(ErrNoiseBudget, "Cannot PartialDecrypt: the noise of the ciphertext is unknown, its estimate must be given")
+	}
+
+	shares := bound.Shares
+	if shares < 1 {
+		shares = 1
+	}
+
+	logNoise := math.Log2(math.Exp2(bound.LogNoise) + 6*sigma*math.Sqrt(float64(shares)))
+	if !(logNoise < bound.LogBudget) {
+		return nil, NewError(ErrNoiseBudget, "Cannot PartialDecrypt: a smudging noise of %d bits of security takes the noise to 2^%.1f, beyond the budget 2^%.1f of the ciphertext",
+			smudging.Security, logNoise, bound.LogBudget)
+	}
+
+	sigmas := smudging.sigmas(decryptor.params.N(), bound.LogNoise)
+	for _, sigma := range sigmas {
+		for _, qi := range decryptor.ringQ.Modulus {
+			if 6*sigma >= float64(qi) {
+				return nil, NewError(ErrInvalidInput, "invalid input: smudging noise digits exceed the moduli")
+			}
+		}
+	}
+
+	return sigmas, nil
+}
+
+// readSmudgingLvl samples the smudging noise of the given digits on pol at the given level.
+func (decryptor *Decryptor) readSmudgingLvl(level int, pol *ring.Poly, sigmas []float64) {
+	ringQ := decryptor.ringQ
+
+	if len(sigmas) == 0 {
+		pol.Zero()
+		return
+	}
+
+	for j, sigma := range sigmas {
+		if j == 0 {
+			decryptor.NFgaussianSamplerQ.ReadFromDistLvl(level, pol, ringQ, sigma, uint64(6*sigma))
+		} else {
+			ringQ.MulScalarLvl(level, pol, 1<<smudgingLogBase, pol)
+			decryptor.NFgaussianSamplerQ.ReadAndAddFromDistLvl(level, pol, ringQ, sigma, uint64(6*sigma))
+		}
+	}
+}